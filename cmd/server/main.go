@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"project/internal/api"
+	"project/internal/events"
 	"project/internal/router"
+	"project/internal/security"
 	"project/internal/storage"
+	"project/internal/telegrambot"
+	"project/internal/webhooks"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,9 +32,30 @@ func main() {
 	if err := storage.LoadObjects(); err != nil {
 		log.Fatalf("Failed to load objects: %v", err)
 	}
+	if err := storage.LoadMarkTypes(); err != nil {
+		log.Fatalf("Failed to load mark types: %v", err)
+	}
+	if err := storage.LoadLeaveEntitlements(); err != nil {
+		log.Fatalf("Failed to load leave entitlements: %v", err)
+	}
+	if err := storage.LoadLeaveRequests(); err != nil {
+		log.Fatalf("Failed to load leave requests: %v", err)
+	}
+	if err := storage.LoadCalendar(); err != nil {
+		log.Fatalf("Failed to load production calendar: %v", err)
+	}
+	if err := storage.LoadPayroll(); err != nil {
+		log.Fatalf("Failed to load payroll: %v", err)
+	}
 	if err := storage.LoadTimesheets(); err != nil {
 		log.Fatalf("Failed to load timesheets: %v", err)
 	}
+	if err := storage.LoadMonthClosures(); err != nil {
+		log.Fatalf("Failed to load month closures: %v", err)
+	}
+	if err := storage.LoadAttendance(); err != nil {
+		log.Fatalf("Failed to load attendance: %v", err)
+	}
 	if err := storage.LoadImprovements(); err != nil {
 		log.Fatalf("Failed to load improvements: %v", err)
 	}
@@ -32,14 +65,58 @@ func main() {
 	if err := storage.LoadTelegramContacts(); err != nil {
 		log.Fatalf("Failed to load telegram contacts: %v", err)
 	}
+	if err := storage.LoadAPITokens(); err != nil {
+		log.Fatalf("Failed to load API tokens: %v", err)
+	}
+	if err := storage.LoadWebhooks(); err != nil {
+		log.Fatalf("Failed to load webhooks: %v", err)
+	}
+	if err := storage.LoadICSFeeds(); err != nil {
+		log.Fatalf("Failed to load calendar feeds: %v", err)
+	}
+	if err := storage.LoadPasswordLinks(); err != nil {
+		log.Fatalf("Failed to load password links: %v", err)
+	}
+	security.SubscribeAudit()
+	telegrambot.Subscribe()
+	webhooks.Subscribe()
+	webhooks.Resume()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// TELEGRAM_API_URL points the bot at a self-hosted or fake Bot API server
+	telegrambot.SetAPIBaseURL(os.Getenv("TELEGRAM_API_URL"))
+	telegrambot.Start(ctx)
 
 	r := gin.Default()
 
 	// Setup all routes from the router package
 	router.SetupRouter(r)
+	if missing := api.UndocumentedRoutes(r.Routes()); len(missing) > 0 {
+		log.Printf("Warning: routes missing from the OpenAPI description: %v", missing)
+	}
+
+	server := &http.Server{Addr: ":8099", Handler: r}
+	go func() {
+		log.Println("Starting HTTP server on port 8099")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start HTTP server: %v", err)
+		}
+	}()
 
-	log.Println("Starting HTTP server on port 8099")
-	if err := r.Run(":8099"); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down")
+
+	// Finish the requests and the bot update in progress, then let subscribers handle the last events.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if err := telegrambot.Stop(shutdownCtx); err != nil {
+		log.Printf("Telegram worker shutdown: %v", err)
 	}
+	events.Flush()
 }
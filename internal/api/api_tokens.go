@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func tokenScopeLabel(scope string) string {
+	switch scope {
+	case models.TokenScopeRead:
+		return "Чтение"
+	case models.TokenScopeScheduleWrite:
+		return "Запись расписания"
+	case models.TokenScopeExport:
+		return "Выгрузки"
+	default:
+		return scope
+	}
+}
+
+func humanizeAPITokenError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "name is required"):
+		return "Укажите название токена."
+	case strings.Contains(msg, "scope is required"):
+		return "Выберите хотя бы одно право."
+	case strings.Contains(msg, "unknown token scope"):
+		return "Неизвестное право токена."
+	case strings.Contains(msg, "user not found"):
+		return "Пользователь токена не найден."
+	default:
+		return "Не удалось сохранить токен: " + msg
+	}
+}
+
+// APITokensPage lists the API tokens and issues new ones.
+func APITokensPage(c *gin.Context) {
+	renderAPITokensPage(c, "")
+}
+
+// renderAPITokensPage shows the page; secret is the just-issued token, displayed this one time only.
+func renderAPITokensPage(c *gin.Context, secret string) {
+	tokens, err := storage.GetAPITokens()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load tokens: %v", err)
+		return
+	}
+	users, err := storage.GetUsers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load users: %v", err)
+		return
+	}
+	userNames := map[string]string{}
+	var userOptions strings.Builder
+	for _, user := range users {
+		userNames[user.ID] = user.Name
+		selected := ""
+		if user.ID == c.GetString("userID") {
+			selected = " selected"
+		}
+		userOptions.WriteString(`<option value="` + template.HTMLEscapeString(user.ID) + `"` + selected + `>` + template.HTMLEscapeString(user.Name+" ("+userStatusLabel(user.Status)+")") + `</option>`)
+	}
+
+	var rows strings.Builder
+	for _, token := range tokens {
+		scopes := make([]string, 0, len(token.Scopes))
+		for _, scope := range token.Scopes {
+			scopes = append(scopes, tokenScopeLabel(scope))
+		}
+		lastUsed := "—"
+		if token.LastUsedAt != "" {
+			lastUsed = formatClosureTime(token.LastUsedAt)
+		}
+		action := `<form method="POST" action="/settings/tokens/revoke/` + template.HTMLEscapeString(token.ID) + `">` + CSRFHiddenInput(c) + `<button type="submit" class="btn btn-danger btn-compact" onclick="return confirm('Отозвать токен? Скрипты с ним перестанут работать.')">Отозвать</button></form>`
+		if token.RevokedAt != "" {
+			action = `<span class="status-badge">отозван ` + template.HTMLEscapeString(formatClosureTime(token.RevokedAt)) + `</span>`
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td><strong>%s</strong><br><code>%s…</code></td><td>%s</td><td>%s</td><td>%s<br><span class="leave-balance-meta">%s</span></td><td>%s</td><td>%s</td></tr>`,
+			template.HTMLEscapeString(token.Name),
+			template.HTMLEscapeString(token.Prefix),
+			template.HTMLEscapeString(strings.Join(scopes, ", ")),
+			template.HTMLEscapeString(userNames[token.UserID]),
+			template.HTMLEscapeString(formatClosureTime(token.CreatedAt)),
+			template.HTMLEscapeString(token.CreatedByName),
+			template.HTMLEscapeString(lastUsed),
+			action))
+	}
+	if len(tokens) == 0 {
+		rows.WriteString(`<tr><td colspan="6">Токенов пока нет.</td></tr>`)
+	}
+
+	var scopeBoxes strings.Builder
+	for _, scope := range storage.APITokenScopes() {
+		scopeBoxes.WriteString(`<label><input type="checkbox" name="scopes" value="` + template.HTMLEscapeString(scope) + `"` + checkedAttr(scope == models.TokenScopeRead) + `> ` + template.HTMLEscapeString(tokenScopeLabel(scope)) + `</label>`)
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+	if secret != "" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Токен создан</strong><p>Скопируйте его сейчас — больше он показан не будет:</p><p><code>` + template.HTMLEscapeString(secret) + `</code></p><p>Передавайте в заголовке <code>Authorization: Bearer &lt;токен&gt;</code>.</p></div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>API-токены</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/settings" class="back-link">← К настройкам</a>
+<div class="page-header"><h1>API-токены</h1><p>Для скриптов и интеграций вместо входа через браузер. Токен действует с правами выбранного пользователя, но только в пределах отмеченных прав: «Чтение» — GET-запросы JSON API <code>/api/v1</code>; «Запись расписания» — создание, изменение и удаление назначений через <code>/api/v1/schedule</code>; «Выгрузки» — Excel, Т-13, PDF и файл для бухгалтерии. Страницы сервиса и настройки токен не открывает. Описание всех адресов — в <a href="/api/docs">документации API</a>.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><h2>Новый токен</h2>
+<form method="POST" action="/settings/tokens" class="mark-type-form">{{CSRF_FIELD}}
+<input type="text" name="name" placeholder="Название, напр. «Выгрузка в 1С»" required>
+<select name="user_id" title="Пользователь">{{USER_OPTIONS}}</select>
+{{SCOPES}}
+<button type="submit" class="btn btn-primary">Создать</button>
+</form></div>
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Токен</th><th>Права</th><th>Пользователь</th><th>Создан</th><th>Последнее использование</th><th></th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "settings"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	final = strings.Replace(final, "{{USER_OPTIONS}}", userOptions.String(), 1)
+	final = strings.Replace(final, "{{SCOPES}}", scopeBoxes.String(), 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func CreateAPIToken(c *gin.Context) {
+	token, secret, err := storage.CreateAPIToken(c.PostForm("name"), c.PostFormArray("scopes"), c.PostForm("user_id"), c.GetString("userName"))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/settings/tokens?error="+url.QueryEscape(humanizeAPITokenError(err)))
+		return
+	}
+	security.LogEvent("api_token_created", fmt.Sprintf("token=%s name=%q scopes=%s by=%s", token.Prefix, token.Name, strings.Join(token.Scopes, ","), c.GetString("userName")))
+	renderAPITokensPage(c, secret)
+}
+
+func RevokeAPIToken(c *gin.Context) {
+	token, err := storage.RevokeAPIToken(c.Param("id"))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/settings/tokens?error="+url.QueryEscape(humanizeAPITokenError(err)))
+		return
+	}
+	security.LogEvent("api_token_revoked", fmt.Sprintf("token=%s name=%q by=%s", token.Prefix, token.Name, c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/tokens")
+}
@@ -0,0 +1,516 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attendanceDeviationHours is the plan/fact difference that gets highlighted in the табель.
+const attendanceDeviationHours = 0.25
+
+func humanizeAttendanceError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.TrimSpace(err.Error())
+	switch {
+	case strings.Contains(msg, "already clocked in"):
+		return "Приход на это назначение уже отмечен."
+	case strings.Contains(msg, "already clocked out"):
+		return "Уход уже отмечен."
+	case strings.Contains(msg, "not clocked in"):
+		return "Сначала отметьте приход."
+	case strings.Contains(msg, "worker is not assigned"):
+		return "Вы не назначены на эту работу."
+	case strings.Contains(msg, "day of the entry"):
+		return "Приход отмечается только в день назначения."
+	case strings.Contains(msg, "month is closed"):
+		return "Месяц закрыт, отметки не принимаются."
+	case strings.Contains(msg, "invalid coordinates"):
+		return "Устройство передало некорректные координаты."
+	case strings.Contains(msg, "special mark"):
+		return "На отметку (отпуск, больничный и т.п.) нельзя отметить приход."
+	case strings.Contains(msg, "timesheet entry not found"), strings.Contains(msg, "not found"):
+		return "Назначение не найдено."
+	default:
+		return humanizeScheduleError(err)
+	}
+}
+
+func attendanceStatusLabel(status string) string {
+	switch status {
+	case "confirmed":
+		return "Подтверждено"
+	case "closed":
+		return "Ждёт подтверждения"
+	default:
+		return "На смене"
+	}
+}
+
+// attendanceHours returns the worked hours of a closed record, or false while the shift is still open.
+func attendanceHours(record models.AttendanceRecord) (float64, bool) {
+	if strings.TrimSpace(record.EndTime) == "" {
+		return 0, false
+	}
+	hours, err := strconv.ParseFloat(formatWorkHours(record.StartTime, record.EndTime, record.LunchBreakMinutes), 64)
+	if err != nil {
+		return 0, false
+	}
+	return hours, true
+}
+
+func attendanceKey(entryID, workerID string) string {
+	return entryID + "|" + workerID
+}
+
+func buildAttendanceMap() (map[string]models.AttendanceRecord, error) {
+	records, err := storage.GetAttendanceRecords()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]models.AttendanceRecord, len(records))
+	for _, record := range records {
+		result[attendanceKey(record.EntryID, record.WorkerID)] = record
+	}
+	return result, nil
+}
+
+// canSuperviseEntry allows admins and the responsible user of any object of the entry
+// to confirm actual hours and review pending entries.
+func canSuperviseEntry(c *gin.Context, entry models.TimesheetEntry, objects map[string]models.Object) bool {
+	if isAdmin(c) {
+		return true
+	}
+	userID := c.GetString("userID")
+	for _, oid := range entry.ObjectIDs {
+		if object, ok := objects[oid]; ok && object.ResponsibleUserID != "" && object.ResponsibleUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// renderClockButtons shows clock-in for the current user's own assignment on today's date and clock-out
+// while the shift is open, also after midnight.
+func renderClockButtons(c *gin.Context, entry models.TimesheetEntry, ownWorkerID string, records map[string]models.AttendanceRecord, returnPath string) string {
+	if ownWorkerID == "" || isSpecialMark(entry.UserMark) {
+		return ""
+	}
+	if record, ok := records[attendanceKey(entry.ID, ownWorkerID)]; entry.Date != time.Now().Format("2006-01-02") && (!ok || record.Status != "open") {
+		return ""
+	}
+	assigned := false
+	for _, wid := range entry.WorkerIDs {
+		if wid == ownWorkerID {
+			assigned = true
+			break
+		}
+	}
+	if !assigned {
+		return ""
+	}
+	hidden := CSRFHiddenInput(c) + `<input type="hidden" name="return_to" value="` + template.HTMLEscapeString(returnPath) + `">`
+	record, ok := records[attendanceKey(entry.ID, ownWorkerID)]
+	switch {
+	case !ok:
+		return `<form method="POST" action="/attendance/clock-in/` + template.HTMLEscapeString(entry.ID) + `" data-geo-checkin>` + hidden + `<input type="hidden" name="latitude"><input type="hidden" name="longitude"><input type="hidden" name="accuracy"><button type="submit" class="btn btn-primary btn-compact">Пришёл</button></form>`
+	case record.Status == "open":
+		return `<span class="status-badge">С ` + template.HTMLEscapeString(record.StartTime) + `</span><form method="POST" action="/attendance/clock-out/` + template.HTMLEscapeString(entry.ID) + `">` + hidden + `<button type="submit" class="btn btn-secondary btn-compact">Ушёл</button></form>`
+	default:
+		return `<span class="status-badge">Факт ` + template.HTMLEscapeString(record.StartTime+"–"+record.EndTime) + `</span>`
+	}
+}
+
+// lunchBreakSelect offers the same lunch breaks as the timesheet form; storage accepts only these.
+func lunchBreakSelect(selected int) string {
+	var b strings.Builder
+	b.WriteString(`<select name="lunch_break_minutes" title="Обед">`)
+	for _, option := range []struct {
+		minutes int
+		label   string
+	}{{0, "Без обеда"}, {30, "30 минут"}, {60, "60 минут"}, {90, "90 минут"}} {
+		attr := ""
+		if option.minutes == selected {
+			attr = " selected"
+		}
+		fmt.Fprintf(&b, `<option value="%d"%s>%s</option>`, option.minutes, attr, option.label)
+	}
+	b.WriteString(`</select>`)
+	return b.String()
+}
+
+// geoCheckInScript fills the location fields of clock-in forms from the device before submitting.
+// Without permission or on timeout the form is still sent, the record is then kept without location.
+const geoCheckInScript = `<script>(function(){
+document.querySelectorAll('form[data-geo-checkin]').forEach(function(form){
+  form.addEventListener('submit', function(ev){
+    if(form.dataset.geoDone || !navigator.geolocation) return;
+    ev.preventDefault();
+    var btn=form.querySelector('button[type="submit"]');
+    if(btn) btn.disabled=true;
+    var send=function(){ form.dataset.geoDone='1'; form.submit(); };
+    navigator.geolocation.getCurrentPosition(function(pos){
+      form.querySelector('input[name="latitude"]').value=pos.coords.latitude;
+      form.querySelector('input[name="longitude"]').value=pos.coords.longitude;
+      form.querySelector('input[name="accuracy"]').value=pos.coords.accuracy;
+      send();
+    }, send, {enableHighAccuracy:true, timeout:15000, maximumAge:60000});
+  });
+});
+})();</script>`
+
+// parseCheckInLocation reads an optional device position; nil means the device did not report one.
+func parseCheckInLocation(latitude, longitude, accuracy string) (*models.AttendanceLocation, error) {
+	if strings.TrimSpace(latitude) == "" && strings.TrimSpace(longitude) == "" {
+		return nil, nil
+	}
+	lat, err := parseCoordinate(latitude)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, errors.New("invalid coordinates")
+	}
+	lon, err := parseCoordinate(longitude)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil, errors.New("invalid coordinates")
+	}
+	acc, _ := parseCoordinate(accuracy)
+	return &models.AttendanceLocation{Latitude: lat, Longitude: lon, AccuracyMeters: acc}, nil
+}
+
+func geofenceLabel(record models.AttendanceRecord, objectsMap map[string]string) (string, string) {
+	if record.Location == nil {
+		if record.Source == "telegram" {
+			return "Telegram, без геоданных", ""
+		}
+		return "Нет геоданных", "attendance-no-location"
+	}
+	if !record.Location.Verified {
+		return "Геозона объекта не задана", ""
+	}
+	where := objectsMap[record.Location.ObjectID]
+	if record.Location.OutOfZone {
+		return fmt.Sprintf("Вне зоны: %d м от «%s»", record.Location.DistanceMeters, where), "attendance-out-of-zone"
+	}
+	return fmt.Sprintf("В зоне (%d м)", record.Location.DistanceMeters), ""
+}
+
+func attendanceReturnTo(c *gin.Context) string {
+	returnTo := c.PostForm("return_to")
+	if !strings.HasPrefix(returnTo, "/") {
+		returnTo = "/schedule"
+	}
+	return returnTo
+}
+
+func redirectWithAttendanceError(c *gin.Context, returnTo string, err error) {
+	separator := "?"
+	if strings.Contains(returnTo, "?") {
+		separator = "&"
+	}
+	c.Redirect(http.StatusFound, returnTo+separator+"attendance_error="+url.QueryEscape(humanizeAttendanceError(err)))
+}
+
+// ClockIn marks the start of the current user's own shift on a planned entry.
+func ClockIn(c *gin.Context) {
+	returnTo := attendanceReturnTo(c)
+	worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+	if err != nil {
+		c.String(http.StatusForbidden, "Нет привязанного работника")
+		return
+	}
+	location, err := parseCheckInLocation(c.PostForm("latitude"), c.PostForm("longitude"), c.PostForm("accuracy"))
+	if err != nil {
+		redirectWithAttendanceError(c, returnTo, err)
+		return
+	}
+	if _, err := storage.ClockIn(c.Param("id"), worker.ID, "web", time.Now(), location); err != nil {
+		redirectWithAttendanceError(c, returnTo, err)
+		return
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+type checkInRequest struct {
+	EntryID   string  `json:"entryId"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy"`
+}
+
+// CheckIn is the PWA geolocation endpoint: it clocks the worker in on today's assignment
+// (the given one, or the one whose object is closest) and reports the geofence verdict.
+func CheckIn(c *gin.Context) {
+	var req checkInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос"})
+		return
+	}
+	location, err := parseCheckInLocation(strconv.FormatFloat(req.Latitude, 'f', -1, 64), strconv.FormatFloat(req.Longitude, 'f', -1, 64), strconv.FormatFloat(req.Accuracy, 'f', -1, 64))
+	if err != nil || location == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные координаты"})
+		return
+	}
+	worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Нет привязанного работника"})
+		return
+	}
+
+	entryID := strings.TrimSpace(req.EntryID)
+	if entryID == "" {
+		entries, err := storage.GetTimesheets()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		today := time.Now().Format("2006-01-02")
+		bestDistance := -1
+		for _, entry := range entries {
+			if entry.Date != today || isSpecialMark(entry.UserMark) || !entryHasWorker(entry, worker.ID) {
+				continue
+			}
+			if _, err := storage.FindAttendance(entry.ID, worker.ID); err == nil {
+				continue
+			}
+			verdict := storage.EvaluateGeofence(entry, location.Latitude, location.Longitude, location.AccuracyMeters)
+			distance := verdict.DistanceMeters
+			if !verdict.Verified {
+				distance = math.MaxInt32
+			}
+			if bestDistance < 0 || distance < bestDistance {
+				bestDistance = distance
+				entryID = entry.ID
+			}
+		}
+		if entryID == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "На сегодня нет назначений для отметки"})
+			return
+		}
+	}
+
+	record, err := storage.ClockIn(entryID, worker.ID, "web", time.Now(), location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": humanizeAttendanceError(err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "attendance": record})
+}
+
+// ClockOut closes the current user's open shift on a planned entry.
+func ClockOut(c *gin.Context) {
+	returnTo := attendanceReturnTo(c)
+	worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+	if err != nil {
+		c.String(http.StatusForbidden, "Нет привязанного работника")
+		return
+	}
+	if _, err := storage.ClockOut(c.Param("id"), worker.ID, time.Now()); err != nil {
+		redirectWithAttendanceError(c, returnTo, err)
+		return
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// AttendancePage is the foreman queue of actual hours waiting for confirmation.
+func AttendancePage(c *gin.Context) {
+	records, err := storage.GetAttendanceRecords()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	objectsByID := make(map[string]models.Object, len(objects))
+	objectsMap := make(map[string]string, len(objects))
+	for _, object := range objects {
+		objectsByID[object.ID] = object
+		objectsMap[object.ID] = object.Name
+	}
+	workersMap, err := buildWorkersMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	ownWorkerID := ""
+	if worker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil {
+		ownWorkerID = worker.ID
+	}
+
+	selectedMonth, _, _ := resolveSelectedMonth(c.Query("month"))
+	onlyPending := c.Query("all") == ""
+
+	var rows strings.Builder
+	pending := 0
+	outOfZone := 0
+	for _, record := range records {
+		if !strings.HasPrefix(record.Date, selectedMonth+"-") {
+			continue
+		}
+		entry, err := storage.GetTimesheetByID(record.EntryID)
+		if err != nil {
+			continue
+		}
+		canConfirm := canSuperviseEntry(c, entry, objectsByID)
+		if !canConfirm && record.WorkerID != ownWorkerID {
+			continue
+		}
+		if record.Status == "closed" {
+			pending++
+		}
+		if onlyPending && record.Status == "confirmed" {
+			continue
+		}
+
+		planHours := formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes)
+		factHours := "—"
+		deviation := "—"
+		rowClass := ""
+		if hours, ok := attendanceHours(record); ok {
+			factHours = fmt.Sprintf("%.2f", hours)
+			if plan, err := strconv.ParseFloat(planHours, 64); err == nil {
+				diff := hours - plan
+				deviation = fmt.Sprintf("%+.2f", diff)
+				if math.Abs(diff) > attendanceDeviationHours {
+					rowClass = ` class="attendance-deviation"`
+				}
+			}
+		}
+
+		action := template.HTMLEscapeString(record.ConfirmedByName)
+		if action == "" {
+			action = "—"
+		}
+		if canConfirm {
+			endValue := record.EndTime
+			if endValue == "" {
+				endValue = entry.EndTime
+			}
+			action = fmt.Sprintf(`<form method="POST" action="/attendance/confirm/%s" class="attendance-confirm-form">%s<input type="hidden" name="return_to" value="%s"><input type="time" name="start_time" value="%s" required><input type="time" name="end_time" value="%s" required>%s<input type="text" name="notes" value="%s" placeholder="Комментарий"><button type="submit" class="btn btn-primary btn-compact">Подтвердить</button></form>`,
+				template.HTMLEscapeString(record.ID),
+				CSRFHiddenInput(c),
+				template.HTMLEscapeString("/attendance?month="+selectedMonth),
+				template.HTMLEscapeString(record.StartTime),
+				template.HTMLEscapeString(endValue),
+				lunchBreakSelect(record.LunchBreakMinutes),
+				template.HTMLEscapeString(record.Notes),
+			)
+		}
+
+		geoLabel, geoClass := geofenceLabel(record, objectsMap)
+		if geoClass == "attendance-out-of-zone" {
+			outOfZone++
+		}
+
+		rows.WriteString(fmt.Sprintf(`<tr%s><td>%s</td><td>%s</td><td>%s</td><td>%s–%s · %s ч</td><td>%s–%s · %s ч</td><td>%s</td><td class="%s">%s</td><td><span class="status-badge">%s</span></td><td>%s</td></tr>`,
+			rowClass,
+			template.HTMLEscapeString(formatScheduleDateLabel(record.Date)),
+			joinMappedLinks([]string{record.WorkerID}, workersMap, "/worker"),
+			joinMappedValues(entry.ObjectIDs, objectsMap),
+			template.HTMLEscapeString(entry.StartTime),
+			template.HTMLEscapeString(entry.EndTime),
+			template.HTMLEscapeString(planHours),
+			template.HTMLEscapeString(record.StartTime),
+			template.HTMLEscapeString(record.EndTime),
+			template.HTMLEscapeString(factHours),
+			template.HTMLEscapeString(deviation),
+			geoClass,
+			template.HTMLEscapeString(geoLabel),
+			template.HTMLEscapeString(attendanceStatusLabel(record.Status)),
+			action,
+		))
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="9">Нет отметок за выбранный месяц.</td></tr>`)
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+	toggleLink := `<a class="btn btn-secondary" href="/attendance?all=1&month=` + template.URLQueryEscaper(selectedMonth) + `">Показать все</a>`
+	if !onlyPending {
+		toggleLink = `<a class="btn btn-secondary" href="/attendance?month=` + template.URLQueryEscaper(selectedMonth) + `">Только неподтверждённые</a>`
+	}
+	outOfZoneBadge := ""
+	if outOfZone > 0 {
+		outOfZoneBadge = `<span class="status-badge badge-warning">Вне зоны: ` + strconv.Itoa(outOfZone) + `</span>`
+	}
+	monthOptions := monthOptionsHTML(selectedMonth)
+	allField := ""
+	if !onlyPending {
+		allField = `<input type="hidden" name="all" value="1">`
+	}
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><span class="status-badge">Ждут подтверждения: `+strconv.Itoa(pending)+`</span>`+outOfZoneBadge+`<form method="GET" action="/attendance" class="month-selector">`+allField+`<select name="month" onchange="this.form.submit()">`+monthOptions+`</select></form>`+toggleLink+`</div>`)
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Факт</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header page-header-desktop-hidden"><h1>Фактические часы</h1></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table attendance-table"><thead><tr><th>Дата</th><th>Работник</th><th>Объекты</th><th>План</th><th>Факт</th><th>Откл., ч</th><th>Геозона</th><th>Статус</th><th>Подтверждение</th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "attendance"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// ConfirmAttendanceRecord stores the foreman-corrected actual time.
+func ConfirmAttendanceRecord(c *gin.Context) {
+	record, err := storage.GetAttendanceByID(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Attendance record not found")
+		return
+	}
+	entry, err := storage.GetTimesheetByID(record.EntryID)
+	if err != nil {
+		c.String(http.StatusNotFound, "Schedule entry not found")
+		return
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	objectsByID := make(map[string]models.Object, len(objects))
+	for _, object := range objects {
+		objectsByID[object.ID] = object
+	}
+	if !canSuperviseEntry(c, entry, objectsByID) {
+		c.String(http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	returnTo := c.PostForm("return_to")
+	if !strings.HasPrefix(returnTo, "/") {
+		returnTo = "/attendance"
+	}
+	lunch, _ := strconv.Atoi(strings.TrimSpace(c.PostForm("lunch_break_minutes")))
+	if err := storage.ConfirmAttendance(record.ID, c.PostForm("start_time"), c.PostForm("end_time"), lunch, c.PostForm("notes"), c.GetString("userID"), c.GetString("userName")); err != nil {
+		separator := "?"
+		if strings.Contains(returnTo, "?") {
+			separator = "&"
+		}
+		c.Redirect(http.StatusFound, returnTo+separator+"error="+url.QueryEscape(humanizeScheduleError(err)))
+		return
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"project/internal/models"
 	"project/internal/security"
 	"project/internal/storage"
 
@@ -249,39 +250,129 @@ func Logout(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/login")
 }
 
-// AuthRequired is a middleware to ensure the user is authenticated.
-func AuthRequired() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		cleanExpiredSessions()
-		token, err := c.Cookie(sessionCookie)
-		if err != nil || token == "" {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
-			return
+// sessionUser resolves the session cookie into the signed-in user; an expired cookie is cleared.
+func sessionUser(c *gin.Context) (models.User, authSession, bool) {
+	cleanExpiredSessions()
+	token, err := c.Cookie(sessionCookie)
+	if err != nil || token == "" {
+		return models.User{}, authSession{}, false
+	}
+
+	sessionMutex.RLock()
+	sess, exists := sessions[token]
+	sessionMutex.RUnlock()
+	if !exists || time.Now().After(sess.ExpiresAt) {
+		clearSessionCookie(c)
+		return models.User{}, authSession{}, false
+	}
+
+	user, err := storage.GetUserByID(sess.UserID)
+	if err != nil {
+		return models.User{}, authSession{}, false
+	}
+	return user, sess, true
+}
+
+func setAuthContext(c *gin.Context, user models.User, csrfToken string) {
+	c.Set("userID", user.ID)
+	c.Set("userName", user.Name)
+	c.Set("userStatus", user.Status)
+	c.Set("csrfToken", csrfToken)
+}
+
+func bearerSecret(c *gin.Context) string {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// API tokens reach only the JSON API and the downloads below; HTML pages, admin settings included,
+// always need a session. Routes are gin patterns (c.FullPath()).
+var (
+	tokenExportRoutes = map[string]bool{
+		"GET /timesheets/export":        true,
+		"GET /timesheets/export/t13":    true,
+		"GET /timesheets/export/pdf":    true,
+		"GET /worker/:id/statement.pdf": true,
+		"GET /object/:id/week.pdf":      true,
+		"GET /payroll/export/download":  true,
+	}
+	tokenScheduleWriteRoutes = map[string]bool{
+		"POST /api/v1/schedule":       true,
+		"PUT /api/v1/schedule/:id":    true,
+		"DELETE /api/v1/schedule/:id": true,
+	}
+)
+
+// tokenAllowsRequest maps the route onto a scope: downloads need export, JSON API reads need read,
+// and the only writes a token can make are schedule changes through the JSON API.
+func tokenAllowsRequest(scopes []string, method, route string) bool {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	key := method + " " + route
+	need := ""
+	switch {
+	case tokenExportRoutes[key]:
+		need = models.TokenScopeExport
+	case tokenScheduleWriteRoutes[key]:
+		need = models.TokenScopeScheduleWrite
+	case method == http.MethodGet && strings.HasPrefix(route, "/api/v1/"):
+		need = models.TokenScopeRead
+	default:
+		return false
+	}
+	for _, scope := range scopes {
+		if scope == need {
+			return true
 		}
+	}
+	return false
+}
 
-		sessionMutex.RLock()
-		sess, exists := sessions[token]
-		sessionMutex.RUnlock()
-		if !exists || time.Now().After(sess.ExpiresAt) {
-			clearSessionCookie(c)
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
+// authenticateBearer signs the request in with an API token. It returns 0 on success,
+// otherwise the status and message to answer with.
+func authenticateBearer(c *gin.Context, secret string) (int, string) {
+	token, err := storage.AuthenticateAPIToken(secret)
+	if err != nil {
+		security.LogEvent("api_token_failed", fmt.Sprintf("path=%s ip=%s", c.Request.URL.Path, c.ClientIP()))
+		return http.StatusUnauthorized, "Недействительный API-токен"
+	}
+	user, err := storage.GetUserByID(token.UserID)
+	if err != nil {
+		return http.StatusUnauthorized, "Недействительный API-токен"
+	}
+	if !tokenAllowsRequest(token.Scopes, c.Request.Method, c.FullPath()) {
+		security.LogEvent("api_token_denied", fmt.Sprintf("token=%s method=%s path=%s", token.Prefix, c.Request.Method, c.Request.URL.Path))
+		return http.StatusForbidden, "У API-токена нет прав на этот запрос"
+	}
+	setAuthContext(c, user, "")
+	c.Set("apiTokenID", token.ID)
+	return 0, ""
+}
+
+// AuthRequired is a middleware to ensure the user is authenticated, by session cookie or Bearer API token.
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret := bearerSecret(c); secret != "" {
+			if status, message := authenticateBearer(c, secret); status != 0 {
+				c.String(status, message)
+				c.Abort()
+				return
+			}
+			c.Next()
 			return
 		}
-
-		user, err := storage.GetUserByID(sess.UserID)
-		if err != nil {
+		user, sess, ok := sessionUser(c)
+		if !ok {
 			c.Redirect(http.StatusFound, "/login")
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", user.ID)
-		c.Set("userName", user.Name)
-		c.Set("userStatus", user.Status)
-		c.Set("csrfToken", sess.CSRFToken)
-
+		setAuthContext(c, user, sess.CSRFToken)
 		c.Next()
 	}
 }
@@ -296,6 +387,11 @@ func CSRFMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		// API tokens come in a header a browser never adds on its own, so there is nothing to forge.
+		if c.GetString("apiTokenID") != "" {
+			c.Next()
+			return
+		}
 		csrfValue, ok := c.Get("csrfToken")
 		if !ok {
 			c.String(http.StatusForbidden, "CSRF token missing")
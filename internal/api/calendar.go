@@ -0,0 +1,248 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+var monthNamesNominative = []string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+func humanizeCalendarError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unknown calendar day kind"):
+		return "Неизвестный тип дня: используйте праздник, выходной, рабочий или сокращённый."
+	case strings.Contains(msg, "invalid date format"):
+		return "Некорректная дата: " + msg
+	case strings.Contains(msg, "outside of year"), strings.Contains(msg, "listed twice"), strings.HasPrefix(msg, "line "):
+		return "Файл не загружен: " + msg
+	default:
+		return "Не удалось сохранить календарь: " + msg
+	}
+}
+
+func calendarKindLabel(kind string) string {
+	switch kind {
+	case "holiday":
+		return "Праздник"
+	case "weekend":
+		return "Выходной"
+	case "short":
+		return "Сокращённый"
+	default:
+		return "Рабочий"
+	}
+}
+
+func calendarYearFrom(value string) int {
+	year, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || year < 2000 || year > 2100 {
+		return time.Now().Year()
+	}
+	return year
+}
+
+func calendarReturn(year int, errMsg string) string {
+	path := "/settings/calendar?year=" + strconv.Itoa(year)
+	if errMsg != "" {
+		path += "&error=" + url.QueryEscape(errMsg)
+	}
+	return path
+}
+
+// CalendarPage shows the production calendar of a year: monthly norms and the date overrides.
+func CalendarPage(c *gin.Context) {
+	year := calendarYearFrom(c.Query("year"))
+	days, err := storage.GetCalendarDays(year)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load calendar: %v", err)
+		return
+	}
+
+	var normRows strings.Builder
+	yearDays, yearHours := 0, 0.0
+	for month := 1; month <= 12; month++ {
+		workDays, hours := storage.MonthNorm(fmt.Sprintf("%04d-%02d", year, month))
+		yearDays += workDays
+		yearHours += hours
+		normRows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%d</td><td>%.0f</td></tr>`, monthNamesNominative[month-1], workDays, hours))
+	}
+	normRows.WriteString(fmt.Sprintf(`<tr><th>Год</th><th>%d</th><th>%.0f</th></tr>`, yearDays, yearHours))
+
+	var dayRows strings.Builder
+	for _, day := range days {
+		note := day.Note
+		if note == "" {
+			note = "—"
+		}
+		dayRows.WriteString(fmt.Sprintf(`<tr class="calendar-kind-%s"><td>%s</td><td>%s</td><td>%s</td><td><form method="POST" action="/settings/calendar/delete">%s<input type="hidden" name="date" value="%s"><button type="submit" class="btn btn-danger btn-compact">Удалить</button></form></td></tr>`,
+			template.HTMLEscapeString(day.Kind),
+			template.HTMLEscapeString(formatScheduleDateLabel(day.Date)),
+			template.HTMLEscapeString(calendarKindLabel(day.Kind)),
+			template.HTMLEscapeString(note),
+			CSRFHiddenInput(c),
+			template.HTMLEscapeString(day.Date),
+		))
+	}
+	if dayRows.Len() == 0 {
+		dayRows.WriteString(`<tr><td colspan="4">Исключений нет: считаются только субботы и воскресенья.</td></tr>`)
+	}
+
+	var yearOptions strings.Builder
+	for y := time.Now().Year() - 2; y <= time.Now().Year()+2; y++ {
+		selected := ""
+		if y == year {
+			selected = " selected"
+		}
+		yearOptions.WriteString(fmt.Sprintf(`<option value="%d"%s>%d</option>`, y, selected, y))
+	}
+
+	statusBlock := ""
+	switch c.Query("ok") {
+	case "imported":
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Календарь загружен</strong><p>Исключения года заменены данными из файла.</p></div>`
+	case "rules":
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Правила учёта часов сохранены</strong><p>Табель и Excel пересчитываются по новым порогам.</p></div>`
+	}
+	rules := currentHourRules()
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock += `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Производственный календарь</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/settings" class="back-link">← К настройкам</a>
+<div class="page-header"><h1>Производственный календарь</h1><p>Суббота и воскресенье — выходные, будни — по {{DAY_HOURS}} ч. Праздники, переносы и сокращённые предпраздничные дни задаются исключениями.</p></div>
+<form method="GET" action="/settings/calendar" class="month-selector"><label for="year">Год:</label><select id="year" name="year" onchange="this.form.submit()">{{YEAR_OPTIONS}}</select></form>
+{{STATUS_BLOCK}}
+<div class="compact-grid dashboard-panels">
+<div class="card"><h2>Норма по месяцам</h2><table class="table"><thead><tr><th>Месяц</th><th>Рабочих дней</th><th>Норма, ч</th></tr></thead><tbody>{{NORM_ROWS}}</tbody></table></div>
+<div class="card"><h2>Исключения {{YEAR}}</h2>
+<div class="table-scroll"><table class="table"><thead><tr><th>Дата</th><th>Тип</th><th>Описание</th><th></th></tr></thead><tbody>{{DAY_ROWS}}</tbody></table></div>
+<form method="POST" action="/settings/calendar/day" class="mark-type-form">{{CSRF_FIELD}}
+<input type="date" name="date" required>
+<select name="kind"><option value="holiday">Праздник</option><option value="weekend">Выходной (перенос)</option><option value="workday">Рабочий (перенос)</option><option value="short">Сокращённый</option></select>
+<input type="text" name="note" placeholder="Описание">
+<button type="submit" class="btn btn-primary btn-compact">Сохранить день</button>
+</form>
+<form method="POST" action="/settings/calendar/fixed" class="info-card-actions">{{CSRF_FIELD}}<input type="hidden" name="year" value="{{YEAR}}"><button type="submit" class="btn btn-secondary">Добавить праздники с фиксированной датой</button></form>
+</div>
+</div>
+<div class="card"><h2>Правила учёта часов</h2>
+<p>Часы сверх дневного порога в рабочий день — сверхурочные; обычные часы сверх недельного порога (ISO‑неделя) тоже переходят в сверхурочные. Работа в выходные и праздники считается отдельно. Ночные часы — надбавка и входят в остальные категории.</p>
+<form method="POST" action="/settings/hour-rules" class="mark-type-form">{{CSRF_FIELD}}<input type="hidden" name="year" value="{{YEAR}}">
+<label>В день, ч <input type="number" name="daily_overtime_after" min="1" max="24" step="0.5" value="{{RULE_DAILY}}"></label>
+<label>В неделю, ч <input type="number" name="weekly_overtime_after" min="1" max="168" step="0.5" value="{{RULE_WEEKLY}}"></label>
+<label>Ночь с <input type="time" name="night_start" value="{{RULE_NIGHT_START}}"></label>
+<label>до <input type="time" name="night_end" value="{{RULE_NIGHT_END}}"></label>
+<button type="submit" class="btn btn-primary btn-compact">Сохранить правила</button>
+</form></div>
+<div class="card"><h2>Загрузка года из файла</h2>
+<p>Текстовый файл или CSV: по строке на дату — <code>дата;тип;описание</code>, например <code>2026-01-01;праздник;Новый год</code> или <code>04.01.2026;рабочий;перенос</code>. Исключения года {{YEAR}} будут заменены.</p>
+<form method="POST" action="/settings/calendar/import" enctype="multipart/form-data" class="mark-type-form">{{CSRF_FIELD}}<input type="hidden" name="year" value="{{YEAR}}"><input type="file" name="file" accept=".csv,.txt,text/plain,text/csv" required><button type="submit" class="btn btn-primary">Загрузить</button></form>
+</div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "settings"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{YEAR_OPTIONS}}", yearOptions.String(), 1)
+	final = strings.Replace(final, "{{NORM_ROWS}}", normRows.String(), 1)
+	final = strings.Replace(final, "{{DAY_ROWS}}", dayRows.String(), 1)
+	final = strings.Replace(final, "{{DAY_HOURS}}", fmt.Sprintf("%.0f", storage.StandardDayHours), 1)
+	final = strings.Replace(final, "{{RULE_DAILY}}", formatLeaveDays(rules.DailyOvertimeAfter), 1)
+	final = strings.Replace(final, "{{RULE_WEEKLY}}", formatLeaveDays(rules.WeeklyOvertimeAfter), 1)
+	final = strings.Replace(final, "{{RULE_NIGHT_START}}", template.HTMLEscapeString(rules.NightStart), 1)
+	final = strings.Replace(final, "{{RULE_NIGHT_END}}", template.HTMLEscapeString(rules.NightEnd), 1)
+	final = strings.Replace(final, "{{YEAR}}", strconv.Itoa(year), -1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), -1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func SaveCalendarDay(c *gin.Context) {
+	day := models.CalendarDay{Date: c.PostForm("date"), Kind: c.PostForm("kind"), Note: c.PostForm("note")}
+	year := calendarYearFrom(strings.SplitN(day.Date, "-", 2)[0])
+	if err := storage.SetCalendarDay(day); err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, humanizeCalendarError(err)))
+		return
+	}
+	security.LogEvent("calendar_day_saved", fmt.Sprintf("user=%s date=%s kind=%s", c.GetString("userName"), day.Date, day.Kind))
+	c.Redirect(http.StatusFound, calendarReturn(year, ""))
+}
+
+func DeleteCalendarDay(c *gin.Context) {
+	date := c.PostForm("date")
+	year := calendarYearFrom(strings.SplitN(date, "-", 2)[0])
+	if err := storage.DeleteCalendarDay(date); err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, humanizeCalendarError(err)))
+		return
+	}
+	security.LogEvent("calendar_day_deleted", fmt.Sprintf("user=%s date=%s", c.GetString("userName"), date))
+	c.Redirect(http.StatusFound, calendarReturn(year, ""))
+}
+
+// AddFixedHolidays adds the fixed-date public holidays of the year, keeping existing overrides.
+func AddFixedHolidays(c *gin.Context) {
+	year := calendarYearFrom(c.PostForm("year"))
+	existing, _ := storage.GetCalendarDays(year)
+	taken := make(map[string]struct{}, len(existing))
+	for _, day := range existing {
+		taken[day.Date] = struct{}{}
+	}
+	for _, day := range storage.FixedHolidays(year) {
+		if _, ok := taken[day.Date]; ok {
+			continue
+		}
+		if err := storage.SetCalendarDay(day); err != nil {
+			c.Redirect(http.StatusFound, calendarReturn(year, humanizeCalendarError(err)))
+			return
+		}
+	}
+	security.LogEvent("calendar_fixed_holidays", fmt.Sprintf("user=%s year=%d", c.GetString("userName"), year))
+	c.Redirect(http.StatusFound, calendarReturn(year, ""))
+}
+
+// ImportCalendar replaces the overrides of a year with the uploaded file.
+func ImportCalendar(c *gin.Context) {
+	year := calendarYearFrom(c.PostForm("year"))
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, "Выберите файл."))
+		return
+	}
+	opened, err := file.Open()
+	if err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, "Не удалось прочитать файл."))
+		return
+	}
+	defer opened.Close()
+	data, err := io.ReadAll(io.LimitReader(opened, 1<<20))
+	if err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, "Не удалось прочитать файл."))
+		return
+	}
+	days, err := storage.ParseCalendarFile(data)
+	if err == nil {
+		err = storage.ImportCalendarYear(year, days)
+	}
+	if err != nil {
+		c.Redirect(http.StatusFound, calendarReturn(year, humanizeCalendarError(err)))
+		return
+	}
+	security.LogEvent("calendar_imported", fmt.Sprintf("user=%s year=%d days=%d", c.GetString("userName"), year, len(days)))
+	c.Redirect(http.StatusFound, "/settings/calendar?ok=imported&year="+strconv.Itoa(year))
+}
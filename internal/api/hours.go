@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectWorkerShifts gathers approved work shifts of a worker for the month and the six days before it,
+// so weekly overtime sees the whole first week. With useFact, confirmed actual times replace the plan.
+func collectWorkerShifts(entries []models.TimesheetEntry, workerID, month string, attendanceMap map[string]models.AttendanceRecord, useFact bool) []models.WorkedShift {
+	monthStart, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil
+	}
+	from := monthStart.AddDate(0, 0, -6).Format("2006-01-02")
+	to := monthStart.AddDate(0, 1, -1).Format("2006-01-02")
+	shifts := make([]models.WorkedShift, 0)
+	for _, entry := range entries {
+		if entry.Date < from || entry.Date > to || isSpecialMark(entry.UserMark) || !storage.IsTimesheetApproved(entry) {
+			continue
+		}
+		assigned := false
+		for _, wid := range entry.WorkerIDs {
+			if wid == workerID {
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			continue
+		}
+		shift := models.WorkedShift{Date: entry.Date, Start: entry.StartTime, End: entry.EndTime, Lunch: entry.LunchBreakMinutes}
+		if useFact {
+			if record, ok := attendanceMap[attendanceKey(entry.ID, workerID)]; ok && record.Status == "confirmed" && record.EndTime != "" {
+				shift = models.WorkedShift{Date: entry.Date, Start: record.StartTime, End: record.EndTime, Lunch: record.LunchBreakMinutes}
+			}
+		}
+		shifts = append(shifts, shift)
+	}
+	return shifts
+}
+
+// workerMonthHours is the month summary every табель view shows for a worker, so the page, the Excel
+// file and the PDF agree: confirmed actual times split by the hour rules, as payroll counts them, and
+// the month norm less the calendar norm of each day with an approved mark.
+func workerMonthHours(entries []models.TimesheetEntry, workerID, month string, monthDates []string, calendar []calendarColumn, attendanceMap map[string]models.AttendanceRecord, rules models.HourRules) (models.HourBreakdown, float64) {
+	breakdown := storage.ClassifyHours(collectWorkerShifts(entries, workerID, month, attendanceMap, true), month, rules)
+	_, norm := storage.MonthNorm(month)
+	marked := make(map[string]bool)
+	for _, entry := range entries {
+		if isSpecialMark(entry.UserMark) && storage.IsTimesheetApproved(entry) && entryHasWorker(entry, workerID) {
+			marked[entry.Date] = true
+		}
+	}
+	for i, date := range monthDates {
+		if marked[date] {
+			norm -= calendar[i].NormHours
+		}
+	}
+	return breakdown, norm
+}
+
+func currentHourRules() models.HourRules {
+	settings, _ := storage.GetAppSettings()
+	return settings.HourRules
+}
+
+// SaveHourRules stores the overtime thresholds and the night interval.
+func SaveHourRules(c *gin.Context) {
+	settings, err := storage.GetAppSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load app settings: %v", err)
+		return
+	}
+	parse := func(name string) float64 {
+		value, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(c.PostForm(name)), ",", "."), 64)
+		return value
+	}
+	settings.HourRules = models.HourRules{
+		DailyOvertimeAfter:  parse("daily_overtime_after"),
+		WeeklyOvertimeAfter: parse("weekly_overtime_after"),
+		NightStart:          c.PostForm("night_start"),
+		NightEnd:            c.PostForm("night_end"),
+	}
+	if err := storage.UpdateAppSettings(settings); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to save hour rules: %v", err)
+		return
+	}
+	rules := currentHourRules()
+	security.LogEvent("hour_rules_saved", fmt.Sprintf("user=%s daily=%.2f weekly=%.2f night=%s-%s", c.GetString("userName"), rules.DailyOvertimeAfter, rules.WeeklyOvertimeAfter, rules.NightStart, rules.NightEnd))
+	c.Redirect(http.StatusFound, "/settings/calendar?ok=rules&year="+template.URLQueryEscaper(c.PostForm("year")))
+}
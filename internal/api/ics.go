@@ -0,0 +1,411 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Feeds cover this window around today so calendar apps do not download the whole history.
+const (
+	icsDaysBack    = 60
+	icsDaysForward = 180
+)
+
+// icsEscape escapes a TEXT value (RFC 5545, 3.3.11).
+func icsEscape(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+	return replacer.Replace(value)
+}
+
+// icsFold splits a content line into 75-octet pieces without breaking UTF-8 characters.
+func icsFold(line string) string {
+	if len(line) <= 75 {
+		return line + "\r\n"
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+type icsEvent struct {
+	UID         string
+	Date        string // YYYY-MM-DD
+	StartTime   string // HH:MM; empty for all-day events
+	EndTime     string
+	Summary     string
+	Location    string
+	Description string
+	Tentative   bool
+}
+
+// renderICS builds the calendar; times are floating (the phone's local time), like in the schedule itself.
+func renderICS(name string, items []icsEvent) string {
+	var b strings.Builder
+	line := func(value string) { b.WriteString(icsFold(value)) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//АВАЮССТРОЙ//Расписание//RU")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icsEscape(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, item := range items {
+		day, err := time.Parse("2006-01-02", item.Date)
+		if err != nil {
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line("UID:" + item.UID + "@avayus")
+		line("DTSTAMP:" + stamp)
+		if item.StartTime == "" {
+			line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+			line("TRANSP:TRANSPARENT")
+		} else {
+			line("DTSTART:" + day.Format("20060102") + "T" + strings.ReplaceAll(item.StartTime, ":", "") + "00")
+			line("DTEND:" + day.Format("20060102") + "T" + strings.ReplaceAll(item.EndTime, ":", "") + "00")
+		}
+		line("SUMMARY:" + icsEscape(item.Summary))
+		if item.Location != "" {
+			line("LOCATION:" + icsEscape(item.Location))
+		}
+		if item.Description != "" {
+			line("DESCRIPTION:" + icsEscape(item.Description))
+		}
+		if item.Tentative {
+			line("STATUS:TENTATIVE")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// writeICS answers with the calendar; the ETag ignores DTSTAMP so unchanged feeds get 304.
+func writeICS(c *gin.Context, name string, items []icsEvent) {
+	body := renderICS(name, items)
+	hash := sha256.New()
+	for _, l := range strings.Split(body, "\r\n") {
+		if !strings.HasPrefix(l, "DTSTAMP:") {
+			hash.Write([]byte(l))
+		}
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(body))
+}
+
+// icsFeedData is what the feeds need besides the entries, loaded once per request.
+type icsFeedData struct {
+	entries []models.TimesheetEntry
+	objects map[string]models.Object
+	workers map[string]string
+}
+
+func loadICSFeedData() (icsFeedData, error) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return icsFeedData{}, err
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		return icsFeedData{}, err
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		return icsFeedData{}, err
+	}
+	data := icsFeedData{objects: map[string]models.Object{}, workers: map[string]string{}}
+	for _, object := range objects {
+		data.objects[object.ID] = object
+	}
+	for _, worker := range workers {
+		data.workers[worker.ID] = worker.Name
+	}
+	from := time.Now().AddDate(0, 0, -icsDaysBack).Format("2006-01-02")
+	to := time.Now().AddDate(0, 0, icsDaysForward).Format("2006-01-02")
+	for _, entry := range entries {
+		if entry.Date < from || entry.Date > to || entry.ApprovalStatus == "rejected" {
+			continue
+		}
+		data.entries = append(data.entries, entry)
+	}
+	sort.Slice(data.entries, func(i, j int) bool {
+		if data.entries[i].Date == data.entries[j].Date {
+			return data.entries[i].StartTime < data.entries[j].StartTime
+		}
+		return data.entries[i].Date < data.entries[j].Date
+	})
+	return data, nil
+}
+
+func (d icsFeedData) objectText(ids []string) (names, addresses string) {
+	nameList := make([]string, 0, len(ids))
+	addressList := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if object, ok := d.objects[id]; ok {
+			nameList = append(nameList, object.Name)
+			if object.Address != "" {
+				addressList = append(addressList, object.Address)
+			}
+		}
+	}
+	return strings.Join(nameList, ", "), strings.Join(addressList, "; ")
+}
+
+func (d icsFeedData) workerNames(ids []string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := d.workers[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// workerEvents lists a worker's shifts and marks; pending entries are shown as tentative.
+func (d icsFeedData) workerEvents(workerID string) []icsEvent {
+	items := make([]icsEvent, 0)
+	for _, entry := range d.entries {
+		if !entryHasWorker(entry, workerID) {
+			continue
+		}
+		item := icsEvent{UID: entry.ID, Date: entry.Date, Description: entry.Notes, Tentative: entry.ApprovalStatus == "pending"}
+		if mark, ok := storage.FindMarkType(entry.UserMark); ok {
+			item.Summary = mark.Label + " (" + mark.Code + ")"
+		} else {
+			item.StartTime, item.EndTime = entry.StartTime, entry.EndTime
+			item.Summary, item.Location = d.objectText(entry.ObjectIDs)
+			if item.Summary == "" {
+				item.Summary = "Смена"
+			}
+		}
+		if item.Tentative {
+			item.Summary += " — на согласовании"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// objectEvents lists the shifts on the objects; withObjectName prefixes the summary for multi-object feeds.
+func (d icsFeedData) objectEvents(objectIDs map[string]bool, withObjectName bool) []icsEvent {
+	items := make([]icsEvent, 0)
+	for _, entry := range d.entries {
+		if isSpecialMark(entry.UserMark) {
+			continue
+		}
+		matched := false
+		for _, id := range entry.ObjectIDs {
+			if objectIDs[id] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		names, addresses := d.objectText(entry.ObjectIDs)
+		summary := d.workerNames(entry.WorkerIDs)
+		if withObjectName {
+			summary = names + ": " + summary
+		}
+		description := "Работники: " + d.workerNames(entry.WorkerIDs)
+		if entry.Notes != "" {
+			description += "\n" + entry.Notes
+		}
+		item := icsEvent{UID: entry.ID, Date: entry.Date, StartTime: entry.StartTime, EndTime: entry.EndTime,
+			Summary: summary, Location: addresses, Description: description, Tentative: entry.ApprovalStatus == "pending"}
+		if item.Tentative {
+			item.Summary += " — на согласовании"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// icsFeedUser resolves the token of a public feed URL; unknown tokens get 404 so they cannot be probed.
+func icsFeedUser(c *gin.Context) (models.User, bool) {
+	user, err := storage.AuthenticateICSToken(c.Param("token"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Calendar feed not found")
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// ICSWorkerFeed is the user's own shifts and marks.
+func ICSWorkerFeed(c *gin.Context) {
+	user, ok := icsFeedUser(c)
+	if !ok {
+		return
+	}
+	data, err := loadICSFeedData()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule: %v", err)
+		return
+	}
+	items := []icsEvent{}
+	if worker, err := storage.GetWorkerByUserID(user.ID); err == nil {
+		items = data.workerEvents(worker.ID)
+	}
+	writeICS(c, "Мои смены — "+user.Name, items)
+}
+
+// ICSObjectFeed is one object's schedule, for admins and the responsible user.
+func ICSObjectFeed(c *gin.Context) {
+	user, ok := icsFeedUser(c)
+	if !ok {
+		return
+	}
+	object, err := storage.GetObjectByID(strings.TrimSuffix(c.Param("id"), ".ics"))
+	if err != nil || (user.Status != "admin" && object.ResponsibleUserID != user.ID) {
+		c.String(http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+	data, err := loadICSFeedData()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule: %v", err)
+		return
+	}
+	writeICS(c, object.Name, data.objectEvents(map[string]bool{object.ID: true}, false))
+}
+
+// ICSAllObjectsFeed is every object's schedule, for admins.
+func ICSAllObjectsFeed(c *gin.Context) {
+	user, ok := icsFeedUser(c)
+	if !ok {
+		return
+	}
+	if user.Status != "admin" {
+		c.String(http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+	data, err := loadICSFeedData()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule: %v", err)
+		return
+	}
+	all := map[string]bool{}
+	for id := range data.objects {
+		all[id] = true
+	}
+	writeICS(c, "Все объекты", data.objectEvents(all, true))
+}
+
+// siteBaseURL prefers the site address from the Telegram settings, so links work behind a proxy.
+func siteBaseURL(c *gin.Context) string {
+	if settings, err := storage.GetAppSettings(); err == nil {
+		if site := strings.TrimRight(strings.TrimSpace(settings.TelegramSiteURL), "/"); site != "" {
+			return site
+		}
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// ICSFeedsPage shows the user's subscription links.
+func ICSFeedsPage(c *gin.Context) {
+	feed, err := storage.GetICSFeed(c.GetString("userID"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load calendar feed: %v", err)
+		return
+	}
+	base := siteBaseURL(c) + "/ics/" + feed.Token
+	feedRow := func(title, path string) string {
+		link := base + path
+		webcal := "webcal://" + strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+		return fmt.Sprintf(`<tr><td><strong>%s</strong></td><td><input type="text" readonly value="%s" onclick="this.select()"></td><td><a class="btn btn-secondary btn-compact" href="%s">Подписаться</a></td></tr>`,
+			template.HTMLEscapeString(title), template.HTMLEscapeString(link), template.HTMLEscapeString(webcal))
+	}
+
+	var rows strings.Builder
+	if _, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil {
+		rows.WriteString(feedRow("Мои смены и отметки", "/my.ics"))
+	} else if !isAdmin(c) {
+		rows.WriteString(`<tr><td colspan="3">К учётной записи не привязан работник — личного календаря нет. Обратитесь к администратору.</td></tr>`)
+	}
+	if isAdmin(c) {
+		rows.WriteString(feedRow("Все объекты", "/all.ics"))
+	}
+	objects, _ := storage.GetObjects()
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	for _, object := range objects {
+		if object.Status == "completed" || (!isAdmin(c) && object.ResponsibleUserID != c.GetString("userID")) {
+			continue
+		}
+		rows.WriteString(feedRow("Объект: "+object.Name, "/object/"+object.ID+".ics"))
+	}
+
+	lastFetched := "ещё не запрашивался"
+	if feed.LastFetchedAt != "" {
+		lastFetched = formatClosureTime(feed.LastFetchedAt)
+	}
+	statusBlock := ""
+	if c.Query("reset") == "1" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><p>Ссылки обновлены. Старые подписки перестали работать — подпишитесь заново.</p></div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Календарь в телефоне</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/schedule" class="back-link">← К расписанию</a>
+<div class="page-header"><h1>Календарь в телефоне</h1><p>Подпишитесь на ссылку в приложении календаря — смены появятся в телефоне и будут обновляться сами (обычно раз в час). iPhone: «Подписаться» или Настройки → Календарь → Учётные записи → Другое → Подписной календарь. Google Календарь: «Другие календари» → «Добавить по URL». Показываются 60 дней назад и 180 вперёд; записи на согласовании помечены.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table ics-feeds"><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>Ссылки — это пароль</h2><p>Любой, у кого есть ссылка, видит этот календарь. Если ссылка попала не туда, создайте новые — старые перестанут работать. Последний запрос календаря: {{LAST_FETCHED}}.</p>
+<form method="POST" action="/schedule/ics/reset">{{CSRF_FIELD}}<button type="submit" class="btn btn-danger" onclick="return confirm('Создать новые ссылки? Текущие подписки перестанут обновляться.')">Создать новые ссылки</button></form></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "schedule"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{LAST_FETCHED}}", template.HTMLEscapeString(lastFetched), 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func ResetICSFeed(c *gin.Context) {
+	if _, err := storage.ResetICSFeed(c.GetString("userID")); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to reset calendar feed: %v", err)
+		return
+	}
+	c.Redirect(http.StatusFound, "/schedule/ics?reset=1")
+}
@@ -0,0 +1,509 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// importField is a target field of the bulk import; aliases are lower-case headers recognised automatically.
+type importField struct {
+	Key      string
+	Label    string
+	Required bool
+	Aliases  []string
+}
+
+type importRow struct {
+	Line    int
+	Values  []string
+	Worker  models.Worker
+	Object  models.Object
+	Status  string // ok | duplicate | error
+	Message string
+}
+
+const importPreviewLimit = 500
+
+func importFields(kind string) []importField {
+	if kind == "objects" {
+		return []importField{
+			{Key: "name", Label: "Название", Required: true, Aliases: []string{"название", "объект", "наименование", "name"}},
+			{Key: "address", Label: "Адрес", Required: true, Aliases: []string{"адрес", "address"}},
+			{Key: "status", Label: "Статус", Aliases: []string{"статус", "status"}},
+			{Key: "responsible", Label: "Ответственный", Aliases: []string{"ответственный", "прораб", "responsible"}},
+		}
+	}
+	return []importField{
+		{Key: "name", Label: "Ф.И.О.", Required: true, Aliases: []string{"ф.и.о.", "фио", "ф.и.о", "работник", "имя", "name"}},
+		{Key: "position", Label: "Должность", Required: true, Aliases: []string{"должность", "профессия", "position"}},
+		{Key: "phone", Label: "Телефон", Aliases: []string{"телефон", "тел.", "тел", "phone"}},
+		{Key: "personnel_no", Label: "Табельный номер", Aliases: []string{"табельный номер", "таб. №", "таб.№", "табельный", "personnel_no"}},
+		{Key: "birth_date", Label: "Дата рождения", Aliases: []string{"дата рождения", "birth_date", "birthdate"}},
+		{Key: "hourly_rate", Label: "Ставка", Aliases: []string{"ставка", "ставка (руб/час)", "hourly_rate", "rate"}},
+	}
+}
+
+func importKindLabel(kind string) string {
+	if kind == "objects" {
+		return "объекты"
+	}
+	return "работники"
+}
+
+func humanizeImportError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unsupported file type"):
+		return "Поддерживаются файлы .xlsx и .csv."
+	case strings.Contains(msg, "invalid xlsx"), strings.Contains(msg, "invalid csv"):
+		return "Не удалось прочитать файл — проверьте формат."
+	case strings.Contains(msg, "no rows"):
+		return "В файле нет строк."
+	case strings.Contains(msg, "too many rows"):
+		return "Слишком много строк: не больше 5000 за один импорт."
+	case strings.Contains(msg, "too large"):
+		return "Файл больше 5 МБ."
+	case strings.Contains(msg, "upload not found"):
+		return "Загрузка устарела — выберите файл ещё раз."
+	default:
+		return "Не удалось импортировать: " + msg
+	}
+}
+
+// importMapping resolves the column index of every field: posted selects win, otherwise headers are matched by alias.
+func importMapping(c *gin.Context, fields []importField, header []string, hasHeader bool) map[string]int {
+	mapping := make(map[string]int, len(fields))
+	if c.PostForm("mapped") != "" {
+		for _, field := range fields {
+			index, err := strconv.Atoi(c.PostForm("map_" + field.Key))
+			if err != nil {
+				index = -1
+			}
+			mapping[field.Key] = index
+		}
+		return mapping
+	}
+	for i, field := range fields {
+		mapping[field.Key] = -1
+		if !hasHeader {
+			if i < len(header) {
+				mapping[field.Key] = i
+			}
+			continue
+		}
+		for col, title := range header {
+			title = strings.ToLower(strings.TrimSpace(title))
+			for _, alias := range field.Aliases {
+				if title == alias {
+					mapping[field.Key] = col
+				}
+			}
+		}
+	}
+	return mapping
+}
+
+func importCell(row []string, mapping map[string]int, key string) string {
+	index, ok := mapping[key]
+	if !ok || index < 0 || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+func parseImportDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006", "02/01/2006", "01-02-06"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01-02"), nil
+		}
+	}
+	// Excel keeps dates as serial numbers when the cell has no date format.
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 1000 {
+		if parsed, err := excelize.ExcelDateToTime(serial, false); err == nil {
+			return parsed.Format("2006-01-02"), nil
+		}
+	}
+	return "", errors.New("invalid date")
+}
+
+func importObjectStatus(value string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "в работе", "in_progress", "активен", "активный":
+		return "in_progress", true
+	case "на паузе", "пауза", "paused":
+		return "paused", true
+	case "окончен", "завершён", "завершен", "completed":
+		return "completed", true
+	default:
+		return "", false
+	}
+}
+
+// buildImportRows validates every data row and marks duplicates against the directory and earlier rows of the file.
+func buildImportRows(kind string, rows [][]string, fields []importField, mapping map[string]int, hasHeader bool, defaultResponsible string) []importRow {
+	users, _ := storage.GetUsers()
+	start := 0
+	if hasHeader {
+		start = 1
+	}
+	seen := map[string]int{}
+	result := make([]importRow, 0, len(rows))
+	for i := start; i < len(rows); i++ {
+		item := importRow{Line: i + 1, Status: "ok"}
+		for _, field := range fields {
+			item.Values = append(item.Values, importCell(rows[i], mapping, field.Key))
+		}
+		problems := make([]string, 0)
+		for j, field := range fields {
+			if field.Required && item.Values[j] == "" {
+				problems = append(problems, "не заполнено поле «"+field.Label+"»")
+			}
+		}
+		keys := make([]string, 0, 3)
+
+		if kind == "objects" {
+			object := models.Object{Name: importCell(rows[i], mapping, "name"), Address: importCell(rows[i], mapping, "address")}
+			status, ok := importObjectStatus(importCell(rows[i], mapping, "status"))
+			if !ok {
+				problems = append(problems, "неизвестный статус")
+			}
+			object.Status = status
+			responsible := importCell(rows[i], mapping, "responsible")
+			if responsible == "" {
+				object.ResponsibleUserID = defaultResponsible
+			} else {
+				for _, user := range users {
+					if strings.EqualFold(user.Name, responsible) || strings.EqualFold(user.Username, responsible) {
+						object.ResponsibleUserID = user.ID
+						break
+					}
+				}
+				if object.ResponsibleUserID == "" {
+					problems = append(problems, "ответственный «"+responsible+"» не найден среди пользователей")
+				}
+			}
+			if object.ResponsibleUserID == "" && responsible == "" {
+				problems = append(problems, "не указан ответственный")
+			}
+			item.Object = object
+			if len(problems) == 0 {
+				if existing, ok := storage.FindDuplicateObject(object); ok {
+					item.Status, item.Message = "duplicate", "уже есть объект «"+existing.Name+"»"
+				}
+				keys = append(keys, "object|"+strings.ToLower(object.Name)+"|"+strings.ToLower(object.Address))
+			}
+		} else {
+			worker := models.Worker{
+				Name:        importCell(rows[i], mapping, "name"),
+				Position:    importCell(rows[i], mapping, "position"),
+				PersonnelNo: importCell(rows[i], mapping, "personnel_no"),
+			}
+			if raw := importCell(rows[i], mapping, "phone"); raw != "" {
+				worker.Phone = storage.NormalizePhoneNumber(raw)
+				if len(worker.Phone) < 9 {
+					problems = append(problems, "некорректный телефон «"+raw+"»")
+				}
+			}
+			birthDate, err := parseImportDate(importCell(rows[i], mapping, "birth_date"))
+			if err != nil {
+				problems = append(problems, "некорректная дата рождения")
+			}
+			worker.BirthDate = birthDate
+			if raw := importCell(rows[i], mapping, "hourly_rate"); raw != "" {
+				rate, err := strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(raw, " ", ""), ",", "."), 64)
+				if err != nil || rate < 0 {
+					problems = append(problems, "некорректная ставка")
+				}
+				worker.HourlyRate = rate
+			}
+			item.Worker = worker
+			if len(problems) == 0 {
+				if existing, ok := storage.FindDuplicateWorker(worker); ok {
+					item.Status, item.Message = "duplicate", "уже есть работник «"+existing.Name+"»"
+				}
+				if worker.Phone != "" {
+					keys = append(keys, "phone|"+worker.Phone)
+				}
+				if worker.PersonnelNo != "" {
+					keys = append(keys, "no|"+strings.ToLower(worker.PersonnelNo))
+				}
+				keys = append(keys, "name|"+strings.ToLower(worker.Name)+"|"+worker.BirthDate)
+			}
+		}
+
+		if len(problems) > 0 {
+			item.Status, item.Message = "error", strings.Join(problems, "; ")
+		}
+		if item.Status == "ok" {
+			for _, key := range keys {
+				if line, ok := seen[key]; ok {
+					item.Status, item.Message = "duplicate", fmt.Sprintf("повторяет строку %d", line)
+					break
+				}
+			}
+		}
+		for _, key := range keys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = item.Line
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func importStatusLabel(item importRow) string {
+	label := map[string]string{"ok": "готово", "duplicate": "дубликат", "error": "ошибка", "created": "создано", "skipped": "пропущено", "failed": "не создано"}[item.Status]
+	if item.Message != "" {
+		label += ": " + item.Message
+	}
+	return `<span class="import-status is-` + item.Status + `">` + template.HTMLEscapeString(label) + `</span>`
+}
+
+func renderImportPage(c *gin.Context, kind, body string) {
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Импорт</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header"><h1>Импорт из Excel/CSV</h1><p>Сначала файл проверяется без записи: видно, какие строки будут созданы, какие уже есть в справочнике и где ошибки.</p></div>
+{{STATUS_BLOCK}}
+{{BODY}}
+</div>
+</body></html>`
+	pageID := "workers"
+	if kind == "objects" {
+		pageID = "objects"
+	}
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, pageID), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{BODY}}", body, 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// ImportPage shows the upload form for the bulk import of workers or objects.
+func ImportPage(c *gin.Context) {
+	kind := c.DefaultQuery("kind", "workers")
+	kindOptions := ""
+	for _, option := range []string{"workers", "objects"} {
+		selected := ""
+		if option == kind {
+			selected = " selected"
+		}
+		kindOptions += `<option value="` + option + `"` + selected + `>` + importKindLabel(option) + `</option>`
+	}
+	body := `<div class="card"><form method="POST" action="/import/preview" enctype="multipart/form-data" class="mark-type-form">` + CSRFHiddenInput(c) + `
+<label>Что загружаем <select name="kind">` + kindOptions + `</select></label>
+<input type="file" name="file" accept=".xlsx,.csv" required>
+<label><input type="checkbox" name="has_header" value="1" checked> первая строка — заголовки</label>
+<button type="submit" class="btn btn-primary">Проверить</button>
+</form>
+<p class="leave-balance-meta">Работники: Ф.И.О., должность, телефон, табельный номер, дата рождения, ставка. Объекты: название, адрес, статус, ответственный (имя или логин пользователя). Колонки можно сопоставить на следующем шаге.</p></div>`
+	renderImportPage(c, kind, body)
+}
+
+func loadImportForm(c *gin.Context) (id, kind, fileName string, rows [][]string, err error) {
+	if file, formErr := c.FormFile("file"); formErr == nil {
+		if file.Size > storage.MaxImportFileSize {
+			return "", "", "", nil, errors.New("file too large")
+		}
+		opened, openErr := file.Open()
+		if openErr != nil {
+			return "", "", "", nil, openErr
+		}
+		defer opened.Close()
+		data, readErr := io.ReadAll(io.LimitReader(opened, storage.MaxImportFileSize+1))
+		if readErr != nil {
+			return "", "", "", nil, readErr
+		}
+		kind = c.PostForm("kind")
+		if kind != "objects" {
+			kind = "workers"
+		}
+		if rows, err = storage.ParseImportTable(file.Filename, data); err != nil {
+			return "", "", "", nil, err
+		}
+		id, err = storage.SaveImportUpload(kind, file.Filename, rows)
+		return id, kind, file.Filename, rows, err
+	}
+	id = c.PostForm("upload_id")
+	kind, fileName, rows, err = storage.LoadImportUpload(id)
+	return id, kind, fileName, rows, err
+}
+
+// PreviewImport is the dry run: it parses the file, applies the column mapping and reports every row.
+func PreviewImport(c *gin.Context) {
+	id, kind, fileName, rows, err := loadImportForm(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/import?kind="+url.QueryEscape(c.PostForm("kind"))+"&error="+url.QueryEscape(humanizeImportError(err)))
+		return
+	}
+	hasHeader := c.PostForm("has_header") != ""
+	fields := importFields(kind)
+	mapping := importMapping(c, fields, rows[0], hasHeader)
+	defaultResponsible := c.PostForm("default_responsible")
+	items := buildImportRows(kind, rows, fields, mapping, hasHeader, defaultResponsible)
+
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Status]++
+	}
+
+	columnCount := 0
+	for _, row := range rows {
+		if len(row) > columnCount {
+			columnCount = len(row)
+		}
+	}
+	var mappingHTML strings.Builder
+	for _, field := range fields {
+		required := ""
+		if field.Required {
+			required = " *"
+		}
+		mappingHTML.WriteString(`<label>` + template.HTMLEscapeString(field.Label+required) + ` <select name="map_` + field.Key + `"><option value="-1">— не загружать —</option>`)
+		for col := 0; col < columnCount; col++ {
+			letter, _ := excelize.ColumnNumberToName(col + 1)
+			title := letter
+			if hasHeader && col < len(rows[0]) && rows[0][col] != "" {
+				title += ": " + rows[0][col]
+			}
+			selected := ""
+			if mapping[field.Key] == col {
+				selected = " selected"
+			}
+			mappingHTML.WriteString(fmt.Sprintf(`<option value="%d"%s>%s</option>`, col, selected, template.HTMLEscapeString(title)))
+		}
+		mappingHTML.WriteString(`</select></label>`)
+	}
+	if kind == "objects" {
+		users, _ := storage.GetUsers()
+		mappingHTML.WriteString(`<label>Ответственный по умолчанию <select name="default_responsible"><option value="">—</option>`)
+		for _, user := range users {
+			selected := ""
+			if user.ID == defaultResponsible {
+				selected = " selected"
+			}
+			mappingHTML.WriteString(`<option value="` + template.HTMLEscapeString(user.ID) + `"` + selected + `>` + template.HTMLEscapeString(user.Name) + `</option>`)
+		}
+		mappingHTML.WriteString(`</select></label>`)
+	}
+
+	var headerHTML strings.Builder
+	headerHTML.WriteString(`<th>Строка</th>`)
+	for _, field := range fields {
+		headerHTML.WriteString(`<th>` + template.HTMLEscapeString(field.Label) + `</th>`)
+	}
+	headerHTML.WriteString(`<th>Результат</th>`)
+	var rowsHTML strings.Builder
+	for i, item := range items {
+		if i >= importPreviewLimit {
+			rowsHTML.WriteString(fmt.Sprintf(`<tr><td colspan="%d">… ещё %d строк</td></tr>`, len(fields)+2, len(items)-importPreviewLimit))
+			break
+		}
+		rowsHTML.WriteString(fmt.Sprintf(`<tr><td>%d</td>`, item.Line))
+		for _, value := range item.Values {
+			rowsHTML.WriteString(`<td>` + template.HTMLEscapeString(value) + `</td>`)
+		}
+		rowsHTML.WriteString(`<td>` + importStatusLabel(item) + `</td></tr>`)
+	}
+
+	applyDisabled := ""
+	if counts["ok"] == 0 {
+		applyDisabled = " disabled"
+	}
+	body := fmt.Sprintf(`<div class="card"><form method="POST" action="/import/preview">%s<input type="hidden" name="upload_id" value="%s"><input type="hidden" name="mapped" value="1">
+<p><strong>%s</strong> · %s · строк: %d · к созданию: %d · дубликатов: %d · с ошибками: %d</p>
+<div class="mark-type-form">%s<label><input type="checkbox" name="has_header" value="1"%s> первая строка — заголовки</label></div>
+<div class="form-actions"><button type="submit" class="btn btn-secondary">Обновить предпросмотр</button><button type="submit" formaction="/import/apply" class="btn btn-primary"%s onclick="return confirm('Создать записи из строк со статусом «готово»?')">Импортировать %d</button><a class="btn btn-secondary" href="/import?kind=%s">Другой файл</a></div>
+</form></div>
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr>%s</tr></thead><tbody>%s</tbody></table></div></div>`,
+		CSRFHiddenInput(c), template.HTMLEscapeString(id),
+		template.HTMLEscapeString(fileName), importKindLabel(kind), len(items), counts["ok"], counts["duplicate"], counts["error"],
+		mappingHTML.String(), checkedAttr(hasHeader),
+		applyDisabled, counts["ok"], kind,
+		headerHTML.String(), rowsHTML.String())
+	renderImportPage(c, kind, body)
+}
+
+// ApplyImport re-validates the rows with the same mapping and creates the ones that are ready.
+func ApplyImport(c *gin.Context) {
+	id, kind, fileName, rows, err := loadImportForm(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/import?error="+url.QueryEscape(humanizeImportError(err)))
+		return
+	}
+	hasHeader := c.PostForm("has_header") != ""
+	fields := importFields(kind)
+	mapping := importMapping(c, fields, rows[0], hasHeader)
+	items := buildImportRows(kind, rows, fields, mapping, hasHeader, c.PostForm("default_responsible"))
+
+	userID := c.GetString("userID")
+	userName := c.GetString("userName")
+	counts := map[string]int{}
+	for i := range items {
+		item := &items[i]
+		switch item.Status {
+		case "ok":
+			var createErr error
+			if kind == "objects" {
+				_, createErr = storage.CreateObject(item.Object)
+			} else {
+				item.Worker.CreatedBy = userID
+				item.Worker.CreatedByName = userName
+				_, createErr = storage.CreateWorker(item.Worker)
+			}
+			if createErr != nil {
+				item.Status, item.Message = "failed", createErr.Error()
+			} else {
+				item.Status, item.Message = "created", ""
+			}
+		default:
+			item.Status = map[string]string{"duplicate": "skipped", "error": "failed"}[item.Status]
+		}
+		counts[item.Status]++
+	}
+	storage.DeleteImportUpload(id)
+	security.LogEvent("bulk_import", fmt.Sprintf("user=%s kind=%s file=%q created=%d skipped=%d failed=%d", userName, kind, fileName, counts["created"], counts["skipped"], counts["failed"]))
+
+	var rowsHTML strings.Builder
+	for _, item := range items {
+		if item.Status == "created" {
+			continue
+		}
+		rowsHTML.WriteString(fmt.Sprintf(`<tr><td>%d</td><td>%s</td><td>%s</td></tr>`, item.Line, template.HTMLEscapeString(strings.Join(item.Values[:2], " · ")), importStatusLabel(item)))
+	}
+	if rowsHTML.Len() == 0 {
+		rowsHTML.WriteString(`<tr><td colspan="3">Все строки загружены.</td></tr>`)
+	}
+	backPath := "/workers"
+	if kind == "objects" {
+		backPath = "/objects"
+	}
+	body := fmt.Sprintf(`<div class="dashboard-alert-item is-success"><strong>Импорт завершён</strong><p>%s · создано: %d · пропущено дубликатов: %d · не создано из-за ошибок: %d</p></div>
+<div class="card"><h2>Строки, которые не загружены</h2><div class="table-scroll"><table class="table"><thead><tr><th>Строка</th><th>Данные</th><th>Причина</th></tr></thead><tbody>%s</tbody></table></div>
+<div class="form-actions"><a class="btn btn-primary" href="%s">К списку</a><a class="btn btn-secondary" href="/import?kind=%s">Загрузить ещё</a></div></div>`,
+		template.HTMLEscapeString(fileName), counts["created"], counts["skipped"], counts["failed"], rowsHTML.String(), backPath, kind)
+	renderImportPage(c, kind, body)
+}
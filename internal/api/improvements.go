@@ -111,7 +111,7 @@ func CreateImprovement(c *gin.Context) {
 		item.CreatedBy = "Пользователь"
 	}
 
-	if err := storage.AddImprovement(item); err != nil {
+	if _, err := storage.AddImprovement(item); err != nil {
 		c.String(http.StatusInternalServerError, "Failed to add improvement: %v", err)
 		return
 	}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultLeaveDaysPerYear = 28
+
+func humanizeLeaveError(err error) string {
+	if err == nil {
+		return ""
+	}
+	switch err.Error() {
+	case "invalid accrual start date":
+		return "Укажите дату начала начисления."
+	case "invalid leave entitlement values":
+		return "Проверьте число дней: оно не может быть отрицательным или больше 366."
+	case "worker not found":
+		return "Работник не найден."
+	default:
+		return "Не удалось сохранить отпускные дни: " + err.Error()
+	}
+}
+
+func formatLeaveDays(value float64) string {
+	return strings.TrimSuffix(strings.TrimSuffix(strconv.FormatFloat(value, 'f', 2, 64), "0"), ".0")
+}
+
+// renderLeaveBalanceCard is the vacation balance widget on the worker profile; admins also get the entitlement form.
+func renderLeaveBalanceCard(c *gin.Context, worker models.Worker, returnPath string) string {
+	entitlement, hasEntitlement := storage.GetLeaveEntitlement(worker.ID)
+	balance := storage.ComputeLeaveBalance(worker.ID, time.Now())
+
+	var card strings.Builder
+	card.WriteString(`<div class="placeholder-card leave-balance-card"><div class="history-header"><h2>Отпуск</h2></div>`)
+	if errMsg := strings.TrimSpace(c.Query("leave_error")); errMsg != "" {
+		card.WriteString(`<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`)
+	}
+	if balance.HasEntitlement {
+		availableClass := ""
+		if balance.Available < 0 {
+			availableClass = " is-negative"
+		}
+		card.WriteString(fmt.Sprintf(`<div class="leave-balance-grid"><div><small>Перенесено</small><strong>%s</strong></div><div><small>Начислено в %d</small><strong>%s</strong></div><div><small>Использовано</small><strong>%s</strong></div><div class="leave-balance-available%s"><small>Остаток</small><strong>%s дн.</strong></div></div>`,
+			formatLeaveDays(balance.CarriedOver), time.Now().Year(), formatLeaveDays(balance.Accrued), formatLeaveDays(balance.Used), availableClass, formatLeaveDays(balance.Available)))
+		carryText := "без ограничения"
+		if entitlement.MaxCarryOver > 0 {
+			carryText = "не более " + formatLeaveDays(entitlement.MaxCarryOver) + " дн."
+		}
+		card.WriteString(`<p class="leave-balance-meta">` + template.HTMLEscapeString(fmt.Sprintf("%s дн. в год, начисление с %s, перенос %s.", formatLeaveDays(entitlement.DaysPerYear), formatScheduleDateLabel(entitlement.AccrualStart), carryText)) + `</p>`)
+	} else {
+		card.WriteString(`<p>Норма отпуска не задана — остаток не считается.</p>`)
+	}
+
+	if isAdmin(c) {
+		if !hasEntitlement {
+			entitlement = models.LeaveEntitlement{DaysPerYear: defaultLeaveDaysPerYear, AccrualStart: time.Now().Format("2006") + "-01-01"}
+		}
+		card.WriteString(fmt.Sprintf(`<form method="POST" action="/workers/leave/%s" class="form-grid-edit leave-entitlement-form">%s<input type="hidden" name="return_to" value="%s">
+<div class="form-group-edit"><label for="leave_days_per_year">Дней в год</label><input id="leave_days_per_year" name="days_per_year" type="number" min="0" max="366" step="0.5" value="%s"></div>
+<div class="form-group-edit"><label for="leave_accrual_start">Начисление с</label><input id="leave_accrual_start" name="accrual_start" type="date" value="%s"></div>
+<div class="form-group-edit"><label for="leave_opening_balance">Остаток на начало</label><input id="leave_opening_balance" name="opening_balance" type="number" step="0.5" value="%s"></div>
+<div class="form-group-edit"><label for="leave_max_carry_over">Перенос, макс.</label><input id="leave_max_carry_over" name="max_carry_over" type="number" min="0" step="0.5" value="%s" placeholder="0 — без ограничения"></div>
+<div class="form-actions-edit"><button type="submit" class="btn btn-secondary">Сохранить норму</button></div></form>`,
+			template.HTMLEscapeString(worker.ID), CSRFHiddenInput(c), template.HTMLEscapeString(returnPath),
+			formatLeaveDays(entitlement.DaysPerYear), template.HTMLEscapeString(entitlement.AccrualStart),
+			formatLeaveDays(entitlement.OpeningBalance), formatLeaveDays(entitlement.MaxCarryOver)))
+	}
+	card.WriteString(`</div>`)
+	return card.String()
+}
+
+// SaveLeaveEntitlement stores the vacation norm of a worker from the profile form.
+func SaveLeaveEntitlement(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	workerID := c.Param("id")
+	returnTo := c.PostForm("return_to")
+	if !strings.HasPrefix(returnTo, "/") {
+		returnTo = "/worker/" + workerID
+	}
+	parse := func(name string) float64 {
+		value, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(c.PostForm(name)), ",", "."), 64)
+		return value
+	}
+	entitlement := models.LeaveEntitlement{
+		WorkerID:       workerID,
+		DaysPerYear:    parse("days_per_year"),
+		AccrualStart:   c.PostForm("accrual_start"),
+		OpeningBalance: parse("opening_balance"),
+		MaxCarryOver:   parse("max_carry_over"),
+	}
+	if err := storage.SaveLeaveEntitlement(entitlement); err != nil {
+		c.Redirect(http.StatusFound, appendQuery(returnTo, "leave_error", humanizeLeaveError(err)))
+		return
+	}
+	security.LogEvent("leave_entitlement_saved", fmt.Sprintf("user=%s worker=%s days=%s", c.GetString("userName"), workerID, formatLeaveDays(entitlement.DaysPerYear)))
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+func appendQuery(path, key, value string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + key + "=" + url.QueryEscape(value)
+}
+
+// leaveBalanceWarning explains which workers would go below zero if the leave entry is saved.
+// A period crossing the new year is checked year by year, each year against its own balance;
+// an edited entry (with an ID) is counted with its new dates instead of the stored ones.
+func leaveBalanceWarning(entry models.TimesheetEntry, periodEnd string) string {
+	mark, ok := storage.FindMarkType(entry.UserMark)
+	if !ok || !mark.DeductsLeave {
+		return ""
+	}
+	start, err := time.Parse("2006-01-02", entry.Date)
+	if err != nil {
+		return ""
+	}
+	end := start
+	if parsed, err := time.Parse("2006-01-02", strings.TrimSpace(periodEnd)); err == nil && mark.AllowPeriod && !parsed.Before(start) {
+		end = parsed
+	}
+	planned := make([]time.Time, 0)
+	years := make([]int, 0)
+	firstDay := map[int]time.Time{}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		planned = append(planned, day)
+		if _, ok := firstDay[day.Year()]; !ok {
+			firstDay[day.Year()] = day
+			years = append(years, day.Year())
+		}
+	}
+	warnings := make([]string, 0)
+	for _, workerID := range entry.WorkerIDs {
+		for _, year := range years {
+			balance := storage.ProjectLeaveBalance(workerID, firstDay[year], planned, entry.ID)
+			if !balance.HasEntitlement || balance.Available >= 0 {
+				continue
+			}
+			name := workerID
+			if worker, err := storage.GetWorkerByID(workerID); err == nil {
+				name = worker.Name
+			}
+			shortage := fmt.Sprintf("%s — не хватает %s дн.", name, formatLeaveDays(-balance.Available))
+			if len(years) > 1 {
+				shortage = fmt.Sprintf("%s — в %d году не хватает %s дн.", name, year, formatLeaveDays(-balance.Available))
+			}
+			warnings = append(warnings, shortage)
+			break
+		}
+	}
+	if len(warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Запрошено %s дн. отпуска, это больше остатка: %s. Отметьте «Сохранить сверх остатка», чтобы продолжить.", formatLeaveDays(float64(len(planned))), strings.Join(warnings, "; "))
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func humanizeLeaveRequestError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "period end is before start"):
+		return "Дата окончания раньше даты начала."
+	case strings.Contains(msg, "leave period is too long"):
+		return "Слишком длинный период: разбейте заявку на несколько."
+	case strings.Contains(msg, "mark does not allow periods"):
+		return "Эту отметку можно запросить только на один день."
+	case strings.Contains(msg, "overlaps another request"):
+		return "На эти даты уже есть заявка."
+	case strings.Contains(msg, "attachment is too large"):
+		return "Файл больше 10 МБ."
+	case strings.Contains(msg, "unsupported attachment type"):
+		return "Приложите фото (JPEG, PNG, WebP) или PDF."
+	case strings.Contains(msg, "worker is fired"):
+		return "Работник уволен."
+	case strings.Contains(msg, "comment is required"):
+		return "Укажите причину отклонения."
+	case strings.Contains(msg, "not pending"):
+		return "Заявка уже рассмотрена."
+	default:
+		return humanizeScheduleError(err)
+	}
+}
+
+func leaveRequestStatusLabel(status string) string {
+	switch status {
+	case "approved":
+		return "Одобрена"
+	case "rejected":
+		return "Отклонена"
+	default:
+		return "На рассмотрении"
+	}
+}
+
+func leaveRequestDays(request models.LeaveRequest) int {
+	from, err := time.Parse("2006-01-02", request.DateFrom)
+	if err != nil {
+		return 0
+	}
+	to, err := time.Parse("2006-01-02", request.DateTo)
+	if err != nil {
+		return 0
+	}
+	return int(to.Sub(from).Hours()/24) + 1
+}
+
+// canViewLeaveRequest allows admins and the worker the request belongs to.
+func canViewLeaveRequest(c *gin.Context, request models.LeaveRequest) bool {
+	if isAdmin(c) {
+		return true
+	}
+	worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+	return err == nil && worker.ID == request.WorkerID
+}
+
+// LeavePage lets workers request time off and admins review the requests.
+func LeavePage(c *gin.Context) {
+	requests, err := storage.GetLeaveRequests()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load leave requests: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	workersMap := make(map[string]string, len(workers))
+	for _, worker := range workers {
+		workersMap[worker.ID] = worker.Name
+	}
+	ownWorker, ownErr := storage.GetWorkerByUserID(c.GetString("userID"))
+	hasOwnWorker := ownErr == nil && !ownWorker.IsFired
+
+	var rows strings.Builder
+	pending := 0
+	for _, request := range requests {
+		if !isAdmin(c) && (!hasOwnWorker || request.WorkerID != ownWorker.ID) {
+			continue
+		}
+		if request.Status == "pending" {
+			pending++
+		}
+		attachment := "—"
+		if request.AttachmentFile != "" {
+			attachment = `<a href="/leave/attachment/` + template.HTMLEscapeString(request.ID) + `" target="_blank" rel="noreferrer">` + template.HTMLEscapeString(request.AttachmentName) + `</a>`
+		}
+		comment := strings.TrimSpace(request.Comment)
+		if request.ReviewComment != "" {
+			comment = strings.TrimSpace(comment + " · Ответ: " + request.ReviewComment)
+		}
+		if comment == "" {
+			comment = "—"
+		}
+		decision := template.HTMLEscapeString(request.ReviewedByName)
+		if decision == "" {
+			decision = "—"
+		}
+		if isAdmin(c) && request.Status == "pending" {
+			balanceHint := ""
+			if mark, ok := storage.FindMarkType(request.MarkCode); ok && mark.DeductsLeave {
+				if from, err := time.Parse("2006-01-02", request.DateFrom); err == nil {
+					if balance := storage.ComputeLeaveBalance(request.WorkerID, from); balance.HasEntitlement {
+						hintClass := "leave-balance-meta"
+						if float64(leaveRequestDays(request)) > balance.Available {
+							hintClass += " is-negative"
+						}
+						balanceHint = `<small class="` + hintClass + `">Остаток: ` + formatLeaveDays(balance.Available) + ` дн.</small>`
+					}
+				}
+			}
+			decision = fmt.Sprintf(`<form method="POST" action="/leave/review/%s" class="approval-form">%s%s<input type="text" name="comment" placeholder="Комментарий (обязателен при отклонении)"><button type="submit" name="decision" value="approve" class="btn btn-primary btn-compact">Одобрить</button><button type="submit" name="decision" value="reject" class="btn btn-danger btn-compact">Отклонить</button></form>`,
+				template.HTMLEscapeString(request.ID), CSRFHiddenInput(c), balanceHint)
+		}
+		period := formatScheduleDateLabel(request.DateFrom)
+		if request.DateTo != request.DateFrom {
+			period += " — " + formatScheduleDateLabel(request.DateTo)
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%d</td><td>%s</td><td>%s</td><td><span class="status-badge leave-request-%s">%s</span></td><td>%s</td></tr>`,
+			joinMappedLinks([]string{request.WorkerID}, workersMap, "/worker"),
+			template.HTMLEscapeString(specialMarkTitle(request.MarkCode)),
+			template.HTMLEscapeString(period),
+			leaveRequestDays(request),
+			template.HTMLEscapeString(comment),
+			attachment,
+			template.HTMLEscapeString(request.Status),
+			template.HTMLEscapeString(leaveRequestStatusLabel(request.Status)),
+			decision,
+		))
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="8">Заявок пока нет.</td></tr>`)
+	}
+
+	statusBlock := ""
+	if c.Query("ok") == "created" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Заявка отправлена</strong><p>После одобрения отметки появятся в табеле.</p></div>`
+	}
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock += `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	formCard := `<div class="card"><p>Чтобы подать заявку, учётная запись должна быть привязана к работнику.</p></div>`
+	if isAdmin(c) || hasOwnWorker {
+		workerField := ""
+		balanceBlock := ""
+		if isAdmin(c) {
+			var options strings.Builder
+			for _, worker := range workers {
+				if worker.IsFired {
+					continue
+				}
+				selected := ""
+				if hasOwnWorker && worker.ID == ownWorker.ID {
+					selected = " selected"
+				}
+				options.WriteString(`<option value="` + template.HTMLEscapeString(worker.ID) + `"` + selected + `>` + template.HTMLEscapeString(worker.Name) + `</option>`)
+			}
+			workerField = `<div class="form-group-edit timesheet-span-2"><label for="leave_worker">Работник</label><select id="leave_worker" name="worker_id" required>` + options.String() + `</select></div>`
+		} else if balance := storage.ComputeLeaveBalance(ownWorker.ID, time.Now()); balance.HasEntitlement {
+			balanceBlock = `<p class="leave-balance-meta">Остаток отпуска: <strong>` + formatLeaveDays(balance.Available) + ` дн.</strong></p>`
+		}
+		var markOptions strings.Builder
+		for _, mark := range activeMarkTypes() {
+			markOptions.WriteString(fmt.Sprintf(`<option value="%s">%s (%s)</option>`, template.HTMLEscapeString(mark.Code), template.HTMLEscapeString(mark.Label), template.HTMLEscapeString(mark.Code)))
+		}
+		today := time.Now().Format("2006-01-02")
+		formCard = `<div class="card"><h2>Новая заявка</h2>` + balanceBlock + `<form method="POST" action="/leave" enctype="multipart/form-data" class="form-grid-edit">` + CSRFHiddenInput(c) + workerField + `
+<div class="form-group-edit timesheet-span-2"><label for="leave_mark">Тип</label><select id="leave_mark" name="mark" required>` + markOptions.String() + `</select></div>
+<div class="form-group-edit"><label for="leave_from">С</label><input id="leave_from" name="date_from" type="date" value="` + today + `" required></div>
+<div class="form-group-edit"><label for="leave_to">По</label><input id="leave_to" name="date_to" type="date" value="` + today + `"></div>
+<div class="form-group-edit timesheet-span-2"><label for="leave_comment">Комментарий</label><input id="leave_comment" name="comment" type="text" placeholder="Причина, номер листка нетрудоспособности..."></div>
+<div class="form-group-edit timesheet-span-2"><label for="leave_attachment">Документ (фото или PDF, до 10 МБ)</label><input id="leave_attachment" name="attachment" type="file" accept="image/*,application/pdf" capture="environment"></div>
+<div class="form-actions-edit"><button type="submit" class="btn btn-primary">Отправить заявку</button></div>
+</form></div>`
+	}
+
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><span class="status-badge">На рассмотрении: `+strconv.Itoa(pending)+`</span></div>`)
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Отпуска</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header page-header-desktop-hidden"><h1>Отпуска и больничные</h1></div>
+{{STATUS_BLOCK}}
+{{FORM_CARD}}
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Работник</th><th>Тип</th><th>Период</th><th>Дней</th><th>Комментарий</th><th>Документ</th><th>Статус</th><th>Решение</th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "leave"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{FORM_CARD}}", formCard, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// CreateLeaveRequest accepts the request form; non-admins always request for their own worker.
+func CreateLeaveRequest(c *gin.Context) {
+	workerID := c.PostForm("worker_id")
+	if !isAdmin(c) {
+		worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+		if err != nil {
+			c.String(http.StatusForbidden, "Нет привязанного работника")
+			return
+		}
+		workerID = worker.ID
+	}
+
+	var attachment []byte
+	attachmentName := ""
+	if file, err := c.FormFile("attachment"); err == nil && file.Size > 0 {
+		if file.Size > storage.MaxLeaveAttachmentSize {
+			c.Redirect(http.StatusFound, "/leave?error="+url.QueryEscape("Файл больше 10 МБ."))
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.Redirect(http.StatusFound, "/leave?error="+url.QueryEscape("Не удалось прочитать файл."))
+			return
+		}
+		attachment, err = io.ReadAll(io.LimitReader(opened, storage.MaxLeaveAttachmentSize+1))
+		opened.Close()
+		if err != nil {
+			c.Redirect(http.StatusFound, "/leave?error="+url.QueryEscape("Не удалось прочитать файл."))
+			return
+		}
+		attachmentName = file.Filename
+	}
+
+	request, err := storage.CreateLeaveRequest(models.LeaveRequest{
+		WorkerID:      workerID,
+		MarkCode:      normalizeSpecialMark(c.PostForm("mark")),
+		DateFrom:      c.PostForm("date_from"),
+		DateTo:        c.PostForm("date_to"),
+		Comment:       c.PostForm("comment"),
+		Source:        "web",
+		CreatedByID:   c.GetString("userID"),
+		CreatedByName: c.GetString("userName"),
+	}, attachment, attachmentName)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/leave?error="+url.QueryEscape(humanizeLeaveRequestError(err)))
+		return
+	}
+	security.LogEvent("leave_requested", fmt.Sprintf("user=%s worker=%s mark=%s from=%s to=%s", c.GetString("userName"), request.WorkerID, request.MarkCode, request.DateFrom, request.DateTo))
+	c.Redirect(http.StatusFound, "/leave?ok=created")
+}
+
+// ReviewLeaveRequest approves (creating the mark entries) or rejects a request.
+func ReviewLeaveRequest(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	approve := c.PostForm("decision") == "approve"
+	request, err := storage.ReviewLeaveRequest(c.Param("id"), approve, c.PostForm("comment"), c.GetString("userID"), c.GetString("userName"))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/leave?error="+url.QueryEscape(humanizeLeaveRequestError(err)))
+		return
+	}
+	security.LogEvent("leave_reviewed", fmt.Sprintf("user=%s request=%s status=%s entries=%d", c.GetString("userName"), request.ID, request.Status, len(request.EntryIDs)))
+	c.Redirect(http.StatusFound, "/leave")
+}
+
+// LeaveAttachment serves the document attached to a request.
+func LeaveAttachment(c *gin.Context) {
+	request, err := storage.GetLeaveRequestByID(c.Param("id"))
+	if err != nil || request.AttachmentFile == "" {
+		c.String(http.StatusNotFound, "Attachment not found")
+		return
+	}
+	if !canViewLeaveRequest(c, request) {
+		c.String(http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.FileAttachment(storage.LeaveAttachmentPath(request), request.AttachmentName)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func humanizeMarkError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "code and label are required"):
+		return "Укажите код и название отметки."
+	case strings.Contains(msg, "at most 4 characters"):
+		return "Код отметки — не длиннее 4 символов."
+	case strings.Contains(msg, "invalid mark color"):
+		return "Цвет указывается в формате #rrggbb."
+	case strings.Contains(msg, "alias"):
+		return "Код или синоним уже используется другой отметкой."
+	case strings.Contains(msg, "used by entries"):
+		return "Отметка используется в записях — её можно только отключить."
+	default:
+		return "Не удалось сохранить отметку: " + msg
+	}
+}
+
+func checkedAttr(value bool) string {
+	if value {
+		return " checked"
+	}
+	return ""
+}
+
+// MarkTypesPage is the admin catalogue of non-working day marks.
+func MarkTypesPage(c *gin.Context) {
+	marks, err := storage.GetMarkTypes()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load marks: %v", err)
+		return
+	}
+
+	var rows strings.Builder
+	for _, mark := range marks {
+		color := mark.Color
+		if color == "" {
+			color = "#cccccc"
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td colspan="8"><form method="POST" action="/settings/marks" class="mark-type-form">%s<input type="hidden" name="code" value="%s"><span class="mark-type-code" style="background:%s">%s</span><input type="text" name="label" value="%s" required><input type="text" name="aliases" value="%s" placeholder="синонимы через запятую"><input type="color" name="color" value="%s"><input type="number" name="sort_order" value="%d" title="Порядок"><label><input type="checkbox" name="paid" value="1"%s> оплачиваемый</label><label><input type="checkbox" name="requires_document" value="1"%s> нужен документ</label><label><input type="checkbox" name="allow_period" value="1"%s> период</label><label><input type="checkbox" name="deducts_leave" value="1"%s> из отпуска</label><label><input type="checkbox" name="active" value="1"%s> активна</label><button type="submit" class="btn btn-primary btn-compact">Сохранить</button><button type="submit" formaction="/settings/marks/delete/%s" class="btn btn-danger btn-compact" onclick="return confirm('Удалить отметку?')">Удалить</button></form></td></tr>`,
+			CSRFHiddenInput(c),
+			template.HTMLEscapeString(mark.Code),
+			template.HTMLEscapeString(color),
+			template.HTMLEscapeString(mark.Code),
+			template.HTMLEscapeString(mark.Label),
+			template.HTMLEscapeString(strings.Join(mark.Aliases, ", ")),
+			template.HTMLEscapeString(color),
+			mark.SortOrder,
+			checkedAttr(mark.Paid),
+			checkedAttr(mark.RequiresDocument),
+			checkedAttr(mark.AllowPeriod),
+			checkedAttr(mark.DeductsLeave),
+			checkedAttr(mark.Active),
+			template.HTMLEscapeString(url.PathEscape(mark.Code)),
+		))
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Справочник отметок</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/settings" class="back-link">← К настройкам</a>
+<div class="page-header"><h1>Справочник отметок</h1><p>Коды неявок для расписания, табеля и Excel. Отметку, которая уже есть в записях, нельзя удалить — только отключить.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table"><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>Новая отметка</h2>
+<form method="POST" action="/settings/marks" class="mark-type-form">{{CSRF_FIELD}}
+<input type="text" name="code" placeholder="Код, напр. К" maxlength="4" required>
+<input type="text" name="label" placeholder="Название" required>
+<input type="text" name="aliases" placeholder="синонимы через запятую">
+<input type="color" name="color" value="#6c8ebf">
+<input type="number" name="sort_order" value="100" title="Порядок">
+<label><input type="checkbox" name="paid" value="1"> оплачиваемый</label>
+<label><input type="checkbox" name="requires_document" value="1"> нужен документ</label>
+<label><input type="checkbox" name="allow_period" value="1"> период</label><label><input type="checkbox" name="deducts_leave" value="1"> из отпуска</label>
+<label><input type="checkbox" name="active" value="1" checked> активна</label>
+<button type="submit" class="btn btn-primary">Добавить</button>
+</form></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "settings"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// SaveMarkType creates or updates a catalogue mark by its code.
+func SaveMarkType(c *gin.Context) {
+	sortOrder, _ := strconv.Atoi(strings.TrimSpace(c.PostForm("sort_order")))
+	mark := models.MarkType{
+		Code:             c.PostForm("code"),
+		Label:            c.PostForm("label"),
+		Aliases:          strings.Split(c.PostForm("aliases"), ","),
+		Color:            c.PostForm("color"),
+		Paid:             c.PostForm("paid") != "",
+		RequiresDocument: c.PostForm("requires_document") != "",
+		AllowPeriod:      c.PostForm("allow_period") != "",
+		DeductsLeave:     c.PostForm("deducts_leave") != "",
+		Active:           c.PostForm("active") != "",
+		SortOrder:        sortOrder,
+	}
+	if err := storage.SaveMarkType(mark); err != nil {
+		c.Redirect(http.StatusFound, "/settings/marks?error="+url.QueryEscape(humanizeMarkError(err)))
+		return
+	}
+	security.LogEvent("mark_type_saved", fmt.Sprintf("code=%s user=%s", strings.TrimSpace(mark.Code), c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/marks")
+}
+
+func DeleteMarkType(c *gin.Context) {
+	code := c.Param("code")
+	if err := storage.DeleteMarkType(code); err != nil {
+		c.Redirect(http.StatusFound, "/settings/marks?error="+url.QueryEscape(humanizeMarkError(err)))
+		return
+	}
+	security.LogEvent("mark_type_deleted", fmt.Sprintf("code=%s user=%s", code, c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/marks")
+}
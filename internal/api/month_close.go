@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func formatClosureTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Format("02.01.2006 15:04")
+}
+
+// renderMonthClosure returns the табель banner and the admin close/reopen control for a month.
+func renderMonthClosure(c *gin.Context, month, returnPath string) (string, string) {
+	closure, ok := storage.GetMonthClosure(month)
+	hidden := CSRFHiddenInput(c) + `<input type="hidden" name="month" value="` + template.HTMLEscapeString(month) + `"><input type="hidden" name="return_to" value="` + template.HTMLEscapeString(returnPath) + `">`
+
+	banner := ""
+	if errMsg := strings.TrimSpace(c.Query("close_error")); errMsg != "" {
+		banner = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+	if ok && closure.Status == "closed" {
+		banner += fmt.Sprintf(`<div class="dashboard-alert-item month-closed-banner"><strong>Месяц закрыт</strong><p>%s, %s. Изменения записей и отметок за этот месяц запрещены.</p></div>`,
+			template.HTMLEscapeString(closure.ClosedByName),
+			template.HTMLEscapeString(formatClosureTime(closure.ClosedAt)))
+		if !isAdmin(c) {
+			return banner, ""
+		}
+		return banner, `<form method="POST" action="/timesheets/reopen" class="month-reopen-form">` + hidden + `<input type="text" name="reason" placeholder="Причина открытия" required><button type="submit" class="btn btn-secondary">Открыть месяц</button></form>`
+	}
+	if ok && closure.Status == "reopened" {
+		banner += fmt.Sprintf(`<div class="dashboard-alert-item"><strong>Месяц открыт повторно</strong><p>%s, %s: %s</p></div>`,
+			template.HTMLEscapeString(closure.ReopenedBy),
+			template.HTMLEscapeString(formatClosureTime(closure.ReopenedAt)),
+			template.HTMLEscapeString(closure.ReopenReason))
+	}
+	if !isAdmin(c) {
+		return banner, ""
+	}
+	return banner, `<form method="POST" action="/timesheets/close" onsubmit="return confirm('Закрыть месяц? Все записи за месяц станут недоступны для изменения.')">` + hidden + `<button type="submit" class="btn btn-primary">Закрыть месяц</button></form>`
+}
+
+func monthCloseRedirect(c *gin.Context, err error) {
+	returnTo := c.PostForm("return_to")
+	if !strings.HasPrefix(returnTo, "/") {
+		returnTo = "/timesheets"
+	}
+	if err != nil {
+		separator := "?"
+		if strings.Contains(returnTo, "?") {
+			separator = "&"
+		}
+		returnTo += separator + "close_error=" + url.QueryEscape(err.Error())
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// CloseTimesheetMonth freezes all entries of the month.
+func CloseTimesheetMonth(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	month := strings.TrimSpace(c.PostForm("month"))
+	if err := storage.CloseMonth(month, c.GetString("userID"), c.GetString("userName")); err != nil {
+		monthCloseRedirect(c, fmt.Errorf("Не удалось закрыть месяц: %v", err))
+		return
+	}
+	security.LogEvent("month_closed", fmt.Sprintf("month=%s user=%s", month, c.GetString("userName")))
+	if err := storage.SnapshotPayroll(month, c.GetString("userName")); err != nil {
+		monthCloseRedirect(c, fmt.Errorf("Месяц закрыт, но расчёт зарплаты не зафиксирован: %v", err))
+		return
+	}
+	monthCloseRedirect(c, nil)
+}
+
+// ReopenTimesheetMonth unfreezes a closed month; the reason goes to the audit log.
+func ReopenTimesheetMonth(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	month := strings.TrimSpace(c.PostForm("month"))
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if err := storage.ReopenMonth(month, reason, c.GetString("userID"), c.GetString("userName")); err != nil {
+		monthCloseRedirect(c, fmt.Errorf("Не удалось открыть месяц: %v", err))
+		return
+	}
+	security.LogEvent("month_reopened", fmt.Sprintf("month=%s user=%s reason=%q", month, c.GetString("userName"), reason))
+	monthCloseRedirect(c, nil)
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 
 	"project/internal/models"
@@ -84,7 +86,7 @@ func ObjectsPage(c *gin.Context) {
 		cards.WriteString(`<div class="info-card"><p>Объекты пока не добавлены.</p></div>`)
 	}
 	currentObjectsPath := "/objects?tab=" + template.URLQueryEscaper(selectedTab)
-	SetTopNavActions(c, `<div class="top-nav-toolbar"><a href="/objects/new" class="btn btn-primary" data-modal-url="/objects/new" data-modal-title="Новый объект" data-modal-return="`+currentObjectsPath+`">Новый объект</a></div>`)
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><a href="/objects/new" class="btn btn-primary" data-modal-url="/objects/new" data-modal-title="Новый объект" data-modal-return="`+currentObjectsPath+`">Новый объект</a><a href="/import?kind=objects" class="btn btn-secondary">Импорт</a></div>`)
 
 	page := `
 <!DOCTYPE html>
@@ -191,6 +193,22 @@ func renderObjectForm(c *gin.Context, object models.Object, actionURL, title, su
                         {{RESPONSIBLE_OPTIONS}}
                     </select>
                 </div>
+                <div class="form-group-edit">
+                    <label for="latitude">Широта</label>
+                    <input type="text" id="latitude" name="latitude" value="{{OBJECT_LATITUDE}}" inputmode="decimal" placeholder="53.902284">
+                </div>
+                <div class="form-group-edit">
+                    <label for="longitude">Долгота</label>
+                    <input type="text" id="longitude" name="longitude" value="{{OBJECT_LONGITUDE}}" inputmode="decimal" placeholder="27.561831">
+                </div>
+                <div class="form-group-edit">
+                    <label for="geofence_radius">Радиус геозоны, м</label>
+                    <input type="number" id="geofence_radius" name="geofence_radius" min="0" step="10" value="{{OBJECT_RADIUS}}" placeholder="{{DEFAULT_RADIUS}}">
+                </div>
+                <div class="form-group-edit">
+                    <label>&nbsp;</label>
+                    <button type="button" class="btn btn-secondary" onclick="fillObjectLocation(this)">Текущее местоположение</button>
+                </div>
                 <div class="form-actions-edit">
                     <button type="submit" class="btn btn-primary">{{SUBMIT_LABEL}}</button>
                     <a href="/objects" class="btn btn-secondary">Отмена</a>
@@ -219,10 +237,24 @@ func renderObjectForm(c *gin.Context, object models.Object, actionURL, title, su
     <script>
         function showDeleteModal(){document.getElementById('deleteModal').style.display='grid';}
         function closeDeleteModal(){document.getElementById('deleteModal').style.display='none';}
+        function fillObjectLocation(btn){
+            if(!navigator.geolocation){ alert('Геолокация недоступна в этом браузере'); return; }
+            btn.disabled=true;
+            navigator.geolocation.getCurrentPosition(function(pos){
+                document.getElementById('latitude').value=pos.coords.latitude.toFixed(6);
+                document.getElementById('longitude').value=pos.coords.longitude.toFixed(6);
+                btn.disabled=false;
+            }, function(){ btn.disabled=false; alert('Не удалось определить местоположение'); }, {enableHighAccuracy:true, timeout:15000});
+        }
     </script>
 </body>
 </html>`
 
+	radiusValue := ""
+	if object.GeofenceRadius > 0 {
+		radiusValue = strconv.Itoa(object.GeofenceRadius)
+	}
+
 	layoutStart := RenderSidebar(c, "objects")
 	layoutEnd := ""
 	mainClass := ""
@@ -253,6 +285,10 @@ func renderObjectForm(c *gin.Context, object models.Object, actionURL, title, su
 	final = strings.Replace(final, "{{RETURN_TO}}", template.HTMLEscapeString(returnTo), 1)
 	final = strings.Replace(final, "{{OBJECT_NAME}}", template.HTMLEscapeString(object.Name), 1)
 	final = strings.Replace(final, "{{OBJECT_ADDRESS}}", template.HTMLEscapeString(object.Address), 1)
+	final = strings.Replace(final, "{{OBJECT_LATITUDE}}", template.HTMLEscapeString(formatCoordinate(object.Latitude, object.Longitude, object.Latitude)), 1)
+	final = strings.Replace(final, "{{OBJECT_LONGITUDE}}", template.HTMLEscapeString(formatCoordinate(object.Latitude, object.Longitude, object.Longitude)), 1)
+	final = strings.Replace(final, "{{OBJECT_RADIUS}}", radiusValue, 1)
+	final = strings.Replace(final, "{{DEFAULT_RADIUS}}", strconv.Itoa(storage.DefaultGeofenceRadius), 1)
 	final = strings.Replace(final, "{{RESPONSIBLE_OPTIONS}}", responsibleOptions.String(), 1)
 	final = strings.Replace(final, "{{STATUS_IN_PROGRESS}}", statusOptions["in_progress"], 1)
 	final = strings.Replace(final, "{{STATUS_PAUSED}}", statusOptions["paused"], 1)
@@ -316,6 +352,12 @@ func ObjectProfilePage(c *gin.Context) {
 		}
 	}
 
+	geofence := "не задана"
+	if object.Latitude != 0 || object.Longitude != 0 {
+		coords := fmt.Sprintf("%.6f,%.6f", object.Latitude, object.Longitude)
+		geofence = fmt.Sprintf(`<a class="entity-link" href="https://www.openstreetmap.org/?mlat=%.6f&mlon=%.6f#map=17/%.6f/%.6f" target="_blank" rel="noopener">%s</a>, радиус %d м`, object.Latitude, object.Longitude, object.Latitude, object.Longitude, template.HTMLEscapeString(coords), object.GeofenceRadius)
+	}
+
 	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Объект: {{OBJECT_NAME}}</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
 {{SIDEBAR_HTML}}
@@ -326,9 +368,9 @@ func ObjectProfilePage(c *gin.Context) {
       <div class="worker-avatar">🏗</div>
       <div class="profile-header-info"><h1>{{OBJECT_NAME}}</h1><p>{{OBJECT_STATUS}}</p></div>
     </div>
-    <div class="profile-actions"><a class="btn btn-secondary" href="/objects/edit/{{OBJECT_ID}}" data-modal-url="/objects/edit/{{OBJECT_ID}}" data-modal-title="Редактировать объект" data-modal-return="/object/{{OBJECT_ID}}">Редактировать</a></div>
+    <div class="profile-actions"><a class="btn btn-secondary" href="/object/{{OBJECT_ID}}/week.pdf">График недели (PDF)</a><a class="btn btn-secondary" href="/objects/edit/{{OBJECT_ID}}" data-modal-url="/objects/edit/{{OBJECT_ID}}" data-modal-title="Редактировать объект" data-modal-return="/object/{{OBJECT_ID}}">Редактировать</a></div>
   </div>
-  <ul class="profile-details"><li><strong>Адрес:</strong> {{OBJECT_ADDRESS}}</li><li><strong>Ответственный:</strong> {{RESPONSIBLE}}</li></ul>
+  <ul class="profile-details"><li><strong>Адрес:</strong> {{OBJECT_ADDRESS}}</li><li><strong>Ответственный:</strong> {{RESPONSIBLE}}</li><li><strong>Геозона:</strong> {{GEOFENCE}}</li></ul>
   <div class="card"><div class="history-header"><h2>Назначения по объекту</h2></div><div class="schedule-vertical">{{ASSIGNMENTS}}</div></div>
 </div></body></html>`
 
@@ -337,11 +379,49 @@ func ObjectProfilePage(c *gin.Context) {
 	final = strings.Replace(final, "{{OBJECT_STATUS}}", template.HTMLEscapeString(objectStatusLabel(object.Status)), 1)
 	final = strings.Replace(final, "{{OBJECT_ADDRESS}}", template.HTMLEscapeString(object.Address), 1)
 	final = strings.Replace(final, "{{RESPONSIBLE}}", template.HTMLEscapeString(responsible), 1)
+	final = strings.Replace(final, "{{GEOFENCE}}", geofence, 1)
 	final = strings.Replace(final, "{{OBJECT_ID}}", template.HTMLEscapeString(object.ID), -1)
 	final = strings.Replace(final, "{{ASSIGNMENTS}}", assignments.String(), 1)
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
 }
 
+// formatCoordinate renders one coordinate for the form, leaving both empty when the object has no geofence.
+func formatCoordinate(latitude, longitude, value float64) string {
+	if latitude == 0 && longitude == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', 6, 64)
+}
+
+func parseCoordinate(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", ".")
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func applyObjectGeofenceForm(c *gin.Context, object *models.Object) error {
+	latitude, err := parseCoordinate(c.PostForm("latitude"))
+	if err != nil {
+		return errors.New("invalid latitude")
+	}
+	longitude, err := parseCoordinate(c.PostForm("longitude"))
+	if err != nil {
+		return errors.New("invalid longitude")
+	}
+	radius := 0
+	if value := strings.TrimSpace(c.PostForm("geofence_radius")); value != "" {
+		if radius, err = strconv.Atoi(value); err != nil {
+			return errors.New("invalid geofence radius")
+		}
+	}
+	object.Latitude = latitude
+	object.Longitude = longitude
+	object.GeofenceRadius = radius
+	return nil
+}
+
 func AddObjectPage(c *gin.Context) {
 	if !requireAdmin(c) {
 		return
@@ -359,6 +439,10 @@ func CreateObject(c *gin.Context) {
 		Address:           c.PostForm("address"),
 		ResponsibleUserID: c.PostForm("responsible_user_id"),
 	}
+	if err := applyObjectGeofenceForm(c, &newObject); err != nil {
+		c.String(http.StatusBadRequest, "Failed to create object: %v", err)
+		return
+	}
 	if _, err := storage.GetUserByID(newObject.ResponsibleUserID); err != nil {
 		c.String(http.StatusBadRequest, "Invalid responsible user")
 		return
@@ -400,6 +484,10 @@ func UpdateObject(c *gin.Context) {
 	object.Status = c.PostForm("status")
 	object.Address = c.PostForm("address")
 	object.ResponsibleUserID = c.PostForm("responsible_user_id")
+	if err := applyObjectGeofenceForm(c, &object); err != nil {
+		c.String(http.StatusBadRequest, "Failed to update object: %v", err)
+		return
+	}
 
 	if _, err := storage.GetUserByID(object.ResponsibleUserID); err != nil {
 		c.String(http.StatusBadRequest, "Invalid responsible user")
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIParam is a query or form field. Type is one of string, integer, number, boolean,
+// date (YYYY-MM-DD), time (HH:MM), month (YYYY-MM), file, or a type with "[]" for repeated fields.
+type openAPIParam struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// openAPIOperation describes one route of SetupRouter; the spec is built from these and the live route list.
+type openAPIOperation struct {
+	Tag     string
+	Summary string
+	Admin   bool
+	Query   []openAPIParam
+	Form    []openAPIParam // form post; multipart when one of the fields is a file
+	Body    interface{}    // JSON request body, described by reflection
+	// Response is page (HTML), redirect, json, list (paged JSON list of Result), file:<mime> or empty (204).
+	Response string
+	Result   interface{}
+}
+
+func qp(name, typ, description string) openAPIParam {
+	return openAPIParam{Name: name, Type: typ, Description: description}
+}
+
+func rp(name, typ, description string) openAPIParam {
+	return openAPIParam{Name: name, Type: typ, Description: description, Required: true}
+}
+
+// openAPIPath turns gin's /worker/:id and /static/*filepath into OpenAPI's /worker/{id}.
+func openAPIPath(path string) (string, []string) {
+	parts := strings.Split(path, "/")
+	params := make([]string, 0)
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func openAPIKey(method, path string) string {
+	return method + " " + path
+}
+
+// UndocumentedRoutes lists the registered routes that have no entry in openAPIOperations.
+func UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	missing := make([]string, 0)
+	for _, route := range routes {
+		if _, ok := openAPIOperations[openAPIKey(route.Method, route.Path)]; !ok {
+			missing = append(missing, openAPIKey(route.Method, route.Path))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func paramSchema(typ string) gin.H {
+	if strings.HasSuffix(typ, "[]") {
+		return gin.H{"type": "array", "items": paramSchema(strings.TrimSuffix(typ, "[]"))}
+	}
+	switch typ {
+	case "integer", "number", "boolean":
+		return gin.H{"type": typ}
+	case "date":
+		return gin.H{"type": "string", "format": "date"}
+	case "time":
+		return gin.H{"type": "string", "pattern": "^\\d{2}:\\d{2}$"}
+	case "month":
+		return gin.H{"type": "string", "pattern": "^\\d{4}-\\d{2}$"}
+	case "file":
+		return gin.H{"type": "string", "format": "binary"}
+	default:
+		return gin.H{"type": "string"}
+	}
+}
+
+// openAPISchemas collects the component schemas of the JSON bodies and results by Go type name.
+type openAPISchemas map[string]interface{}
+
+func (s openAPISchemas) ref(value interface{}) gin.H {
+	return s.schema(reflect.TypeOf(value))
+}
+
+func (s openAPISchemas) schema(t reflect.Type) gin.H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return gin.H{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		name := openAPISchemaName(t)
+		if _, ok := s[name]; !ok {
+			s[name] = nil // reserve the name first: structs may refer to themselves
+			properties := gin.H{}
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+				if !field.IsExported() || jsonName == "-" {
+					continue
+				}
+				if jsonName == "" {
+					jsonName = field.Name
+				}
+				properties[jsonName] = s.schema(field.Type)
+			}
+			s[name] = gin.H{"type": "object", "properties": properties}
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	default:
+		return gin.H{}
+	}
+}
+
+// openAPISchemaName exports api package view types under readable names: apiWorkerInput -> WorkerInput.
+func openAPISchemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	if name == "" {
+		return "Object"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func openAPIOperationSpec(op openAPIOperation, pathParams []string, schemas openAPISchemas) gin.H {
+	description := ""
+	if op.Admin {
+		description = "Только для администратора."
+	}
+	params := make([]gin.H, 0)
+	for _, name := range pathParams {
+		params = append(params, gin.H{"name": name, "in": "path", "required": true, "schema": gin.H{"type": "string"}})
+	}
+	for _, p := range op.Query {
+		params = append(params, gin.H{"name": p.Name, "in": "query", "required": p.Required, "description": p.Description, "schema": paramSchema(p.Type)})
+	}
+	spec := gin.H{"tags": []string{op.Tag}, "summary": op.Summary, "parameters": params}
+	if description != "" {
+		spec["description"] = description
+		spec["x-admin"] = true
+	}
+
+	switch {
+	case op.Body != nil:
+		spec["requestBody"] = gin.H{"required": true, "content": gin.H{"application/json": gin.H{"schema": schemas.ref(op.Body)}}}
+	case len(op.Form) > 0:
+		properties := gin.H{}
+		required := make([]string, 0)
+		contentType := "application/x-www-form-urlencoded"
+		for _, p := range op.Form {
+			schema := paramSchema(p.Type)
+			if p.Description != "" {
+				schema["description"] = p.Description
+			}
+			properties[p.Name] = schema
+			if p.Required {
+				required = append(required, p.Name)
+			}
+			if p.Type == "file" {
+				contentType = "multipart/form-data"
+			}
+		}
+		properties["_csrf_token"] = gin.H{"type": "string", "description": "CSRF-токен сессии (не нужен при входе по API-токену)"}
+		formSchema := gin.H{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			formSchema["required"] = required
+		}
+		spec["requestBody"] = gin.H{"content": gin.H{contentType: gin.H{"schema": formSchema}}}
+	}
+
+	errorResponse := gin.H{"description": "Ошибка", "content": gin.H{"application/json": gin.H{"schema": schemas.ref(apiErrorResponse{})}}}
+	responses := gin.H{}
+	switch {
+	case op.Response == "page":
+		responses["200"] = gin.H{"description": "HTML-страница", "content": gin.H{"text/html": gin.H{}}}
+	case op.Response == "redirect":
+		responses["302"] = gin.H{"description": "Перенаправление после выполнения; ошибки формы передаются в ?error="}
+	case op.Response == "list":
+		list := gin.H{"type": "object", "properties": gin.H{
+			"items":   gin.H{"type": "array", "items": schemas.ref(op.Result)},
+			"total":   gin.H{"type": "integer"},
+			"page":    gin.H{"type": "integer"},
+			"perPage": gin.H{"type": "integer"},
+		}}
+		responses["200"] = gin.H{"description": "Страница списка", "content": gin.H{"application/json": gin.H{"schema": list}}}
+		responses["400"] = errorResponse
+	case op.Response == "json":
+		content := gin.H{}
+		if op.Result != nil {
+			content["application/json"] = gin.H{"schema": schemas.ref(op.Result)}
+		} else {
+			content["application/json"] = gin.H{}
+		}
+		status := "200"
+		if strings.HasPrefix(op.Summary, "Создать") {
+			status = "201"
+		}
+		responses[status] = gin.H{"description": "Успешно", "content": content}
+		responses["400"] = errorResponse
+		responses["404"] = errorResponse
+	case strings.HasPrefix(op.Response, "file:"):
+		responses["200"] = gin.H{"description": "Файл", "content": gin.H{strings.TrimPrefix(op.Response, "file:"): gin.H{"schema": gin.H{"type": "string", "format": "binary"}}}}
+	default:
+		responses["204"] = gin.H{"description": "Выполнено"}
+		responses["404"] = errorResponse
+	}
+	if strings.HasPrefix(op.Tag, "API") {
+		responses["401"] = errorResponse
+		responses["403"] = errorResponse
+	}
+	spec["responses"] = responses
+	if op.Tag == "Вход" || op.Tag == "Служебные" || op.Tag == "Календарь" {
+		spec["security"] = []gin.H{}
+	}
+	return spec
+}
+
+// apiErrorResponse is the JSON error of /api/v1 and the JSON endpoints of the pages.
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// BuildOpenAPISpec describes every registered route; routes missing from openAPIOperations are still listed
+// and marked x-undocumented.
+func BuildOpenAPISpec(routes gin.RoutesInfo) gin.H {
+	schemas := openAPISchemas{}
+	paths := gin.H{}
+	for _, route := range routes {
+		path, pathParams := openAPIPath(route.Path)
+		op, ok := openAPIOperations[openAPIKey(route.Method, route.Path)]
+		var spec gin.H
+		if ok {
+			spec = openAPIOperationSpec(op, pathParams, schemas)
+		} else {
+			spec = openAPIOperationSpec(openAPIOperation{Tag: "Без описания", Summary: route.Path, Response: "page"}, pathParams, schemas)
+			spec["x-undocumented"] = true
+		}
+		item, _ := paths[path].(gin.H)
+		if item == nil {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = spec
+	}
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "АВАЮССТРОЙ",
+			"version":     "1.0",
+			"description": "Страницы сервиса (HTML-формы) и JSON API /api/v1. Вход — cookie session_token после POST /login (изменяющие запросы передают CSRF-токен в поле _csrf_token или заголовке X-CSRF-Token) либо API-токен в заголовке Authorization: Bearer.",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+			"securitySchemes": gin.H{
+				"cookieAuth": gin.H{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "description": "API-токен из /settings/tokens"},
+			},
+		},
+		"security": []gin.H{{"cookieAuth": []string{}}, {"bearerAuth": []string{}}},
+	}
+}
+
+// OpenAPISpec serves the description of the engine's routes as /api/openapi.json.
+func OpenAPISpec(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, BuildOpenAPISpec(engine.Routes()))
+	}
+}
+
+func openAPIParamsHTML(op openAPIOperation, pathParams []string) string {
+	rows := make([]string, 0)
+	for _, name := range pathParams {
+		rows = append(rows, `<tr><td><code>`+template.HTMLEscapeString(name)+`</code></td><td>путь</td><td>string</td><td></td></tr>`)
+	}
+	for _, group := range []struct {
+		where  string
+		params []openAPIParam
+	}{{"query", op.Query}, {"форма", op.Form}} {
+		for _, p := range group.params {
+			name := `<code>` + template.HTMLEscapeString(p.Name) + `</code>`
+			if p.Required {
+				name += " *"
+			}
+			rows = append(rows, `<tr><td>`+name+`</td><td>`+group.where+`</td><td>`+template.HTMLEscapeString(p.Type)+`</td><td>`+template.HTMLEscapeString(p.Description)+`</td></tr>`)
+		}
+	}
+	if op.Body != nil {
+		t := reflect.TypeOf(op.Body)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if jsonName == "" || jsonName == "-" {
+				continue
+			}
+			rows = append(rows, `<tr><td><code>`+template.HTMLEscapeString(jsonName)+`</code></td><td>JSON</td><td>`+template.HTMLEscapeString(field.Type.String())+`</td><td></td></tr>`)
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return `<table class="table api-docs-params"><thead><tr><th>Поле</th><th>Где</th><th>Тип</th><th>Описание</th></tr></thead><tbody>` + strings.Join(rows, "") + `</tbody></table>`
+}
+
+// APIDocsPage is the browsable version of the OpenAPI description, grouped by section.
+func APIDocsPage(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type docRoute struct {
+			method, path string
+			op           openAPIOperation
+			known        bool
+		}
+		groups := map[string][]docRoute{}
+		order := make([]string, 0)
+		for _, route := range engine.Routes() {
+			op, ok := openAPIOperations[openAPIKey(route.Method, route.Path)]
+			if !ok {
+				op = openAPIOperation{Tag: "Без описания", Summary: "Маршрут ещё не описан"}
+			}
+			if _, seen := groups[op.Tag]; !seen {
+				order = append(order, op.Tag)
+			}
+			groups[op.Tag] = append(groups[op.Tag], docRoute{method: route.Method, path: route.Path, op: op, known: ok})
+		}
+		sort.Strings(order)
+
+		var body strings.Builder
+		for _, tag := range order {
+			body.WriteString(`<div class="card"><h2>` + template.HTMLEscapeString(tag) + `</h2>`)
+			routes := groups[tag]
+			sort.Slice(routes, func(i, j int) bool {
+				if routes[i].path == routes[j].path {
+					return routes[i].method < routes[j].method
+				}
+				return routes[i].path < routes[j].path
+			})
+			for _, route := range routes {
+				path, pathParams := openAPIPath(route.path)
+				admin := ""
+				if route.op.Admin {
+					admin = ` <span class="status-badge">админ</span>`
+				}
+				body.WriteString(fmt.Sprintf(`<div class="api-docs-route"><p><span class="api-docs-method is-%s">%s</span> <code>%s</code>%s — %s</p>%s</div>`,
+					strings.ToLower(route.method), route.method, template.HTMLEscapeString(path), admin,
+					template.HTMLEscapeString(route.op.Summary), openAPIParamsHTML(route.op, pathParams)))
+			}
+			body.WriteString(`</div>`)
+		}
+
+		page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Документация API</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header"><h1>Документация API</h1><p>Все маршруты сервиса с полями форм и JSON. Машиночитаемое описание OpenAPI 3 — <a href="/api/openapi.json">/api/openapi.json</a>. Вход: cookie после POST /login (изменяющие запросы передают <code>_csrf_token</code> или заголовок <code>X-CSRF-Token</code>) или API-токен в заголовке <code>Authorization: Bearer</code>. Поля со звёздочкой обязательны.</p></div>
+{{BODY}}
+</div>
+</body></html>`
+		final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "settings"), 1)
+		final = strings.Replace(final, "{{BODY}}", body.String(), 1)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import "project/internal/models"
+
+var (
+	scheduleFormFields = []openAPIParam{
+		rp("date", "date", "День назначения (первый день периода для отметки)"),
+		qp("start_time", "time", "Начало смены; для отметки не передаётся"),
+		qp("end_time", "time", "Окончание смены"),
+		qp("lunch_break_minutes", "integer", "Обед: 0, 30, 60 или 90"),
+		rp("worker_ids", "string[]", "Работники; не-админ всегда добавляется сам"),
+		qp("object_ids", "string[]", "Объекты (только «в работе»)"),
+		qp("notes", "string", "Комментарий"),
+		qp("special_mark", "string", "Код или синоним отметки из справочника (ОТ, Б …); пусто — рабочее время"),
+		qp("document_ref", "string", "Документ-основание для отметок, где он обязателен"),
+		qp("return_to", "string", "Куда вернуться после сохранения (путь, начинающийся с /)"),
+	}
+	scheduleEditFields = append(append([]openAPIParam{}, scheduleFormFields...),
+		qp("confirm_over_balance", "string", "1 — сохранить отпуск сверх остатка"),
+	)
+	scheduleCreateFields = append(append([]openAPIParam{}, scheduleEditFields...),
+		qp("period_end", "date", "Последний день периода для отметок, которые вводятся периодом"),
+	)
+	workerFormFields = []openAPIParam{
+		rp("name", "string", "Ф.И.О."),
+		qp("position", "string", "Должность"),
+		qp("personnel_no", "string", "Табельный номер"),
+		qp("phone", "string", "Телефон"),
+		qp("birth_date", "date", "Дата рождения"),
+		qp("hourly_rate", "number", "Ставка в час"),
+	}
+	objectFormFields = []openAPIParam{
+		rp("name", "string", "Название"),
+		qp("status", "string", "in_progress | paused | completed"),
+		rp("address", "string", "Адрес"),
+		rp("responsible_user_id", "string", "Ответственный пользователь"),
+		qp("latitude", "number", "Широта зоны отметки"),
+		qp("longitude", "number", "Долгота зоны отметки"),
+		qp("geofence_radius", "integer", "Радиус зоны, м"),
+		qp("return_to", "string", "Куда вернуться после сохранения"),
+	}
+	userFormFields = []openAPIParam{
+		rp("username", "string", "Логин"),
+		rp("name", "string", "Имя"),
+		qp("password", "string", "Пароль; при изменении пусто — оставить прежний"),
+		qp("phone", "string", "Телефон"),
+		qp("status", "string", "admin | user"),
+		qp("worker_id", "string", "Привязать существующего работника (для user)"),
+	}
+	monthQuery      = []openAPIParam{qp("month", "month", "Месяц, по умолчанию текущий")}
+	pageErrorQuery  = []openAPIParam{qp("error", "string", "Текст ошибки для показа на странице")}
+	returnToField   = []openAPIParam{qp("return_to", "string", "Куда вернуться после выполнения")}
+	importFileField = []openAPIParam{qp("file", "file", "Файл при первой загрузке"), qp("upload_id", "string", "Загруженный ранее файл при повторной отправке")}
+	apiListQuery    = []openAPIParam{qp("page", "integer", "Номер страницы, с 1"), qp("per_page", "integer", "Размер страницы, по умолчанию 50, не больше 500")}
+)
+
+func withListQuery(params ...openAPIParam) []openAPIParam {
+	return append(params, apiListQuery...)
+}
+
+// openAPIOperations is keyed by "METHOD /gin/path" exactly as registered in SetupRouter.
+var openAPIOperations = map[string]openAPIOperation{
+	// Service files
+	"GET /static/*filepath":           {Tag: "Служебные", Summary: "Статические файлы (CSS, JS, шрифты, иконки)", Response: "file:application/octet-stream"},
+	"HEAD /static/*filepath":          {Tag: "Служебные", Summary: "Заголовки статического файла", Response: "file:application/octet-stream"},
+	"GET /manifest.webmanifest":       {Tag: "Служебные", Summary: "Манифест PWA", Response: "file:application/manifest+json"},
+	"HEAD /manifest.webmanifest":      {Tag: "Служебные", Summary: "Заголовки манифеста PWA", Response: "file:application/manifest+json"},
+	"GET /sw.js":                      {Tag: "Служебные", Summary: "Service worker PWA", Response: "file:text/javascript"},
+	"HEAD /sw.js":                     {Tag: "Служебные", Summary: "Заголовки service worker", Response: "file:text/javascript"},
+	"GET /":                           {Tag: "Служебные", Summary: "Перенаправление на страницу входа", Response: "redirect"},
+	"POST /telegram/webhook":          {Tag: "Служебные", Summary: "Обновления Telegram-бота в режиме вебхука; заголовок X-Telegram-Bot-Api-Secret-Token обязателен"},
+	"GET /api/openapi.json":           {Tag: "Документация", Summary: "Это описание OpenAPI 3", Response: "json"},
+	"GET /api/docs":                   {Tag: "Документация", Summary: "Документация API для просмотра в браузере", Response: "page"},
+	"GET /login":                      {Tag: "Вход", Summary: "Страница входа", Query: []openAPIParam{qp("error", "string", "invalid_credentials — показать ошибку входа")}, Response: "page"},
+	"POST /login":                     {Tag: "Вход", Summary: "Вход: ставит cookie session_token", Form: []openAPIParam{rp("username", "string", "Логин"), rp("password", "string", "Пароль")}, Response: "redirect"},
+	"GET /password/:token":            {Tag: "Вход", Summary: "Установка пароля по одноразовой ссылке из приветствия в Telegram", Query: []openAPIParam{qp("error", "string", "mismatch — пароли не совпали")}, Response: "page"},
+	"POST /password/:token":           {Tag: "Вход", Summary: "Сохранить пароль; ссылка после этого не действует", Form: []openAPIParam{rp("password", "string", "Новый пароль"), rp("password_confirm", "string", "Повтор пароля")}, Response: "redirect"},
+	"GET /logout":                     {Tag: "Вход", Summary: "Выход", Response: "redirect"},
+	"GET /dashboard":                  {Tag: "Главная", Summary: "Сводка", Response: "page"},
+	"GET /profile":                    {Tag: "Профиль", Summary: "Свой профиль", Response: "page"},
+	"POST /profile":                   {Tag: "Профиль", Summary: "Изменить свой профиль", Form: []openAPIParam{rp("username", "string", "Логин"), rp("name", "string", "Имя"), qp("password", "string", "Новый пароль, пусто — не менять"), qp("phone", "string", "Телефон"), qp("position", "string", "Должность (не-админ)"), qp("birth_date", "date", "Дата рождения (не-админ)"), qp("hourly_rate", "number", "Ставка (не-админ)")}, Response: "redirect"},
+	"GET /improvements":               {Tag: "Предложения", Summary: "Ошибки и предложения", Response: "page"},
+	"POST /improvements/new":          {Tag: "Предложения", Summary: "Добавить ошибку или предложение", Form: []openAPIParam{qp("kind", "string", "bug | improvement"), rp("title", "string", "Заголовок"), rp("description", "string", "Описание")}, Response: "redirect"},
+	"POST /improvements/complete/:id": {Tag: "Предложения", Summary: "Отметить выполненным", Response: "redirect"},
+
+	// Workers
+	"GET /workers":                  {Tag: "Работники", Summary: "Список работников", Admin: true, Query: []openAPIParam{qp("q", "string", "Поиск по имени"), qp("position", "string", "Должность"), qp("tab", "string", "active | fired")}, Response: "page"},
+	"GET /worker/:id":               {Tag: "Работники", Summary: "Карточка работника", Query: monthQuery, Response: "page"},
+	"GET /worker/:id/statement.pdf": {Tag: "Работники", Summary: "Месячная выписка работника в PDF (работник — только своя)", Query: monthQuery, Response: "file:application/pdf"},
+	"GET /workers/new":              {Tag: "Работники", Summary: "Форма нового работника", Admin: true, Response: "page"},
+	"POST /workers/new":             {Tag: "Работники", Summary: "Создать работника", Admin: true, Form: workerFormFields, Response: "redirect"},
+	"GET /workers/edit/:id":         {Tag: "Работники", Summary: "Форма редактирования работника", Admin: true, Response: "page"},
+	"POST /workers/edit/:id":        {Tag: "Работники", Summary: "Сохранить работника; изменение ставки попадает в историю", Admin: true, Form: workerFormFields, Response: "redirect"},
+	"POST /workers/delete/:id":      {Tag: "Работники", Summary: "Уволить работника (карточка и история сохраняются)", Admin: true, Response: "redirect"},
+	"POST /workers/leave/:id":       {Tag: "Отпуска", Summary: "Норма отпуска работника", Admin: true, Form: []openAPIParam{qp("days_per_year", "number", "Дней в год"), qp("accrual_start", "date", "Начало начисления"), qp("opening_balance", "number", "Начальный остаток"), qp("max_carry_over", "number", "Максимальный перенос"), qp("return_to", "string", "Куда вернуться")}, Response: "redirect"},
+	"GET /leave":                    {Tag: "Отпуска", Summary: "Заявки на отпуск и отсутствие", Query: []openAPIParam{qp("ok", "string", "Показать сообщение об успехе"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /leave":                   {Tag: "Отпуска", Summary: "Подать заявку", Form: []openAPIParam{qp("worker_id", "string", "Работник (админ); не-админ — всегда свой"), rp("mark", "string", "Код отметки"), rp("date_from", "date", "С"), rp("date_to", "date", "По"), qp("comment", "string", "Комментарий"), qp("attachment", "file", "Документ")}, Response: "redirect"},
+	"POST /leave/review/:id":        {Tag: "Отпуска", Summary: "Согласовать или отклонить заявку", Admin: true, Form: []openAPIParam{rp("decision", "string", "approve | reject"), qp("comment", "string", "Комментарий, обязателен при отказе")}, Response: "redirect"},
+	"GET /leave/attachment/:id":     {Tag: "Отпуска", Summary: "Документ заявки", Response: "file:application/octet-stream"},
+
+	// Objects
+	"GET /objects":             {Tag: "Объекты", Summary: "Список объектов", Query: []openAPIParam{qp("tab", "string", "Вкладка по статусу")}, Response: "page"},
+	"GET /object/:id":          {Tag: "Объекты", Summary: "Карточка объекта", Response: "page"},
+	"GET /object/:id/week.pdf": {Tag: "Объекты", Summary: "График объекта на неделю в PDF", Query: []openAPIParam{qp("week", "date", "Любой день недели")}, Response: "file:application/pdf"},
+	"GET /objects/new":         {Tag: "Объекты", Summary: "Форма нового объекта", Admin: true, Response: "page"},
+	"POST /objects/new":        {Tag: "Объекты", Summary: "Создать объект", Admin: true, Form: objectFormFields, Response: "redirect"},
+	"GET /objects/edit/:id":    {Tag: "Объекты", Summary: "Форма редактирования объекта", Admin: true, Response: "page"},
+	"POST /objects/edit/:id":   {Tag: "Объекты", Summary: "Сохранить объект", Admin: true, Form: objectFormFields, Response: "redirect"},
+	"POST /objects/delete/:id": {Tag: "Объекты", Summary: "Удалить объект", Admin: true, Form: returnToField, Response: "redirect"},
+
+	// Schedule
+	"GET /schedule":                {Tag: "Расписание", Summary: "Назначения за месяц", Query: []openAPIParam{qp("month", "month", "Месяц"), qp("attendance_error", "string", "Ошибка отметки прихода")}, Response: "page"},
+	"GET /schedule/":               {Tag: "Расписание", Summary: "То же, что /schedule", Query: monthQuery, Response: "page"},
+	"GET /schedule/board":          {Tag: "Расписание", Summary: "Недельная доска планирования", Admin: true, Query: []openAPIParam{qp("week", "date", "Любой день недели")}, Response: "page"},
+	"POST /schedule/board/assign":  {Tag: "Расписание", Summary: "Назначить, перенести или скопировать работника на доске", Admin: true, Body: scheduleBoardRequest{}, Response: "json"},
+	"POST /schedule/board/remove":  {Tag: "Расписание", Summary: "Снять работника с назначения на доске", Admin: true, Body: scheduleBoardRemoveRequest{}, Response: "json"},
+	"GET /schedule/approvals":      {Tag: "Расписание", Summary: "Очередь согласования", Query: pageErrorQuery, Response: "page"},
+	"POST /schedule/approvals/:id": {Tag: "Расписание", Summary: "Согласовать или отклонить запись", Form: []openAPIParam{rp("decision", "string", "approve | reject"), qp("comment", "string", "Комментарий, обязателен при отказе")}, Response: "redirect"},
+	"GET /schedule/ics":            {Tag: "Расписание", Summary: "Ссылки для подписки на расписание в календаре телефона", Query: []openAPIParam{qp("reset", "integer", "1 — ссылки только что обновлены")}, Response: "page"},
+	"POST /schedule/ics/reset":     {Tag: "Расписание", Summary: "Выпустить новые ссылки календаря; старые перестают работать", Response: "redirect"},
+	"GET /ics/:token/my.ics":       {Tag: "Календарь", Summary: "iCalendar: свои смены и отметки (токен из ссылки вместо входа)", Response: "file:text/calendar"},
+	"GET /ics/:token/all.ics":      {Tag: "Календарь", Summary: "iCalendar: смены на всех объектах, только для администратора", Response: "file:text/calendar"},
+	"GET /ics/:token/object/:id":   {Tag: "Календарь", Summary: "iCalendar: смены на объекте ({id} с окончанием .ics), для администратора и ответственного", Response: "file:text/calendar"},
+	"GET /schedule/copy":           {Tag: "Расписание", Summary: "Копирование дня или недели: предпросмотр", Admin: true, Query: []openAPIParam{qp("source", "date", "Исходный день"), qp("target", "date", "Целевой день"), qp("scope", "string", "day | week"), qp("object", "string", "Только этот объект"), qp("created", "integer", "Сколько записей создано"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /schedule/copy":          {Tag: "Расписание", Summary: "Скопировать день или неделю", Admin: true, Form: []openAPIParam{rp("source", "date", "Исходный день"), rp("target", "date", "Целевой день"), qp("scope", "string", "day | week"), qp("object", "string", "Только этот объект")}, Response: "redirect"},
+	"GET /schedule/new":            {Tag: "Расписание", Summary: "Форма нового назначения", Query: []openAPIParam{qp("date", "date", "День"), qp("worker_id", "string", "Работник"), qp("object_id", "string", "Объект"), qp("special_mark", "string", "Отметка")}, Response: "page"},
+	"GET /timesheets/new":          {Tag: "Расписание", Summary: "То же, что /schedule/new", Query: []openAPIParam{qp("date", "date", "День"), qp("worker_id", "string", "Работник"), qp("object_id", "string", "Объект"), qp("special_mark", "string", "Отметка")}, Response: "page"},
+	"POST /schedule/new":           {Tag: "Расписание", Summary: "Создать назначение или отметку; записи не-админа уходят на согласование", Form: scheduleCreateFields, Response: "redirect"},
+	"POST /timesheets/new":         {Tag: "Расписание", Summary: "То же, что POST /schedule/new", Form: scheduleCreateFields, Response: "redirect"},
+	"GET /schedule/edit/:id":       {Tag: "Расписание", Summary: "Форма редактирования назначения", Response: "page"},
+	"GET /timesheets/edit/:id":     {Tag: "Расписание", Summary: "То же, что /schedule/edit/{id}", Response: "page"},
+	"POST /schedule/edit/:id":      {Tag: "Расписание", Summary: "Сохранить назначение", Form: scheduleEditFields, Response: "redirect"},
+	"POST /timesheets/edit/:id":    {Tag: "Расписание", Summary: "То же, что POST /schedule/edit/{id}", Form: scheduleEditFields, Response: "redirect"},
+	"POST /schedule/delete/:id":    {Tag: "Расписание", Summary: "Удалить назначение", Form: returnToField, Response: "redirect"},
+	"POST /timesheets/delete/:id":  {Tag: "Расписание", Summary: "То же, что POST /schedule/delete/{id}", Form: returnToField, Response: "redirect"},
+
+	// Attendance
+	"GET /attendance":                {Tag: "Факт", Summary: "Фактические приходы и уходы", Query: []openAPIParam{qp("month", "month", "Месяц"), qp("all", "string", "1 — все записи, а не только требующие внимания"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /attendance/check-in":      {Tag: "Факт", Summary: "Отметка прихода с геолокацией (PWA)", Body: checkInRequest{}, Response: "json"},
+	"POST /attendance/clock-in/:id":  {Tag: "Факт", Summary: "Отметить приход на назначение", Form: []openAPIParam{qp("latitude", "number", "Широта"), qp("longitude", "number", "Долгота"), qp("accuracy", "number", "Точность, м")}, Response: "redirect"},
+	"POST /attendance/clock-out/:id": {Tag: "Факт", Summary: "Отметить уход", Response: "redirect"},
+	"POST /attendance/confirm/:id":   {Tag: "Факт", Summary: "Подтвердить факт (с правкой времени)", Form: []openAPIParam{qp("start_time", "time", "Начало"), qp("end_time", "time", "Окончание"), qp("lunch_break_minutes", "integer", "Обед: 0, 30, 60 или 90"), qp("notes", "string", "Комментарий"), qp("return_to", "string", "Куда вернуться")}, Response: "redirect"},
+
+	// Табель
+	"GET /timesheets":                 {Tag: "Табель", Summary: "Табель за месяц", Query: []openAPIParam{qp("month", "month", "Месяц"), qp("worker", "string", "Один работник")}, Response: "page"},
+	"GET /timesheets/":                {Tag: "Табель", Summary: "То же, что /timesheets", Query: monthQuery, Response: "page"},
+	"GET /timesheet":                  {Tag: "Табель", Summary: "То же, что /timesheets", Query: monthQuery, Response: "page"},
+	"GET /timesheet/":                 {Tag: "Табель", Summary: "То же, что /timesheets", Query: monthQuery, Response: "page"},
+	"GET /tabel":                      {Tag: "Табель", Summary: "То же, что /timesheets", Query: monthQuery, Response: "page"},
+	"GET /tabel/":                     {Tag: "Табель", Summary: "То же, что /timesheets", Query: monthQuery, Response: "page"},
+	"GET /timesheets/export":          {Tag: "Табель", Summary: "Табель в Excel", Query: monthQuery, Response: "file:application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	"GET /timesheets/export/t13":      {Tag: "Табель", Summary: "Табель по форме Т-13 в Excel", Query: monthQuery, Response: "file:application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	"GET /timesheets/export/pdf":      {Tag: "Табель", Summary: "Табель в PDF", Query: monthQuery, Response: "file:application/pdf"},
+	"POST /timesheets/close":          {Tag: "Табель", Summary: "Закрыть месяц", Admin: true, Form: []openAPIParam{rp("month", "month", "Месяц"), qp("return_to", "string", "Куда вернуться")}, Response: "redirect"},
+	"POST /timesheets/reopen":         {Tag: "Табель", Summary: "Открыть закрытый месяц", Admin: true, Form: []openAPIParam{rp("month", "month", "Месяц"), rp("reason", "string", "Причина"), qp("return_to", "string", "Куда вернуться")}, Response: "redirect"},
+	"GET /timesheets/import":          {Tag: "Табель", Summary: "Импорт прошлых табелей: загрузка файла", Admin: true, Query: pageErrorQuery, Response: "page"},
+	"POST /timesheets/import/preview": {Tag: "Табель", Summary: "Импорт табеля: сверка без записи", Admin: true, Form: append(append([]openAPIParam{}, importFileField...), qp("month", "month", "Месяц, если не удалось взять из файла"), qp("mapped", "string", "1 — взять сопоставление из match_N"), qp("match_N", "string", "Работник для N-й строки файла (с 0), пусто — не загружать")), Response: "page"},
+	"POST /timesheets/import/apply":   {Tag: "Табель", Summary: "Импорт табеля: создать записи и показать отчёт", Admin: true, Form: []openAPIParam{rp("upload_id", "string", "Загруженный файл"), rp("month", "month", "Месяц"), qp("mapped", "string", "1"), qp("match_N", "string", "Работник для N-й строки файла")}, Response: "page"},
+
+	// Users
+	"GET /users":             {Tag: "Пользователи", Summary: "Список пользователей", Admin: true, Query: []openAPIParam{qp("notice", "string", "Результат отправки данных в Telegram")}, Response: "page"},
+	"GET /users/new":         {Tag: "Пользователи", Summary: "Форма нового пользователя", Admin: true, Response: "page"},
+	"POST /users/new":        {Tag: "Пользователи", Summary: "Создать пользователя", Admin: true, Form: userFormFields, Response: "redirect"},
+	"GET /users/edit/:id":    {Tag: "Пользователи", Summary: "Форма редактирования пользователя", Admin: true, Response: "page"},
+	"POST /users/edit/:id":   {Tag: "Пользователи", Summary: "Сохранить пользователя", Admin: true, Form: userFormFields, Response: "redirect"},
+	"POST /users/delete/:id": {Tag: "Пользователи", Summary: "Удалить пользователя", Admin: true, Response: "redirect"},
+
+	// Settings
+	"GET /settings":                      {Tag: "Настройки", Summary: "Настройки", Admin: true, Query: []openAPIParam{qp("ok", "string", "Сообщение об успехе"), qp("processed", "integer", "Обработано контактов"), qp("linked", "integer", "Привязано контактов"), qp("telegram_error", "string", "Ошибка Telegram")}, Response: "page"},
+	"POST /settings/backup":              {Tag: "Настройки", Summary: "Резервная копия файлов данных", Admin: true, Response: "redirect"},
+	"GET /settings/marks":                {Tag: "Настройки", Summary: "Справочник отметок", Admin: true, Query: pageErrorQuery, Response: "page"},
+	"POST /settings/marks":               {Tag: "Настройки", Summary: "Создать или изменить отметку по коду", Admin: true, Form: []openAPIParam{rp("code", "string", "Код, до 4 символов"), rp("label", "string", "Название"), qp("aliases", "string", "Синонимы через запятую"), qp("color", "string", "#rrggbb"), qp("sort_order", "integer", "Порядок"), qp("paid", "boolean", "Оплачиваемая"), qp("requires_document", "boolean", "Нужен документ"), qp("allow_period", "boolean", "Ввод периодом"), qp("deducts_leave", "boolean", "Списывается из отпуска"), qp("active", "boolean", "Активна")}, Response: "redirect"},
+	"POST /settings/marks/delete/:code":  {Tag: "Настройки", Summary: "Удалить неиспользуемую отметку", Admin: true, Response: "redirect"},
+	"GET /settings/calendar":             {Tag: "Настройки", Summary: "Производственный календарь", Admin: true, Query: []openAPIParam{qp("year", "integer", "Год"), qp("ok", "string", "Сообщение об успехе"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /settings/calendar/day":        {Tag: "Настройки", Summary: "Исключение календаря", Admin: true, Form: []openAPIParam{rp("date", "date", "День"), rp("kind", "string", "holiday | weekend | workday | short"), qp("note", "string", "Комментарий")}, Response: "redirect"},
+	"POST /settings/calendar/delete":     {Tag: "Настройки", Summary: "Удалить исключение календаря", Admin: true, Form: []openAPIParam{rp("date", "date", "День")}, Response: "redirect"},
+	"POST /settings/calendar/fixed":      {Tag: "Настройки", Summary: "Добавить фиксированные праздники года", Admin: true, Form: []openAPIParam{rp("year", "integer", "Год")}, Response: "redirect"},
+	"POST /settings/calendar/import":     {Tag: "Настройки", Summary: "Загрузить календарь года из CSV", Admin: true, Form: []openAPIParam{rp("year", "integer", "Год"), rp("file", "file", "CSV: дата;вид;комментарий")}, Response: "redirect"},
+	"POST /settings/hour-rules":          {Tag: "Настройки", Summary: "Правила сверхурочных и ночных часов", Admin: true, Form: []openAPIParam{qp("daily_overtime_hours", "number", "Дневной порог"), qp("weekly_overtime_hours", "number", "Недельный порог"), qp("night_start", "time", "Начало ночи"), qp("night_end", "time", "Конец ночи"), qp("year", "integer", "Год для возврата на страницу")}, Response: "redirect"},
+	"POST /settings/organization":        {Tag: "Настройки", Summary: "Реквизиты организации для Т-13", Admin: true, Form: []openAPIParam{qp("org_name", "string", "Наименование"), qp("org_unp", "string", "УНП"), qp("org_address", "string", "Адрес"), qp("org_department", "string", "Подразделение"), qp("org_responsible_title", "string", "Ответственный — должность"), qp("org_responsible_name", "string", "Ответственный — Ф.И.О."), qp("org_head_title", "string", "Руководитель — должность"), qp("org_head_name", "string", "Руководитель — Ф.И.О."), qp("org_hr_name", "string", "Кадровая служба — Ф.И.О.")}, Response: "redirect"},
+	"GET /settings/tokens":               {Tag: "Настройки", Summary: "API-токены", Admin: true, Query: pageErrorQuery, Response: "page"},
+	"POST /settings/tokens":              {Tag: "Настройки", Summary: "Выпустить API-токен (показывается один раз)", Admin: true, Form: []openAPIParam{rp("name", "string", "Название"), rp("scopes", "string[]", "read | schedule:write | export"), rp("user_id", "string", "Пользователь, от имени которого действует токен")}, Response: "page"},
+	"POST /settings/tokens/revoke/:id":   {Tag: "Настройки", Summary: "Отозвать API-токен", Admin: true, Response: "redirect"},
+	"GET /settings/webhooks":             {Tag: "Настройки", Summary: "Вебхуки и журнал доставок", Admin: true, Query: []openAPIParam{qp("webhook", "string", "Журнал только этой подписки"), qp("ok", "string", "Сообщение об успехе"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /settings/webhooks":            {Tag: "Настройки", Summary: "Добавить подписку; секрет подписи создаётся автоматически", Admin: true, Form: []openAPIParam{rp("name", "string", "Название"), rp("url", "string", "Адрес http(s), на который отправляется POST"), rp("events", "string[]", "schedule.created | schedule.updated | schedule.deleted | worker.fired | object.status_changed")}, Response: "redirect"},
+	"POST /settings/webhooks/toggle/:id": {Tag: "Настройки", Summary: "Приостановить или включить подписку", Admin: true, Form: []openAPIParam{rp("active", "string", "1 — включить, 0 — приостановить")}, Response: "redirect"},
+	"POST /settings/webhooks/delete/:id": {Tag: "Настройки", Summary: "Удалить подписку с журналом доставок", Admin: true, Response: "redirect"},
+	"POST /settings/webhooks/test/:id":   {Tag: "Настройки", Summary: "Отправить тестовое событие и показать ответ", Admin: true, Response: "redirect"},
+	"POST /settings/telegram":            {Tag: "Настройки", Summary: "Настройки Telegram-бота", Admin: true, Form: []openAPIParam{qp("telegram_bot_token", "string", "Токен бота"), qp("telegram_bot_username", "string", "Username бота"), qp("telegram_site_url", "string", "Адрес сайта"), qp("telegram_mode", "string", "polling | webhook")}, Response: "redirect"},
+	"POST /settings/telegram/sync":       {Tag: "Настройки", Summary: "Синхронизировать контакты из бота", Admin: true, Response: "redirect"},
+	"GET /import":                        {Tag: "Настройки", Summary: "Импорт работников и объектов: загрузка файла", Admin: true, Query: []openAPIParam{qp("kind", "string", "workers | objects"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /import/preview":               {Tag: "Настройки", Summary: "Импорт: предпросмотр с сопоставлением колонок", Admin: true, Form: append(append([]openAPIParam{}, importFileField...), qp("kind", "string", "workers | objects"), qp("has_header", "boolean", "Первая строка — заголовки"), qp("default_responsible", "string", "Ответственный для объектов без него"), qp("mapped", "string", "1 — взять колонки из map_<поле>"), qp("map_<поле>", "integer", "Номер колонки для поля, -1 — не загружать")), Response: "page"},
+	"POST /import/apply":                 {Tag: "Настройки", Summary: "Импорт: загрузить строки без ошибок и дубликатов", Admin: true, Form: []openAPIParam{rp("upload_id", "string", "Загруженный файл"), qp("has_header", "boolean", "Первая строка — заголовки"), qp("default_responsible", "string", "Ответственный для объектов без него"), qp("mapped", "string", "1"), qp("map_<поле>", "integer", "Номер колонки для поля")}, Response: "page"},
+
+	// Payroll
+	"GET /payroll":                         {Tag: "Зарплата", Summary: "Расчёт за месяц", Admin: true, Query: []openAPIParam{qp("month", "month", "Месяц"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /payroll/adjustments":            {Tag: "Зарплата", Summary: "Премия, удержание или аванс", Admin: true, Form: []openAPIParam{rp("month", "month", "Месяц"), rp("worker_id", "string", "Работник"), rp("kind", "string", "bonus | deduction | advance"), rp("amount", "number", "Сумма"), qp("note", "string", "Комментарий")}, Response: "redirect"},
+	"POST /payroll/adjustments/delete/:id": {Tag: "Зарплата", Summary: "Удалить корректировку", Admin: true, Form: returnToField, Response: "redirect"},
+	"GET /payroll/export":                  {Tag: "Зарплата", Summary: "Настройка выгрузки для бухгалтерии", Admin: true, Query: []openAPIParam{qp("ok", "string", "Сообщение об успехе")}, Response: "page"},
+	"POST /payroll/export":                 {Tag: "Зарплата", Summary: "Сохранить колонки выгрузки", Admin: true, Form: []openAPIParam{rp("field_count", "integer", "Число строк настройки"), qp("field_N", "string", "Поле N-й строки"), qp("include_N", "boolean", "Выгружать N-ю строку"), qp("order_N", "integer", "Порядок"), qp("header_N", "string", "Заголовок колонки"), qp("delimiter", "string", "Разделитель CSV"), qp("decimal_comma", "boolean", "Десятичная запятая")}, Response: "redirect"},
+	"GET /payroll/export/download":         {Tag: "Зарплата", Summary: "Файл для бухгалтерии", Admin: true, Query: []openAPIParam{qp("month", "month", "Месяц"), qp("format", "string", "csv | xlsx")}, Response: "file:text/csv"},
+	"GET /payroll/rates":                   {Tag: "Зарплата", Summary: "История ставок", Admin: true, Query: []openAPIParam{qp("worker", "string", "Работник"), qp("error", "string", "Текст ошибки")}, Response: "page"},
+	"POST /payroll/rates":                  {Tag: "Зарплата", Summary: "Новая ставка с даты", Admin: true, Form: []openAPIParam{qp("worker_id", "string", "Работник (или должность)"), qp("position", "string", "Должность"), qp("object_id", "string", "Только на объекте"), rp("effective_from", "date", "Действует с"), rp("hourly_rate", "number", "Ставка в час"), qp("note", "string", "Комментарий")}, Response: "redirect"},
+	"POST /payroll/rates/delete/:id":       {Tag: "Зарплата", Summary: "Удалить запись истории ставок", Admin: true, Response: "redirect"},
+
+	// JSON API
+	"GET /api/v1/workers":             {Tag: "API: работники", Summary: "Список работников (не-админ — только своя карточка)", Query: withListQuery(qp("status", "string", "active (по умолчанию) | fired | all"), qp("position", "string", "Должность"), qp("q", "string", "Поиск по имени, телефону, табельному номеру")), Response: "list", Result: models.Worker{}},
+	"POST /api/v1/workers":            {Tag: "API: работники", Summary: "Создать работника", Admin: true, Body: apiWorkerInput{}, Response: "json", Result: models.Worker{}},
+	"GET /api/v1/workers/:id":         {Tag: "API: работники", Summary: "Работник", Response: "json", Result: models.Worker{}},
+	"PUT /api/v1/workers/:id":         {Tag: "API: работники", Summary: "Изменить работника (переданные поля)", Admin: true, Body: apiWorkerInput{}, Response: "json", Result: models.Worker{}},
+	"DELETE /api/v1/workers/:id":      {Tag: "API: работники", Summary: "Уволить работника", Admin: true},
+	"GET /api/v1/objects":             {Tag: "API: объекты", Summary: "Список объектов", Query: withListQuery(qp("status", "string", "in_progress | paused | completed"), qp("responsible_user_id", "string", "Ответственный"), qp("q", "string", "Поиск по названию и адресу")), Response: "list", Result: models.Object{}},
+	"POST /api/v1/objects":            {Tag: "API: объекты", Summary: "Создать объект", Admin: true, Body: apiObjectInput{}, Response: "json", Result: models.Object{}},
+	"GET /api/v1/objects/:id":         {Tag: "API: объекты", Summary: "Объект", Response: "json", Result: models.Object{}},
+	"PUT /api/v1/objects/:id":         {Tag: "API: объекты", Summary: "Изменить объект (переданные поля)", Admin: true, Body: apiObjectInput{}, Response: "json", Result: models.Object{}},
+	"DELETE /api/v1/objects/:id":      {Tag: "API: объекты", Summary: "Удалить объект", Admin: true},
+	"GET /api/v1/schedule":            {Tag: "API: назначения", Summary: "Назначения и отметки (не-админ — только свои)", Query: withListQuery(qp("from", "date", "С"), qp("to", "date", "По"), qp("month", "month", "Месяц"), qp("worker_id", "string", "Работник"), qp("object_id", "string", "Объект"), qp("status", "string", "approved | pending | rejected"), qp("mark", "string", "Код отметки или none — только рабочее время")), Response: "list", Result: models.TimesheetEntry{}},
+	"POST /api/v1/schedule":           {Tag: "API: назначения", Summary: "Создать назначение или отметку", Body: apiScheduleInput{}, Response: "json", Result: models.TimesheetEntry{}},
+	"GET /api/v1/schedule/:id":        {Tag: "API: назначения", Summary: "Назначение", Response: "json", Result: models.TimesheetEntry{}},
+	"PUT /api/v1/schedule/:id":        {Tag: "API: назначения", Summary: "Изменить назначение (переданные поля)", Body: apiScheduleInput{}, Response: "json", Result: models.TimesheetEntry{}},
+	"DELETE /api/v1/schedule/:id":     {Tag: "API: назначения", Summary: "Удалить назначение"},
+	"GET /api/v1/marks":               {Tag: "API: отметки", Summary: "Справочник отметок", Query: withListQuery(qp("active", "boolean", "true — только активные")), Response: "list", Result: models.MarkType{}},
+	"POST /api/v1/marks":              {Tag: "API: отметки", Summary: "Создать отметку", Admin: true, Body: models.MarkType{}, Response: "json", Result: models.MarkType{}},
+	"GET /api/v1/marks/:code":         {Tag: "API: отметки", Summary: "Отметка по коду или синониму", Response: "json", Result: models.MarkType{}},
+	"PUT /api/v1/marks/:code":         {Tag: "API: отметки", Summary: "Изменить отметку (переданные поля, код не меняется)", Admin: true, Body: models.MarkType{}, Response: "json", Result: models.MarkType{}},
+	"DELETE /api/v1/marks/:code":      {Tag: "API: отметки", Summary: "Удалить неиспользуемую отметку", Admin: true},
+	"GET /api/v1/improvements":        {Tag: "API: предложения", Summary: "Ошибки и предложения", Query: withListQuery(qp("type", "string", "bug | improvement"), qp("status", "string", "open | done"), qp("q", "string", "Поиск по заголовку и описанию")), Response: "list", Result: models.ImprovementItem{}},
+	"POST /api/v1/improvements":       {Tag: "API: предложения", Summary: "Создать ошибку или предложение", Body: apiImprovementInput{}, Response: "json", Result: models.ImprovementItem{}},
+	"GET /api/v1/improvements/:id":    {Tag: "API: предложения", Summary: "Запись", Response: "json", Result: models.ImprovementItem{}},
+	"PUT /api/v1/improvements/:id":    {Tag: "API: предложения", Summary: "Изменить запись (админ или автор)", Body: apiImprovementInput{}, Response: "json", Result: models.ImprovementItem{}},
+	"DELETE /api/v1/improvements/:id": {Tag: "API: предложения", Summary: "Удалить запись", Admin: true},
+	"GET /api/v1/users":               {Tag: "API: пользователи", Summary: "Пользователи", Admin: true, Query: withListQuery(qp("status", "string", "admin | user"), qp("q", "string", "Поиск по логину, имени, телефону")), Response: "list", Result: apiUser{}},
+	"POST /api/v1/users":              {Tag: "API: пользователи", Summary: "Создать пользователя", Admin: true, Body: apiUserInput{}, Response: "json", Result: apiUser{}},
+	"GET /api/v1/users/:id":           {Tag: "API: пользователи", Summary: "Пользователь", Admin: true, Response: "json", Result: apiUser{}},
+	"PUT /api/v1/users/:id":           {Tag: "API: пользователи", Summary: "Изменить пользователя (переданные поля)", Admin: true, Body: apiUserInput{}, Response: "json", Result: apiUser{}},
+	"DELETE /api/v1/users/:id":        {Tag: "API: пользователи", Summary: "Удалить пользователя", Admin: true},
+}
@@ -0,0 +1,21 @@
+package api_test
+
+import (
+	"testing"
+
+	"project/internal/api"
+	"project/internal/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEveryRouteIsDocumented fails when a route is registered without an entry in openAPIOperations.
+func TestEveryRouteIsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.SetupRouter(r)
+
+	if missing := api.UndocumentedRoutes(r.Routes()); len(missing) > 0 {
+		t.Fatalf("routes missing from internal/api/openapi_routes.go: %v", missing)
+	}
+}
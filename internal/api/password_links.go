@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const passwordSetupTemplate = `
+<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover">
+    <meta name="theme-color" content="#efe7db">
+    <title>Установка пароля</title>
+    <link rel="stylesheet" href="/static/css/style.css">
+</head>
+<body class="login-screen">
+    <div class="center-page">
+        <div class="card center-card login-card">
+            <div class="login-card-head">
+                <h2>Установка пароля</h2>
+                {{BODY}}
+            </div>
+        </div>
+    </div>
+</body>
+</html>`
+
+const passwordSetupErrorStyle = `margin-bottom: 16px; color: #b42318; background: #fee4e2; border: 1px solid #fecdca; border-radius: 8px; padding: 10px 12px; font-size: 14px;`
+
+func renderPasswordSetup(c *gin.Context, status int, body string) {
+	final := strings.Replace(passwordSetupTemplate, "{{BODY}}", body, 1)
+	c.Data(status, "text/html; charset=utf-8", []byte(final))
+}
+
+func renderPasswordLinkInvalid(c *gin.Context) {
+	renderPasswordSetup(c, http.StatusNotFound, `<p style="margin-bottom: 25px;">Ссылка недействительна: она уже использована или срок её действия истёк. Попросите администратора задать пароль.</p><a href="/login" class="btn btn-primary" style="width: 100%;">Ко входу</a>`)
+}
+
+// PasswordSetupPage is opened from the welcome message: a new user chooses their own password.
+func PasswordSetupPage(c *gin.Context) {
+	user, err := storage.GetPasswordLinkUser(c.Param("token"))
+	if err != nil {
+		renderPasswordLinkInvalid(c)
+		return
+	}
+	errorBlock := ""
+	if c.Query("error") == "mismatch" {
+		errorBlock = `<div style="` + passwordSetupErrorStyle + `">Пароли не совпадают.</div>`
+	}
+	renderPasswordSetup(c, http.StatusOK, fmt.Sprintf(`<p style="margin-bottom: 25px;">Логин: <strong>%s</strong>. Придумайте пароль для входа, ссылка сработает один раз.</p>%s
+                <form action="/password/%s" method="POST">
+                    <div class="form-group">
+                        <label for="password">Новый пароль</label>
+                        <input type="password" id="password" name="password" required autofocus autocomplete="new-password">
+                    </div>
+                    <div class="form-group">
+                        <label for="password_confirm">Повторите пароль</label>
+                        <input type="password" id="password_confirm" name="password_confirm" required autocomplete="new-password">
+                    </div>
+                    <button type="submit" class="btn btn-primary" style="width: 100%%;">Сохранить и войти</button>
+                </form>`,
+		template.HTMLEscapeString(user.Username),
+		errorBlock,
+		template.HTMLEscapeString(c.Param("token")),
+	))
+}
+
+// PasswordSetup stores the chosen password and uses up the link.
+func PasswordSetup(c *gin.Context) {
+	token := c.Param("token")
+	password := c.PostForm("password")
+	if password == "" || password != c.PostForm("password_confirm") {
+		c.Redirect(http.StatusFound, "/password/"+url.PathEscape(token)+"?error=mismatch")
+		return
+	}
+	user, err := storage.UsePasswordLink(token, password)
+	if errors.Is(err, storage.ErrInvalidPasswordLink) {
+		renderPasswordLinkInvalid(c)
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Не удалось сохранить пароль: %v", err)
+		return
+	}
+	security.LogEvent("password_link_used", fmt.Sprintf("user=%s ip=%s", user.Username, c.ClientIP()))
+	c.Redirect(http.StatusFound, "/login")
+}
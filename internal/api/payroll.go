@@ -0,0 +1,375 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func humanizePayrollError(err error) string {
+	if errors.Is(err, storage.ErrMonthClosed) {
+		return "Месяц закрыт: начисления зафиксированы, изменения запрещены."
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "worker or a position"):
+		return "Укажите работника или должность — что-то одно."
+	case strings.Contains(msg, "invalid date format"):
+		return "Укажите дату, с которой действует ставка."
+	case strings.Contains(msg, "must not be negative"), strings.Contains(msg, "must be positive"):
+		return "Сумма должна быть больше нуля."
+	case strings.Contains(msg, "unknown adjustment kind"):
+		return "Неизвестный вид начисления."
+	case strings.Contains(msg, "worker not found"):
+		return "Работник не найден."
+	case strings.Contains(msg, "object not found"):
+		return "Объект не найден."
+	default:
+		return "Не удалось сохранить: " + msg
+	}
+}
+
+func adjustmentKindLabel(kind string) string {
+	switch kind {
+	case "bonus":
+		return "Премия"
+	case "deduction":
+		return "Удержание"
+	case "advance":
+		return "Аванс"
+	default:
+		return kind
+	}
+}
+
+func formatMoney(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+func parseMoney(value string) float64 {
+	parsed, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(value), ",", "."), 64)
+	return parsed
+}
+
+func formatRates(rates []float64) string {
+	if len(rates) == 0 {
+		return "—"
+	}
+	parts := make([]string, 0, len(rates))
+	for _, rate := range rates {
+		parts = append(parts, formatMoney(rate))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// workerPayrollLine returns the payroll of one worker for the month (frozen if the month is closed).
+func workerPayrollLine(workerID, month string) (models.PayrollLine, bool) {
+	lines, _, err := storage.GetPayroll(month)
+	if err != nil {
+		return models.PayrollLine{}, false
+	}
+	for _, line := range lines {
+		if line.WorkerID == workerID {
+			return line, true
+		}
+	}
+	return models.PayrollLine{}, false
+}
+
+func workerSelectOptions(workers []models.Worker, selectedID string, withEmpty bool) string {
+	var options strings.Builder
+	if withEmpty {
+		options.WriteString(`<option value="">—</option>`)
+	}
+	for _, worker := range workers {
+		if worker.IsFired && worker.ID != selectedID {
+			continue
+		}
+		selected := ""
+		if worker.ID == selectedID {
+			selected = " selected"
+		}
+		options.WriteString(`<option value="` + template.HTMLEscapeString(worker.ID) + `"` + selected + `>` + template.HTMLEscapeString(worker.Name) + `</option>`)
+	}
+	return options.String()
+}
+
+// PayrollPage is the monthly payroll report with bonuses, deductions and advances.
+func PayrollPage(c *gin.Context) {
+	selectedMonth, _, _ := resolveSelectedMonth(c.Query("month"))
+	lines, snapshot, err := storage.GetPayroll(selectedMonth)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to calculate payroll: %v", err)
+		return
+	}
+	adjustments, err := storage.GetPayrollAdjustments(selectedMonth)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load adjustments: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	workersMap := make(map[string]string, len(workers))
+	for _, worker := range workers {
+		workersMap[worker.ID] = worker.Name
+	}
+	closed := storage.IsMonthClosed(selectedMonth)
+	returnPath := "/payroll?month=" + selectedMonth
+
+	var rows strings.Builder
+	var total models.PayrollLine
+	for _, line := range lines {
+		rows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%.2f</td><td>%.2f</td><td>%.2f</td><td>%.2f</td><td>%.2f</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td><strong>%s</strong></td></tr>`,
+			joinMappedLinks([]string{line.WorkerID}, map[string]string{line.WorkerID: line.WorkerName}, "/worker"),
+			line.Hours.Total, line.Hours.Regular, line.Hours.Overtime, line.Hours.Night, line.Hours.Weekend, line.PaidDays,
+			template.HTMLEscapeString(formatRates(line.Rates)),
+			formatMoney(line.Earned), formatMoney(line.Bonuses), formatMoney(line.Deductions), formatMoney(line.Advances), formatMoney(line.Payable)))
+		total.Hours.Total += line.Hours.Total
+		total.Earned += line.Earned
+		total.Bonuses += line.Bonuses
+		total.Deductions += line.Deductions
+		total.Advances += line.Advances
+		total.Payable += line.Payable
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="13">За месяц нет начислений.</td></tr>`)
+	} else {
+		rows.WriteString(fmt.Sprintf(`<tr class="payroll-total"><th>Итого</th><th>%.2f</th><th colspan="6"></th><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr>`,
+			total.Hours.Total, formatMoney(total.Earned), formatMoney(total.Bonuses), formatMoney(total.Deductions), formatMoney(total.Advances), formatMoney(total.Payable)))
+	}
+
+	var adjustmentRows strings.Builder
+	for _, adjustment := range adjustments {
+		action := ""
+		if !closed {
+			action = fmt.Sprintf(`<form method="POST" action="/payroll/adjustments/delete/%s">%s<input type="hidden" name="return_to" value="%s"><button type="submit" class="btn btn-danger btn-compact">Удалить</button></form>`,
+				template.HTMLEscapeString(adjustment.ID), CSRFHiddenInput(c), template.HTMLEscapeString(returnPath))
+		}
+		note := adjustment.Note
+		if note == "" {
+			note = "—"
+		}
+		adjustmentRows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			joinMappedLinks([]string{adjustment.WorkerID}, workersMap, "/worker"),
+			template.HTMLEscapeString(adjustmentKindLabel(adjustment.Kind)),
+			formatMoney(adjustment.Amount),
+			template.HTMLEscapeString(note),
+			template.HTMLEscapeString(adjustment.CreatedByName),
+			action))
+	}
+	if adjustmentRows.Len() == 0 {
+		adjustmentRows.WriteString(`<tr><td colspan="6">Премий, удержаний и авансов нет.</td></tr>`)
+	}
+
+	statusBlock := `<div class="dashboard-alert-item"><strong>Предварительный расчёт</strong><p>Суммы пересчитываются при изменении табеля и ставок. После закрытия месяца расчёт фиксируется.</p></div>`
+	if snapshot != nil {
+		statusBlock = fmt.Sprintf(`<div class="dashboard-alert-item month-closed-banner"><strong>Расчёт зафиксирован</strong><p>При закрытии месяца %s (%s). Изменения ставок и табеля на него не влияют.</p></div>`,
+			template.HTMLEscapeString(formatClosureTime(snapshot.CreatedAt)), template.HTMLEscapeString(snapshot.CreatedByName))
+	}
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock += `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	adjustmentForm := ""
+	if !closed {
+		adjustmentForm = `<form method="POST" action="/payroll/adjustments" class="mark-type-form">` + CSRFHiddenInput(c) + `<input type="hidden" name="month" value="` + template.HTMLEscapeString(selectedMonth) + `">
+<select name="worker_id" required>` + workerSelectOptions(workers, "", false) + `</select>
+<select name="kind"><option value="bonus">Премия</option><option value="deduction">Удержание</option><option value="advance">Аванс</option></select>
+<input type="number" name="amount" min="0.01" step="0.01" placeholder="Сумма" required>
+<input type="text" name="note" placeholder="Комментарий">
+<button type="submit" class="btn btn-primary btn-compact">Добавить</button></form>`
+	}
+
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><form method="GET" action="/payroll" class="month-selector"><select name="month" onchange="this.form.submit()">`+monthOptionsHTML(selectedMonth)+`</select></form><a class="btn btn-secondary" href="/payroll/export/download?format=csv&month=`+selectedMonth+`">CSV</a><a class="btn btn-secondary" href="/payroll/export/download?format=xlsx&month=`+selectedMonth+`">XLSX</a><a class="btn btn-secondary" href="/payroll/export">Колонки</a><a class="btn btn-secondary" href="/payroll/rates">Ставки</a></div>`)
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Зарплата</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header page-header-desktop-hidden"><h1>Зарплата</h1></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table payroll-table"><thead><tr><th>Работник</th><th>Часы</th><th>Обычн.</th><th>Сверх.</th><th>Ночь</th><th>Вых./пр.</th><th>Опл. дни</th><th>Ставка</th><th>Начислено</th><th>Премии</th><th>Удержания</th><th>Аванс</th><th>К выплате</th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>Премии, удержания и авансы</h2>
+<div class="table-scroll"><table class="table"><thead><tr><th>Работник</th><th>Вид</th><th>Сумма</th><th>Комментарий</th><th>Внёс</th><th></th></tr></thead><tbody>{{ADJUSTMENT_ROWS}}</tbody></table></div>
+{{ADJUSTMENT_FORM}}
+</div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "payroll"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{ADJUSTMENT_ROWS}}", adjustmentRows.String(), 1)
+	final = strings.Replace(final, "{{ADJUSTMENT_FORM}}", adjustmentForm, 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func SavePayrollAdjustment(c *gin.Context) {
+	month := strings.TrimSpace(c.PostForm("month"))
+	adjustment, err := storage.AddPayrollAdjustment(models.PayrollAdjustment{
+		WorkerID:      c.PostForm("worker_id"),
+		Month:         month,
+		Kind:          c.PostForm("kind"),
+		Amount:        parseMoney(c.PostForm("amount")),
+		Note:          c.PostForm("note"),
+		CreatedByName: c.GetString("userName"),
+	})
+	if err != nil {
+		c.Redirect(http.StatusFound, "/payroll?month="+url.QueryEscape(month)+"&error="+url.QueryEscape(humanizePayrollError(err)))
+		return
+	}
+	security.LogEvent("payroll_adjustment_added", fmt.Sprintf("user=%s worker=%s month=%s kind=%s amount=%.2f", c.GetString("userName"), adjustment.WorkerID, adjustment.Month, adjustment.Kind, adjustment.Amount))
+	c.Redirect(http.StatusFound, "/payroll?month="+url.QueryEscape(month))
+}
+
+func DeletePayrollAdjustment(c *gin.Context) {
+	returnTo := c.PostForm("return_to")
+	if !strings.HasPrefix(returnTo, "/") {
+		returnTo = "/payroll"
+	}
+	if err := storage.DeletePayrollAdjustment(c.Param("id")); err != nil {
+		c.Redirect(http.StatusFound, appendQuery(returnTo, "error", humanizePayrollError(err)))
+		return
+	}
+	security.LogEvent("payroll_adjustment_deleted", fmt.Sprintf("user=%s id=%s", c.GetString("userName"), c.Param("id")))
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// PayrollRatesPage lists the effective-dated rate history.
+func PayrollRatesPage(c *gin.Context) {
+	changes, err := storage.GetRateChanges()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load rates: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	objectsMap, err := buildObjectsMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	workersMap := make(map[string]string, len(workers))
+	for _, worker := range workers {
+		workersMap[worker.ID] = worker.Name
+	}
+	filterWorker := strings.TrimSpace(c.Query("worker"))
+
+	var rows strings.Builder
+	for _, change := range changes {
+		if filterWorker != "" && change.WorkerID != filterWorker {
+			continue
+		}
+		target := "Должность: " + template.HTMLEscapeString(change.Position)
+		if change.WorkerID != "" {
+			target = joinMappedLinks([]string{change.WorkerID}, workersMap, "/worker")
+		}
+		object := "Все объекты"
+		if change.ObjectID != "" {
+			object = joinMappedLinks([]string{change.ObjectID}, objectsMap, "/object")
+		}
+		note := change.Note
+		if note == "" {
+			note = "—"
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td><form method="POST" action="/payroll/rates/delete/%s" onsubmit="return confirm('Удалить ставку? Расчёт незакрытых месяцев изменится.')">%s<button type="submit" class="btn btn-danger btn-compact">Удалить</button></form></td></tr>`,
+			template.HTMLEscapeString(formatScheduleDateLabel(change.EffectiveFrom)),
+			target,
+			object,
+			formatMoney(change.HourlyRate),
+			template.HTMLEscapeString(note),
+			template.HTMLEscapeString(change.CreatedByName),
+			template.HTMLEscapeString(change.ID),
+			CSRFHiddenInput(c)))
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="7">История ставок пуста — используется ставка из карточки работника.</td></tr>`)
+	}
+
+	var objectOptions strings.Builder
+	objectOptions.WriteString(`<option value="">Все объекты</option>`)
+	if objects, err := storage.GetObjects(); err == nil {
+		for _, object := range objects {
+			objectOptions.WriteString(`<option value="` + template.HTMLEscapeString(object.ID) + `">` + template.HTMLEscapeString(object.Name) + `</option>`)
+		}
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Ставки</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/payroll" class="back-link">← К расчёту зарплаты</a>
+<div class="page-header"><h1>История ставок</h1><p>Ставка действует с указанной даты. Приоритет: работник на объекте, работник, должность на объекте, должность; если ничего не подошло — ставка из карточки работника.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>С даты</th><th>Кому</th><th>Объект</th><th>Ставка, руб/ч</th><th>Комментарий</th><th>Внёс</th><th></th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>Новая ставка</h2>
+<form method="POST" action="/payroll/rates" class="mark-type-form">{{CSRF_FIELD}}
+<select name="worker_id">{{WORKER_OPTIONS}}</select>
+<input type="text" name="position" placeholder="или должность">
+<select name="object_id">{{OBJECT_OPTIONS}}</select>
+<input type="date" name="effective_from" value="{{TODAY}}" required>
+<input type="number" name="hourly_rate" min="0" step="0.01" placeholder="руб/ч" required>
+<input type="text" name="note" placeholder="Комментарий">
+<button type="submit" class="btn btn-primary btn-compact">Добавить</button>
+</form></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "payroll"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	final = strings.Replace(final, "{{WORKER_OPTIONS}}", workerSelectOptions(workers, filterWorker, true), 1)
+	final = strings.Replace(final, "{{OBJECT_OPTIONS}}", objectOptions.String(), 1)
+	final = strings.Replace(final, "{{TODAY}}", time.Now().Format("2006-01-02"), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func SaveRateChange(c *gin.Context) {
+	change, err := storage.AddRateChange(models.RateChange{
+		WorkerID:      c.PostForm("worker_id"),
+		Position:      c.PostForm("position"),
+		ObjectID:      c.PostForm("object_id"),
+		EffectiveFrom: c.PostForm("effective_from"),
+		HourlyRate:    parseMoney(c.PostForm("hourly_rate")),
+		Note:          c.PostForm("note"),
+		CreatedByName: c.GetString("userName"),
+	})
+	if err != nil {
+		c.Redirect(http.StatusFound, "/payroll/rates?error="+url.QueryEscape(humanizePayrollError(err)))
+		return
+	}
+	security.LogEvent("rate_change_added", fmt.Sprintf("user=%s worker=%s position=%q object=%s from=%s rate=%.2f", c.GetString("userName"), change.WorkerID, change.Position, change.ObjectID, change.EffectiveFrom, change.HourlyRate))
+	c.Redirect(http.StatusFound, "/payroll/rates")
+}
+
+func DeleteRateChange(c *gin.Context) {
+	if err := storage.DeleteRateChange(c.Param("id")); err != nil {
+		c.Redirect(http.StatusFound, "/payroll/rates?error="+url.QueryEscape(humanizePayrollError(err)))
+		return
+	}
+	security.LogEvent("rate_change_deleted", fmt.Sprintf("user=%s id=%s", c.GetString("userName"), c.Param("id")))
+	c.Redirect(http.StatusFound, "/payroll/rates")
+}
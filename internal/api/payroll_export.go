@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// payrollExportValue returns a number for numeric fields so XLSX cells stay numeric.
+func payrollExportValue(line models.PayrollLine, field, month string) any {
+	if strings.HasPrefix(field, "mark:") {
+		return line.MarkDays[strings.TrimPrefix(field, "mark:")]
+	}
+	switch field {
+	case "month":
+		return month
+	case "worker_id":
+		return line.WorkerID
+	case "worker_name":
+		return line.WorkerName
+	case "position":
+		return line.Position
+	case "hours_total":
+		return line.Hours.Total
+	case "hours_regular":
+		return line.Hours.Regular
+	case "hours_overtime":
+		return line.Hours.Overtime
+	case "hours_night":
+		return line.Hours.Night
+	case "hours_weekend":
+		return line.Hours.Weekend
+	case "paid_days":
+		return line.PaidDays
+	case "rate":
+		// Several rates in a month are exported as the average paid per hour.
+		if len(line.Rates) == 1 {
+			return line.Rates[0]
+		}
+		if line.Hours.Total > 0 {
+			return roundMoneyValue(line.Earned / line.Hours.Total)
+		}
+		return 0.0
+	case "earned":
+		return line.Earned
+	case "bonuses":
+		return line.Bonuses
+	case "deductions":
+		return line.Deductions
+	case "advances":
+		return line.Advances
+	case "payable":
+		return line.Payable
+	default:
+		return ""
+	}
+}
+
+func roundMoneyValue(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func formatExportCell(value any, decimalComma bool) string {
+	switch v := value.(type) {
+	case float64:
+		text := strconv.FormatFloat(v, 'f', 2, 64)
+		if decimalComma {
+			text = strings.Replace(text, ".", ",", 1)
+		}
+		return text
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// PayrollExportPage configures which payroll values go to which export columns.
+func PayrollExportPage(c *gin.Context) {
+	settings, err := storage.GetAppSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load app settings: %v", err)
+		return
+	}
+	marks, err := storage.GetMarkTypes()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load marks: %v", err)
+		return
+	}
+	export := settings.PayrollExport
+
+	type fieldRow struct {
+		Key, Label, Header string
+		Order              int
+		Included           bool
+	}
+	fields := make([]fieldRow, 0)
+	for _, field := range storage.PayrollExportFields() {
+		fields = append(fields, fieldRow{Key: field.Key, Label: field.Header, Header: field.Header})
+	}
+	for _, mark := range marks {
+		fields = append(fields, fieldRow{Key: storage.MarkExportField(mark.Code), Label: "Дни «" + mark.Label + "»", Header: mark.Code})
+	}
+	for i := range fields {
+		fields[i].Order = 100 + i
+		for position, column := range export.Columns {
+			if column.Field == fields[i].Key {
+				fields[i].Included = true
+				fields[i].Header = column.Header
+				fields[i].Order = position + 1
+			}
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Order < fields[j].Order })
+
+	var rows strings.Builder
+	for i, field := range fields {
+		rows.WriteString(fmt.Sprintf(`<tr><td><input type="hidden" name="field_%d" value="%s"><label><input type="checkbox" name="include_%d" value="1"%s> %s</label></td><td><code>%s</code></td><td><input type="text" name="header_%d" value="%s"></td><td><input type="number" name="order_%d" value="%d" min="1"></td></tr>`,
+			i, template.HTMLEscapeString(field.Key),
+			i, checkedAttr(field.Included), template.HTMLEscapeString(field.Label),
+			template.HTMLEscapeString(field.Key),
+			i, template.HTMLEscapeString(field.Header),
+			i, field.Order))
+	}
+
+	delimiterOptions := ""
+	for _, option := range []struct{ Value, Label string }{{";", "точка с запятой"}, {",", "запятая"}, {"tab", "табуляция"}} {
+		value := option.Value
+		if value == "tab" {
+			value = "\t"
+		}
+		selected := ""
+		if export.Delimiter == value {
+			selected = " selected"
+		}
+		delimiterOptions += `<option value="` + option.Value + `"` + selected + `>` + option.Label + `</option>`
+	}
+
+	statusBlock := ""
+	if c.Query("ok") != "" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Сохранено</strong><p>Новый набор колонок применяется к следующим выгрузкам.</p></div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Выгрузка зарплаты</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/payroll" class="back-link">← К расчёту зарплаты</a>
+<div class="page-header"><h1>Выгрузка для бухгалтерии</h1><p>Одна строка на работника за месяц. Отметьте нужные поля, задайте заголовки колонок так, как их ждёт учётная программа, и порядок.</p></div>
+{{STATUS_BLOCK}}
+<form method="POST" action="/payroll/export">{{CSRF_FIELD}}<input type="hidden" name="field_count" value="{{FIELD_COUNT}}">
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Поле</th><th>Ключ</th><th>Заголовок колонки</th><th>Порядок</th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>Формат CSV</h2><div class="mark-type-form">
+<label>Разделитель <select name="delimiter">{{DELIMITER_OPTIONS}}</select></label>
+<label><input type="checkbox" name="decimal_comma" value="1"{{DECIMAL_COMMA}}> десятичная запятая</label>
+<button type="submit" class="btn btn-primary">Сохранить</button>
+</div></div>
+</form>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "payroll"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	final = strings.Replace(final, "{{FIELD_COUNT}}", strconv.Itoa(len(fields)), 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{DELIMITER_OPTIONS}}", delimiterOptions, 1)
+	final = strings.Replace(final, "{{DECIMAL_COMMA}}", checkedAttr(export.DecimalComma), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func SavePayrollExportSettings(c *gin.Context) {
+	settings, err := storage.GetAppSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load app settings: %v", err)
+		return
+	}
+	type orderedColumn struct {
+		column models.PayrollExportColumn
+		order  int
+	}
+	count, _ := strconv.Atoi(c.PostForm("field_count"))
+	selected := make([]orderedColumn, 0)
+	for i := 0; i < count; i++ {
+		if c.PostForm(fmt.Sprintf("include_%d", i)) == "" {
+			continue
+		}
+		order, _ := strconv.Atoi(strings.TrimSpace(c.PostForm(fmt.Sprintf("order_%d", i))))
+		selected = append(selected, orderedColumn{
+			column: models.PayrollExportColumn{Field: c.PostForm(fmt.Sprintf("field_%d", i)), Header: c.PostForm(fmt.Sprintf("header_%d", i))},
+			order:  order,
+		})
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].order < selected[j].order })
+	columns := make([]models.PayrollExportColumn, 0, len(selected))
+	for _, item := range selected {
+		columns = append(columns, item.column)
+	}
+	delimiter := c.PostForm("delimiter")
+	if delimiter == "tab" {
+		delimiter = "\t"
+	}
+	settings.PayrollExport = models.PayrollExportSettings{Columns: columns, Delimiter: delimiter, DecimalComma: c.PostForm("decimal_comma") != ""}
+	if err := storage.UpdateAppSettings(settings); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to save app settings: %v", err)
+		return
+	}
+	security.LogEvent("payroll_export_saved", fmt.Sprintf("user=%s columns=%d", c.GetString("userName"), len(columns)))
+	c.Redirect(http.StatusFound, "/payroll/export?ok=1")
+}
+
+// DownloadPayrollExport writes the month payroll as CSV or XLSX using the saved column mapping.
+func DownloadPayrollExport(c *gin.Context) {
+	selectedMonth, _, _ := resolveSelectedMonth(c.Query("month"))
+	lines, _, err := storage.GetPayroll(selectedMonth)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to calculate payroll: %v", err)
+		return
+	}
+	settings, err := storage.GetAppSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load app settings: %v", err)
+		return
+	}
+	columns := settings.PayrollExport.Columns
+	security.LogEvent("payroll_exported", fmt.Sprintf("user=%s month=%s format=%s", c.GetString("userName"), selectedMonth, c.Query("format")))
+
+	if c.Query("format") == "xlsx" {
+		f := excelize.NewFile()
+		defer f.Close()
+		sheet := f.GetSheetName(0)
+		headerStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		for col, column := range columns {
+			cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+			f.SetCellValue(sheet, cell, column.Header)
+			f.SetCellStyle(sheet, cell, cell, headerStyle)
+		}
+		for row, line := range lines {
+			for col, column := range columns {
+				cell, _ := excelize.CoordinatesToCellName(col+1, row+2)
+				f.SetCellValue(sheet, cell, payrollExportValue(line, column.Field, selectedMonth))
+			}
+		}
+		if len(columns) > 0 {
+			lastCol, _ := excelize.ColumnNumberToName(len(columns))
+			f.SetColWidth(sheet, "A", lastCol, 16)
+		}
+
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to build xlsx: %v", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "payroll-"+selectedMonth+".xlsx"))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+		return
+	}
+
+	var buf bytes.Buffer
+	// BOM so that Excel opens the UTF-8 file with Cyrillic headers correctly.
+	buf.WriteString("\ufeff")
+	writer := csv.NewWriter(&buf)
+	writer.Comma = []rune(settings.PayrollExport.Delimiter)[0]
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.Header)
+	}
+	writer.Write(header)
+	for _, line := range lines {
+		record := make([]string, 0, len(columns))
+		for _, column := range columns {
+			record = append(record, formatExportCell(payrollExportValue(line, column.Field, selectedMonth), settings.PayrollExport.DecimalComma))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to build csv: %v", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "payroll-"+selectedMonth+".csv"))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,504 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfFontDir holds the DejaVu fonts: the PDF core fonts have no Cyrillic.
+const pdfFontDir = "./web/static/fonts"
+
+const pdfFontFamily = "DejaVu"
+
+// pdfDocument wraps gofpdf with the table helpers shared by the PDF exports.
+type pdfDocument struct {
+	*gofpdf.Fpdf
+	header func()
+}
+
+func newPDFDocument(orientation, title string) *pdfDocument {
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	pdf.AddUTF8Font(pdfFontFamily, "", filepath.Join(pdfFontDir, "DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font(pdfFontFamily, "B", filepath.Join(pdfFontDir, "DejaVuSansCondensed-Bold.ttf"))
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("АВАЮССТРОЙ", true)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(false, 12)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont(pdfFontFamily, "", 7)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, title+" · сформировано "+time.Now().Format("02.01.2006 15:04"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("стр. %d из {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	doc := &pdfDocument{Fpdf: pdf}
+	doc.AddPage()
+	return doc
+}
+
+func (doc *pdfDocument) title(text, subtitle string) {
+	doc.SetFont(pdfFontFamily, "B", 13)
+	doc.CellFormat(0, 7, text, "", 1, "L", false, 0, "")
+	if subtitle != "" {
+		doc.SetFont(pdfFontFamily, "", 9)
+		doc.CellFormat(0, 5, subtitle, "", 1, "L", false, 0, "")
+	}
+	doc.Ln(2)
+}
+
+// ensureSpace starts a new page (repeating the table header) when height does not fit.
+func (doc *pdfDocument) ensureSpace(height float64) {
+	_, pageHeight := doc.GetPageSize()
+	_, _, _, bottom := doc.GetMargins()
+	if doc.GetY()+height <= pageHeight-bottom {
+		return
+	}
+	doc.AddPage()
+	if doc.header != nil {
+		doc.header()
+	}
+}
+
+// row draws one table row; long texts wrap and the row grows to the tallest cell.
+// fills holds optional per-cell background colors as #rrggbb.
+func (doc *pdfDocument) row(widths []float64, cells []string, aligns string, lineHeight float64, fills []string) {
+	lines := make([][]string, len(cells))
+	maxLines := 1
+	for i, cell := range cells {
+		lines[i] = doc.SplitText(cell, widths[i]-1)
+		if len(lines[i]) == 0 {
+			lines[i] = []string{""}
+		}
+		if len(lines[i]) > maxLines {
+			maxLines = len(lines[i])
+		}
+	}
+	height := float64(maxLines) * lineHeight
+	doc.ensureSpace(height)
+	x, y := doc.GetXY()
+	for i := range cells {
+		style := "D"
+		if i < len(fills) && fills[i] != "" {
+			r, g, b := hexToRGB(fills[i])
+			doc.SetFillColor(r, g, b)
+			style = "FD"
+		}
+		doc.Rect(x, y, widths[i], height, style)
+		align := "L"
+		if i < len(aligns) {
+			align = string(aligns[i])
+		}
+		for n, line := range lines[i] {
+			doc.SetXY(x, y+float64(n)*lineHeight)
+			doc.CellFormat(widths[i], lineHeight, line, "", 0, align, false, 0, "")
+		}
+		x += widths[i]
+	}
+	doc.SetXY(doc.GetX(), y+height)
+	left, _, _, _ := doc.GetMargins()
+	doc.SetX(left)
+}
+
+func (doc *pdfDocument) send(c *gin.Context, filename string) {
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to build pdf: %v", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func hexToRGB(value string) (int, int, int) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) != 6 {
+		return 255, 255, 255
+	}
+	parsed, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return 255, 255, 255
+	}
+	return int(parsed >> 16 & 0xff), int(parsed >> 8 & 0xff), int(parsed & 0xff)
+}
+
+// lightenHex mixes a mark color with white so the cell text stays readable in print.
+func lightenHex(value string) string {
+	r, g, b := hexToRGB(value)
+	mix := func(v int) int { return v + (255-v)*3/5 }
+	return fmt.Sprintf("#%02x%02x%02x", mix(r), mix(g), mix(b))
+}
+
+func formatPDFHours(hours float64) string {
+	if hours == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(hours, 'f', -1, 64)
+}
+
+// ExportTimesheetsPDF prints the табель matrix for the month, scoped like the Excel export.
+func ExportTimesheetsPDF(c *gin.Context) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load timesheets: %v", err)
+		return
+	}
+	entries, err = getScopedEntries(c, entries)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to scope timesheets: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	if !isAdmin(c) {
+		if ownWorker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil && !ownWorker.IsFired {
+			workers = []models.Worker{ownWorker}
+		} else {
+			workers = []models.Worker{}
+		}
+	}
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+
+	selectedMonth, monthStart, daysInMonth := resolveSelectedMonth(c.Query("month"))
+	monthDates := buildMonthDates(selectedMonth, daysInMonth)
+	calendar := buildMonthCalendar(monthDates)
+	_, monthNormHours := storage.MonthNorm(selectedMonth)
+	hourRules := currentHourRules()
+
+	title := fmt.Sprintf("Табель за %s %d", strings.ToLower(monthNamesNominative[monthStart.Month()-1]), monthStart.Year())
+	doc := newPDFDocument("L", title)
+	subtitle := fmt.Sprintf("Норма месяца: %s ч", formatPDFHours(monthNormHours))
+	if storage.IsMonthClosed(selectedMonth) {
+		subtitle += " · месяц закрыт"
+	}
+	doc.title(title, subtitle)
+
+	widths := []float64{44}
+	header := []string{"Работник"}
+	headerFills := []string{"#eef2f6"}
+	aligns := "L"
+	for day := 1; day <= daysInMonth; day++ {
+		widths = append(widths, 6.8)
+		header = append(header, strconv.Itoa(day))
+		fill := "#eef2f6"
+		if kind := calendar[day-1].Kind; kind == "holiday" || kind == "weekend" {
+			fill = "#fde8e8"
+		}
+		headerFills = append(headerFills, fill)
+		aligns += "C"
+	}
+	widths = append(widths, 12, 12)
+	header = append(header, "Итого", "Норма")
+	headerFills = append(headerFills, "#eef2f6", "#eef2f6")
+	aligns += "CC"
+	doc.header = func() {
+		doc.SetFont(pdfFontFamily, "B", 6.5)
+		doc.row(widths, header, aligns, 4.5, headerFills)
+		doc.SetFont(pdfFontFamily, "", 6.5)
+	}
+	doc.header()
+
+	markColors := map[string]string{}
+	if marks, err := storage.GetMarkTypes(); err == nil {
+		for _, mark := range marks {
+			if mark.Color != "" {
+				markColors[mark.Code] = lightenHex(mark.Color)
+			}
+		}
+	}
+	printed := 0
+	for _, worker := range workers {
+		if worker.IsFired {
+			continue
+		}
+		days := t13WorkerDays(entries, worker.ID, monthDates, calendar, attendanceMap)
+		cells := []string{worker.Name}
+		fills := []string{""}
+		total := 0.0
+		_, norm := workerMonthHours(entries, worker.ID, selectedMonth, monthDates, calendar, attendanceMap, hourRules)
+		for _, day := range days {
+			switch {
+			case day.Mark:
+				cells = append(cells, day.Code)
+				fills = append(fills, markColors[day.Code])
+			default:
+				cells = append(cells, formatPDFHours(roundHoursValue(day.Hours)))
+				fills = append(fills, "")
+				total += day.Hours
+			}
+		}
+		cells = append(cells, formatPDFHours(roundHoursValue(total)), formatPDFHours(norm))
+		doc.row(widths, cells, aligns, 4.5, fills)
+		printed++
+	}
+	if printed == 0 {
+		doc.row([]float64{sumWidths(widths)}, []string{"Нет данных за выбранный месяц"}, "C", 6, nil)
+	}
+	doc.send(c, "tabel-"+selectedMonth+".pdf")
+}
+
+func sumWidths(widths []float64) float64 {
+	total := 0.0
+	for _, width := range widths {
+		total += width
+	}
+	return total
+}
+
+// ExportObjectWeekPDF prints the week schedule of one object for the site trailer.
+func ExportObjectWeekPDF(c *gin.Context) {
+	object, err := storage.GetObjectByID(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Object not found")
+		return
+	}
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load timesheets: %v", err)
+		return
+	}
+	workersMap, err := buildWorkersMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	weekStart := resolveBoardWeek(c.Query("week"))
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	weekStartKey, weekEndKey := weekStart.Format("2006-01-02"), weekEnd.Format("2006-01-02")
+
+	weekEntries := make([]models.TimesheetEntry, 0)
+	for _, entry := range entries {
+		if entry.Date < weekStartKey || entry.Date > weekEndKey || isSpecialMark(entry.UserMark) || !entryHasObject(entry, object.ID) {
+			continue
+		}
+		weekEntries = append(weekEntries, entry)
+	}
+	sort.Slice(weekEntries, func(i, j int) bool {
+		if weekEntries[i].Date == weekEntries[j].Date {
+			return weekEntries[i].StartTime < weekEntries[j].StartTime
+		}
+		return weekEntries[i].Date < weekEntries[j].Date
+	})
+
+	title := "График: " + object.Name
+	doc := newPDFDocument("P", title)
+	subtitle := fmt.Sprintf("Неделя %s — %s", weekStart.Format("02.01.2006"), weekEnd.Format("02.01.2006"))
+	if strings.TrimSpace(object.Address) != "" {
+		subtitle += " · " + object.Address
+	}
+	doc.title(title, subtitle)
+
+	widths := []float64{28, 22, 90, 50}
+	aligns := "LCLL"
+	doc.header = func() {
+		doc.SetFont(pdfFontFamily, "B", 9)
+		doc.row(widths, []string{"День", "Время", "Работники", "Комментарий"}, aligns, 5.5, []string{"#eef2f6", "#eef2f6", "#eef2f6", "#eef2f6"})
+		doc.SetFont(pdfFontFamily, "", 9)
+	}
+	doc.header()
+	weekdayNames := []string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"}
+	for i := 0; i < 7; i++ {
+		day := weekStart.AddDate(0, 0, i)
+		dayKey := day.Format("2006-01-02")
+		dayLabel := weekdayNames[day.Weekday()] + " " + day.Format("02.01")
+		kind, _, note := storage.CalendarDayInfo(day)
+		fill := ""
+		if kind == "weekend" || kind == "holiday" {
+			fill = "#fde8e8"
+			if note != "" {
+				dayLabel += "\n" + note
+			}
+		}
+		found := false
+		for _, entry := range weekEntries {
+			if entry.Date != dayKey {
+				continue
+			}
+			found = true
+			names := make([]string, 0, len(entry.WorkerIDs))
+			for _, wid := range entry.WorkerIDs {
+				if name, ok := workersMap[wid]; ok {
+					names = append(names, name)
+				}
+			}
+			comment := strings.TrimSpace(entry.Notes)
+			if !storage.IsTimesheetApproved(entry) {
+				comment = strings.TrimSpace("на согласовании. " + comment)
+			}
+			timeLabel := entry.StartTime + "–" + entry.EndTime
+			doc.row(widths, []string{dayLabel, timeLabel, strings.Join(names, ", "), comment}, aligns, 5, []string{fill})
+			dayLabel = ""
+		}
+		if !found {
+			doc.row(widths, []string{dayLabel, "", "—", ""}, aligns, 5, []string{fill})
+		}
+	}
+	doc.send(c, fmt.Sprintf("object-week-%s.pdf", weekStartKey))
+}
+
+// ExportWorkerStatementPDF prints a worker's monthly statement: shifts, marks and totals.
+// Workers can only download their own statement.
+func ExportWorkerStatementPDF(c *gin.Context) {
+	worker, err := storage.GetWorkerByID(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Worker not found: %v", err)
+		return
+	}
+	if !isAdmin(c) {
+		ownWorker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+		if err != nil || ownWorker.ID != worker.ID {
+			c.String(http.StatusForbidden, "Доступ запрещён")
+			return
+		}
+	}
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load timesheets: %v", err)
+		return
+	}
+	objectsMap, err := buildObjectsMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
+	selectedMonth, monthStart, daysInMonth := resolveSelectedMonth(c.Query("month"))
+	monthDates := buildMonthDates(selectedMonth, daysInMonth)
+	calendar := buildMonthCalendar(monthDates)
+
+	monthEntries := make([]models.TimesheetEntry, 0)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Date, selectedMonth+"-") && entryHasWorker(entry, worker.ID) && storage.IsTimesheetApproved(entry) {
+			monthEntries = append(monthEntries, entry)
+		}
+	}
+	sort.Slice(monthEntries, func(i, j int) bool {
+		if monthEntries[i].Date == monthEntries[j].Date {
+			return monthEntries[i].StartTime < monthEntries[j].StartTime
+		}
+		return monthEntries[i].Date < monthEntries[j].Date
+	})
+
+	title := fmt.Sprintf("Выписка за %s %d", strings.ToLower(monthNamesNominative[monthStart.Month()-1]), monthStart.Year())
+	doc := newPDFDocument("P", title)
+	subtitle := worker.Name
+	if worker.Position != "" {
+		subtitle += ", " + worker.Position
+	}
+	if worker.PersonnelNo != "" {
+		subtitle += " · таб. № " + worker.PersonnelNo
+	}
+	doc.title(title, subtitle)
+
+	widths := []float64{24, 52, 26, 14, 74}
+	aligns := "LLCCL"
+	doc.header = func() {
+		doc.SetFont(pdfFontFamily, "B", 9)
+		doc.row(widths, []string{"Дата", "Объект", "Время", "Часы", "Отметка / комментарий"}, aligns, 5.5, []string{"#eef2f6", "#eef2f6", "#eef2f6", "#eef2f6", "#eef2f6"})
+		doc.SetFont(pdfFontFamily, "", 9)
+	}
+	doc.header()
+
+	markDays := map[string]map[string]struct{}{}
+	markLabels := map[string]string{}
+	totalHours := 0.0
+	for _, entry := range monthEntries {
+		dateLabel := formatScheduleDateLabel(entry.Date)
+		if mark, ok := storage.FindMarkType(entry.UserMark); ok {
+			if markDays[mark.Code] == nil {
+				markDays[mark.Code] = map[string]struct{}{}
+			}
+			markDays[mark.Code][entry.Date] = struct{}{}
+			markLabels[mark.Code] = mark.Label
+			text := mark.Code + " — " + mark.Label
+			if note := strings.TrimSpace(entry.Notes); note != "" {
+				text += ". " + note
+			}
+			fill := ""
+			if mark.Color != "" {
+				fill = lightenHex(mark.Color)
+			}
+			doc.row(widths, []string{dateLabel, "", "", "", text}, aligns, 5, []string{"", "", "", "", fill})
+			continue
+		}
+		objectNames := make([]string, 0, len(entry.ObjectIDs))
+		for _, oid := range entry.ObjectIDs {
+			if name, ok := objectsMap[oid]; ok {
+				objectNames = append(objectNames, name)
+			}
+		}
+		timeLabel := entry.StartTime + "–" + entry.EndTime
+		hours, _ := strconv.ParseFloat(formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes), 64)
+		comment := strings.TrimSpace(entry.Notes)
+		if record, ok := attendanceMap[attendanceKey(entry.ID, worker.ID)]; ok && record.Status == "confirmed" {
+			if factHours, closed := attendanceHours(record); closed {
+				comment = strings.TrimSpace(fmt.Sprintf("факт %s–%s (план %s ч). %s", record.StartTime, record.EndTime, formatPDFHours(hours), comment))
+				timeLabel = record.StartTime + "–" + record.EndTime
+				hours = factHours
+			}
+		}
+		totalHours += hours
+		doc.row(widths, []string{dateLabel, strings.Join(objectNames, ", "), timeLabel, formatPDFHours(roundHoursValue(hours)), comment}, aligns, 5, nil)
+	}
+	if len(monthEntries) == 0 {
+		doc.row([]float64{sumWidths(widths)}, []string{"За месяц нет согласованных записей"}, "C", 6, nil)
+	}
+
+	breakdown, norm := workerMonthHours(entries, worker.ID, selectedMonth, monthDates, calendar, attendanceMap, currentHourRules())
+	summary := [][2]string{
+		{"Отработано часов", formatPDFHours(roundHoursValue(totalHours))},
+		{"Норма с учётом неявок", formatPDFHours(norm)},
+		{"Обычные", formatPDFHours(breakdown.Regular)},
+		{"Сверхурочные", formatPDFHours(breakdown.Overtime)},
+		{"Ночные", formatPDFHours(breakdown.Night)},
+		{"Выходные и праздничные", formatPDFHours(breakdown.Weekend)},
+	}
+	codes := make([]string, 0, len(markDays))
+	for code := range markDays {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		summary = append(summary, [2]string{code + " — " + markLabels[code] + ", дней", strconv.Itoa(len(markDays[code]))})
+	}
+
+	doc.Ln(4)
+	doc.ensureSpace(8 + float64(len(summary))*5)
+	doc.SetFont(pdfFontFamily, "B", 10)
+	doc.CellFormat(0, 6, "Итоги месяца", "", 1, "L", false, 0, "")
+	doc.SetFont(pdfFontFamily, "", 9)
+	doc.header = nil
+	for _, item := range summary {
+		value := item[1]
+		if value == "" {
+			value = "0"
+		}
+		doc.row([]float64{70, 25}, []string{item[0], value}, "LR", 5, nil)
+	}
+	doc.send(c, fmt.Sprintf("statement-%s-%s.pdf", selectedMonth, worker.ID))
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON API under /api/v1. Handlers reuse the storage validation and the same role rules as the HTML pages;
+// errors are {"error": "<message in Russian>"}, lists are {"items", "total", "page", "perPage"}.
+
+const (
+	apiDefaultPerPage = 50
+	apiMaxPerPage     = 500
+)
+
+func apiError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// APIAuthRequired is AuthRequired for the JSON API: it answers 401 instead of redirecting to the login page.
+func APIAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret := bearerSecret(c); secret != "" {
+			if status, message := authenticateBearer(c, secret); status != 0 {
+				apiError(c, status, message)
+				return
+			}
+			c.Next()
+			return
+		}
+		user, sess, ok := sessionUser(c)
+		if !ok {
+			apiError(c, http.StatusUnauthorized, "Требуется авторизация")
+			return
+		}
+		setAuthContext(c, user, sess.CSRFToken)
+		c.Next()
+	}
+}
+
+func apiRequireAdmin(c *gin.Context) bool {
+	if isAdmin(c) {
+		return true
+	}
+	apiError(c, http.StatusForbidden, "Доступ запрещен")
+	return false
+}
+
+// bindAPIBody decodes the JSON body over the given value, so fields missing from the body keep their current values.
+func bindAPIBody(c *gin.Context, target interface{}) bool {
+	if err := c.ShouldBindJSON(target); err != nil {
+		apiError(c, http.StatusBadRequest, "Некорректный JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// respondAPIList pages the items by ?page= and ?per_page= (50 by default, at most 500).
+func respondAPIList[T any](c *gin.Context, items []T) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(apiDefaultPerPage)))
+	if err != nil || perPage < 1 {
+		perPage = apiDefaultPerPage
+	}
+	if perPage > apiMaxPerPage {
+		perPage = apiMaxPerPage
+	}
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items[start:end], "total": len(items), "page": page, "perPage": perPage})
+}
+
+// apiQueryMatch reports whether any of the values contains the ?q= search string (case-insensitive).
+func apiQueryMatch(c *gin.Context, values ...string) bool {
+	query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	if query == "" {
+		return true
+	}
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
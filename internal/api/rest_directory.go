@@ -0,0 +1,428 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiWorkerInput struct {
+	Name        string  `json:"name"`
+	Position    string  `json:"position"`
+	PersonnelNo string  `json:"personnelNo"`
+	Phone       string  `json:"phone"`
+	HourlyRate  float64 `json:"hourlyRate"`
+	BirthDate   string  `json:"birthDate"`
+}
+
+func (in apiWorkerInput) validate() string {
+	if strings.TrimSpace(in.Name) == "" {
+		return "Укажите Ф.И.О. работника."
+	}
+	if in.HourlyRate < 0 {
+		return "Ставка не может быть отрицательной."
+	}
+	if in.BirthDate != "" {
+		if _, err := time.Parse("2006-01-02", in.BirthDate); err != nil {
+			return "Дата рождения указывается в формате ГГГГ-ММ-ДД."
+		}
+	}
+	return ""
+}
+
+func (in apiWorkerInput) apply(worker *models.Worker) {
+	worker.Name = strings.TrimSpace(in.Name)
+	worker.Position = strings.TrimSpace(in.Position)
+	worker.PersonnelNo = strings.TrimSpace(in.PersonnelNo)
+	worker.Phone = strings.TrimSpace(in.Phone)
+	worker.HourlyRate = in.HourlyRate
+	worker.BirthDate = strings.TrimSpace(in.BirthDate)
+}
+
+// apiWorkerVisible limits regular users to their own worker card, as on the HTML pages.
+func apiWorkerVisible(c *gin.Context, worker models.Worker) bool {
+	return isAdmin(c) || (worker.UserID != "" && worker.UserID == c.GetString("userID"))
+}
+
+// APIListWorkers filters by ?status=active|fired|all (active by default), ?position= and ?q= (name, phone, табельный номер).
+func APIListWorkers(c *gin.Context) {
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := c.DefaultQuery("status", "active")
+	position := strings.TrimSpace(c.Query("position"))
+	result := make([]models.Worker, 0, len(workers))
+	for _, worker := range workers {
+		if !apiWorkerVisible(c, worker) {
+			continue
+		}
+		if (status == "active" && worker.IsFired) || (status == "fired" && !worker.IsFired) {
+			continue
+		}
+		if position != "" && !strings.EqualFold(worker.Position, position) {
+			continue
+		}
+		if !apiQueryMatch(c, worker.Name, worker.Phone, worker.PersonnelNo) {
+			continue
+		}
+		result = append(result, worker)
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetWorker(c *gin.Context) {
+	worker, err := storage.GetWorkerByID(c.Param("id"))
+	if err != nil || !apiWorkerVisible(c, worker) {
+		apiError(c, http.StatusNotFound, "Работник не найден")
+		return
+	}
+	c.JSON(http.StatusOK, worker)
+}
+
+func APICreateWorker(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	var input apiWorkerInput
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		apiError(c, http.StatusBadRequest, msg)
+		return
+	}
+	worker := models.Worker{CreatedBy: c.GetString("userID"), CreatedByName: c.GetString("userName")}
+	input.apply(&worker)
+	created, err := storage.CreateWorker(worker)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func APIUpdateWorker(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	worker, err := storage.GetWorkerByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Работник не найден")
+		return
+	}
+	input := apiWorkerInput{Name: worker.Name, Position: worker.Position, PersonnelNo: worker.PersonnelNo, Phone: worker.Phone, HourlyRate: worker.HourlyRate, BirthDate: worker.BirthDate}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		apiError(c, http.StatusBadRequest, msg)
+		return
+	}
+	previousRate := worker.HourlyRate
+	input.apply(&worker)
+	if err := storage.UpdateWorker(worker); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := storage.RecordWorkerRateChange(worker, previousRate, c.GetString("userName")); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, worker)
+}
+
+// APIDeleteWorker dismisses the worker like the HTML page does; the card and its history are kept.
+func APIDeleteWorker(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	if err := storage.DeleteWorker(c.Param("id")); err != nil {
+		apiError(c, http.StatusNotFound, "Работник не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type apiObjectInput struct {
+	Name              string  `json:"name"`
+	Status            string  `json:"status"`
+	Address           string  `json:"address"`
+	ResponsibleUserID string  `json:"responsibleUserId"`
+	Latitude          float64 `json:"latitude"`
+	Longitude         float64 `json:"longitude"`
+	GeofenceRadius    int     `json:"geofenceRadiusMeters"`
+}
+
+func (in apiObjectInput) apply(object *models.Object) {
+	object.Name = in.Name
+	object.Status = in.Status
+	object.Address = in.Address
+	object.ResponsibleUserID = in.ResponsibleUserID
+	object.Latitude = in.Latitude
+	object.Longitude = in.Longitude
+	object.GeofenceRadius = in.GeofenceRadius
+}
+
+func humanizeObjectError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "are required"):
+		return "Укажите название, адрес и ответственного."
+	case strings.Contains(msg, "invalid coordinates"):
+		return "Некорректные координаты объекта."
+	case strings.Contains(msg, "invalid geofence radius"):
+		return "Некорректный радиус зоны отметки."
+	default:
+		return "Не удалось сохранить объект: " + msg
+	}
+}
+
+// APIListObjects filters by ?status=, ?responsible_user_id= and ?q= (name, address).
+func APIListObjects(c *gin.Context) {
+	objects, err := storage.GetObjects()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := strings.TrimSpace(c.Query("status"))
+	responsible := strings.TrimSpace(c.Query("responsible_user_id"))
+	result := make([]models.Object, 0, len(objects))
+	for _, object := range objects {
+		if status != "" && object.Status != status {
+			continue
+		}
+		if responsible != "" && object.ResponsibleUserID != responsible {
+			continue
+		}
+		if !apiQueryMatch(c, object.Name, object.Address) {
+			continue
+		}
+		result = append(result, object)
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetObject(c *gin.Context) {
+	object, err := storage.GetObjectByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Объект не найден")
+		return
+	}
+	c.JSON(http.StatusOK, object)
+}
+
+func APICreateObject(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	input := apiObjectInput{Status: "in_progress"}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	var object models.Object
+	input.apply(&object)
+	if _, err := storage.GetUserByID(object.ResponsibleUserID); err != nil {
+		apiError(c, http.StatusBadRequest, "Ответственный пользователь не найден")
+		return
+	}
+	created, err := storage.CreateObject(object)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, humanizeObjectError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func APIUpdateObject(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	object, err := storage.GetObjectByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Объект не найден")
+		return
+	}
+	input := apiObjectInput{Name: object.Name, Status: object.Status, Address: object.Address, ResponsibleUserID: object.ResponsibleUserID, Latitude: object.Latitude, Longitude: object.Longitude, GeofenceRadius: object.GeofenceRadius}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	input.apply(&object)
+	if _, err := storage.GetUserByID(object.ResponsibleUserID); err != nil {
+		apiError(c, http.StatusBadRequest, "Ответственный пользователь не найден")
+		return
+	}
+	if err := storage.UpdateObject(object); err != nil {
+		apiError(c, http.StatusBadRequest, humanizeObjectError(err))
+		return
+	}
+	updated, _ := storage.GetObjectByID(object.ID)
+	c.JSON(http.StatusOK, updated)
+}
+
+func APIDeleteObject(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	if err := storage.DeleteObject(c.Param("id")); err != nil {
+		apiError(c, http.StatusNotFound, "Объект не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// apiUser is the user without the password hash.
+type apiUser struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Phone       string `json:"phone,omitempty"`
+	Status      string `json:"status"`
+	LastLoginAt string `json:"lastLoginAt,omitempty"`
+	WorkerID    string `json:"workerId,omitempty"`
+}
+
+func newAPIUser(user models.User) apiUser {
+	view := apiUser{ID: user.ID, Username: user.Username, Name: user.Name, Phone: user.Phone, Status: user.Status, LastLoginAt: user.LastLoginAt}
+	if worker, err := storage.GetWorkerByUserID(user.ID); err == nil {
+		view.WorkerID = worker.ID
+	}
+	return view
+}
+
+type apiUserInput struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Status   string `json:"status"`
+	Password string `json:"password"` // empty keeps the current password on update
+	WorkerID string `json:"workerId"`
+}
+
+func humanizeUserError(err error) (int, string) {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "are required"):
+		return http.StatusBadRequest, "Укажите логин, пароль и имя."
+	case strings.Contains(msg, "already exists"):
+		return http.StatusConflict, "Пользователь с таким логином уже есть."
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound, "Пользователь не найден"
+	default:
+		return http.StatusBadRequest, "Не удалось сохранить пользователя: " + msg
+	}
+}
+
+// APIListUsers filters by ?status=admin|user and ?q= (login, name, phone); admins only.
+func APIListUsers(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	users, err := storage.GetUsers()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := strings.TrimSpace(c.Query("status"))
+	result := make([]apiUser, 0, len(users))
+	for _, user := range users {
+		if status != "" && user.Status != status {
+			continue
+		}
+		if !apiQueryMatch(c, user.Username, user.Name, user.Phone) {
+			continue
+		}
+		result = append(result, newAPIUser(user))
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetUser(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	user, err := storage.GetUserByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Пользователь не найден")
+		return
+	}
+	c.JSON(http.StatusOK, newAPIUser(user))
+}
+
+func APICreateUser(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	input := apiUserInput{Status: "user"}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	created, err := storage.CreateUser(models.User{Username: input.Username, Name: input.Name, Phone: input.Phone, Status: input.Status, Password: input.Password})
+	if err != nil {
+		status, msg := humanizeUserError(err)
+		apiError(c, status, msg)
+		return
+	}
+	if err := syncUserWorker(c, created, input.WorkerID); err != nil {
+		_ = storage.DeleteUser(created.ID)
+		apiError(c, http.StatusBadRequest, "Не удалось привязать работника: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, newAPIUser(created))
+}
+
+func APIUpdateUser(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	user, err := storage.GetUserByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Пользователь не найден")
+		return
+	}
+	input := apiUserInput{Username: user.Username, Name: user.Name, Phone: user.Phone, Status: user.Status}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	user.Username = input.Username
+	user.Name = input.Name
+	user.Phone = input.Phone
+	user.Status = input.Status
+	if input.Password != "" {
+		user.Password = input.Password
+	}
+	if err := storage.UpdateUser(user); err != nil {
+		status, msg := humanizeUserError(err)
+		apiError(c, status, msg)
+		return
+	}
+	updated, _ := storage.GetUserByID(user.ID)
+	if err := syncUserWorker(c, updated, input.WorkerID); err != nil {
+		apiError(c, http.StatusBadRequest, "Не удалось привязать работника: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, newAPIUser(updated))
+}
+
+func APIDeleteUser(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	userID := c.Param("id")
+	if userID == c.GetString("userID") {
+		apiError(c, http.StatusBadRequest, "Нельзя удалить текущего пользователя")
+		return
+	}
+	_ = storage.ClearWorkerLinkByUserID(userID)
+	if err := storage.DeleteUser(userID); err != nil {
+		apiError(c, http.StatusNotFound, "Пользователь не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
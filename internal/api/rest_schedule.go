@@ -0,0 +1,374 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiScheduleInput struct {
+	Date              string   `json:"date"`
+	StartTime         string   `json:"startTime"`
+	EndTime           string   `json:"endTime"`
+	LunchBreakMinutes int      `json:"lunchBreakMinutes"`
+	WorkerIDs         []string `json:"workerIds"`
+	ObjectIDs         []string `json:"objectIds"`
+	Notes             string   `json:"notes"`
+	UserMark          string   `json:"userMark"`
+	DocumentRef       string   `json:"documentRef"`
+	// ConfirmOverBalance saves a vacation mark even when it exceeds the leave balance.
+	ConfirmOverBalance bool `json:"confirmOverBalance"`
+}
+
+func (in apiScheduleInput) apply(entry *models.TimesheetEntry) {
+	entry.Date = in.Date
+	entry.StartTime = in.StartTime
+	entry.EndTime = in.EndTime
+	entry.LunchBreakMinutes = in.LunchBreakMinutes
+	entry.WorkerIDs = cleanIDList(in.WorkerIDs)
+	entry.ObjectIDs = cleanIDList(in.ObjectIDs)
+	entry.Notes = in.Notes
+	entry.UserMark = normalizeSpecialMark(in.UserMark)
+	entry.DocumentRef = in.DocumentRef
+}
+
+func scheduleErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrMonthClosed) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// checkScheduleEntry runs the checks the schedule form runs before saving; it answers the request itself on failure.
+func checkScheduleEntry(c *gin.Context, entry models.TimesheetEntry, confirmOverBalance bool) bool {
+	if !isSpecialMark(entry.UserMark) {
+		if err := validateScheduleLinks(entry.WorkerIDs, entry.ObjectIDs); err != nil {
+			apiError(c, http.StatusBadRequest, humanizeScheduleError(err))
+			return false
+		}
+	}
+	if !confirmOverBalance {
+		if warning := leaveBalanceWarning(entry, ""); warning != "" {
+			apiError(c, http.StatusConflict, warning)
+			return false
+		}
+	}
+	return true
+}
+
+// APIListSchedule returns the entries visible to the user (getScopedEntries) filtered by ?from=, ?to=, ?month=,
+// ?worker_id=, ?object_id=, ?status=approved|pending|rejected and ?mark= (a code, or "none" for working time).
+func APIListSchedule(c *gin.Context) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entries, err = getScopedEntries(c, entries)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	from, to := strings.TrimSpace(c.Query("from")), strings.TrimSpace(c.Query("to"))
+	month := strings.TrimSpace(c.Query("month"))
+	workerID, objectID := strings.TrimSpace(c.Query("worker_id")), strings.TrimSpace(c.Query("object_id"))
+	status := strings.TrimSpace(c.Query("status"))
+	mark := strings.TrimSpace(c.Query("mark"))
+	if mark != "" && mark != "none" {
+		mark = normalizeSpecialMark(mark)
+	}
+	result := make([]models.TimesheetEntry, 0, len(entries))
+	for _, entry := range entries {
+		if (from != "" && entry.Date < from) || (to != "" && entry.Date > to) {
+			continue
+		}
+		if month != "" && !strings.HasPrefix(entry.Date, month+"-") {
+			continue
+		}
+		if (workerID != "" && !entryHasWorker(entry, workerID)) || (objectID != "" && !entryHasObject(entry, objectID)) {
+			continue
+		}
+		switch status {
+		case "approved":
+			if !storage.IsTimesheetApproved(entry) {
+				continue
+			}
+		case "pending", "rejected":
+			if entry.ApprovalStatus != status {
+				continue
+			}
+		}
+		if (mark == "none" && entry.UserMark != "") || (mark != "" && mark != "none" && entry.UserMark != mark) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetScheduleEntry(c *gin.Context) {
+	entry, err := storage.GetTimesheetByID(c.Param("id"))
+	if err != nil || scheduleEntryAccessError(c, entry) != "" {
+		apiError(c, http.StatusNotFound, "Назначение не найдено")
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// APICreateScheduleEntry follows CreateScheduleEntry: entries of regular users include their own worker and wait for approval.
+func APICreateScheduleEntry(c *gin.Context) {
+	var input apiScheduleInput
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	entry := models.TimesheetEntry{CreatedByID: c.GetString("userID"), CreatedByName: c.GetString("userName")}
+	input.apply(&entry)
+	prepareScheduleEntry(c, &entry)
+	if !checkScheduleEntry(c, entry, input.ConfirmOverBalance) {
+		return
+	}
+	created, err := storage.CreateTimesheet(entry)
+	if err != nil {
+		apiError(c, scheduleErrorStatus(err), humanizeScheduleError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func APIUpdateScheduleEntry(c *gin.Context) {
+	entry, err := storage.GetTimesheetByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Назначение не найдено")
+		return
+	}
+	if msg := scheduleEntryAccessError(c, entry); msg != "" {
+		apiError(c, http.StatusForbidden, msg)
+		return
+	}
+	input := apiScheduleInput{Date: entry.Date, StartTime: entry.StartTime, EndTime: entry.EndTime, LunchBreakMinutes: entry.LunchBreakMinutes,
+		WorkerIDs: entry.WorkerIDs, ObjectIDs: entry.ObjectIDs, Notes: entry.Notes, UserMark: entry.UserMark, DocumentRef: entry.DocumentRef}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	input.apply(&entry)
+	prepareScheduleEntry(c, &entry)
+	if !checkScheduleEntry(c, entry, input.ConfirmOverBalance) {
+		return
+	}
+	if err := storage.UpdateTimesheet(entry); err != nil {
+		apiError(c, scheduleErrorStatus(err), humanizeScheduleError(err))
+		return
+	}
+	updated, _ := storage.GetTimesheetByID(entry.ID)
+	c.JSON(http.StatusOK, updated)
+}
+
+func APIDeleteScheduleEntry(c *gin.Context) {
+	entry, err := storage.GetTimesheetByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Назначение не найдено")
+		return
+	}
+	if msg := scheduleEntryAccessError(c, entry); msg != "" {
+		apiError(c, http.StatusForbidden, msg)
+		return
+	}
+	if err := storage.DeleteTimesheet(entry.ID); err != nil {
+		apiError(c, scheduleErrorStatus(err), humanizeScheduleError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// APIListMarks returns the mark catalogue; ?active=true hides disabled marks.
+func APIListMarks(c *gin.Context) {
+	marks, err := storage.GetMarkTypes()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]models.MarkType, 0, len(marks))
+	for _, mark := range marks {
+		if c.Query("active") == "true" && !mark.Active {
+			continue
+		}
+		result = append(result, mark)
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetMark(c *gin.Context) {
+	mark, ok := storage.FindMarkType(c.Param("code"))
+	if !ok {
+		apiError(c, http.StatusNotFound, "Отметка не найдена")
+		return
+	}
+	c.JSON(http.StatusOK, mark)
+}
+
+func APICreateMark(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	mark := models.MarkType{Active: true}
+	if !bindAPIBody(c, &mark) {
+		return
+	}
+	if _, exists := storage.FindMarkType(mark.Code); exists {
+		apiError(c, http.StatusConflict, "Код или синоним уже используется другой отметкой.")
+		return
+	}
+	if err := storage.SaveMarkType(mark); err != nil {
+		apiError(c, http.StatusBadRequest, humanizeMarkError(err))
+		return
+	}
+	security.LogEvent("mark_type_saved", fmt.Sprintf("code=%s user=%s", strings.TrimSpace(mark.Code), c.GetString("userName")))
+	saved, _ := storage.FindMarkType(mark.Code)
+	c.JSON(http.StatusCreated, saved)
+}
+
+// APIUpdateMark edits the mark found by code or alias; the code itself is the key and cannot be changed.
+func APIUpdateMark(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	mark, ok := storage.FindMarkType(c.Param("code"))
+	if !ok {
+		apiError(c, http.StatusNotFound, "Отметка не найдена")
+		return
+	}
+	code := mark.Code
+	if !bindAPIBody(c, &mark) {
+		return
+	}
+	mark.Code = code
+	if err := storage.SaveMarkType(mark); err != nil {
+		apiError(c, http.StatusBadRequest, humanizeMarkError(err))
+		return
+	}
+	security.LogEvent("mark_type_saved", fmt.Sprintf("code=%s user=%s", code, c.GetString("userName")))
+	saved, _ := storage.FindMarkType(code)
+	c.JSON(http.StatusOK, saved)
+}
+
+func APIDeleteMark(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	mark, ok := storage.FindMarkType(c.Param("code"))
+	if !ok {
+		apiError(c, http.StatusNotFound, "Отметка не найдена")
+		return
+	}
+	if err := storage.DeleteMarkType(mark.Code); err != nil {
+		apiError(c, http.StatusConflict, humanizeMarkError(err))
+		return
+	}
+	security.LogEvent("mark_type_deleted", fmt.Sprintf("code=%s user=%s", mark.Code, c.GetString("userName")))
+	c.Status(http.StatusNoContent)
+}
+
+type apiImprovementInput struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// APIListImprovements filters by ?type=bug|improvement, ?status=open|done and ?q= (title, description).
+func APIListImprovements(c *gin.Context) {
+	items, err := storage.GetImprovements()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	kind, status := strings.TrimSpace(c.Query("type")), strings.TrimSpace(c.Query("status"))
+	result := make([]models.ImprovementItem, 0, len(items))
+	for _, item := range items {
+		if (kind != "" && item.Type != kind) || (status != "" && item.Status != status) {
+			continue
+		}
+		if !apiQueryMatch(c, item.Title, item.Description) {
+			continue
+		}
+		result = append(result, item)
+	}
+	respondAPIList(c, result)
+}
+
+func APIGetImprovement(c *gin.Context) {
+	item, err := storage.GetImprovementByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Запись не найдена")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func APICreateImprovement(c *gin.Context) {
+	var input apiImprovementInput
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	item := models.ImprovementItem{
+		Type:        input.Type,
+		Title:       input.Title,
+		Description: input.Description,
+		CreatedByID: c.GetString("userID"),
+		CreatedBy:   c.GetString("userName"),
+	}
+	created, err := storage.AddImprovement(item)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Укажите заголовок и описание.")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// APIUpdateImprovement is open to admins and the author; moving to "done" stamps who closed it.
+func APIUpdateImprovement(c *gin.Context) {
+	item, err := storage.GetImprovementByID(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "Запись не найдена")
+		return
+	}
+	if !isAdmin(c) && item.CreatedByID != c.GetString("userID") {
+		apiError(c, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+	input := apiImprovementInput{Type: item.Type, Title: item.Title, Description: item.Description, Status: item.Status}
+	if !bindAPIBody(c, &input) {
+		return
+	}
+	if input.Status == "done" && item.Status != "done" {
+		item.DoneAt = time.Now()
+		item.DoneByID = c.GetString("userID")
+		item.DoneBy = c.GetString("userName")
+	}
+	item.Type, item.Title, item.Description, item.Status = input.Type, input.Title, input.Description, input.Status
+	if err := storage.UpdateImprovement(item); err != nil {
+		apiError(c, http.StatusBadRequest, "Укажите заголовок и описание.")
+		return
+	}
+	updated, _ := storage.GetImprovementByID(item.ID)
+	c.JSON(http.StatusOK, updated)
+}
+
+func APIDeleteImprovement(c *gin.Context) {
+	if !apiRequireAdmin(c) {
+		return
+	}
+	if err := storage.DeleteImprovement(c.Param("id")); err != nil {
+		apiError(c, http.StatusNotFound, "Запись не найдена")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func approvalStatusLabel(status string) string {
+	switch status {
+	case "pending":
+		return "На согласовании"
+	case "rejected":
+		return "Отклонено"
+	default:
+		return "Согласовано"
+	}
+}
+
+// renderApprovalMeta is the schedule card block for entries that are not (yet) approved.
+func renderApprovalMeta(entry models.TimesheetEntry) string {
+	if storage.IsTimesheetApproved(entry) {
+		return ""
+	}
+	text := approvalStatusLabel(entry.ApprovalStatus)
+	if entry.ApprovalStatus == "rejected" {
+		reviewer := strings.TrimSpace(entry.ReviewedByName)
+		if reviewer != "" {
+			text += " (" + reviewer + ")"
+		}
+		if entry.ApprovalComment != "" {
+			text += ": " + entry.ApprovalComment
+		}
+	}
+	return `<div class="assignment-meta approval-` + template.HTMLEscapeString(entry.ApprovalStatus) + `"><span>Согласование</span><p>` + template.HTMLEscapeString(text) + `</p></div>`
+}
+
+// reviewableEntries returns pending entries the current user may approve: all for admins,
+// entries on own objects for responsible users, never entries the user created.
+func reviewableEntries(c *gin.Context) ([]models.TimesheetEntry, error) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		return nil, err
+	}
+	objectsByID := make(map[string]models.Object, len(objects))
+	for _, object := range objects {
+		objectsByID[object.ID] = object
+	}
+	userID := c.GetString("userID")
+	result := make([]models.TimesheetEntry, 0)
+	for _, entry := range entries {
+		if entry.ApprovalStatus != "pending" {
+			continue
+		}
+		if !isAdmin(c) && entry.CreatedByID == userID {
+			continue
+		}
+		if canSuperviseEntry(c, entry, objectsByID) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func countReviewableEntries(c *gin.Context) int {
+	entries, err := reviewableEntries(c)
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
+
+// ScheduleApprovalsPage is the queue of entries created by non-admins that wait for review.
+func ScheduleApprovalsPage(c *gin.Context) {
+	entries, err := reviewableEntries(c)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule entries: %v", err)
+		return
+	}
+	workersMap, err := buildWorkersMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	objectsMap, err := buildObjectsMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date == entries[j].Date {
+			return entries[i].StartTime < entries[j].StartTime
+		}
+		return entries[i].Date < entries[j].Date
+	})
+
+	var rows strings.Builder
+	for _, entry := range entries {
+		what := specialMarkTitle(entry.UserMark)
+		if !isSpecialMark(entry.UserMark) {
+			what = fmt.Sprintf("%s–%s · %s ч", entry.StartTime, entry.EndTime, formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes))
+		}
+		notes := strings.TrimSpace(entry.Notes)
+		if notes == "" {
+			notes = "—"
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td><form method="POST" action="/schedule/approvals/%s" class="approval-form">%s<input type="text" name="comment" placeholder="Комментарий (обязателен при отклонении)"><button type="submit" name="decision" value="approve" class="btn btn-primary btn-compact">Согласовать</button><button type="submit" name="decision" value="reject" class="btn btn-danger btn-compact">Отклонить</button></form></td></tr>`,
+			template.HTMLEscapeString(formatScheduleDateLabel(entry.Date)),
+			template.HTMLEscapeString(what),
+			joinMappedLinks(entry.ObjectIDs, objectsMap, "/object"),
+			joinMappedLinks(entry.WorkerIDs, workersMap, "/worker"),
+			template.HTMLEscapeString(notes),
+			template.HTMLEscapeString(entry.CreatedByName),
+			template.HTMLEscapeString(entry.ID),
+			CSRFHiddenInput(c),
+		))
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="7">Нет записей, ожидающих согласования.</td></tr>`)
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Согласование</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/schedule" class="back-link">← К расписанию</a>
+<div class="page-header"><h1>Согласование записей</h1><p>Записи, созданные работниками, попадают в табель только после согласования.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Дата</th><th>Запись</th><th>Объекты</th><th>Работники</th><th>Комментарий</th><th>Создал</th><th>Решение</th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "schedule"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// ReviewScheduleEntry approves or rejects one pending entry.
+func ReviewScheduleEntry(c *gin.Context) {
+	entries, err := reviewableEntries(c)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule entries: %v", err)
+		return
+	}
+	allowed := false
+	for _, entry := range entries {
+		if entry.ID == c.Param("id") {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.String(http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	approve := c.PostForm("decision") == "approve"
+	if _, err := storage.ReviewTimesheet(c.Param("id"), approve, c.PostForm("comment"), c.GetString("userID"), c.GetString("userName")); err != nil {
+		msg := "Не удалось сохранить решение: " + err.Error()
+		if strings.Contains(err.Error(), "comment is required") {
+			msg = "Укажите причину отклонения."
+		}
+		c.Redirect(http.StatusFound, "/schedule/approvals?error="+url.QueryEscape(msg))
+		return
+	}
+	c.Redirect(http.StatusFound, "/schedule/approvals")
+}
@@ -0,0 +1,479 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scheduleBoardRequest struct {
+	Mode          string `json:"mode"` // create | move | copy
+	WorkerID      string `json:"workerId"`
+	SourceEntryID string `json:"sourceEntryId"`
+	ObjectID      string `json:"objectId"`
+	Date          string `json:"date"`
+}
+
+type scheduleBoardRemoveRequest struct {
+	WorkerID string `json:"workerId"`
+	EntryID  string `json:"entryId"`
+}
+
+func resolveBoardWeek(value string) time.Time {
+	day, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	if err != nil {
+		now := time.Now()
+		day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func entryHasWorker(entry models.TimesheetEntry, workerID string) bool {
+	for _, wid := range entry.WorkerIDs {
+		if wid == workerID {
+			return true
+		}
+	}
+	return false
+}
+
+func entryHasObject(entry models.TimesheetEntry, objectID string) bool {
+	for _, oid := range entry.ObjectIDs {
+		if oid == objectID {
+			return true
+		}
+	}
+	return false
+}
+
+func findBoardCellEntry(entries []models.TimesheetEntry, objectID, date string, shape *models.TimesheetEntry) (models.TimesheetEntry, bool) {
+	for _, entry := range entries {
+		// An entry awaiting approval is not joined: the board's admin gets an approved entry of their own.
+		if entry.Date != date || isSpecialMark(entry.UserMark) || !entryHasObject(entry, objectID) || !storage.IsTimesheetApproved(entry) {
+			continue
+		}
+		if shape != nil && (entry.StartTime != shape.StartTime || entry.EndTime != shape.EndTime || entry.LunchBreakMinutes != shape.LunchBreakMinutes) {
+			continue
+		}
+		return entry, true
+	}
+	return models.TimesheetEntry{}, false
+}
+
+// ScheduleBoardPage renders the week planning board: objects by rows, days by columns.
+func ScheduleBoardPage(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	weekStart := resolveBoardWeek(c.Query("week"))
+	weekDates := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		weekDates = append(weekDates, weekStart.AddDate(0, 0, i).Format("2006-01-02"))
+	}
+	weekStartKey := weekDates[0]
+	weekEndKey := weekDates[6]
+
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load schedule entries: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	workersMap := make(map[string]models.Worker, len(workers))
+	for _, worker := range workers {
+		workersMap[worker.ID] = worker
+	}
+
+	weekEntries := make([]models.TimesheetEntry, 0)
+	objectsInWeek := map[string]struct{}{}
+	workerDays := map[string]map[string]struct{}{}
+	for _, entry := range entries {
+		if entry.Date < weekStartKey || entry.Date > weekEndKey || isSpecialMark(entry.UserMark) {
+			continue
+		}
+		weekEntries = append(weekEntries, entry)
+		for _, oid := range entry.ObjectIDs {
+			objectsInWeek[oid] = struct{}{}
+		}
+		for _, wid := range entry.WorkerIDs {
+			if workerDays[wid] == nil {
+				workerDays[wid] = map[string]struct{}{}
+			}
+			workerDays[wid][entry.Date] = struct{}{}
+		}
+	}
+
+	weekdayNames := []string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+	today := time.Now().Format("2006-01-02")
+	var headers strings.Builder
+	for _, date := range weekDates {
+		day, _ := time.Parse("2006-01-02", date)
+		class := ""
+		if date == today {
+			class = ` class="is-today"`
+		}
+		headers.WriteString(fmt.Sprintf(`<th%s>%s %s</th>`, class, weekdayNames[int(day.Weekday())], day.Format("02.01")))
+	}
+
+	var rows strings.Builder
+	for _, object := range objects {
+		_, hasEntries := objectsInWeek[object.ID]
+		if object.Status != "in_progress" && !hasEntries {
+			continue
+		}
+		rowLabel := `<a class="entity-link" href="/object/` + template.HTMLEscapeString(object.ID) + `">` + template.HTMLEscapeString(object.Name) + `</a>`
+		if object.Status != "in_progress" {
+			rowLabel += `<span class="status-badge status-warning">` + template.HTMLEscapeString(objectStatusLabel(object.Status)) + `</span>`
+		}
+		var cells strings.Builder
+		for _, date := range weekDates {
+			var blocks strings.Builder
+			for _, entry := range weekEntries {
+				if entry.Date != date || !entryHasObject(entry, object.ID) {
+					continue
+				}
+				var chips strings.Builder
+				for _, wid := range entry.WorkerIDs {
+					worker, ok := workersMap[wid]
+					if !ok {
+						continue
+					}
+					chips.WriteString(fmt.Sprintf(`<span class="board-chip" draggable="true" data-board-worker="%s" data-board-entry="%s" title="%s">%s</span>`,
+						template.HTMLEscapeString(worker.ID),
+						template.HTMLEscapeString(entry.ID),
+						template.HTMLEscapeString(worker.Name),
+						template.HTMLEscapeString(shortWorkerDisplayName(worker.Name)),
+					))
+				}
+				editURL := "/schedule/edit/" + template.HTMLEscapeString(entry.ID) + "?return=" + template.URLQueryEscaper("/schedule/board?week="+weekStartKey)
+				blocks.WriteString(fmt.Sprintf(`<div class="board-entry"><a class="board-entry-time" href="%s" data-modal-url="%s" data-modal-title="Редактирование назначения">%s–%s</a><div class="board-chips">%s</div></div>`,
+					editURL,
+					editURL,
+					template.HTMLEscapeString(entry.StartTime),
+					template.HTMLEscapeString(entry.EndTime),
+					chips.String(),
+				))
+			}
+			cellClass := "board-cell"
+			if object.Status == "in_progress" {
+				cellClass += " is-droppable"
+			}
+			cells.WriteString(fmt.Sprintf(`<td class="%s" data-board-object="%s" data-board-date="%s">%s</td>`, cellClass, template.HTMLEscapeString(object.ID), template.HTMLEscapeString(date), blocks.String()))
+		}
+		rows.WriteString(`<tr><th>` + rowLabel + `</th>` + cells.String() + `</tr>`)
+	}
+	if rows.Len() == 0 {
+		rows.WriteString(`<tr><td colspan="8">Нет объектов в работе.</td></tr>`)
+	}
+
+	var palette strings.Builder
+	for _, worker := range workers {
+		if worker.IsFired {
+			continue
+		}
+		palette.WriteString(fmt.Sprintf(`<span class="board-chip" draggable="true" data-board-worker="%s" title="%s">%s<small>%d</small></span>`,
+			template.HTMLEscapeString(worker.ID),
+			template.HTMLEscapeString(worker.Name),
+			template.HTMLEscapeString(shortWorkerDisplayName(worker.Name)),
+			len(workerDays[worker.ID]),
+		))
+	}
+	if palette.Len() == 0 {
+		palette.WriteString(`<p class="text-muted">Нет активных работников.</p>`)
+	}
+
+	prevWeek := weekStart.AddDate(0, 0, -7).Format("2006-01-02")
+	nextWeek := weekStart.AddDate(0, 0, 7).Format("2006-01-02")
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><a class="btn btn-secondary" href="/schedule/board?week=`+prevWeek+`">←</a><span class="status-badge">`+template.HTMLEscapeString(weekStart.Format("02.01")+" – "+weekStart.AddDate(0, 0, 6).Format("02.01.2006"))+`</span><a class="btn btn-secondary" href="/schedule/board?week=`+nextWeek+`">→</a><a class="btn btn-secondary" href="/schedule?month=`+template.URLQueryEscaper(weekStart.Format("2006-01"))+`">Список</a><a class="btn btn-secondary" href="/schedule/copy?scope=week&source=`+weekStartKey+`">Копировать неделю</a></div>`)
+
+	csrfToken := ""
+	if token, ok := c.Get("csrfToken"); ok {
+		csrfToken, _ = token.(string)
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Планирование недели</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<div class="page-header page-header-desktop-hidden"><h1>Планирование недели</h1><a class="btn btn-secondary" href="/schedule/board?week={{PREV_WEEK}}">← Неделя</a><a class="btn btn-secondary" href="/schedule/board?week={{NEXT_WEEK}}">Неделя →</a></div>
+<div class="card board-card" data-board-root data-csrf-token="{{CSRF_TOKEN}}">
+  <div class="board-toolbar">
+    <p>Перетащите работника в ячейку объекта и дня. Перенос из ячейки перемещает назначение, с зажатым Ctrl/Alt — копирует. Перетащите работника обратно в список, чтобы снять его с назначения.</p>
+    <label class="board-copy-toggle"><input type="checkbox" data-board-copy> Копировать вместо переноса</label>
+  </div>
+  <div class="board-palette" data-board-palette>{{PALETTE}}</div>
+  <div class="form-error" data-board-error style="display:none;"></div>
+  <div class="table-scroll"><table class="table board-table"><thead><tr><th>Объект</th>{{HEADERS}}</tr></thead><tbody>{{ROWS}}</tbody></table></div>
+</div>
+</div>
+<script>
+(function(){
+  const root=document.querySelector('[data-board-root]');
+  if(!root) return;
+  const token=root.getAttribute('data-csrf-token') || '';
+  const copyToggle=root.querySelector('[data-board-copy]');
+  const errorBox=root.querySelector('[data-board-error]');
+  const palette=root.querySelector('[data-board-palette]');
+  let busy=false;
+
+  function showError(message){
+    if(!errorBox) return;
+    errorBox.textContent=message;
+    errorBox.style.display='';
+  }
+
+  function send(url, payload){
+    if(busy) return;
+    busy=true;
+    root.classList.add('is-busy');
+    fetch(url, {
+      method: 'POST',
+      credentials: 'same-origin',
+      headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': token, 'X-Requested-With': 'XMLHttpRequest' },
+      body: JSON.stringify(payload)
+    }).then(function(response){
+      return response.json().catch(function(){ return {}; }).then(function(data){ return { ok: response.ok, data: data }; });
+    }).then(function(result){
+      if(result.ok){
+        window.location.reload();
+        return;
+      }
+      showError(result.data.error || 'Не удалось сохранить изменение.');
+    }).catch(function(){
+      showError('Нет связи с сервером.');
+    }).finally(function(){
+      busy=false;
+      root.classList.remove('is-busy');
+    });
+  }
+
+  root.addEventListener('dragstart', function(e){
+    const chip=e.target.closest('[data-board-worker]');
+    if(!chip) return;
+    e.dataTransfer.effectAllowed='copyMove';
+    e.dataTransfer.setData('text/plain', JSON.stringify({
+      workerId: chip.getAttribute('data-board-worker'),
+      entryId: chip.getAttribute('data-board-entry') || ''
+    }));
+  });
+
+  root.addEventListener('dragover', function(e){
+    const cell=e.target.closest('.board-cell.is-droppable');
+    if(cell || (palette && palette.contains(e.target))){
+      e.preventDefault();
+      if(cell) cell.classList.add('is-over');
+    }
+  });
+
+  root.addEventListener('dragleave', function(e){
+    const cell=e.target.closest('.board-cell');
+    if(cell && !cell.contains(e.relatedTarget)) cell.classList.remove('is-over');
+  });
+
+  root.addEventListener('drop', function(e){
+    let drag;
+    try{ drag=JSON.parse(e.dataTransfer.getData('text/plain') || '{}'); }catch(_){ return; }
+    if(!drag.workerId) return;
+    e.preventDefault();
+    root.querySelectorAll('.board-cell.is-over').forEach(function(cell){ cell.classList.remove('is-over'); });
+    if(palette && palette.contains(e.target)){
+      if(drag.entryId) send('/schedule/board/remove', { workerId: drag.workerId, entryId: drag.entryId });
+      return;
+    }
+    const cell=e.target.closest('.board-cell.is-droppable');
+    if(!cell) return;
+    let mode='create';
+    if(drag.entryId){
+      mode=(e.ctrlKey || e.altKey || e.metaKey || (copyToggle && copyToggle.checked)) ? 'copy' : 'move';
+    }
+    send('/schedule/board/assign', {
+      mode: mode,
+      workerId: drag.workerId,
+      sourceEntryId: drag.entryId,
+      objectId: cell.getAttribute('data-board-object'),
+      date: cell.getAttribute('data-board-date')
+    });
+  });
+})();
+</script>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "schedule"), 1)
+	final = strings.Replace(final, "{{PREV_WEEK}}", prevWeek, 1)
+	final = strings.Replace(final, "{{NEXT_WEEK}}", nextWeek, 1)
+	final = strings.Replace(final, "{{CSRF_TOKEN}}", template.HTMLEscapeString(csrfToken), 1)
+	final = strings.Replace(final, "{{PALETTE}}", palette.String(), 1)
+	final = strings.Replace(final, "{{HEADERS}}", headers.String(), 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// ScheduleBoardAssign creates, moves or copies a worker assignment dropped on the week board.
+func ScheduleBoardAssign(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+		return
+	}
+	var req scheduleBoardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос"})
+		return
+	}
+	req.Mode = strings.TrimSpace(req.Mode)
+	req.WorkerID = strings.TrimSpace(req.WorkerID)
+	req.SourceEntryID = strings.TrimSpace(req.SourceEntryID)
+	req.ObjectID = strings.TrimSpace(req.ObjectID)
+	req.Date = strings.TrimSpace(req.Date)
+	if req.WorkerID == "" || req.ObjectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не указан работник или объект"})
+		return
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": humanizeScheduleError(fmt.Errorf("invalid date format"))})
+		return
+	}
+
+	var source *models.TimesheetEntry
+	switch req.Mode {
+	case "create":
+	case "move", "copy":
+		entry, err := storage.GetTimesheetByID(req.SourceEntryID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Исходное назначение не найдено"})
+			return
+		}
+		if !entryHasWorker(entry, req.WorkerID) || isSpecialMark(entry.UserMark) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Работник не входит в исходное назначение"})
+			return
+		}
+		source = &entry
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестное действие"})
+		return
+	}
+
+	if source != nil && req.Mode == "move" && source.Date == req.Date && entryHasObject(*source, req.ObjectID) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "entryId": source.ID})
+		return
+	}
+	if err := validateScheduleLinks([]string{req.WorkerID}, []string{req.ObjectID}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": humanizeScheduleError(err)})
+		return
+	}
+
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, ok := findBoardCellEntry(entries, req.ObjectID, req.Date, source)
+	if ok {
+		if entryHasWorker(target, req.WorkerID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Работник уже назначен на этот объект в этот день"})
+			return
+		}
+		target.WorkerIDs = append(target.WorkerIDs, req.WorkerID)
+	} else {
+		target = models.TimesheetEntry{
+			Date:              req.Date,
+			StartTime:         "08:00",
+			EndTime:           "17:00",
+			LunchBreakMinutes: 60,
+			WorkerIDs:         []string{req.WorkerID},
+			ObjectIDs:         []string{req.ObjectID},
+			CreatedByID:       c.GetString("userID"),
+			CreatedByName:     c.GetString("userName"),
+		}
+		if source != nil {
+			target.StartTime = source.StartTime
+			target.EndTime = source.EndTime
+			target.LunchBreakMinutes = source.LunchBreakMinutes
+			target.Notes = source.Notes
+		}
+	}
+	if busy := boardWorkerConflict(entries, target, req.WorkerID, source, req.Mode); busy != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Работник " + busy})
+		return
+	}
+
+	// A move adds the worker to the target and takes them off the source in one save.
+	// An existing target is re-read by storage, so only its worker list changes.
+	switch {
+	case source != nil && req.Mode == "move":
+		target, err = storage.MoveTimesheetWorker(source.ID, req.WorkerID, target)
+	case ok:
+		target, err = storage.AddTimesheetWorker(target.ID, req.WorkerID)
+	default:
+		target, err = storage.CreateTimesheet(target)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": humanizeScheduleError(err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "entryId": target.ID})
+}
+
+// boardWorkerConflict describes the worker's other entry that day colliding with target, or returns "".
+// The target itself and the entry a move takes the worker off do not count.
+func boardWorkerConflict(entries []models.TimesheetEntry, target models.TimesheetEntry, workerID string, source *models.TimesheetEntry, mode string) string {
+	for _, existing := range entries {
+		if existing.Date != target.Date || existing.ApprovalStatus == "rejected" || !entryHasWorker(existing, workerID) {
+			continue
+		}
+		if existing.ID == target.ID || (source != nil && mode == "move" && existing.ID == source.ID) {
+			continue
+		}
+		if !entriesOverlap(existing, target) {
+			continue
+		}
+		if isSpecialMark(existing.UserMark) {
+			return "в этот день отметка «" + specialMarkTitle(existing.UserMark) + "»"
+		}
+		return "уже занят " + existing.StartTime + "–" + existing.EndTime
+	}
+	return ""
+}
+
+// ScheduleBoardRemove takes a worker off an assignment when the chip is dropped back on the palette.
+func ScheduleBoardRemove(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+		return
+	}
+	var req scheduleBoardRemoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос"})
+		return
+	}
+	entry, err := storage.GetTimesheetByID(strings.TrimSpace(req.EntryID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Назначение не найдено"})
+		return
+	}
+	if !entryHasWorker(entry, strings.TrimSpace(req.WorkerID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Работник не входит в назначение"})
+		return
+	}
+	if err := storage.RemoveTimesheetWorker(entry.ID, strings.TrimSpace(req.WorkerID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": humanizeScheduleError(err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
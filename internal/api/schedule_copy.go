@@ -0,0 +1,406 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scheduleCopyParams struct {
+	SourceFrom time.Time
+	TargetFrom time.Time
+	Days       int
+	Scope      string // day | week
+	ObjectID   string
+}
+
+type scheduleCopySkip struct {
+	Name   string
+	Reason string
+}
+
+type scheduleCopyItem struct {
+	Source  models.TimesheetEntry
+	Entry   models.TimesheetEntry
+	Skipped []scheduleCopySkip
+}
+
+func parseScheduleCopyParams(source, target, scope, objectID string) (scheduleCopyParams, error) {
+	params := scheduleCopyParams{Scope: "day", Days: 1, ObjectID: strings.TrimSpace(objectID)}
+	sourceDate, err := time.Parse("2006-01-02", strings.TrimSpace(source))
+	if err != nil {
+		return params, fmt.Errorf("укажите исходную дату")
+	}
+	targetDate, err := time.Parse("2006-01-02", strings.TrimSpace(target))
+	if err != nil {
+		return params, fmt.Errorf("укажите дату, куда копировать")
+	}
+	if strings.TrimSpace(scope) == "week" {
+		params.Scope = "week"
+		params.Days = 7
+		sourceDate = resolveBoardWeek(sourceDate.Format("2006-01-02"))
+		targetDate = resolveBoardWeek(targetDate.Format("2006-01-02"))
+	}
+	if sourceDate.Equal(targetDate) {
+		return params, fmt.Errorf("исходный и целевой период совпадают")
+	}
+	params.SourceFrom = sourceDate
+	params.TargetFrom = targetDate
+	return params, nil
+}
+
+func clockMinutes(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// entriesOverlap reports whether a work shift collides with an existing entry; special marks block the whole day.
+func entriesOverlap(existing, candidate models.TimesheetEntry) bool {
+	if isSpecialMark(existing.UserMark) {
+		return true
+	}
+	existingStart, ok1 := clockMinutes(existing.StartTime)
+	existingEnd, ok2 := clockMinutes(existing.EndTime)
+	candidateStart, ok3 := clockMinutes(candidate.StartTime)
+	candidateEnd, ok4 := clockMinutes(candidate.EndTime)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return true
+	}
+	return candidateStart < existingEnd && existingStart < candidateEnd
+}
+
+// buildScheduleCopyPlan computes what copying would create without touching storage.
+func buildScheduleCopyPlan(params scheduleCopyParams, createdByID, createdByName string) ([]scheduleCopyItem, error) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		return nil, err
+	}
+	workersByID := make(map[string]models.Worker, len(workers))
+	for _, worker := range workers {
+		workersByID[worker.ID] = worker
+	}
+	objectsByID := make(map[string]models.Object, len(objects))
+	for _, object := range objects {
+		objectsByID[object.ID] = object
+	}
+
+	sourceStart := params.SourceFrom.Format("2006-01-02")
+	sourceEnd := params.SourceFrom.AddDate(0, 0, params.Days-1).Format("2006-01-02")
+	offset := int(params.TargetFrom.Sub(params.SourceFrom).Hours() / 24)
+
+	// Entries planned earlier in this run count as existing, so copies of one period never collide with each other silently.
+	planned := make([]models.TimesheetEntry, 0)
+	plan := make([]scheduleCopyItem, 0)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date == entries[j].Date {
+			return entries[i].StartTime < entries[j].StartTime
+		}
+		return entries[i].Date < entries[j].Date
+	})
+	findConflict := func(list []models.TimesheetEntry, candidate models.TimesheetEntry, workerID string) string {
+		for _, existing := range list {
+			// A rejected plan does not occupy the slot.
+			if existing.ApprovalStatus == "rejected" {
+				continue
+			}
+			if existing.Date != candidate.Date || !entryHasWorker(existing, workerID) || !entriesOverlap(existing, candidate) {
+				continue
+			}
+			if isSpecialMark(existing.UserMark) {
+				return "в этот день отметка «" + specialMarkTitle(existing.UserMark) + "»"
+			}
+			return "уже занят " + existing.StartTime + "–" + existing.EndTime
+		}
+		return ""
+	}
+	for _, entry := range entries {
+		// Only approved entries are copied: the copies are created approved, so a pending one would skip review.
+		if entry.Date < sourceStart || entry.Date > sourceEnd || isSpecialMark(entry.UserMark) || !storage.IsTimesheetApproved(entry) {
+			continue
+		}
+		if params.ObjectID != "" && !entryHasObject(entry, params.ObjectID) {
+			continue
+		}
+		sourceDate, err := time.Parse("2006-01-02", entry.Date)
+		if err != nil {
+			continue
+		}
+		item := scheduleCopyItem{Source: entry}
+		copied := entry
+		copied.ID = ""
+		copied.Date = sourceDate.AddDate(0, 0, offset).Format("2006-01-02")
+		copied.CreatedByID = createdByID
+		copied.CreatedByName = createdByName
+		copied.ApprovalStatus = ""
+		copied.ApprovalComment = ""
+		copied.ReviewedByID = ""
+		copied.ReviewedByName = ""
+		copied.ReviewedAt = ""
+		copied.ObjectIDs = []string{}
+		copied.WorkerIDs = []string{}
+
+		for _, oid := range entry.ObjectIDs {
+			if params.ObjectID != "" && oid != params.ObjectID {
+				continue
+			}
+			object, ok := objectsByID[oid]
+			if !ok || object.Status != "in_progress" {
+				name := oid
+				if ok {
+					name = object.Name
+				}
+				item.Skipped = append(item.Skipped, scheduleCopySkip{Name: name, Reason: "объект не в работе"})
+				continue
+			}
+			copied.ObjectIDs = append(copied.ObjectIDs, oid)
+		}
+
+		for _, wid := range entry.WorkerIDs {
+			worker, ok := workersByID[wid]
+			if !ok || worker.IsFired {
+				name := wid
+				if ok {
+					name = worker.Name
+				}
+				item.Skipped = append(item.Skipped, scheduleCopySkip{Name: name, Reason: "работник уволен"})
+				continue
+			}
+			conflict := findConflict(entries, copied, wid)
+			if conflict == "" {
+				conflict = findConflict(planned, copied, wid)
+			}
+			if conflict != "" {
+				item.Skipped = append(item.Skipped, scheduleCopySkip{Name: worker.Name, Reason: conflict})
+				continue
+			}
+			copied.WorkerIDs = append(copied.WorkerIDs, wid)
+		}
+
+		if len(copied.ObjectIDs) == 0 || len(copied.WorkerIDs) == 0 {
+			// Skips of single objects and workers explain themselves; an empty source entry needs its own reason.
+			if len(entry.ObjectIDs) == 0 {
+				item.Skipped = append(item.Skipped, scheduleCopySkip{Name: "Назначение", Reason: "не указан объект"})
+			}
+			if len(entry.WorkerIDs) == 0 {
+				item.Skipped = append(item.Skipped, scheduleCopySkip{Name: "Назначение", Reason: "не назначены работники"})
+			}
+			copied.WorkerIDs = []string{}
+		} else {
+			planned = append(planned, copied)
+		}
+		item.Entry = copied
+		plan = append(plan, item)
+	}
+	return plan, nil
+}
+
+// ScheduleCopyPage shows the copy form and, when parameters are given, a dry-run preview with conflicts.
+func ScheduleCopyPage(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	objects, err := storage.GetObjects()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+	workersMap, err := buildWorkersMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	objectsMap, err := buildObjectsMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
+		return
+	}
+
+	sourceValue := strings.TrimSpace(c.Query("source"))
+	targetValue := strings.TrimSpace(c.Query("target"))
+	scopeValue := c.DefaultQuery("scope", "day")
+	objectValue := strings.TrimSpace(c.Query("object"))
+	if sourceValue == "" {
+		sourceValue = time.Now().Format("2006-01-02")
+	}
+
+	var objectOptions strings.Builder
+	objectOptions.WriteString(`<option value="">Все объекты</option>`)
+	for _, object := range objects {
+		selected := ""
+		if object.ID == objectValue {
+			selected = " selected"
+		}
+		objectOptions.WriteString(fmt.Sprintf(`<option value="%s"%s>%s</option>`, template.HTMLEscapeString(object.ID), selected, template.HTMLEscapeString(object.Name)))
+	}
+	scopeDay, scopeWeek := " selected", ""
+	if scopeValue == "week" {
+		scopeDay, scopeWeek = "", " selected"
+	}
+
+	statusBlock := ""
+	if created := c.Query("created"); created != "" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Расписание скопировано</strong><p>Создано назначений: ` + template.HTMLEscapeString(created) + `.</p></div>`
+	}
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock += `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+
+	previewHTML := ""
+	if strings.TrimSpace(c.Query("target")) != "" {
+		params, err := parseScheduleCopyParams(sourceValue, targetValue, scopeValue, objectValue)
+		if err != nil {
+			previewHTML = `<div class="form-error">` + template.HTMLEscapeString(err.Error()) + `</div>`
+		} else {
+			plan, err := buildScheduleCopyPlan(params, c.GetString("userID"), c.GetString("userName"))
+			if err != nil {
+				c.String(http.StatusInternalServerError, "Failed to build copy plan: %v", err)
+				return
+			}
+			previewHTML = renderScheduleCopyPreview(c, params, plan, workersMap, objectsMap)
+		}
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Копирование расписания</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/schedule/board" class="back-link">← К доске недели</a>
+<div class="page-header"><h1>Копирование расписания</h1><p>Перенос назначений дня или недели на другой период. Уволенные работники и объекты на паузе или оконченные пропускаются.</p></div>
+{{STATUS_BLOCK}}
+<div class="card">
+<form method="GET" action="/schedule/copy" class="form-grid-edit">
+  <div class="form-group-edit"><label for="scope">Что копировать</label><select id="scope" name="scope"><option value="day"{{SCOPE_DAY}}>День</option><option value="week"{{SCOPE_WEEK}}>Неделю</option></select></div>
+  <div class="form-group-edit"><label for="object">Объект</label><select id="object" name="object">{{OBJECT_OPTIONS}}</select></div>
+  <div class="form-group-edit"><label for="source">Исходная дата</label><input id="source" name="source" type="date" value="{{SOURCE}}" required></div>
+  <div class="form-group-edit"><label for="target">Копировать на</label><input id="target" name="target" type="date" value="{{TARGET}}" required></div>
+  <div class="form-actions-edit"><button type="submit" class="btn btn-primary">Предпросмотр</button></div>
+</form>
+</div>
+{{PREVIEW}}
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "schedule"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{SCOPE_DAY}}", scopeDay, 1)
+	final = strings.Replace(final, "{{SCOPE_WEEK}}", scopeWeek, 1)
+	final = strings.Replace(final, "{{OBJECT_OPTIONS}}", objectOptions.String(), 1)
+	final = strings.Replace(final, "{{SOURCE}}", template.HTMLEscapeString(sourceValue), 1)
+	final = strings.Replace(final, "{{TARGET}}", template.HTMLEscapeString(targetValue), 1)
+	final = strings.Replace(final, "{{PREVIEW}}", previewHTML, 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func renderScheduleCopyPreview(c *gin.Context, params scheduleCopyParams, plan []scheduleCopyItem, workersMap, objectsMap map[string]string) string {
+	targetEnd := params.TargetFrom.AddDate(0, 0, params.Days-1)
+	var rows strings.Builder
+	toCreate := 0
+	skipped := 0
+	for _, item := range plan {
+		status := `<span class="status-badge status-active">Будет создано</span>`
+		if len(item.Entry.WorkerIDs) == 0 {
+			status = `<span class="status-badge status-danger">Пропуск</span>`
+		} else {
+			toCreate++
+		}
+		var skips strings.Builder
+		for _, skip := range item.Skipped {
+			skipped++
+			skips.WriteString(`<p>` + template.HTMLEscapeString(skip.Name) + `: ` + template.HTMLEscapeString(skip.Reason) + `</p>`)
+		}
+		if skips.Len() == 0 {
+			skips.WriteString("—")
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td>%s → %s</td><td>%s–%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			template.HTMLEscapeString(item.Source.Date),
+			template.HTMLEscapeString(item.Entry.Date),
+			template.HTMLEscapeString(item.Source.StartTime),
+			template.HTMLEscapeString(item.Source.EndTime),
+			joinMappedValues(item.Entry.ObjectIDs, objectsMap),
+			joinMappedValues(item.Entry.WorkerIDs, workersMap),
+			skips.String(),
+			status,
+		))
+	}
+	if len(plan) == 0 {
+		return `<div class="info-card"><p>В исходном периоде нет назначений для копирования.</p></div>`
+	}
+
+	applyForm := ""
+	if toCreate > 0 {
+		applyForm = `<form method="POST" action="/schedule/copy">` + CSRFHiddenInput(c) +
+			`<input type="hidden" name="source" value="` + template.HTMLEscapeString(params.SourceFrom.Format("2006-01-02")) + `">` +
+			`<input type="hidden" name="target" value="` + template.HTMLEscapeString(params.TargetFrom.Format("2006-01-02")) + `">` +
+			`<input type="hidden" name="scope" value="` + template.HTMLEscapeString(params.Scope) + `">` +
+			`<input type="hidden" name="object" value="` + template.HTMLEscapeString(params.ObjectID) + `">` +
+			`<button type="submit" class="btn btn-primary">Скопировать ` + strconv.Itoa(toCreate) + ` назнач.</button></form>`
+	}
+
+	return fmt.Sprintf(`<div class="card"><div class="history-header"><h2>Предпросмотр: %s – %s</h2></div><p>Будет создано: <strong>%d</strong>. Пропущено работников/объектов: <strong>%d</strong>.</p><div class="table-scroll"><table class="table"><thead><tr><th>Дата</th><th>Время</th><th>Объекты</th><th>Работники</th><th>Конфликты</th><th>Статус</th></tr></thead><tbody>%s</tbody></table></div><div class="info-card-actions">%s</div></div>`,
+		template.HTMLEscapeString(params.TargetFrom.Format("02.01.2006")),
+		template.HTMLEscapeString(targetEnd.Format("02.01.2006")),
+		toCreate,
+		skipped,
+		rows.String(),
+		applyForm,
+	)
+}
+
+// CopySchedule recomputes the copy plan and creates the non-conflicting entries.
+func CopySchedule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	query := url.Values{}
+	query.Set("source", c.PostForm("source"))
+	query.Set("target", c.PostForm("target"))
+	query.Set("scope", c.PostForm("scope"))
+	query.Set("object", c.PostForm("object"))
+
+	params, err := parseScheduleCopyParams(c.PostForm("source"), c.PostForm("target"), c.PostForm("scope"), c.PostForm("object"))
+	if err != nil {
+		query.Set("error", err.Error())
+		c.Redirect(http.StatusFound, "/schedule/copy?"+query.Encode())
+		return
+	}
+	plan, err := buildScheduleCopyPlan(params, c.GetString("userID"), c.GetString("userName"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to build copy plan: %v", err)
+		return
+	}
+	created := 0
+	for _, item := range plan {
+		if len(item.Entry.WorkerIDs) == 0 {
+			continue
+		}
+		if _, err := storage.CreateTimesheet(item.Entry); err != nil {
+			query.Set("error", humanizeScheduleError(err))
+			continue
+		}
+		created++
+	}
+	query.Del("target")
+	query.Set("created", strconv.Itoa(created))
+	c.Redirect(http.StatusFound, "/schedule/copy?"+query.Encode())
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"project/internal/models"
 	"project/internal/security"
 	"project/internal/storage"
 	"project/internal/telegrambot"
@@ -35,7 +37,7 @@ func CreateBackup(c *gin.Context) {
 		return
 	}
 	ts := time.Now().Format("20060102-150405")
-	files := []string{"users.json", "workers.json", "objects.json", "timesheets.json"}
+	files := []string{"users.json", "workers.json", "objects.json", "timesheets.json", "attendance.json", "month_closures.json", "mark_types.json", "leave_entitlements.json", "leave_requests.json", "production_calendar.json", "payroll_rates.json", "payroll_adjustments.json", "payroll_snapshots.json", "api_tokens.json", "webhooks.json", "ics_feeds.json"}
 	for _, f := range files {
 		src := filepath.Join("storage", f)
 		dst := filepath.Join(backupDir, strings.TrimSuffix(f, ".json")+"-"+ts+".json")
@@ -57,13 +59,108 @@ func SaveTelegramSettings(c *gin.Context) {
 	settings.TelegramBotToken = c.PostForm("telegram_bot_token")
 	settings.TelegramBotUsername = c.PostForm("telegram_bot_username")
 	settings.TelegramSiteURL = c.PostForm("telegram_site_url")
+	settings.TelegramMode = c.PostForm("telegram_mode")
+	if settings.TelegramMode == "webhook" && settings.TelegramWebhookSecret == "" {
+		settings.TelegramWebhookSecret = telegrambot.NewWebhookSecret()
+	}
 	if err := storage.UpdateAppSettings(settings); err != nil {
 		c.String(http.StatusInternalServerError, "Failed to save telegram settings: %v", err)
 		return
 	}
+	telegrambot.Reload()
 	c.Redirect(http.StatusFound, "/settings?ok=telegram_saved")
 }
 
+// TelegramWebhook receives bot updates in webhook mode; Telegram proves itself with the secret token header.
+func TelegramWebhook(c *gin.Context) {
+	err := telegrambot.HandleWebhook(c.GetHeader("X-Telegram-Bot-Api-Secret-Token"), c.Request.Body)
+	switch {
+	case errors.Is(err, telegrambot.ErrWebhookForbidden):
+		security.LogEvent("telegram_webhook_rejected", fmt.Sprintf("ip=%s", c.ClientIP()))
+		c.Status(http.StatusForbidden)
+	case errors.Is(err, telegrambot.ErrMalformedUpdate):
+		c.Status(http.StatusBadRequest)
+	case err != nil:
+		// A non-2xx answer makes Telegram retry the update later.
+		c.Status(http.StatusInternalServerError)
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func humanizeTelegramError(msg string) string {
+	switch msg {
+	case telegrambot.ErrWorkerReceiving.Error():
+		return "Обновления уже принимаются автоматически, синхронизация вручную не нужна."
+	case telegrambot.ErrBotNotConfigured.Error():
+		return "Укажите токен бота."
+	case telegrambot.ErrWebhookNeedsSite.Error():
+		return "Для режима вебхука укажите адрес сайта, начинающийся с https://."
+	default:
+		return msg
+	}
+}
+
+// telegramWorkerPanel shows how the background worker receives updates and its last error.
+func telegramWorkerPanel() string {
+	st := telegrambot.GetStatus()
+	state := `<span class="import-status is-skipped">остановлен</span>`
+	switch st.State {
+	case "idle":
+		state = `<span class="import-status is-skipped">ждёт токен бота</span>`
+	case "polling":
+		state = `<span class="import-status is-ok">long polling</span>`
+	case "webhook":
+		state = `<span class="import-status is-ok">вебхук</span>`
+	case "retrying":
+		state = `<span class="import-status is-failed">ошибка, повтор в ` + template.HTMLEscapeString(formatClosureTime(st.RetryAt)) + `</span>`
+	}
+	timeOrDash := func(value string) string {
+		if value == "" {
+			return "—"
+		}
+		return formatClosureTime(value)
+	}
+	meta := fmt.Sprintf("Последнее обновление: %s · связь с Telegram: %s · обработано с запуска: %d",
+		timeOrDash(st.LastUpdateAt), timeOrDash(st.LastContactAt), st.Processed)
+	var b strings.Builder
+	b.WriteString(`<div class="dashboard-list-item"><strong>Приём обновлений: ` + state + `</strong><p>` + template.HTMLEscapeString(meta) + `</p>`)
+	if st.WebhookURL != "" {
+		b.WriteString(`<p>Адрес вебхука: <code>` + template.HTMLEscapeString(st.WebhookURL) + `</code></p>`)
+	}
+	if st.LastError != "" {
+		b.WriteString(`<p class="form-error">` + template.HTMLEscapeString(formatClosureTime(st.LastErrorAt)+": "+humanizeTelegramError(st.LastError)) + `</p>`)
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// SaveOrganization stores the requisites printed on the Т-13 табель.
+func SaveOrganization(c *gin.Context) {
+	settings, err := storage.GetAppSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load app settings: %v", err)
+		return
+	}
+	settings.Organization = models.Organization{
+		Name:             c.PostForm("org_name"),
+		UNP:              c.PostForm("org_unp"),
+		Address:          c.PostForm("org_address"),
+		Department:       c.PostForm("org_department"),
+		ResponsibleTitle: c.PostForm("org_responsible_title"),
+		ResponsibleName:  c.PostForm("org_responsible_name"),
+		HeadTitle:        c.PostForm("org_head_title"),
+		HeadName:         c.PostForm("org_head_name"),
+		HRName:           c.PostForm("org_hr_name"),
+	}
+	if err := storage.UpdateAppSettings(settings); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to save organization: %v", err)
+		return
+	}
+	security.LogEvent("organization_saved", fmt.Sprintf("user=%s", c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings?ok=organization_saved")
+}
+
 func SyncTelegramContacts(c *gin.Context) {
 	summary, err := telegrambot.SyncContacts()
 	if err != nil {
@@ -94,11 +191,22 @@ func SettingsPage(c *gin.Context) {
 		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Резервная копия создана</strong><p>Файлы users, workers, objects и timesheets сохранены в локальный backup.</p></div>`
 	case "telegram_saved":
 		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Настройки Telegram сохранены</strong><p>Токен, username бота и адрес сайта обновлены.</p></div>`
+	case "organization_saved":
+		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Реквизиты сохранены</strong><p>Они попадут в шапку и подписи табеля по форме Т-13.</p></div>`
 	case "telegram_synced":
 		statusBlock = `<div class="dashboard-alert-item is-success"><strong>Контакты Telegram синхронизированы</strong><p>Обновлений обработано: ` + template.HTMLEscapeString(c.Query("processed")) + `. Привязок по телефону обновлено: ` + template.HTMLEscapeString(c.Query("linked")) + `.</p></div>`
 	}
 	if errMsg := strings.TrimSpace(c.Query("telegram_error")); errMsg != "" {
-		statusBlock += `<div class="dashboard-alert-item is-warning"><strong>Telegram не синхронизирован</strong><p>` + template.HTMLEscapeString(errMsg) + `</p></div>`
+		statusBlock += `<div class="dashboard-alert-item is-warning"><strong>Telegram не синхронизирован</strong><p>` + template.HTMLEscapeString(humanizeTelegramError(errMsg)) + `</p></div>`
+	}
+
+	syncButton := ""
+	if !telegrambot.Receiving() {
+		syncButton = `<form method="POST" action="/settings/telegram/sync"><button type="submit" class="btn btn-secondary">Синхронизировать контакты из бота</button></form>`
+	}
+	webhookSelected := ""
+	if settings.TelegramMode == "webhook" {
+		webhookSelected = " selected"
 	}
 
 	startBotLink := ""
@@ -156,6 +264,62 @@ func SettingsPage(c *gin.Context) {
             </form>
         </div>
 
+        <div class="info-card">
+            <div class="info-card-header">
+                <h2>Справочник отметок</h2>
+                <span class="status-badge">ОТ · Б · ПР · В</span>
+            </div>
+            <p>Коды неявок, их цвета, оплачиваемость, необходимость документа и ввод периодом.</p>
+            <div class="info-card-actions"><a class="btn btn-secondary" href="/settings/marks">Открыть справочник</a></div>
+        </div>
+
+        <div class="info-card">
+            <div class="info-card-header">
+                <h2>Производственный календарь</h2>
+                <span class="status-badge">норма часов</span>
+            </div>
+            <p>Праздники, переносы и сокращённые дни. По календарю табель считает норму и переработку.</p>
+            <div class="info-card-actions"><a class="btn btn-secondary" href="/settings/calendar">Открыть календарь</a></div>
+        </div>
+
+        <div class="info-card">
+            <div class="info-card-header">
+                <h2>API-токены</h2>
+                <span class="status-badge">Bearer</span>
+            </div>
+            <p>Именные токены для скриптов и интеграций с ограниченными правами; их можно отозвать в любой момент.</p>
+            <div class="info-card-actions"><a class="btn btn-secondary" href="/settings/tokens">Управлять токенами</a></div>
+        </div>
+
+        <div class="info-card">
+            <div class="info-card-header">
+                <h2>Вебхуки</h2>
+                <span class="status-badge">HMAC</span>
+            </div>
+            <p>Уведомления внешних систем о назначениях, увольнениях и статусах объектов: подписки, журнал доставок, тестовое событие.</p>
+            <div class="info-card-actions"><a class="btn btn-secondary" href="/settings/webhooks">Настроить вебхуки</a></div>
+        </div>
+
+        <div class="info-card">
+            <div class="info-card-header">
+                <h2>Организация</h2>
+                <span class="status-badge">Т-13</span>
+            </div>
+            <p>Реквизиты и подписанты для унифицированной формы табеля.</p>
+            <form method="POST" action="/settings/organization" class="form-grid-edit">
+                <div class="form-group-edit timesheet-span-2"><label for="org_name">Наименование</label><input type="text" id="org_name" name="org_name" value="` + template.HTMLEscapeString(settings.Organization.Name) + `"></div>
+                <div class="form-group-edit"><label for="org_unp">УНП</label><input type="text" id="org_unp" name="org_unp" value="` + template.HTMLEscapeString(settings.Organization.UNP) + `"></div>
+                <div class="form-group-edit"><label for="org_department">Структурное подразделение</label><input type="text" id="org_department" name="org_department" value="` + template.HTMLEscapeString(settings.Organization.Department) + `"></div>
+                <div class="form-group-edit timesheet-span-2"><label for="org_address">Адрес</label><input type="text" id="org_address" name="org_address" value="` + template.HTMLEscapeString(settings.Organization.Address) + `"></div>
+                <div class="form-group-edit"><label for="org_responsible_title">Ответственное лицо — должность</label><input type="text" id="org_responsible_title" name="org_responsible_title" value="` + template.HTMLEscapeString(settings.Organization.ResponsibleTitle) + `"></div>
+                <div class="form-group-edit"><label for="org_responsible_name">Ответственное лицо — Ф.И.О.</label><input type="text" id="org_responsible_name" name="org_responsible_name" value="` + template.HTMLEscapeString(settings.Organization.ResponsibleName) + `"></div>
+                <div class="form-group-edit"><label for="org_head_title">Руководитель — должность</label><input type="text" id="org_head_title" name="org_head_title" value="` + template.HTMLEscapeString(settings.Organization.HeadTitle) + `"></div>
+                <div class="form-group-edit"><label for="org_head_name">Руководитель — Ф.И.О.</label><input type="text" id="org_head_name" name="org_head_name" value="` + template.HTMLEscapeString(settings.Organization.HeadName) + `"></div>
+                <div class="form-group-edit timesheet-span-2"><label for="org_hr_name">Работник кадровой службы — Ф.И.О.</label><input type="text" id="org_hr_name" name="org_hr_name" value="` + template.HTMLEscapeString(settings.Organization.HRName) + `"></div>
+                <div class="form-actions-edit"><button type="submit" class="btn btn-primary">Сохранить реквизиты</button></div>
+            </form>
+        </div>
+
         <div class="info-card">
             <div class="info-card-header">
                 <h2>Установка на телефон</h2>
@@ -187,12 +351,14 @@ func SettingsPage(c *gin.Context) {
                 <div class="form-group-edit form-group-name"><label for="telegram_bot_token">Токен бота</label><input type="text" id="telegram_bot_token" name="telegram_bot_token" value="` + template.HTMLEscapeString(settings.TelegramBotToken) + `" placeholder="123456:ABC..."></div>
                 <div class="form-group-edit form-group-position"><label for="telegram_bot_username">Username бота</label><input type="text" id="telegram_bot_username" name="telegram_bot_username" value="` + template.HTMLEscapeString(settings.TelegramBotUsername) + `" placeholder="my_company_bot"></div>
                 <div class="form-group-edit timesheet-span-2"><label for="telegram_site_url">Адрес сайта</label><input type="url" id="telegram_site_url" name="telegram_site_url" value="` + template.HTMLEscapeString(settings.TelegramSiteURL) + `" placeholder="https://example.com"></div>
+                <div class="form-group-edit timesheet-span-2"><label for="telegram_mode">Приём обновлений</label><select id="telegram_mode" name="telegram_mode"><option value="polling">Long polling — сервер сам опрашивает Telegram</option><option value="webhook"` + webhookSelected + `>Вебхук — Telegram присылает обновления на адрес сайта (нужен https)</option></select></div>
                 <div class="form-actions-edit"><button type="submit" class="btn btn-primary">Сохранить настройки бота</button></div>
             </form>
             <div class="info-card-actions">
-                <form method="POST" action="/settings/telegram/sync"><button type="submit" class="btn btn-secondary">Синхронизировать контакты из бота</button></form>
+                ` + syncButton + `
                 ` + startBotLink + `
             </div>
+            <div class="dashboard-list">` + telegramWorkerPanel() + `</div>
             <div class="dashboard-list">` + contactsHTML.String() + `</div>
         </div>
 
@@ -211,8 +377,8 @@ func SettingsPage(c *gin.Context) {
                     <p>Сотрудник отправляет в бот свой контакт с тем же номером, который указан у него в системе.</p>
                 </div>
                 <div class="pwa-step">
-                    <strong>3. Синхронизировать</strong>
-                    <p>В настройках нажмите «Синхронизировать контакты из бота». После этого при создании учётки система сможет отправить логин, сайт, PWA-инструкцию и пароль в этот Telegram-чат.</p>
+                    <strong>3. Готово</strong>
+                    <p>Сервер принимает сообщения бота в фоне и сразу связывает телефон с чатом. После этого при создании учётки система сможет отправить логин, сайт, PWA-инструкцию и пароль в этот Telegram-чат, а сотрудник — пользоваться командами /today, /week, /hours.</p>
                 </div>
             </div>
         </div>
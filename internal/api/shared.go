@@ -42,6 +42,8 @@ func RenderSidebar(c *gin.Context, activePage string) string {
 	navItems := []navItem{
 		{PageID: "schedule", Path: "/schedule", Label: "Расписание"},
 		{PageID: "timesheets", Path: "/timesheets", Label: "Табель"},
+		{PageID: "attendance", Path: "/attendance", Label: "Факт"},
+		{PageID: "leave", Path: "/leave", Label: "Отпуска"},
 		{PageID: "improvements", Path: "/improvements", Label: "Улучшения/ошибки"},
 	}
 	if userStatus == "admin" {
@@ -50,6 +52,7 @@ func RenderSidebar(c *gin.Context, activePage string) string {
 			{PageID: "workers", Path: "/workers", Label: "Работники"},
 			{PageID: "objects", Path: "/objects", Label: "Объекты"},
 		}, navItems...)
+		navItems = append(navItems, navItem{PageID: "payroll", Path: "/payroll", Label: "Зарплата"})
 		navItems = append(navItems,
 			navItem{PageID: "users", Path: "/users", Label: "Пользователи"},
 			navItem{PageID: "settings", Path: "/settings", Label: "Настройки"},
@@ -0,0 +1,430 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tabelImportNote = "Импорт табеля"
+
+type tabelImportCell struct {
+	Day     int
+	Raw     string
+	Entry   models.TimesheetEntry
+	Status  string // ok | conflict | error
+	Message string
+}
+
+type tabelImportLine struct {
+	Name     string
+	WorkerID string
+	Auto     bool
+	Problem  string
+	Cells    []tabelImportCell
+}
+
+func normalizePersonName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "ё", "е"))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// initialsKey turns "Иванов Иван Петрович" and "Иванов И. П." into the same "ивановип".
+func initialsKey(name string) string {
+	parts := strings.Fields(strings.ReplaceAll(normalizePersonName(name), ".", ". "))
+	if len(parts) == 0 {
+		return ""
+	}
+	key := parts[0]
+	for _, part := range parts[1:] {
+		if runes := []rune(part); len(runes) > 0 {
+			key += string(runes[0])
+		}
+	}
+	return key
+}
+
+// matchTabelWorker finds the worker by full name, then by surname with initials; ambiguous names stay unmatched.
+func matchTabelWorker(name string, workers []models.Worker) (string, string) {
+	for _, keyOf := range []func(string) string{normalizePersonName, initialsKey} {
+		key := keyOf(name)
+		found := make([]string, 0, 1)
+		for _, worker := range workers {
+			if keyOf(worker.Name) == key {
+				found = append(found, worker.ID)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], ""
+		}
+		if len(found) > 1 {
+			return "", "несколько работников с таким именем — выберите вручную"
+		}
+	}
+	return "", "работник не найден"
+}
+
+func tabelCellEntry(raw, date, workerID, fileName string) (models.TimesheetEntry, error) {
+	if mark, ok := storage.FindMarkType(raw); ok {
+		entry := models.TimesheetEntry{Date: date, WorkerIDs: []string{workerID}, UserMark: mark.Code, Notes: tabelImportNote}
+		if mark.RequiresDocument {
+			entry.DocumentRef = tabelImportNote + " (" + fileName + ")"
+		}
+		return entry, nil
+	}
+	hours, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64)
+	if err != nil {
+		return models.TimesheetEntry{}, errors.New("не распознано «" + raw + "»")
+	}
+	if hours <= 0 || hours >= 24 {
+		return models.TimesheetEntry{}, fmt.Errorf("недопустимое число часов %s", raw)
+	}
+	// Only the total is known: the shift is placed from 08:00 (or midnight for long ones) without a lunch break.
+	start := time.Date(2000, 1, 1, 8, 0, 0, 0, time.UTC)
+	if hours > 15.5 {
+		start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	end := start.Add(time.Duration(hours*60+0.5) * time.Minute)
+	return models.TimesheetEntry{
+		Date:      date,
+		StartTime: start.Format("15:04"),
+		EndTime:   end.Format("15:04"),
+		WorkerIDs: []string{workerID},
+		Notes:     tabelImportNote,
+	}, nil
+}
+
+func describeExistingEntry(entry models.TimesheetEntry) string {
+	if entry.UserMark != "" {
+		return "уже есть отметка " + specialMarkLabel(entry.UserMark)
+	}
+	return "уже есть смена " + entry.StartTime + "–" + entry.EndTime
+}
+
+// buildTabelImport matches the names and turns every day value into an entry, flagging conflicts with existing entries.
+func buildTabelImport(c *gin.Context, month, fileName string, rows []storage.TabelImportRow) ([]tabelImportLine, error) {
+	monthStart, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, errors.New("invalid month")
+	}
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]models.TimesheetEntry{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Date, month+"-") || entry.ApprovalStatus == "rejected" {
+			continue
+		}
+		for _, wid := range entry.WorkerIDs {
+			existing[wid+"|"+entry.Date] = entry
+		}
+	}
+
+	lines := make([]tabelImportLine, 0, len(rows))
+	for i, row := range rows {
+		line := tabelImportLine{Name: row.Name}
+		if c.PostForm("mapped") != "" {
+			line.WorkerID = c.PostForm(fmt.Sprintf("match_%d", i))
+			if line.WorkerID == "" {
+				line.Problem = "не загружается"
+			}
+		} else {
+			line.WorkerID, line.Problem = matchTabelWorker(row.Name, workers)
+			line.Auto = line.WorkerID != ""
+		}
+		if line.WorkerID == "" {
+			lines = append(lines, line)
+			continue
+		}
+		for day := 1; day <= 31; day++ {
+			raw, ok := row.Days[day]
+			if !ok {
+				continue
+			}
+			switch raw {
+			case "—", "-", "–", "X", "x", "Х", "х":
+				continue
+			}
+			cell := tabelImportCell{Day: day, Raw: raw, Status: "ok"}
+			if day > daysInMonth {
+				cell.Status, cell.Message = "error", "в месяце нет такого дня"
+				line.Cells = append(line.Cells, cell)
+				continue
+			}
+			date := fmt.Sprintf("%s-%02d", month, day)
+			entry, err := tabelCellEntry(raw, date, line.WorkerID, fileName)
+			if err != nil {
+				cell.Status, cell.Message = "error", err.Error()
+			} else if other, ok := existing[line.WorkerID+"|"+date]; ok {
+				cell.Status, cell.Message = "conflict", describeExistingEntry(other)
+			}
+			cell.Entry = entry
+			line.Cells = append(line.Cells, cell)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func tabelImportStats(lines []tabelImportLine) (matched, shifts, marks, conflicts, failed int) {
+	for _, line := range lines {
+		if line.WorkerID != "" {
+			matched++
+		}
+		for _, cell := range line.Cells {
+			switch {
+			case cell.Status == "conflict":
+				conflicts++
+			case cell.Status == "error":
+				failed++
+			case cell.Entry.UserMark != "":
+				marks++
+			default:
+				shifts++
+			}
+		}
+	}
+	return
+}
+
+func tabelCellProblems(line tabelImportLine) string {
+	problems := make([]string, 0)
+	for _, cell := range line.Cells {
+		if cell.Status != "ok" {
+			problems = append(problems, fmt.Sprintf(`<span class="import-status is-%s">%02d: %s</span>`, cell.Status, cell.Day, template.HTMLEscapeString(cell.Message)))
+		}
+	}
+	if len(problems) == 0 {
+		return "—"
+	}
+	return strings.Join(problems, "<br>")
+}
+
+func humanizeTabelImportError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tabel header not found"):
+		return "Не найдена строка заголовков «Работник, 1, 2, …» — файл должен быть в формате выгрузки табеля."
+	case strings.Contains(msg, "invalid month"):
+		return "Не удалось определить месяц — укажите его вручную."
+	case strings.Contains(msg, "unsupported file type"):
+		return "Поддерживаются файлы .xlsx."
+	default:
+		return humanizeImportError(err)
+	}
+}
+
+// TabelImportPage shows the upload form for historical табель workbooks.
+func TabelImportPage(c *gin.Context) {
+	body := `<div class="card"><form method="POST" action="/timesheets/import/preview" enctype="multipart/form-data" class="mark-type-form">` + CSRFHiddenInput(c) + `
+<input type="file" name="file" accept=".xlsx" required>
+<label>Месяц <input type="month" name="month" title="Если пусто — берётся из заголовка «за … года»"></label>
+<button type="submit" class="btn btn-primary">Проверить</button>
+</form>
+<p class="leave-balance-meta">Файл в формате выгрузки «Экспорт»: колонка «Работник», колонки дней, в ячейках часы или коды отметок (ОТ, Б …). Один файл — один месяц. Смены создаются с 08:00 без обеда на указанное число часов.</p></div>`
+	renderTabelImportPage(c, body)
+}
+
+func renderTabelImportPage(c *gin.Context, body string) {
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	}
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Импорт табеля</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/timesheets" class="back-link">← К табелю</a>
+<div class="page-header"><h1>Импорт табеля из Excel</h1><p>Имена сопоставляются с работниками, каждая ячейка становится сменой или отметкой. Дни, где уже есть записи, не перезаписываются — они попадут в отчёт.</p></div>
+{{STATUS_BLOCK}}
+{{BODY}}
+</div>
+</body></html>`
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "timesheets"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{BODY}}", body, 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+// loadTabelImport reads the upload (new file or a stored one) and resolves the month.
+func loadTabelImport(c *gin.Context) (id, month, fileName string, rows []storage.TabelImportRow, err error) {
+	var table [][]string
+	if _, formErr := c.FormFile("file"); formErr == nil {
+		id, _, fileName, table, err = loadImportForm(c)
+	} else {
+		id = c.PostForm("upload_id")
+		_, fileName, table, err = storage.LoadImportUpload(id)
+	}
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	detected, rows, err := storage.ParseTabelRows(table)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	month = strings.TrimSpace(c.PostForm("month"))
+	if month == "" {
+		month = detected
+	}
+	return id, month, fileName, rows, nil
+}
+
+// PreviewTabelImport is the dry run with the reconciliation of names and days.
+func PreviewTabelImport(c *gin.Context) {
+	id, month, fileName, rows, err := loadTabelImport(c)
+	if err == nil && month == "" {
+		err = errors.New("invalid month")
+	}
+	var lines []tabelImportLine
+	if err == nil {
+		lines, err = buildTabelImport(c, month, fileName, rows)
+	}
+	if err != nil {
+		c.Redirect(http.StatusFound, "/timesheets/import?error="+url.QueryEscape(humanizeTabelImportError(err)))
+		return
+	}
+	workers, _ := storage.GetWorkers()
+	matched, shifts, marks, conflicts, failed := tabelImportStats(lines)
+	closed := storage.IsMonthClosed(month)
+
+	var rowsHTML strings.Builder
+	for i, line := range lines {
+		var options strings.Builder
+		options.WriteString(`<option value="">— не загружать —</option>`)
+		for _, worker := range workers {
+			selected := ""
+			if worker.ID == line.WorkerID {
+				selected = " selected"
+			}
+			options.WriteString(`<option value="` + template.HTMLEscapeString(worker.ID) + `"` + selected + `>` + template.HTMLEscapeString(worker.Name) + `</option>`)
+		}
+		lineShifts, lineMarks := 0, 0
+		for _, cell := range line.Cells {
+			if cell.Status == "ok" && cell.Entry.UserMark != "" {
+				lineMarks++
+			} else if cell.Status == "ok" {
+				lineShifts++
+			}
+		}
+		matchNote := ""
+		if line.Problem != "" {
+			matchNote = `<br><span class="import-status is-error">` + template.HTMLEscapeString(line.Problem) + `</span>`
+		} else if line.Auto {
+			matchNote = `<br><span class="import-status is-ok">найден автоматически</span>`
+		}
+		rowsHTML.WriteString(fmt.Sprintf(`<tr><td>%s</td><td><select name="match_%d">%s</select>%s</td><td>%d / %d</td><td>%s</td></tr>`,
+			template.HTMLEscapeString(line.Name), i, options.String(), matchNote, lineShifts, lineMarks, tabelCellProblems(line)))
+	}
+	if len(lines) == 0 {
+		rowsHTML.WriteString(`<tr><td colspan="4">В файле нет строк работников.</td></tr>`)
+	}
+
+	banner := ""
+	applyDisabled := ""
+	if closed {
+		banner = `<div class="dashboard-alert-item month-closed-banner"><strong>Месяц закрыт</strong><p>Импорт в закрытый месяц невозможен — сначала откройте его.</p></div>`
+	}
+	if closed || shifts+marks == 0 {
+		applyDisabled = " disabled"
+	}
+	body := fmt.Sprintf(`%s<form method="POST" action="/timesheets/import/preview">%s<input type="hidden" name="upload_id" value="%s"><input type="hidden" name="mapped" value="1">
+<div class="card"><p><strong>%s</strong> · сопоставлено работников: %d из %d · смен к созданию: %d · отметок: %d · конфликтов с существующими записями: %d · нераспознанных ячеек: %d</p>
+<div class="mark-type-form"><label>Месяц <input type="month" name="month" value="%s" required></label></div>
+<div class="form-actions"><button type="submit" class="btn btn-secondary">Обновить предпросмотр</button><button type="submit" formaction="/timesheets/import/apply" class="btn btn-primary"%s onclick="return confirm('Создать смены и отметки без конфликтов?')">Импортировать %d</button><a class="btn btn-secondary" href="/timesheets/import">Другой файл</a></div></div>
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Имя в файле</th><th>Работник</th><th>Смен / отметок</th><th>Конфликты и ошибки</th></tr></thead><tbody>%s</tbody></table></div></div>
+</form>`,
+		banner, CSRFHiddenInput(c), template.HTMLEscapeString(id),
+		template.HTMLEscapeString(fileName), matched, len(lines), shifts, marks, conflicts, failed,
+		template.HTMLEscapeString(month), applyDisabled, shifts+marks, rowsHTML.String())
+	renderTabelImportPage(c, body)
+}
+
+// ApplyTabelImport creates the entries without conflicts and reports everything that was left out.
+func ApplyTabelImport(c *gin.Context) {
+	id, month, fileName, rows, err := loadTabelImport(c)
+	var lines []tabelImportLine
+	if err == nil {
+		lines, err = buildTabelImport(c, month, fileName, rows)
+	}
+	if err != nil {
+		c.Redirect(http.StatusFound, "/timesheets/import?error="+url.QueryEscape(humanizeTabelImportError(err)))
+		return
+	}
+	userID, userName := c.GetString("userID"), c.GetString("userName")
+	batch := make([]models.TimesheetEntry, 0)
+	for _, line := range lines {
+		for _, cell := range line.Cells {
+			if cell.Status == "ok" {
+				cell.Entry.CreatedByID = userID
+				cell.Entry.CreatedByName = userName
+				batch = append(batch, cell.Entry)
+			}
+		}
+	}
+	_, errs, err := storage.CreateTimesheets(batch)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/timesheets/import?error="+url.QueryEscape(humanizeTabelImportError(err)))
+		return
+	}
+	storage.DeleteImportUpload(id)
+
+	created := 0
+	var report strings.Builder
+	index := 0
+	for _, line := range lines {
+		if line.WorkerID == "" {
+			report.WriteString(`<tr><td>` + template.HTMLEscapeString(line.Name) + `</td><td>—</td><td><span class="import-status is-skipped">` + template.HTMLEscapeString(line.Problem) + `</span></td></tr>`)
+			continue
+		}
+		problems := make([]string, 0)
+		for _, cell := range line.Cells {
+			if cell.Status != "ok" {
+				problems = append(problems, fmt.Sprintf(`<span class="import-status is-%s">%02d: %s</span>`, cell.Status, cell.Day, template.HTMLEscapeString(cell.Message)))
+				continue
+			}
+			if errs[index] != nil {
+				problems = append(problems, fmt.Sprintf(`<span class="import-status is-failed">%02d: %s</span>`, cell.Day, template.HTMLEscapeString(humanizeScheduleError(errs[index]))))
+			} else {
+				created++
+			}
+			index++
+		}
+		if len(problems) > 0 {
+			report.WriteString(`<tr><td>` + template.HTMLEscapeString(line.Name) + `</td><td>` + template.HTMLEscapeString(workerNameByID(line.WorkerID)) + `</td><td>` + strings.Join(problems, "<br>") + `</td></tr>`)
+		}
+	}
+	if report.Len() == 0 {
+		report.WriteString(`<tr><td colspan="3">Все строки и дни загружены.</td></tr>`)
+	}
+	security.LogEvent("tabel_imported", fmt.Sprintf("user=%s month=%s file=%q created=%d", userName, month, fileName, created))
+
+	body := fmt.Sprintf(`<div class="dashboard-alert-item is-success"><strong>Импорт завершён</strong><p>%s за %s · создано записей: %d</p></div>
+<div class="card"><h2>Сверка</h2><div class="table-scroll"><table class="table"><thead><tr><th>Имя в файле</th><th>Работник</th><th>Не загружено</th></tr></thead><tbody>%s</tbody></table></div>
+<div class="form-actions"><a class="btn btn-primary" href="/timesheets?month=%s">Открыть табель</a><a class="btn btn-secondary" href="/timesheets/import">Загрузить ещё</a></div></div>`,
+		template.HTMLEscapeString(fileName), template.HTMLEscapeString(month), created, report.String(), url.QueryEscape(month))
+	renderTabelImportPage(c, body)
+}
+
+func workerNameByID(id string) string {
+	if worker, err := storage.GetWorkerByID(id); err == nil {
+		return worker.Name
+	}
+	return id
+}
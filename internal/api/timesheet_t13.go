@@ -0,0 +1,406 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// t13Day is one cell pair of the unified табель: the letter code and the hours.
+type t13Day struct {
+	Code  string
+	Hours float64
+	Mark  bool
+}
+
+// Work codes of the unified form; absences use the codes of the mark catalogue.
+const (
+	t13CodeWork    = "Я"
+	t13CodeWorkOff = "РВ"
+	t13CodeOff     = "В"
+)
+
+func t13WorkerDays(entries []models.TimesheetEntry, workerID string, monthDates []string, calendar []calendarColumn, attendanceMap map[string]models.AttendanceRecord) []t13Day {
+	days := make([]t13Day, len(monthDates))
+	for i, date := range monthDates {
+		for _, entry := range entries {
+			if entry.Date != date || !storage.IsTimesheetApproved(entry) {
+				continue
+			}
+			contains := false
+			for _, wid := range entry.WorkerIDs {
+				if wid == workerID {
+					contains = true
+					break
+				}
+			}
+			if !contains {
+				continue
+			}
+			if mark, ok := storage.FindMarkType(entry.UserMark); ok {
+				days[i] = t13Day{Code: mark.Code, Mark: true}
+				break
+			}
+			hours, _ := strconv.ParseFloat(formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes), 64)
+			if record, ok := attendanceMap[attendanceKey(entry.ID, workerID)]; ok && record.Status == "confirmed" {
+				if factHours, closed := attendanceHours(record); closed {
+					hours = factHours
+				}
+			}
+			days[i].Hours += hours
+		}
+		if days[i].Mark {
+			continue
+		}
+		offDay := calendar[i].Kind == "weekend" || calendar[i].Kind == "holiday"
+		switch {
+		case days[i].Hours > 0 && offDay:
+			days[i].Code = t13CodeWorkOff
+		case days[i].Hours > 0:
+			days[i].Code = t13CodeWork
+		case offDay:
+			days[i].Code = t13CodeOff
+		}
+	}
+	return days
+}
+
+// t13Totals are the subtotals of one worker: worked days and hours for days 1–15 and 16 onwards,
+// and per absence code the days and the calendar norm hours they cover.
+type t13Totals struct {
+	HalfDays  [2]int
+	HalfHours [2]float64
+	MarkDays  map[string]int
+	MarkHours map[string]float64
+}
+
+func summarizeT13Days(days []t13Day, calendar []calendarColumn) t13Totals {
+	totals := t13Totals{MarkDays: map[string]int{}, MarkHours: map[string]float64{}}
+	for i, day := range days {
+		half := 0
+		if i >= 15 {
+			half = 1
+		}
+		switch {
+		case day.Mark:
+			totals.MarkDays[day.Code]++
+			totals.MarkHours[day.Code] += calendar[i].NormHours
+		case day.Hours > 0:
+			totals.HalfDays[half]++
+			totals.HalfHours[half] += day.Hours
+		}
+	}
+	return totals
+}
+
+// ExportTimesheetT13 builds the табель in the layout of the unified form Т-13:
+// two rows per worker (codes and hours), half-month subtotals, absence totals and signatures.
+func ExportTimesheetT13(c *gin.Context) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load timesheets: %v", err)
+		return
+	}
+	entries, err = getScopedEntries(c, entries)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to scope timesheets: %v", err)
+		return
+	}
+	workers, err := storage.GetWorkers()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load workers: %v", err)
+		return
+	}
+	if c.GetString("userStatus") != "admin" {
+		if ownWorker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil && !ownWorker.IsFired {
+			workers = []models.Worker{ownWorker}
+		} else {
+			workers = []models.Worker{}
+		}
+	}
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
+	marks, err := storage.GetMarkTypes()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load marks: %v", err)
+		return
+	}
+	settings, _ := storage.GetAppSettings()
+	org := settings.Organization
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+
+	selectedMonth, monthStart, daysInMonth := resolveSelectedMonth(c.Query("month"))
+	monthDates := buildMonthDates(selectedMonth, daysInMonth)
+	calendar := buildMonthCalendar(monthDates)
+	hourRules := currentHourRules()
+
+	type workerRow struct {
+		worker    models.Worker
+		days      []t13Day
+		breakdown models.HourBreakdown
+	}
+	rows := make([]workerRow, 0, len(workers))
+	usedMarks := map[string]bool{}
+	for _, worker := range workers {
+		if worker.IsFired {
+			continue
+		}
+		days := t13WorkerDays(entries, worker.ID, monthDates, calendar, attendanceMap)
+		for _, day := range days {
+			if day.Mark {
+				usedMarks[day.Code] = true
+			}
+		}
+		breakdown := storage.ClassifyHours(collectWorkerShifts(entries, worker.ID, selectedMonth, attendanceMap, true), selectedMonth, hourRules)
+		rows = append(rows, workerRow{worker: worker, days: days, breakdown: breakdown})
+	}
+	markCodes := make([]string, 0)
+	markLabels := map[string]string{}
+	for _, mark := range marks {
+		if usedMarks[mark.Code] {
+			markCodes = append(markCodes, mark.Code)
+			markLabels[mark.Code] = mark.Label
+		}
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Т-13"
+	f.SetSheetName("Sheet1", sheet)
+
+	border := []excelize.Border{{Type: "left", Color: "000000", Style: 1}, {Type: "right", Color: "000000", Style: 1}, {Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 1}}
+	titleStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}})
+	smallStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Size: 8}, Alignment: &excelize.Alignment{Horizontal: "right"}})
+	headerStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 9}, Border: border, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true}})
+	cellStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Size: 9}, Border: border, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}})
+	subtotalStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 9}, Border: border, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"EEF2F6"}}})
+	nameStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Size: 9}, Border: border, Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center", WrapText: true}})
+
+	// Column layout: №, name, personnel number, 15 days + I subtotal, 16 days + II subtotal, totals, absences.
+	const firstDayCol = 4
+	firstSubtotalCol := firstDayCol + 15
+	secondHalfCol := firstSubtotalCol + 1
+	secondSubtotalCol := secondHalfCol + 16
+	daysTotalCol := secondSubtotalCol + 1
+	hoursTotalCol := daysTotalCol + 1
+	overtimeCol := hoursTotalCol + 1
+	nightCol := overtimeCol + 1
+	weekendCol := nightCol + 1
+	firstMarkCol := weekendCol + 1
+	lastCol := firstMarkCol + len(markCodes) - 1
+	if len(markCodes) == 0 {
+		lastCol = weekendCol
+	}
+	cellName := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+	dayColumn := func(day int) int {
+		if day <= 15 {
+			return firstDayCol + day - 1
+		}
+		return secondHalfCol + day - 16
+	}
+
+	f.SetCellValue(sheet, cellName(lastCol, 1), "Унифицированная форма № Т-13")
+	f.SetCellStyle(sheet, cellName(lastCol, 1), cellName(lastCol, 1), smallStyle)
+	orgName := org.Name
+	if orgName == "" {
+		orgName = "Наименование организации не указано (Настройки → Организация)"
+	}
+	f.SetCellValue(sheet, "A2", orgName)
+	f.MergeCell(sheet, "A2", cellName(secondSubtotalCol, 2))
+	requisites := make([]string, 0, 2)
+	if org.UNP != "" {
+		requisites = append(requisites, "УНП "+org.UNP)
+	}
+	if org.Address != "" {
+		requisites = append(requisites, org.Address)
+	}
+	f.SetCellValue(sheet, "A3", strings.Join(requisites, ", "))
+	f.MergeCell(sheet, "A3", cellName(secondSubtotalCol, 3))
+	if org.Department != "" {
+		f.SetCellValue(sheet, "A4", "Структурное подразделение: "+org.Department)
+		f.MergeCell(sheet, "A4", cellName(secondSubtotalCol, 4))
+	}
+	f.SetCellValue(sheet, "A5", "ТАБЕЛЬ учёта рабочего времени")
+	f.MergeCell(sheet, "A5", cellName(lastCol, 5))
+	f.SetCellStyle(sheet, "A5", cellName(lastCol, 5), titleStyle)
+	periodEnd := monthStart.AddDate(0, 1, -1)
+	f.SetCellValue(sheet, "A6", fmt.Sprintf("Отчётный период: с %s по %s · дата составления %s", monthStart.Format("02.01.2006"), periodEnd.Format("02.01.2006"), time.Now().Format("02.01.2006")))
+	f.MergeCell(sheet, "A6", cellName(lastCol, 6))
+
+	headerTop, headerBottom := 8, 9
+	mergeHeader := func(col int, text string) {
+		f.SetCellValue(sheet, cellName(col, headerTop), text)
+		f.MergeCell(sheet, cellName(col, headerTop), cellName(col, headerBottom))
+	}
+	mergeHeader(1, "№ п/п")
+	mergeHeader(2, "Фамилия, инициалы, должность")
+	mergeHeader(3, "Табельный номер")
+	f.SetCellValue(sheet, cellName(firstDayCol, headerTop), "Отметки о явках и неявках на работу по числам месяца")
+	f.MergeCell(sheet, cellName(firstDayCol, headerTop), cellName(secondSubtotalCol, headerTop))
+	for day := 1; day <= 31; day++ {
+		col := dayColumn(day)
+		f.SetCellValue(sheet, cellName(col, headerBottom), day)
+	}
+	f.SetCellValue(sheet, cellName(firstSubtotalCol, headerBottom), "Итого I пол.")
+	f.SetCellValue(sheet, cellName(secondSubtotalCol, headerBottom), "Итого II пол.")
+	f.SetCellValue(sheet, cellName(daysTotalCol, headerTop), "Отработано за месяц")
+	f.MergeCell(sheet, cellName(daysTotalCol, headerTop), cellName(hoursTotalCol, headerTop))
+	f.SetCellValue(sheet, cellName(daysTotalCol, headerBottom), "дни")
+	f.SetCellValue(sheet, cellName(hoursTotalCol, headerBottom), "часы")
+	f.SetCellValue(sheet, cellName(overtimeCol, headerTop), "из них, часы")
+	f.MergeCell(sheet, cellName(overtimeCol, headerTop), cellName(weekendCol, headerTop))
+	f.SetCellValue(sheet, cellName(overtimeCol, headerBottom), "сверхурочно")
+	f.SetCellValue(sheet, cellName(nightCol, headerBottom), "ночные")
+	f.SetCellValue(sheet, cellName(weekendCol, headerBottom), "вых. и празд.")
+	if len(markCodes) > 0 {
+		f.SetCellValue(sheet, cellName(firstMarkCol, headerTop), "Неявки по причинам, дни (часы)")
+		f.MergeCell(sheet, cellName(firstMarkCol, headerTop), cellName(lastCol, headerTop))
+		for i, code := range markCodes {
+			f.SetCellValue(sheet, cellName(firstMarkCol+i, headerBottom), code)
+		}
+	}
+	f.SetCellStyle(sheet, cellName(1, headerTop), cellName(lastCol, headerBottom), headerStyle)
+	f.SetCellStyle(sheet, cellName(firstSubtotalCol, headerBottom), cellName(firstSubtotalCol, headerBottom), subtotalStyle)
+	f.SetCellStyle(sheet, cellName(secondSubtotalCol, headerBottom), cellName(secondSubtotalCol, headerBottom), subtotalStyle)
+
+	row := headerBottom + 1
+	for index, item := range rows {
+		codeRow, hoursRow := row, row+1
+		f.SetCellValue(sheet, cellName(1, codeRow), index+1)
+		f.MergeCell(sheet, cellName(1, codeRow), cellName(1, hoursRow))
+		title := item.worker.Name
+		if item.worker.Position != "" {
+			title += ", " + item.worker.Position
+		}
+		f.SetCellValue(sheet, cellName(2, codeRow), title)
+		f.MergeCell(sheet, cellName(2, codeRow), cellName(2, hoursRow))
+		f.SetCellValue(sheet, cellName(3, codeRow), item.worker.PersonnelNo)
+		f.MergeCell(sheet, cellName(3, codeRow), cellName(3, hoursRow))
+		f.SetCellStyle(sheet, cellName(1, codeRow), cellName(lastCol, hoursRow), cellStyle)
+		f.SetCellStyle(sheet, cellName(2, codeRow), cellName(2, hoursRow), nameStyle)
+
+		for day := 1; day <= 31; day++ {
+			col := dayColumn(day)
+			if day > daysInMonth {
+				f.SetCellValue(sheet, cellName(col, codeRow), "X")
+				f.SetCellValue(sheet, cellName(col, hoursRow), "X")
+				continue
+			}
+			cell := item.days[day-1]
+			f.SetCellValue(sheet, cellName(col, codeRow), cell.Code)
+			if !cell.Mark && cell.Hours > 0 {
+				f.SetCellValue(sheet, cellName(col, hoursRow), cell.Hours)
+			}
+		}
+		totals := summarizeT13Days(item.days, calendar)
+		halfDays, halfHours, markDays, markHours := totals.HalfDays, totals.HalfHours, totals.MarkDays, totals.MarkHours
+		for half, col := range []int{firstSubtotalCol, secondSubtotalCol} {
+			f.SetCellValue(sheet, cellName(col, codeRow), halfDays[half])
+			f.SetCellValue(sheet, cellName(col, hoursRow), roundHoursValue(halfHours[half]))
+			f.SetCellStyle(sheet, cellName(col, codeRow), cellName(col, hoursRow), subtotalStyle)
+		}
+		f.SetCellValue(sheet, cellName(daysTotalCol, codeRow), halfDays[0]+halfDays[1])
+		f.MergeCell(sheet, cellName(daysTotalCol, codeRow), cellName(daysTotalCol, hoursRow))
+		f.SetCellValue(sheet, cellName(hoursTotalCol, codeRow), roundHoursValue(halfHours[0]+halfHours[1]))
+		f.MergeCell(sheet, cellName(hoursTotalCol, codeRow), cellName(hoursTotalCol, hoursRow))
+		for col, value := range map[int]float64{overtimeCol: item.breakdown.Overtime, nightCol: item.breakdown.Night, weekendCol: item.breakdown.Weekend} {
+			f.SetCellValue(sheet, cellName(col, codeRow), value)
+			f.MergeCell(sheet, cellName(col, codeRow), cellName(col, hoursRow))
+		}
+		for i, code := range markCodes {
+			if markDays[code] == 0 {
+				continue
+			}
+			f.SetCellValue(sheet, cellName(firstMarkCol+i, codeRow), markDays[code])
+			f.SetCellValue(sheet, cellName(firstMarkCol+i, hoursRow), markHours[code])
+		}
+		row += 2
+	}
+	if len(rows) == 0 {
+		f.SetCellValue(sheet, cellName(1, row), "Нет данных за выбранный месяц")
+		f.MergeCell(sheet, cellName(1, row), cellName(lastCol, row))
+		row++
+	}
+
+	row++
+	legend := []string{t13CodeWork + " — продолжительность работы в дневное время", t13CodeWorkOff + " — работа в выходные и праздничные дни", t13CodeOff + " — выходные и праздничные дни"}
+	for _, code := range markCodes {
+		legend = append(legend, code+" — "+markLabels[code])
+	}
+	f.SetCellValue(sheet, cellName(2, row), "Условные обозначения: "+strings.Join(legend, "; "))
+	f.MergeCell(sheet, cellName(2, row), cellName(lastCol, row))
+	row += 2
+
+	signature := func(role, title, name string) {
+		if title == "" {
+			title = "____________________"
+		}
+		if name == "" {
+			name = "____________________"
+		}
+		f.SetCellValue(sheet, cellName(2, row), role)
+		f.SetCellValue(sheet, cellName(firstDayCol, row), title)
+		f.MergeCell(sheet, cellName(firstDayCol, row), cellName(firstDayCol+8, row))
+		f.SetCellValue(sheet, cellName(firstDayCol+10, row), "________________")
+		f.MergeCell(sheet, cellName(firstDayCol+10, row), cellName(firstDayCol+14, row))
+		f.SetCellValue(sheet, cellName(secondHalfCol+1, row), name)
+		f.MergeCell(sheet, cellName(secondHalfCol+1, row), cellName(secondHalfCol+10, row))
+		f.SetCellValue(sheet, cellName(firstDayCol, row+1), "(должность)")
+		f.SetCellValue(sheet, cellName(firstDayCol+10, row+1), "(подпись)")
+		f.SetCellValue(sheet, cellName(secondHalfCol+1, row+1), "(расшифровка подписи)")
+		f.SetCellStyle(sheet, cellName(firstDayCol, row+1), cellName(secondHalfCol+1, row+1), smallStyle)
+		row += 3
+	}
+	signature("Ответственное лицо", org.ResponsibleTitle, org.ResponsibleName)
+	signature("Руководитель структурного подразделения", org.HeadTitle, org.HeadName)
+	signature("Работник кадровой службы", "", org.HRName)
+
+	f.SetColWidth(sheet, "A", "A", 4)
+	f.SetColWidth(sheet, "B", "B", 30)
+	f.SetColWidth(sheet, "C", "C", 9)
+	firstDayName, _ := excelize.ColumnNumberToName(firstDayCol)
+	secondSubtotalName, _ := excelize.ColumnNumberToName(secondSubtotalCol)
+	f.SetColWidth(sheet, firstDayName, secondSubtotalName, 4)
+	for _, col := range []int{firstSubtotalCol, secondSubtotalCol} {
+		name, _ := excelize.ColumnNumberToName(col)
+		f.SetColWidth(sheet, name, name, 7)
+	}
+	daysTotalName, _ := excelize.ColumnNumberToName(daysTotalCol)
+	lastColName, _ := excelize.ColumnNumberToName(lastCol)
+	f.SetColWidth(sheet, daysTotalName, lastColName, 8)
+	f.SetRowHeight(sheet, headerBottom, 30)
+	f.SetPageLayout(sheet, &excelize.PageLayoutOptions{Orientation: stringPtr("landscape")})
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to build xlsx: %v", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "tabel-t13-"+selectedMonth+".xlsx"))
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+}
+
+func roundHoursValue(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestSummarizeT13Days(t *testing.T) {
+	// A 31-day month: weekdays carry an 8-hour norm, every seventh day is off.
+	calendar := make([]calendarColumn, 31)
+	for i := range calendar {
+		calendar[i] = calendarColumn{Kind: "workday", NormHours: 8}
+		if i%7 == 5 || i%7 == 6 {
+			calendar[i] = calendarColumn{Kind: "weekend"}
+		}
+	}
+	work := func(hours float64) t13Day { return t13Day{Code: t13CodeWork, Hours: hours} }
+	mark := func(code string) t13Day { return t13Day{Code: code, Mark: true} }
+
+	tests := []struct {
+		name      string
+		days      map[int]t13Day // day of month → cell
+		halfDays  [2]int
+		halfHours [2]float64
+		markDays  map[string]int
+		markHours map[string]float64
+	}{
+		{
+			name:      "empty month",
+			days:      map[int]t13Day{},
+			markDays:  map[string]int{},
+			markHours: map[string]float64{},
+		},
+		{
+			name:      "the 15th closes the first half, the 16th opens the second",
+			days:      map[int]t13Day{1: work(8), 15: work(7.5), 16: work(4), 31: work(10)},
+			halfDays:  [2]int{2, 2},
+			halfHours: [2]float64{15.5, 14},
+			markDays:  map[string]int{},
+			markHours: map[string]float64{},
+		},
+		{
+			name:      "marks are not worked days and count the calendar norm",
+			days:      map[int]t13Day{2: mark("ОТ"), 3: mark("ОТ"), 6: mark("ОТ"), 20: work(8), 22: mark("Б")},
+			halfDays:  [2]int{0, 1},
+			halfHours: [2]float64{0, 8},
+			markDays:  map[string]int{"ОТ": 3, "Б": 1},
+			markHours: map[string]float64{"ОТ": 16, "Б": 8},
+		},
+		{
+			name:      "days off and zero hours are not worked days",
+			days:      map[int]t13Day{6: {Code: t13CodeOff}, 9: work(0), 10: {Code: t13CodeWorkOff, Hours: 6}},
+			halfDays:  [2]int{1, 0},
+			halfHours: [2]float64{6, 0},
+			markDays:  map[string]int{},
+			markHours: map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := make([]t13Day, len(calendar))
+			for day, cell := range tt.days {
+				days[day-1] = cell
+			}
+			got := summarizeT13Days(days, calendar)
+			if got.HalfDays != tt.halfDays || got.HalfHours != tt.halfHours {
+				t.Errorf("halves %v days / %v hours, want %v / %v", got.HalfDays, got.HalfHours, tt.halfDays, tt.halfHours)
+			}
+			if len(got.MarkDays) != len(tt.markDays) || len(got.MarkHours) != len(tt.markHours) {
+				t.Fatalf("marks %v / %v, want %v / %v", got.MarkDays, got.MarkHours, tt.markDays, tt.markHours)
+			}
+			for code, want := range tt.markDays {
+				if got.MarkDays[code] != want || got.MarkHours[code] != tt.markHours[code] {
+					t.Errorf("mark %s: %d days / %.1f h, want %d / %.1f", code, got.MarkDays[code], got.MarkHours[code], want, tt.markHours[code])
+				}
+			}
+		})
+	}
+}
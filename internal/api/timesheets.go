@@ -2,8 +2,10 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"math"
 	"net/http"
 	"net/url"
 	"sort"
@@ -110,6 +112,22 @@ func humanizeScheduleError(err error) string {
 		return "Время окончания должно быть позже времени начала."
 	case strings.Contains(msg, "lunch break must be shorter"):
 		return "Обед должен быть короче продолжительности смены."
+	case strings.Contains(msg, "document is required"):
+		return "Для этой отметки нужно указать документ."
+	case strings.Contains(msg, "unknown mark"):
+		return "Неизвестный тип отметки."
+	case strings.Contains(msg, "mark is inactive"):
+		return "Этот тип отметки отключён в справочнике."
+	case strings.Contains(msg, "month is closed"):
+		return "Месяц закрыт: табель передан в бухгалтерию, изменения запрещены."
+	case strings.Contains(msg, "worker is not assigned to entry"):
+		return "Работник не входит в исходное назначение."
+	case strings.Contains(msg, "worker is already assigned to entry"):
+		return "Работник уже назначен на этот объект в этот день."
+	case strings.Contains(msg, "target entry is not approved"):
+		return "Назначение ждёт согласования, к нему нельзя добавить работника."
+	case strings.Contains(msg, "target entry must differ from source entry"):
+		return "Работник уже в этом назначении."
 	case strings.Contains(msg, "нельзя назначить"):
 		return msg
 	default:
@@ -129,54 +147,50 @@ func cleanIDList(ids []string) []string {
 	return clean
 }
 
+// normalizeSpecialMark resolves a mark code or alias through the catalogue; "" means working time.
 func normalizeSpecialMark(mark string) string {
-	switch strings.ToLower(strings.TrimSpace(mark)) {
-	case "vacation", "отпуск", "от":
-		return "ОТ"
-	case "sick", "больничный", "б":
-		return "Б"
-	case "absence", "прогул", "пр":
-		return "ПР"
-	case "weekend", "выходной", "в":
-		return "В"
-	default:
-		return ""
+	if markType, ok := storage.FindMarkType(mark); ok {
+		return markType.Code
 	}
+	return ""
 }
 
 func specialMarkLabel(mark string) string {
-	switch normalizeSpecialMark(mark) {
-	case "ОТ":
-		return "ОТ"
-	case "Б":
-		return "Б"
-	case "ПР":
-		return "ПР"
-	case "В":
-		return "В"
-	default:
-		return ""
-	}
+	return normalizeSpecialMark(mark)
 }
 
 func specialMarkTitle(mark string) string {
-	switch normalizeSpecialMark(mark) {
-	case "ОТ":
-		return "Отпуск"
-	case "Б":
-		return "Больничный"
-	case "ПР":
-		return "Прогул"
-	case "В":
-		return "Выходной"
-	default:
-		return "Отметка"
+	if markType, ok := storage.FindMarkType(mark); ok {
+		return markType.Label
 	}
+	return "Отметка"
+}
+
+func specialMarkColor(mark string) string {
+	if markType, ok := storage.FindMarkType(mark); ok {
+		return markType.Color
+	}
+	return ""
 }
 
 func isSpecialMark(mark string) bool {
-	m := normalizeSpecialMark(mark)
-	return m == "ОТ" || m == "Б" || m == "ПР" || m == "В"
+	_, ok := storage.FindMarkType(mark)
+	return ok
+}
+
+// activeMarkTypes lists marks that may be chosen for new entries.
+func activeMarkTypes() []models.MarkType {
+	marks, err := storage.GetMarkTypes()
+	if err != nil {
+		return nil
+	}
+	result := make([]models.MarkType, 0, len(marks))
+	for _, mark := range marks {
+		if mark.Active {
+			result = append(result, mark)
+		}
+	}
+	return result
 }
 
 func getScopedEntries(c *gin.Context, entries []models.TimesheetEntry) ([]models.TimesheetEntry, error) {
@@ -306,6 +320,16 @@ func SchedulePage(c *gin.Context) {
 		return
 	}
 
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
+	ownWorkerID := ""
+	if worker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil && !worker.IsFired {
+		ownWorkerID = worker.ID
+	}
+
 	sort.Slice(entries, func(i, j int) bool {
 		if entries[i].Date == entries[j].Date {
 			return entries[i].StartTime < entries[j].StartTime
@@ -314,6 +338,9 @@ func SchedulePage(c *gin.Context) {
 	})
 
 	var scheduleRows strings.Builder
+	if errMsg := strings.TrimSpace(c.Query("attendance_error")); errMsg != "" {
+		scheduleRows.WriteString(`<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`)
+	}
 	monthHours := 0.0
 	if len(entries) == 0 {
 		scheduleRows.WriteString(`<div class="info-card"><p>Записей за выбранный месяц нет.</p></div>`)
@@ -337,6 +364,7 @@ func SchedulePage(c *gin.Context) {
 			if strings.TrimSpace(entry.CreatedByName) != "" {
 				creatorHTML = `<div class="assignment-meta"><span>Создал</span><p>` + template.HTMLEscapeString(entry.CreatedByName) + `</p></div>`
 			}
+			creatorHTML += renderApprovalMeta(entry)
 			if isSpecialMark(entry.UserMark) {
 				scheduleRows.WriteString(fmt.Sprintf(`<article class="schedule-entry-vertical assignment-card assignment-card-mark"><div class="assignment-head"><div class="assignment-time"><strong>%s</strong><span class="status-badge">%s</span></div></div><div class="assignment-body"><div class="assignment-section"><div class="assignment-meta"><span>Тип записи</span><p>%s</p></div></div><div class="assignment-section"><div class="assignment-meta"><span>Работники</span><p>%s</p></div></div>%s%s</div><div class="info-card-actions assignment-actions"><a href="%s" class="btn btn-secondary btn-compact" data-modal-url="%s" data-modal-title="Редактирование записи" data-modal-return="%s">Редактировать</a></div></article>`,
 					template.HTMLEscapeString(specialMarkTitle(entry.UserMark)),
@@ -351,7 +379,7 @@ func SchedulePage(c *gin.Context) {
 				))
 				continue
 			}
-			scheduleRows.WriteString(fmt.Sprintf(`<article class="schedule-entry-vertical assignment-card"><div class="assignment-head"><div class="assignment-time"><strong>%s — %s</strong><span>%s ч</span></div></div><div class="assignment-body"><div class="assignment-section"><div class="assignment-meta"><span>Объекты</span><p>%s</p></div></div><div class="assignment-section"><div class="assignment-meta"><span>Работники</span><p>%s</p></div></div>%s%s</div><div class="info-card-actions assignment-actions"><a href="%s" class="btn btn-secondary btn-compact" data-modal-url="%s" data-modal-title="Редактирование назначения" data-modal-return="%s">Редактировать</a></div></article>`,
+			scheduleRows.WriteString(fmt.Sprintf(`<article class="schedule-entry-vertical assignment-card"><div class="assignment-head"><div class="assignment-time"><strong>%s — %s</strong><span>%s ч</span></div></div><div class="assignment-body"><div class="assignment-section"><div class="assignment-meta"><span>Объекты</span><p>%s</p></div></div><div class="assignment-section"><div class="assignment-meta"><span>Работники</span><p>%s</p></div></div>%s%s</div><div class="info-card-actions assignment-actions">%s<a href="%s" class="btn btn-secondary btn-compact" data-modal-url="%s" data-modal-title="Редактирование назначения" data-modal-return="%s">Редактировать</a></div></article>`,
 				template.HTMLEscapeString(entry.StartTime),
 				template.HTMLEscapeString(entry.EndTime),
 				template.HTMLEscapeString(formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes)),
@@ -359,6 +387,7 @@ func SchedulePage(c *gin.Context) {
 				joinMappedLinks(entry.WorkerIDs, workersMap, "/worker"),
 				creatorHTML,
 				commentHTML,
+				renderClockButtons(c, entry, ownWorkerID, attendanceMap, returnPath),
 				editURL,
 				editURL,
 				template.HTMLEscapeString(returnPath),
@@ -380,7 +409,12 @@ func SchedulePage(c *gin.Context) {
 		topNavScheduleActions += hoursBlock
 	}
 	topNavScheduleActions += `<form method="GET" action="/schedule" class="month-selector"><select id="schedule-topbar-month" name="month" onchange="this.form.submit()">` + monthOptions + `</select></form>`
+	if pending := countReviewableEntries(c); pending > 0 || isAdmin(c) {
+		topNavScheduleActions += `<a class="btn btn-secondary" href="/schedule/approvals">Согласование: ` + strconv.Itoa(pending) + `</a>`
+	}
+	topNavScheduleActions += `<a class="btn btn-secondary" href="/schedule/ics">В календарь</a>`
 	if c.GetString("userStatus") == "admin" {
+		topNavScheduleActions += `<a class="btn btn-secondary" href="/schedule/board">Неделя</a>`
 		topNavScheduleActions += `<a class="btn btn-primary" href="/schedule/new" data-modal-url="/schedule/new" data-modal-title="Новое назначение" data-modal-return="` + currentSchedulePath + `">Новое назначение</a>`
 	}
 	topNavScheduleActions += `</div>`
@@ -393,6 +427,7 @@ func SchedulePage(c *gin.Context) {
 <div class="page-header page-header-desktop-hidden"><h1>Расписание</h1>{{USER_MONTH_HOURS}}<form method="GET" action="/schedule" class="month-selector"><select id="month" name="month" onchange="this.form.submit()">{{MONTH_OPTIONS}}</select></form><a class="btn btn-primary" href="/schedule/new" data-modal-url="/schedule/new" data-modal-title="Новое назначение" data-modal-return="{{CURRENT_PATH}}">Добавить назначение</a></div>
 <section class="schedule-page-surface"><div class="schedule-vertical">{{SCHEDULE_ROWS}}</div></section>
 </div>
+{{GEO_SCRIPT}}
 </body></html>`
 
 	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "schedule"), 1)
@@ -400,6 +435,7 @@ func SchedulePage(c *gin.Context) {
 	final = strings.Replace(final, "{{CURRENT_PATH}}", currentSchedulePath, 1)
 	final = strings.Replace(final, "{{USER_MONTH_HOURS}}", hoursBlock, 1)
 	final = strings.Replace(final, "{{SCHEDULE_ROWS}}", scheduleRows.String(), 1)
+	final = strings.Replace(final, "{{GEO_SCRIPT}}", geoCheckInScript, 1)
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
 }
 
@@ -487,18 +523,24 @@ func renderScheduleForm(c *gin.Context, entry models.TimesheetEntry, actionURL,
 		entry.LunchBreakMinutes = 60
 	}
 
-	markWork, markVacation, markSick, markAbsent, markWeekend := "", "", "", "", ""
-	switch normalizeSpecialMark(selectedMark) {
-	case "ОТ":
-		markVacation = " selected"
-	case "Б":
-		markSick = " selected"
-	case "ПР":
-		markAbsent = " selected"
-	case "В":
-		markWeekend = " selected"
-	default:
-		markWork = " selected"
+	selectedCode := normalizeSpecialMark(selectedMark)
+	markOptions := `<option value="work"` + map[bool]string{true: " selected"}[selectedCode == ""] + `>Работа</option>`
+	markTypes := activeMarkTypes()
+	if current, ok := storage.FindMarkType(selectedCode); ok && !current.Active {
+		markTypes = append(markTypes, current)
+	}
+	for _, mark := range markTypes {
+		attrs := ""
+		if mark.Code == selectedCode {
+			attrs += " selected"
+		}
+		if mark.AllowPeriod {
+			attrs += ` data-period="1"`
+		}
+		if mark.RequiresDocument {
+			attrs += ` data-document="1"`
+		}
+		markOptions += fmt.Sprintf(`<option value="%s"%s>%s (%s)</option>`, template.HTMLEscapeString(mark.Code), attrs, template.HTMLEscapeString(mark.Label), template.HTMLEscapeString(mark.Code))
 	}
 
 	l0, l30, l60, l90 := "", "", "", ""
@@ -535,10 +577,12 @@ func renderScheduleForm(c *gin.Context, entry models.TimesheetEntry, actionURL,
 <input type="hidden" name="return_to" value="{{RETURN_TO}}">
 <input type="hidden" name="special_mark" id="special_mark" value="{{SPECIAL_MARK}}">
 {{ERROR_BLOCK}}
-<div class="form-group-edit timesheet-span-2"><label for="entry_kind">Тип отметки</label><select id="entry_kind" name="entry_kind"><option value="work"{{MARK_WORK}}>Работа</option><option value="vacation"{{MARK_VACATION}}>Отпуск (ОТ)</option><option value="sick"{{MARK_SICK}}>Больничный (Б)</option><option value="absence"{{MARK_ABSENT}}>Прогул (ПР)</option><option value="weekend"{{MARK_WEEKEND}}>Выходной (В)</option></select></div>
+{{LEAVE_CONFIRM}}
+<div class="form-group-edit timesheet-span-2"><label for="entry_kind">Тип отметки</label><select id="entry_kind" name="entry_kind">{{MARK_OPTIONS}}</select></div>
 <div class="timesheet-time-row timesheet-span-2">
   <div class="form-group-edit"><label for="date" id="date_label">Дата</label><input id="date" name="date" type="date" value="{{DATE}}" required></div>
   <div class="form-group-edit" id="period_wrap" style="display:none;"><label for="period_end" id="period_end_label">По</label><input id="period_end" name="period_end" type="date" value="{{PERIOD_END}}"></div>
+  <div class="form-group-edit" id="document_wrap" style="display:none;"><label for="document_ref">Документ</label><input id="document_ref" name="document_ref" type="text" value="{{DOCUMENT_REF}}" placeholder="Номер листка, приказа..."></div>
   <div id="work_fields_wrap" class="timesheet-work-fields">
     <div class="form-group-edit"><label for="start_time">Начало смены</label><input id="start_time" name="start_time" type="time" value="{{START_TIME}}" required></div>
     <div class="form-group-edit"><label for="end_time">Окончание смены</label><input id="end_time" name="end_time" type="time" value="{{END_TIME}}" required></div>
@@ -674,14 +718,15 @@ const workFieldsWrap=document.getElementById('work_fields_wrap');
 const dateLabel=document.getElementById('date_label');
 const periodLabel=document.getElementById('period_end_label');
 const objectWrap=document.getElementById('object_wrap');
+const documentWrap=document.getElementById('document_wrap');
 function syncEntryKind(){
   if(!kind) return;
   const v=kind.value;
   const isSpec=v!=='work';
-  if(periodWrap) periodWrap.style.display=(v==='vacation'||v==='sick')?'':'none';
-  if(special){
-    if(v==='vacation') special.value='ОТ'; else if(v==='sick') special.value='Б'; else if(v==='absence') special.value='ПР'; else if(v==='weekend') special.value='В'; else special.value='';
-  }
+  const opt=kind.options[kind.selectedIndex];
+  if(periodWrap) periodWrap.style.display=(opt && opt.dataset.period)?'':'none';
+  if(documentWrap) documentWrap.style.display=(opt && opt.dataset.document)?'':'none';
+  if(special) special.value=isSpec ? v : '';
   if(st&&et&&lunch){ st.disabled=isSpec; et.disabled=isSpec; lunch.disabled=isSpec; if(isSpec){ st.value=''; et.value=''; lunch.value='0'; }}
   if(workFieldsWrap) workFieldsWrap.style.display=isSpec?'none':'contents';
   if(dateLabel) dateLabel.textContent = isSpec ? 'С' : 'Дата';
@@ -733,6 +778,11 @@ if(kind){ kind.addEventListener('change', syncEntryKind); syncEntryKind(); }
 		errorBlock = `<div class="form-error">` + template.HTMLEscapeString(errorMsg) + `</div>`
 	}
 	final = strings.Replace(final, "{{ERROR_BLOCK}}", errorBlock, 1)
+	leaveConfirm := ""
+	if c.GetBool("leaveBalanceWarning") {
+		leaveConfirm = `<label class="form-group-edit timesheet-span-2 leave-confirm"><span><input type="checkbox" name="confirm_over_balance" value="1"> Сохранить сверх остатка</span></label>`
+	}
+	final = strings.Replace(final, "{{LEAVE_CONFIRM}}", leaveConfirm, 1)
 	final = strings.Replace(final, "{{DATE}}", template.HTMLEscapeString(entry.Date), 1)
 	final = strings.Replace(final, "{{START_TIME}}", template.HTMLEscapeString(entry.StartTime), 1)
 	final = strings.Replace(final, "{{END_TIME}}", template.HTMLEscapeString(entry.EndTime), 1)
@@ -740,13 +790,10 @@ if(kind){ kind.addEventListener('change', syncEntryKind); syncEntryKind(); }
 	final = strings.Replace(final, "{{L30}}", l30, 1)
 	final = strings.Replace(final, "{{L60}}", l60, 1)
 	final = strings.Replace(final, "{{L90}}", l90, 1)
-	final = strings.Replace(final, "{{MARK_WORK}}", markWork, 1)
-	final = strings.Replace(final, "{{MARK_VACATION}}", markVacation, 1)
-	final = strings.Replace(final, "{{MARK_SICK}}", markSick, 1)
-	final = strings.Replace(final, "{{MARK_ABSENT}}", markAbsent, 1)
-	final = strings.Replace(final, "{{MARK_WEEKEND}}", markWeekend, 1)
+	final = strings.Replace(final, "{{MARK_OPTIONS}}", markOptions, 1)
+	final = strings.Replace(final, "{{DOCUMENT_REF}}", template.HTMLEscapeString(entry.DocumentRef), 1)
 	final = strings.Replace(final, "{{SPECIAL_MARK}}", template.HTMLEscapeString(normalizeSpecialMark(selectedMark)), 1)
-	final = strings.Replace(final, "{{PERIOD_END}}", template.HTMLEscapeString(c.Query("period_end")), 1)
+	final = strings.Replace(final, "{{PERIOD_END}}", template.HTMLEscapeString(c.DefaultQuery("period_end", c.PostForm("period_end"))), 1)
 	final = strings.Replace(final, "{{WORKER_OPTIONS}}", workerOptions, 1)
 	final = strings.Replace(final, "{{WORKER_SELECTED}}", workerSelected, 1)
 	if c.GetString("userStatus") != "admin" {
@@ -798,6 +845,44 @@ func AddSchedulePage(c *gin.Context) {
 	renderScheduleForm(c, entry, "/schedule/new", "Новое назначение", "Сохранить", false, "", c.Query("special_mark"))
 }
 
+// scheduleEntryAccessError returns why a non-admin may not change the entry; only entries with their own worker are allowed.
+func scheduleEntryAccessError(c *gin.Context, entry models.TimesheetEntry) string {
+	if c.GetString("userStatus") == "admin" {
+		return ""
+	}
+	worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
+	if err != nil {
+		return "Нет привязанного работника"
+	}
+	if !entryHasWorker(entry, worker.ID) {
+		return "Доступ запрещен"
+	}
+	return ""
+}
+
+// prepareScheduleEntry applies the role rules before saving: a non-admin always includes their own worker
+// and any change of theirs goes back to the approval queue. Marks drop working time and objects.
+func prepareScheduleEntry(c *gin.Context, entry *models.TimesheetEntry) {
+	if c.GetString("userStatus") != "admin" {
+		if worker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil && !entryHasWorker(*entry, worker.ID) {
+			entry.WorkerIDs = append([]string{worker.ID}, entry.WorkerIDs...)
+		}
+		entry.ApprovalStatus = "pending"
+		entry.ApprovalComment = ""
+		entry.ReviewedByID = ""
+		entry.ReviewedByName = ""
+		entry.ReviewedAt = ""
+	}
+	if isSpecialMark(entry.UserMark) {
+		entry.StartTime = ""
+		entry.EndTime = ""
+		entry.LunchBreakMinutes = 0
+		entry.ObjectIDs = []string{}
+	} else {
+		entry.DocumentRef = ""
+	}
+}
+
 func validateScheduleLinks(workerIDs, objectIDs []string) error {
 	workers, err := storage.GetWorkers()
 	if err != nil {
@@ -844,43 +929,46 @@ func CreateScheduleEntry(c *gin.Context) {
 		CreatedByID:       c.GetString("userID"),
 		CreatedByName:     c.GetString("userName"),
 		UserMark:          normalizeSpecialMark(c.PostForm("special_mark")),
+		DocumentRef:       c.PostForm("document_ref"),
 	}
 
-	if c.GetString("userStatus") != "admin" {
-		if worker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil {
-			hasOwn := false
-			for _, wid := range entry.WorkerIDs {
-				if wid == worker.ID {
-					hasOwn = true
-					break
-				}
-			}
-			if !hasOwn {
-				entry.WorkerIDs = append([]string{worker.ID}, entry.WorkerIDs...)
-			}
-		}
-		entry.UserMark = normalizeSpecialMark(c.PostForm("special_mark"))
-	}
-	if isSpecialMark(entry.UserMark) {
-		entry.StartTime = ""
-		entry.EndTime = ""
-		entry.LunchBreakMinutes = 0
-		entry.ObjectIDs = []string{}
-	}
+	prepareScheduleEntry(c, &entry)
 	if !isSpecialMark(entry.UserMark) {
 		if err := validateScheduleLinks(entry.WorkerIDs, entry.ObjectIDs); err != nil {
 			renderScheduleForm(c, entry, "/schedule/new", "Новое назначение", "Сохранить", false, humanizeScheduleError(err), c.PostForm("special_mark"))
 			return
 		}
 	}
-	if periodEnd := strings.TrimSpace(c.PostForm("period_end")); (entry.UserMark == "ОТ" || entry.UserMark == "Б") && periodEnd != "" {
+	if c.PostForm("confirm_over_balance") != "1" {
+		if warning := leaveBalanceWarning(entry, c.PostForm("period_end")); warning != "" {
+			c.Set("leaveBalanceWarning", true)
+			renderScheduleForm(c, entry, "/schedule/new", "Новое назначение", "Сохранить", false, warning, c.PostForm("special_mark"))
+			return
+		}
+	}
+	markType, _ := storage.FindMarkType(entry.UserMark)
+	if periodEnd := strings.TrimSpace(c.PostForm("period_end")); markType.AllowPeriod && periodEnd != "" {
 		endDate, err := time.Parse("2006-01-02", periodEnd)
 		startDate, err2 := time.Parse("2006-01-02", entry.Date)
 		if err == nil && err2 == nil && !endDate.Before(startDate) {
+			// The period is created whole or not at all: a day that fails removes the days before it.
+			createdIDs := make([]string, 0)
 			for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
 				copyEntry := entry
 				copyEntry.Date = d.Format("2006-01-02")
-				_, _ = storage.CreateTimesheet(copyEntry)
+				created, err := storage.CreateTimesheet(copyEntry)
+				if err != nil {
+					for _, entryID := range createdIDs {
+						_ = storage.DeleteTimesheet(entryID)
+					}
+					message := humanizeScheduleError(err)
+					if d.After(startDate) {
+						message = d.Format("02.01.2006") + ": " + message
+					}
+					renderScheduleForm(c, entry, "/schedule/new", "Новое назначение", "Сохранить", false, message, c.PostForm("special_mark"))
+					return
+				}
+				createdIDs = append(createdIDs, created.ID)
 			}
 			returnTo := c.PostForm("return_to")
 			if !strings.HasPrefix(returnTo, "/") {
@@ -907,23 +995,9 @@ func EditSchedulePage(c *gin.Context) {
 		c.String(http.StatusNotFound, "Schedule entry not found")
 		return
 	}
-	if c.GetString("userStatus") != "admin" {
-		worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
-		if err != nil {
-			c.String(http.StatusForbidden, "Нет привязанного работника")
-			return
-		}
-		allowed := false
-		for _, wid := range entry.WorkerIDs {
-			if wid == worker.ID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.String(http.StatusForbidden, "Доступ запрещен")
-			return
-		}
+	if msg := scheduleEntryAccessError(c, entry); msg != "" {
+		c.String(http.StatusForbidden, msg)
+		return
 	}
 	renderScheduleForm(c, entry, "/schedule/edit/"+entry.ID, "Редактирование назначения", "Сохранить изменения", true, "", entry.UserMark)
 }
@@ -934,23 +1008,9 @@ func UpdateScheduleEntry(c *gin.Context) {
 		c.String(http.StatusNotFound, "Schedule entry not found")
 		return
 	}
-	if c.GetString("userStatus") != "admin" {
-		worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
-		if err != nil {
-			c.String(http.StatusForbidden, "Нет привязанного работника")
-			return
-		}
-		allowed := false
-		for _, wid := range entry.WorkerIDs {
-			if wid == worker.ID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.String(http.StatusForbidden, "Доступ запрещен")
-			return
-		}
+	if msg := scheduleEntryAccessError(c, entry); msg != "" {
+		c.String(http.StatusForbidden, msg)
+		return
 	}
 	lunch, _ := strconv.Atoi(c.PostForm("lunch_break_minutes"))
 	entry.Date = c.PostForm("date")
@@ -961,27 +1021,8 @@ func UpdateScheduleEntry(c *gin.Context) {
 	entry.ObjectIDs = cleanIDList(c.PostFormArray("object_ids"))
 	entry.Notes = c.PostForm("notes")
 	entry.UserMark = normalizeSpecialMark(c.PostForm("special_mark"))
-	if c.GetString("userStatus") != "admin" {
-		if worker, err := storage.GetWorkerByUserID(c.GetString("userID")); err == nil {
-			hasOwn := false
-			for _, wid := range entry.WorkerIDs {
-				if wid == worker.ID {
-					hasOwn = true
-					break
-				}
-			}
-			if !hasOwn {
-				entry.WorkerIDs = append([]string{worker.ID}, entry.WorkerIDs...)
-			}
-		}
-		entry.UserMark = normalizeSpecialMark(c.PostForm("special_mark"))
-	}
-	if isSpecialMark(entry.UserMark) {
-		entry.StartTime = ""
-		entry.EndTime = ""
-		entry.LunchBreakMinutes = 0
-		entry.ObjectIDs = []string{}
-	}
+	entry.DocumentRef = c.PostForm("document_ref")
+	prepareScheduleEntry(c, &entry)
 
 	if !isSpecialMark(entry.UserMark) {
 		if err := validateScheduleLinks(entry.WorkerIDs, entry.ObjectIDs); err != nil {
@@ -989,6 +1030,13 @@ func UpdateScheduleEntry(c *gin.Context) {
 			return
 		}
 	}
+	if c.PostForm("confirm_over_balance") != "1" {
+		if warning := leaveBalanceWarning(entry, ""); warning != "" {
+			c.Set("leaveBalanceWarning", true)
+			renderScheduleForm(c, entry, "/schedule/edit/"+entry.ID, "Редактирование назначения", "Сохранить изменения", true, warning, c.PostForm("special_mark"))
+			return
+		}
+	}
 	if err := storage.UpdateTimesheet(entry); err != nil {
 		renderScheduleForm(c, entry, "/schedule/edit/"+entry.ID, "Редактирование назначения", "Сохранить изменения", true, humanizeScheduleError(err), c.PostForm("special_mark"))
 		return
@@ -1006,25 +1054,15 @@ func DeleteScheduleEntry(c *gin.Context) {
 		c.String(http.StatusNotFound, "Schedule entry not found")
 		return
 	}
-	if c.GetString("userStatus") != "admin" {
-		worker, err := storage.GetWorkerByUserID(c.GetString("userID"))
-		if err != nil {
-			c.String(http.StatusForbidden, "Нет привязанного работника")
-			return
-		}
-		allowed := false
-		for _, wid := range entry.WorkerIDs {
-			if wid == worker.ID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.String(http.StatusForbidden, "Доступ запрещен")
-			return
-		}
+	if msg := scheduleEntryAccessError(c, entry); msg != "" {
+		c.String(http.StatusForbidden, msg)
+		return
 	}
 	if err := storage.DeleteTimesheet(c.Param("id")); err != nil {
+		if errors.Is(err, storage.ErrMonthClosed) {
+			c.String(http.StatusConflict, humanizeScheduleError(err))
+			return
+		}
 		c.String(http.StatusBadRequest, "Failed to delete schedule entry: %v", err)
 		return
 	}
@@ -1064,6 +1102,11 @@ func ExportTimesheetsExcel(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Failed to load objects: %v", err)
 		return
 	}
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
+	}
 	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
 
 	selectedMonth, monthStart, daysInMonth := resolveSelectedMonth(c.Query("month"))
@@ -1078,25 +1121,59 @@ func ExportTimesheetsExcel(c *gin.Context) {
 	cellStyle, _ := f.NewStyle(&excelize.Style{Border: []excelize.Border{{Type: "left", Color: "000000", Style: 1}, {Type: "right", Color: "000000", Style: 1}, {Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 1}}, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}})
 	nameStyle, _ := f.NewStyle(&excelize.Style{Border: []excelize.Border{{Type: "left", Color: "000000", Style: 1}, {Type: "right", Color: "000000", Style: 1}, {Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 1}}, Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"}})
 
+	// Mark cells are filled with the catalogue color, one style per mark code.
+	markStyles := map[string]int{}
+	markCellStyle := func(code string) int {
+		if styleID, ok := markStyles[code]; ok {
+			return styleID
+		}
+		styleID := cellStyle
+		if color := strings.TrimPrefix(specialMarkColor(code), "#"); color != "" {
+			if id, err := f.NewStyle(&excelize.Style{Border: []excelize.Border{{Type: "left", Color: "000000", Style: 1}, {Type: "right", Color: "000000", Style: 1}, {Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 1}}, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{color}}}); err == nil {
+				styleID = id
+			}
+		}
+		markStyles[code] = styleID
+		return styleID
+	}
+
 	lastCol, _ := excelize.ColumnNumberToName(daysInMonth + 2)
 	totalCol, _ := excelize.ColumnNumberToName(daysInMonth + 3)
+	paidCol, _ := excelize.ColumnNumberToName(daysInMonth + 4)
+	normCol, _ := excelize.ColumnNumberToName(daysInMonth + 5)
+	regularCol, _ := excelize.ColumnNumberToName(daysInMonth + 6)
+	overCol, _ := excelize.ColumnNumberToName(daysInMonth + 7)
+	nightCol, _ := excelize.ColumnNumberToName(daysInMonth + 8)
+	weekendCol, _ := excelize.ColumnNumberToName(daysInMonth + 9)
 	f.SetCellValue(sheet, "A1", "РЭСПУБЛIКА БЕЛАРУСЬ")
-	f.MergeCell(sheet, "A1", totalCol+"1")
-	f.SetCellStyle(sheet, "A1", totalCol+"1", titleStyle)
+	f.MergeCell(sheet, "A1", weekendCol+"1")
+	f.SetCellStyle(sheet, "A1", weekendCol+"1", titleStyle)
 	f.SetCellValue(sheet, "A2", "ТАБЕЛЬ УЧЕТА РАБОЧЕГО ВРЕМЕНИ")
-	f.MergeCell(sheet, "A2", totalCol+"2")
-	f.SetCellStyle(sheet, "A2", totalCol+"2", titleStyle)
+	f.MergeCell(sheet, "A2", weekendCol+"2")
+	f.SetCellStyle(sheet, "A2", weekendCol+"2", titleStyle)
 	f.SetCellValue(sheet, "A3", fmt.Sprintf("за %s %d", monthStart.Month().String(), monthStart.Year()))
-	f.MergeCell(sheet, "A3", totalCol+"3")
-	f.SetCellStyle(sheet, "A3", totalCol+"3", headerStyle)
+	f.MergeCell(sheet, "A3", weekendCol+"3")
+	f.SetCellStyle(sheet, "A3", weekendCol+"3", headerStyle)
 
+	calendar := buildMonthCalendar(monthDates)
+	hourRules := currentHourRules()
+	offDayHeaderStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 12, Color: "B42318"}, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FDE8E8"}}})
 	f.SetCellValue(sheet, "A5", "Работник")
+	f.SetCellValue(sheet, totalCol+"5", "Итого")
+	f.SetCellValue(sheet, paidCol+"5", "Опл. дни")
+	f.SetCellValue(sheet, normCol+"5", "Норма")
+	f.SetCellValue(sheet, regularCol+"5", "Обычн.")
+	f.SetCellValue(sheet, overCol+"5", "Сверх.")
+	f.SetCellValue(sheet, nightCol+"5", "Ночь")
+	f.SetCellValue(sheet, weekendCol+"5", "Вых./пр.")
+	f.SetCellStyle(sheet, "A5", weekendCol+"5", headerStyle)
 	for day := 1; day <= daysInMonth; day++ {
 		col, _ := excelize.ColumnNumberToName(day + 1)
 		f.SetCellValue(sheet, col+"5", day)
+		if kind := calendar[day-1].Kind; kind == "holiday" || kind == "weekend" {
+			f.SetCellStyle(sheet, col+"5", col+"5", offDayHeaderStyle)
+		}
 	}
-	f.SetCellValue(sheet, totalCol+"5", "Итого")
-	f.SetCellStyle(sheet, "A5", totalCol+"5", headerStyle)
 
 	row := 6
 	for _, worker := range workers {
@@ -1106,6 +1183,7 @@ func ExportTimesheetsExcel(c *gin.Context) {
 		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), worker.Name)
 		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), nameStyle)
 
+		paidDays := 0
 		for i, date := range monthDates {
 			total := 0.0
 			cellMark := ""
@@ -1121,7 +1199,7 @@ func ExportTimesheetsExcel(c *gin.Context) {
 						break
 					}
 				}
-				if !contains {
+				if !contains || !storage.IsTimesheetApproved(entry) {
 					continue
 				}
 				if isSpecialMark(entry.UserMark) {
@@ -1130,6 +1208,13 @@ func ExportTimesheetsExcel(c *gin.Context) {
 				}
 				hoursStr := formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes)
 				hours, _ := strconv.ParseFloat(hoursStr, 64)
+				factNote := ""
+				if record, ok := attendanceMap[attendanceKey(entry.ID, worker.ID)]; ok && record.Status == "confirmed" {
+					if factHours, closed := attendanceHours(record); closed {
+						factNote = fmt.Sprintf("\nфакт: %s-%s · %.2f ч (план %s ч)", record.StartTime, record.EndTime, factHours, hoursStr)
+						hours = factHours
+					}
+				}
 				total += hours
 
 				objectNames := make([]string, 0, len(entry.ObjectIDs))
@@ -1146,12 +1231,15 @@ func ExportTimesheetsExcel(c *gin.Context) {
 				if comment == "" {
 					comment = "—"
 				}
-				details = append(details, fmt.Sprintf("%s-%s · %s ч\nгде: %s\nкоммент: %s", entry.StartTime, entry.EndTime, hoursStr, where, comment))
+				details = append(details, fmt.Sprintf("%s-%s · %s ч%s\nгде: %s\nкоммент: %s", entry.StartTime, entry.EndTime, hoursStr, factNote, where, comment))
 			}
 			col, _ := excelize.ColumnNumberToName(i + 2)
 			cell := fmt.Sprintf("%s%d", col, row)
 			if cellMark != "" {
 				f.SetCellValue(sheet, cell, cellMark)
+				if markType, ok := storage.FindMarkType(cellMark); ok && markType.Paid {
+					paidDays++
+				}
 			} else if total == 0 {
 				f.SetCellValue(sheet, cell, "—")
 			} else {
@@ -1168,24 +1256,56 @@ func ExportTimesheetsExcel(c *gin.Context) {
 					})
 				}
 			}
-			f.SetCellStyle(sheet, cell, cell, cellStyle)
+			if cellMark != "" {
+				f.SetCellStyle(sheet, cell, cell, markCellStyle(cellMark))
+			} else {
+				f.SetCellStyle(sheet, cell, cell, cellStyle)
+			}
 		}
 
 		formula := fmt.Sprintf("SUM(B%d:%s%d)", row, lastCol, row)
 		totalCell := fmt.Sprintf("%s%d", totalCol, row)
 		f.SetCellFormula(sheet, totalCell, formula)
 		f.SetCellStyle(sheet, totalCell, totalCell, cellStyle)
+		paidCell := fmt.Sprintf("%s%d", paidCol, row)
+		f.SetCellValue(sheet, paidCell, paidDays)
+		f.SetCellStyle(sheet, paidCell, paidCell, cellStyle)
+		breakdown, workerNorm := workerMonthHours(entries, worker.ID, selectedMonth, monthDates, calendar, attendanceMap, hourRules)
+		normCell := fmt.Sprintf("%s%d", normCol, row)
+		f.SetCellValue(sheet, normCell, workerNorm)
+		f.SetCellStyle(sheet, normCell, normCell, cellStyle)
+		for col, value := range map[string]float64{regularCol: breakdown.Regular, overCol: breakdown.Overtime, nightCol: breakdown.Night, weekendCol: breakdown.Weekend} {
+			cell := fmt.Sprintf("%s%d", col, row)
+			f.SetCellValue(sheet, cell, value)
+			f.SetCellStyle(sheet, cell, cell, cellStyle)
+		}
 		row++
 	}
 
 	if row == 6 {
 		f.SetCellValue(sheet, "A6", "Нет данных за выбранный месяц")
-		f.MergeCell(sheet, "A6", totalCol+"6")
+		f.MergeCell(sheet, "A6", weekendCol+"6")
+		row++
+	}
+
+	// Legend of the mark catalogue, so accounting can read the codes.
+	if marks, err := storage.GetMarkTypes(); err == nil && len(marks) > 0 {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "Условные обозначения")
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
+		for _, mark := range marks {
+			row++
+			legend := mark.Code + " — " + mark.Label
+			if mark.Paid {
+				legend += " (оплачиваемый)"
+			}
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), legend)
+		}
 	}
 
 	f.SetColWidth(sheet, "A", "A", 28)
 	f.SetColWidth(sheet, "B", lastCol, 4.5)
-	f.SetColWidth(sheet, totalCol, totalCol, 10)
+	f.SetColWidth(sheet, totalCol, weekendCol, 10)
 
 	filename := fmt.Sprintf("tabel-%s.xlsx", selectedMonth)
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
@@ -1199,6 +1319,34 @@ func ExportTimesheetsExcel(c *gin.Context) {
 	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
 }
 
+type calendarColumn struct {
+	Kind      string
+	NormHours float64
+	Class     string
+	Title     string
+}
+
+// buildMonthCalendar resolves the production calendar for the табель columns.
+func buildMonthCalendar(monthDates []string) []calendarColumn {
+	columns := make([]calendarColumn, len(monthDates))
+	for i, date := range monthDates {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		kind, hours, note := storage.CalendarDayInfo(day)
+		title := calendarKindLabel(kind)
+		if kind != "weekend" && kind != "holiday" {
+			title += fmt.Sprintf(", %.0f ч", hours)
+		}
+		if note != "" {
+			title += ": " + note
+		}
+		columns[i] = calendarColumn{Kind: kind, NormHours: hours, Class: "day-" + kind, Title: title}
+	}
+	return columns
+}
+
 // TimesheetsPage is new табель matrix by workers/dates with per-cell hover details.
 func TimesheetsPage(c *gin.Context) {
 	entries, err := storage.GetTimesheets()
@@ -1258,15 +1406,37 @@ func TimesheetsPage(c *gin.Context) {
 		currentTimesheetsPath += "&worker=" + template.URLQueryEscaper(selectedWorkerID)
 	}
 
+	quickMarks := activeMarkTypes()
 	buildTimesheetQuickMenu := func(date, workerID string) string {
 		quickReturn := url.QueryEscape(currentTimesheetsPath)
 		base := fmt.Sprintf("/schedule/new?date=%s&worker_id=%s&return=%s", template.URLQueryEscaper(date), template.URLQueryEscaper(workerID), quickReturn)
-		return fmt.Sprintf(`<div class="timesheet-quick-menu"><a href="%s" data-modal-url="%s" data-modal-title="Работа" data-modal-return="%s">Работа</a><a href="%s&special_mark=vacation" data-modal-url="%s&special_mark=vacation" data-modal-title="Отпуск" data-modal-return="%s">Отпуск</a><a href="%s&special_mark=sick" data-modal-url="%s&special_mark=sick" data-modal-title="Больничный" data-modal-return="%s">Больничный</a><a href="%s&special_mark=absence" data-modal-url="%s&special_mark=absence" data-modal-title="Прогул" data-modal-return="%s">Прогул</a><a href="%s&special_mark=weekend" data-modal-url="%s&special_mark=weekend" data-modal-title="Выходной" data-modal-return="%s">Выходной</a></div>`, template.HTMLEscapeString(base), template.HTMLEscapeString(base), template.HTMLEscapeString(currentTimesheetsPath), template.HTMLEscapeString(base), template.HTMLEscapeString(base), template.HTMLEscapeString(currentTimesheetsPath), template.HTMLEscapeString(base), template.HTMLEscapeString(base), template.HTMLEscapeString(currentTimesheetsPath), template.HTMLEscapeString(base), template.HTMLEscapeString(base), template.HTMLEscapeString(currentTimesheetsPath), template.HTMLEscapeString(base), template.HTMLEscapeString(base), template.HTMLEscapeString(currentTimesheetsPath))
+		returnAttr := template.HTMLEscapeString(currentTimesheetsPath)
+		var menu strings.Builder
+		menu.WriteString(fmt.Sprintf(`<div class="timesheet-quick-menu"><a href="%s" data-modal-url="%s" data-modal-title="Работа" data-modal-return="%s">Работа</a>`, template.HTMLEscapeString(base), template.HTMLEscapeString(base), returnAttr))
+		for _, mark := range quickMarks {
+			link := template.HTMLEscapeString(base + "&special_mark=" + url.QueryEscape(mark.Code))
+			menu.WriteString(fmt.Sprintf(`<a href="%s" data-modal-url="%s" data-modal-title="%s" data-modal-return="%s">%s</a>`, link, link, template.HTMLEscapeString(mark.Label), returnAttr, template.HTMLEscapeString(mark.Label)))
+		}
+		menu.WriteString(`</div>`)
+		return menu.String()
+	}
+
+	attendanceMap, err := buildAttendanceMap()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load attendance: %v", err)
+		return
 	}
 
 	type timesheetCellData struct {
 		Total          float64
+		Fact           float64
+		FactPlan       float64
+		HasFact        bool
+		FactPending    bool
+		Unapproved     bool
 		CellMark       string
+		CellColor      string
+		PaidMark       bool
 		DetailsHTML    string
 		MenuHTML       string
 		AutoWeekend    bool
@@ -1296,9 +1466,29 @@ func TimesheetsPage(c *gin.Context) {
 			entryReturn := template.URLQueryEscaper(currentTimesheetsPath)
 			editURL := "/timesheets/edit/" + template.HTMLEscapeString(entry.ID) + "?return=" + entryReturn
 			editAction := `<div class="timesheet-entry-actions"><a class="btn btn-secondary btn-compact" href="` + editURL + `" data-modal-url="` + editURL + `" data-modal-title="Редактировать запись" data-modal-return="` + template.HTMLEscapeString(currentTimesheetsPath) + `">Редактировать</a></div>`
+			if !storage.IsTimesheetApproved(entry) {
+				cellData.Unapproved = true
+				summary := specialMarkTitle(entry.UserMark)
+				if !isSpecialMark(entry.UserMark) {
+					summary = entry.StartTime + "-" + entry.EndTime
+				}
+				details = append(details, `<div class="timesheet-entry-item"><p>`+template.HTMLEscapeString(summary)+` · `+template.HTMLEscapeString(approvalStatusLabel(entry.ApprovalStatus))+` (не учитывается)</p>`+editAction+`</div>`)
+				continue
+			}
 			if isSpecialMark(entry.UserMark) {
 				cellData.CellMark = specialMarkLabel(entry.UserMark)
-				details = append(details, `<div class="timesheet-entry-item"><p>Отметка: `+template.HTMLEscapeString(cellData.CellMark)+`</p>`+editAction+`</div>`)
+				cellData.CellColor = specialMarkColor(entry.UserMark)
+				markInfo := template.HTMLEscapeString(cellData.CellMark + " — " + specialMarkTitle(entry.UserMark))
+				if markType, ok := storage.FindMarkType(entry.UserMark); ok {
+					cellData.PaidMark = markType.Paid
+					if markType.Paid {
+						markInfo += ", оплачиваемый"
+					}
+				}
+				if entry.DocumentRef != "" {
+					markInfo += `</p><p>Документ: ` + template.HTMLEscapeString(entry.DocumentRef)
+				}
+				details = append(details, `<div class="timesheet-entry-item"><p>Отметка: `+markInfo+`</p>`+editAction+`</div>`)
 				continue
 			}
 			hoursStr := formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes)
@@ -1313,43 +1503,105 @@ func TimesheetsPage(c *gin.Context) {
 			if comment == "" {
 				comment = "—"
 			}
-			detailBody := fmt.Sprintf(`<p>%s-%s · %s ч</p><p>Объекты: %s</p><p>Комментарий: %s</p><p>Создал: %s</p>`, template.HTMLEscapeString(entry.StartTime), template.HTMLEscapeString(entry.EndTime), template.HTMLEscapeString(hoursStr), objects, template.HTMLEscapeString(comment), template.HTMLEscapeString(creator))
+			factHTML := ""
+			if record, ok := attendanceMap[attendanceKey(entry.ID, worker.ID)]; ok {
+				if factHours, closed := attendanceHours(record); closed {
+					factHTML = fmt.Sprintf(`<p>Факт: %s-%s · %.2f ч (%s)</p>`, template.HTMLEscapeString(record.StartTime), template.HTMLEscapeString(record.EndTime), factHours, template.HTMLEscapeString(strings.ToLower(attendanceStatusLabel(record.Status))))
+					if record.Location != nil && record.Location.OutOfZone {
+						factHTML += fmt.Sprintf(`<p>Приход вне геозоны: %d м</p>`, record.Location.DistanceMeters)
+					}
+					if record.Status == "confirmed" {
+						cellData.HasFact = true
+						cellData.Fact += factHours
+						cellData.FactPlan += hours
+					} else {
+						cellData.FactPending = true
+					}
+				} else {
+					cellData.FactPending = true
+					factHTML = `<p>Факт: на смене с ` + template.HTMLEscapeString(record.StartTime) + `</p>`
+				}
+			}
+			detailBody := fmt.Sprintf(`<p>%s-%s · %s ч</p>%s<p>Объекты: %s</p><p>Комментарий: %s</p><p>Создал: %s</p>`, template.HTMLEscapeString(entry.StartTime), template.HTMLEscapeString(entry.EndTime), template.HTMLEscapeString(hoursStr), factHTML, objects, template.HTMLEscapeString(comment), template.HTMLEscapeString(creator))
 			details = append(details, `<div class="timesheet-entry-item">`+detailBody+editAction+`</div>`)
 		}
 		if len(details) == 0 {
 			if d, err := time.Parse("2006-01-02", date); err == nil && d.Before(time.Now()) {
 				cellData.CellMark = "В"
 				cellData.AutoWeekend = true
-				details = append(details, `<div class="timesheet-entry-item"><p>Авто: выходной</p></div>`)
+				autoLabel := "Авто: выходной"
+				if kind, _, note := storage.CalendarDayInfo(d); kind == "holiday" {
+					autoLabel = "Праздник"
+					if note != "" {
+						autoLabel += ": " + note
+					}
+				}
+				details = append(details, `<div class="timesheet-entry-item"><p>`+template.HTMLEscapeString(autoLabel)+`</p></div>`)
 			}
 		}
 		cellData.DetailsHTML = strings.Join(details, "")
 		return cellData
 	}
 
+	calendar := buildMonthCalendar(monthDates)
+	_, monthNormHours := storage.MonthNorm(selectedMonth)
+	hourRules := currentHourRules()
 	var headers strings.Builder
-	for d := 1; d <= daysInMonth; d++ {
-		headers.WriteString(fmt.Sprintf(`<th>%d</th>`, d))
+	for i := range monthDates {
+		day := calendar[i]
+		headers.WriteString(fmt.Sprintf(`<th class="%s" title="%s">%d</th>`, day.Class, template.HTMLEscapeString(day.Title), i+1))
 	}
 
 	workerRows := make([]string, 0, len(visibleWorkers))
 	for _, worker := range visibleWorkers {
 		var cells strings.Builder
 		workerTotal := 0.0
-		for _, date := range monthDates {
+		workerPaidDays := 0
+		for i, date := range monthDates {
 			cellData := buildTimesheetCellData(worker, date)
+			dayClass := calendar[i].Class
 			if cellData.DetailsHTML == "" {
-				cells.WriteString(fmt.Sprintf(`<td class="hours-cell empty"><span class="empty-value">—</span><button type="button" class="timesheet-quick-add" data-timesheet-menu-toggle aria-expanded="false">+</button>%s</td>`, cellData.MenuHTML))
+				cells.WriteString(fmt.Sprintf(`<td class="hours-cell empty %s"><span class="empty-value">—</span><button type="button" class="timesheet-quick-add" data-timesheet-menu-toggle aria-expanded="false">+</button>%s</td>`, dayClass, cellData.MenuHTML))
 				continue
 			}
 			if cellData.CellMark != "" {
-				cells.WriteString(fmt.Sprintf(`<td class="hours-cell empty marked"><span class="empty-value">%s</span><button type="button" class="timesheet-quick-add" data-timesheet-menu-toggle aria-expanded="false">+</button>%s<div class="hours-tooltip">%s</div></td>`, template.HTMLEscapeString(cellData.CellMark), cellData.MenuHTML, cellData.DetailsHTML))
+				if cellData.PaidMark {
+					workerPaidDays++
+				}
+				markStyle := ""
+				if cellData.CellColor != "" {
+					markStyle = ` style="background: color-mix(in srgb, ` + template.HTMLEscapeString(cellData.CellColor) + `, transparent 78%);"`
+				}
+				cells.WriteString(fmt.Sprintf(`<td class="hours-cell empty marked %s"%s><span class="empty-value">%s</span><button type="button" class="timesheet-quick-add" data-timesheet-menu-toggle aria-expanded="false">+</button>%s<div class="hours-tooltip">%s</div></td>`, dayClass, markStyle, template.HTMLEscapeString(cellData.CellMark), cellData.MenuHTML, cellData.DetailsHTML))
 			} else {
-				cells.WriteString(fmt.Sprintf(`<td class="hours-cell"><span>%.1f</span><div class="hours-tooltip">%s</div></td>`, cellData.Total, cellData.DetailsHTML))
+				cellClass := strings.TrimSpace("hours-cell " + dayClass)
+				factHTML := ""
+				if cellData.HasFact {
+					factHTML = fmt.Sprintf(`<small class="hours-fact">%.1f</small>`, cellData.Fact)
+					if math.Abs(cellData.Fact-cellData.FactPlan) > attendanceDeviationHours {
+						cellClass += " has-deviation"
+					}
+				}
+				if cellData.FactPending {
+					cellClass += " fact-pending"
+				}
+				if cellData.Unapproved {
+					cellClass += " approval-pending"
+				}
+				cells.WriteString(fmt.Sprintf(`<td class="%s"><span>%.1f</span>%s<div class="hours-tooltip">%s</div></td>`, cellClass, cellData.Total, factHTML, cellData.DetailsHTML))
 				workerTotal += cellData.Total
 			}
 		}
-		workerRows = append(workerRows, fmt.Sprintf(`<tr><th><a class="entity-link" href="/worker/%s" title="%s">%s</a></th>%s<td class="hours-cell month-total">%.1f</td></tr>`, template.HTMLEscapeString(worker.ID), template.HTMLEscapeString(worker.Name), template.HTMLEscapeString(shortWorkerDisplayName(worker.Name)), cells.String(), workerTotal))
+		paidDaysHTML := ""
+		if workerPaidDays > 0 {
+			paidDaysHTML = fmt.Sprintf(`<small class="hours-fact" title="Оплачиваемые дни отметок">+%d дн.</small>`, workerPaidDays)
+		}
+		breakdown, workerNorm := workerMonthHours(entries, worker.ID, selectedMonth, monthDates, calendar, attendanceMap, hourRules)
+		overtimeClass := "hours-cell month-total"
+		if breakdown.Overtime > 0 {
+			overtimeClass += " has-overtime"
+		}
+		workerRows = append(workerRows, fmt.Sprintf(`<tr><th><a class="entity-link" href="/worker/%s" title="%s">%s</a></th>%s<td class="hours-cell month-total">%.1f%s</td><td class="hours-cell month-total">%.1f</td><td class="hours-cell month-total">%.1f</td><td class="%s">%.1f</td><td class="hours-cell month-total">%.1f</td><td class="hours-cell month-total">%.1f</td></tr>`, template.HTMLEscapeString(worker.ID), template.HTMLEscapeString(worker.Name), template.HTMLEscapeString(shortWorkerDisplayName(worker.Name)), cells.String(), workerTotal, paidDaysHTML, workerNorm, breakdown.Regular, overtimeClass, breakdown.Overtime, breakdown.Night, breakdown.Weekend))
 	}
 
 	rows := strings.Join(workerRows, "")
@@ -1362,7 +1614,12 @@ func TimesheetsPage(c *gin.Context) {
 	if selectedWorkerID != "" {
 		workerHiddenField = `<input type="hidden" name="worker" value="` + template.HTMLEscapeString(selectedWorkerID) + `">`
 	}
-	SetTopNavActions(c, `<div class="top-nav-toolbar"><form method="GET" action="/timesheets" class="month-selector">`+workerHiddenField+`<select id="timesheets-topbar-month" name="month" onchange="this.form.submit()">`+monthOptions+`</select></form><a class="btn btn-secondary" href="/timesheets/export?month=`+template.URLQueryEscaper(selectedMonth)+`">Экспорт</a></div>`)
+	closureBanner, closureAction := renderMonthClosure(c, selectedMonth, currentTimesheetsPath)
+	importAction := ""
+	if isAdmin(c) {
+		importAction = `<a class="btn btn-secondary" href="/timesheets/import">Импорт</a>`
+	}
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><form method="GET" action="/timesheets" class="month-selector">`+workerHiddenField+`<select id="timesheets-topbar-month" name="month" onchange="this.form.submit()">`+monthOptions+`</select></form><a class="btn btn-secondary" href="/timesheets/export?month=`+template.URLQueryEscaper(selectedMonth)+`">Экспорт</a><a class="btn btn-secondary" href="/timesheets/export/t13?month=`+template.URLQueryEscaper(selectedMonth)+`">Т-13</a><a class="btn btn-secondary" href="/timesheets/export/pdf?month=`+template.URLQueryEscaper(selectedMonth)+`">PDF</a>`+importAction+closureAction+`</div>`)
 
 	selectedWorkerName := "Нет работника"
 	selectedWorkerMonthTotal := 0.0
@@ -1391,6 +1648,9 @@ func TimesheetsPage(c *gin.Context) {
 				dayTime, _ := time.Parse("2006-01-02", date)
 				weekdayNames := []string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
 				rowClass := "timesheet-mobile-row"
+				if kind, _, _ := storage.CalendarDayInfo(dayTime); kind == "holiday" || kind == "weekend" {
+					rowClass += " day-" + kind
+				}
 				valueLabel := "—"
 				statusLabel := "Пусто"
 				actionLabel := "Добавить"
@@ -1433,7 +1693,8 @@ func TimesheetsPage(c *gin.Context) {
     <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Табель</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
 {{SIDEBAR_HTML}}
 <div class="main-content">
-<div class="page-header page-header-desktop-hidden"><h1>Табель</h1><a class="btn btn-secondary" href="/timesheets/export?month={{SELECTED_MONTH}}">Экспорт в Excel</a></div>
+<div class="page-header page-header-desktop-hidden"><h1>Табель</h1><a class="btn btn-secondary" href="/timesheets/export?month={{SELECTED_MONTH}}">Экспорт в Excel</a><a class="btn btn-secondary" href="/timesheets/export/t13?month={{SELECTED_MONTH}}">Т-13</a><a class="btn btn-secondary" href="/timesheets/export/pdf?month={{SELECTED_MONTH}}">PDF</a></div>
+{{CLOSURE_BANNER}}
 <div class="card timesheet-card">
   <form method="GET" action="/timesheets" class="month-selector desktop-toolbar-hidden">
     {{WORKER_HIDDEN}}
@@ -1451,7 +1712,7 @@ func TimesheetsPage(c *gin.Context) {
     <div class="timesheet-mobile-summary">
       <div>
         <strong>{{SELECTED_WORKER_NAME}}</strong>
-        <p>Итого за месяц: {{SELECTED_WORKER_TOTAL}} ч</p>
+        <p>Итого за месяц: {{SELECTED_WORKER_TOTAL}} ч · норма месяца {{MONTH_NORM}} ч</p>
       </div>
       <a class="btn btn-secondary btn-compact" href="{{SELECTED_WORKER_LINK}}">Профиль</a>
     </div>
@@ -1462,11 +1723,12 @@ func TimesheetsPage(c *gin.Context) {
       </table>
     </div>
   </div>
-  <div class="table-scroll timesheet-table-wrap timesheet-desktop-matrix"><table class="table timesheet-matrix"><thead><tr><th>Работник</th>{{HEADERS}}<th>Итого</th></tr></thead><tbody>{{ROWS}}</tbody></table></div>
+  <div class="table-scroll timesheet-table-wrap timesheet-desktop-matrix"><table class="table timesheet-matrix"><thead><tr><th>Работник</th>{{HEADERS}}<th>Итого</th><th title="Норма по производственному календарю без дней отсутствия">Норма</th><th>Обычн.</th><th title="Сверх дневного или недельного порога">Сверх.</th><th title="Ночные часы, входят в итог">Ночь</th><th title="Выходные и праздничные дни">Вых./пр.</th></tr></thead><tbody>{{ROWS}}</tbody></table></div>
 </div>
 </div></body></html>`
 
 	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "timesheets"), 1)
+	final = strings.Replace(final, "{{CLOSURE_BANNER}}", closureBanner, 1)
 	final = strings.Replace(final, "{{WORKER_HIDDEN}}", workerHiddenField, 1)
 	final = strings.Replace(final, "{{MONTH_OPTIONS}}", monthOptions, 1)
 	final = strings.Replace(final, "{{HEADERS}}", headers.String(), 1)
@@ -1474,6 +1736,7 @@ func TimesheetsPage(c *gin.Context) {
 	final = strings.Replace(final, "{{WORKER_OPTIONS}}", workerOptions, 1)
 	final = strings.Replace(final, "{{SELECTED_WORKER_NAME}}", template.HTMLEscapeString(selectedWorkerName), 1)
 	final = strings.Replace(final, "{{SELECTED_WORKER_TOTAL}}", template.HTMLEscapeString(fmt.Sprintf("%.1f", selectedWorkerMonthTotal)), 1)
+	final = strings.Replace(final, "{{MONTH_NORM}}", fmt.Sprintf("%.0f", monthNormHours), 1)
 	final = strings.Replace(final, "{{SELECTED_WORKER_LINK}}", template.HTMLEscapeString(selectedWorkerProfileID), 1)
 	final = strings.Replace(final, "{{MOBILE_DAYS}}", mobileDaysHTML, 1)
 	final = strings.Replace(final, "{{SELECTED_MONTH}}", template.URLQueryEscaper(selectedMonth), -1)
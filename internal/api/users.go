@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -11,7 +10,6 @@ import (
 
 	"project/internal/models"
 	"project/internal/storage"
-	"project/internal/telegrambot"
 
 	"github.com/gin-gonic/gin"
 )
@@ -43,14 +41,8 @@ func UsersPage(c *gin.Context) {
 
 	noticeBlock := ""
 	switch c.Query("notice") {
-	case "telegram_sent":
-		noticeBlock = `<div class="dashboard-alert-item is-success"><strong>Учетка создана и сообщение отправлено</strong><p>Пользователь получил логин, адрес сайта, PWA-инструкцию и пароль в Telegram.</p></div>`
-	case "telegram_chat_missing":
-		noticeBlock = `<div class="dashboard-alert-item is-warning"><strong>Учетка создана, но Telegram не отправлен</strong><p>Для этого номера не найден подключенный Telegram-чат. Сотрудник должен открыть бота, нажать Start, отправить контакт и после этого нужно выполнить синхронизацию в настройках.</p></div>`
-	case "telegram_bot_missing":
-		noticeBlock = `<div class="dashboard-alert-item is-warning"><strong>Учетка создана, но бот не настроен</strong><p>Заполните токен, username бота и адрес сайта в настройках, чтобы отправлять данные в Telegram.</p></div>`
-	case "telegram_failed":
-		noticeBlock = `<div class="dashboard-alert-item is-warning"><strong>Учетка создана, но отправка в Telegram не удалась</strong><p>Проверьте настройки бота и синхронизацию контактов в разделе настроек.</p></div>`
+	case "user_created":
+		noticeBlock = `<div class="dashboard-alert-item is-success"><strong>Учетка создана</strong><p>Если у сотрудника подключен Telegram-чат, бот пришлет адрес сайта, логин, PWA-инструкцию и одноразовую ссылку для установки пароля. Без чата передайте логин и пароль лично.</p></div>`
 	}
 
 	var rows strings.Builder
@@ -147,6 +139,34 @@ func syncLinkedWorkerPhone(userID, phone string) error {
 	return storage.UpdateWorker(worker)
 }
 
+// syncUserWorker keeps the worker card of a regular user: links the selected one or creates a card,
+// and drops the link when the user becomes an admin.
+func syncUserWorker(c *gin.Context, user models.User, selectedWorkerID string) error {
+	if user.Status != "user" {
+		return storage.ClearWorkerLinkByUserID(user.ID)
+	}
+	if selectedWorkerID != "" {
+		if err := storage.LinkWorkerToUser(selectedWorkerID, user.ID); err != nil {
+			return err
+		}
+		_ = syncWorkerPhoneByID(selectedWorkerID, user.Phone)
+		return nil
+	}
+	if _, err := storage.GetWorkerByUserID(user.ID); err == nil {
+		_ = syncLinkedWorkerPhone(user.ID, user.Phone)
+		return nil
+	}
+	_, _ = storage.CreateWorker(models.Worker{
+		Name:          user.Name,
+		Position:      "Сотрудник",
+		Phone:         user.Phone,
+		CreatedBy:     c.GetString("userID"),
+		CreatedByName: c.GetString("userName"),
+		UserID:        user.ID,
+	})
+	return nil
+}
+
 func renderUserForm(c *gin.Context, user models.User, actionURL, title, submitLabel string, adminEditable bool) {
 	statusAdmin := ""
 	statusUser := ""
@@ -232,7 +252,6 @@ func AddUserPage(c *gin.Context) {
 }
 
 func CreateUser(c *gin.Context) {
-	plainPassword := c.PostForm("password")
 	selectedWorkerID := c.PostForm("worker_id")
 	phone := strings.TrimSpace(c.PostForm("phone"))
 	if phone == "" {
@@ -242,7 +261,7 @@ func CreateUser(c *gin.Context) {
 	newUser := models.User{
 		Name:     c.PostForm("name"),
 		Username: c.PostForm("username"),
-		Password: plainPassword,
+		Password: c.PostForm("password"),
 		Phone:    phone,
 		Status:   c.PostForm("status"),
 	}
@@ -252,42 +271,14 @@ func CreateUser(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Failed to create user: %v", err)
 		return
 	}
-	if createdUser.Status == "user" {
-		if selectedWorkerID != "" {
-			if err := storage.LinkWorkerToUser(selectedWorkerID, createdUser.ID); err != nil {
-				_ = storage.DeleteUser(createdUser.ID)
-				c.String(http.StatusBadRequest, "Failed to link worker: %v", err)
-				return
-			}
-			_ = syncWorkerPhoneByID(selectedWorkerID, createdUser.Phone)
-		} else {
-			_, _ = storage.CreateWorker(models.Worker{
-				Name:          createdUser.Name,
-				Position:      "Сотрудник",
-				Phone:         createdUser.Phone,
-				CreatedBy:     c.GetString("userID"),
-				CreatedByName: c.GetString("userName"),
-				UserID:        createdUser.ID,
-			})
-		}
-	}
-
-	redirectURL := "/users"
-	if createdUser.Status == "user" {
-		notifyErr := telegrambot.SendAccountCreatedNotification(createdUser, plainPassword)
-		switch {
-		case notifyErr == nil:
-			redirectURL += "?notice=telegram_sent"
-		case errors.Is(notifyErr, telegrambot.ErrChatNotLinked):
-			redirectURL += "?notice=telegram_chat_missing"
-		case errors.Is(notifyErr, telegrambot.ErrBotNotConfigured):
-			redirectURL += "?notice=telegram_bot_missing"
-		default:
-			redirectURL += "?notice=telegram_failed"
-		}
+	if err := syncUserWorker(c, createdUser, selectedWorkerID); err != nil {
+		_ = storage.DeleteUser(createdUser.ID)
+		c.String(http.StatusBadRequest, "Failed to link worker: %v", err)
+		return
 	}
 
-	c.Redirect(http.StatusFound, redirectURL)
+	// The welcome message is sent by the telegram subscriber of UserCreated.
+	c.Redirect(http.StatusFound, "/users?notice=user_created")
 }
 
 func EditUserPage(c *gin.Context) {
@@ -320,29 +311,9 @@ func UpdateUser(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Failed to update user: %v", err)
 		return
 	}
-	if user.Status == "user" {
-		if selectedWorkerID != "" {
-			if err := storage.LinkWorkerToUser(selectedWorkerID, user.ID); err != nil {
-				c.String(http.StatusBadRequest, "Failed to link worker: %v", err)
-				return
-			}
-			_ = syncWorkerPhoneByID(selectedWorkerID, user.Phone)
-		} else {
-			if _, err := storage.GetWorkerByUserID(user.ID); err != nil {
-				_, _ = storage.CreateWorker(models.Worker{
-					Name:          user.Name,
-					Position:      "Сотрудник",
-					Phone:         user.Phone,
-					CreatedBy:     c.GetString("userID"),
-					CreatedByName: c.GetString("userName"),
-					UserID:        user.ID,
-				})
-			} else {
-				_ = syncLinkedWorkerPhone(user.ID, user.Phone)
-			}
-		}
-	} else {
-		_ = storage.ClearWorkerLinkByUserID(user.ID)
+	if err := syncUserWorker(c, user, selectedWorkerID); err != nil {
+		c.String(http.StatusBadRequest, "Failed to link worker: %v", err)
+		return
 	}
 	c.Redirect(http.StatusFound, "/users")
 }
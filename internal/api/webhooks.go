@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"project/internal/models"
+	"project/internal/security"
+	"project/internal/storage"
+	"project/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+func webhookEventLabel(event string) string {
+	switch event {
+	case models.WebhookEventScheduleCreated:
+		return "Назначение создано"
+	case models.WebhookEventScheduleUpdated:
+		return "Назначение изменено"
+	case models.WebhookEventScheduleDeleted:
+		return "Назначение удалено"
+	case models.WebhookEventWorkerFired:
+		return "Работник уволен"
+	case models.WebhookEventObjectStatusChanged:
+		return "Статус объекта изменён"
+	case models.WebhookEventTest:
+		return "Тестовое событие"
+	default:
+		return event
+	}
+}
+
+func webhookDeliveryStatus(delivery models.WebhookDelivery) string {
+	switch delivery.Status {
+	case "delivered":
+		return `<span class="import-status is-ok">доставлено</span>`
+	case "failed":
+		return `<span class="import-status is-failed">не доставлено</span>`
+	default:
+		next := ""
+		if delivery.NextAttemptAt != "" {
+			next = ", повтор " + formatClosureTime(delivery.NextAttemptAt)
+		}
+		return `<span class="import-status is-skipped">в очереди` + template.HTMLEscapeString(next) + `</span>`
+	}
+}
+
+func humanizeWebhookError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "name is required"):
+		return "Укажите название подписки."
+	case strings.Contains(msg, "http or https"):
+		return "Адрес должен начинаться с http:// или https://."
+	case strings.Contains(msg, "event is required"):
+		return "Выберите хотя бы одно событие."
+	case strings.Contains(msg, "unknown webhook event"):
+		return "Неизвестное событие."
+	case strings.Contains(msg, "webhook not found"):
+		return "Подписка не найдена."
+	default:
+		return "Не удалось сохранить подписку: " + msg
+	}
+}
+
+// WebhooksPage lists outgoing webhook subscriptions and the latest deliveries.
+func WebhooksPage(c *gin.Context) {
+	hooks, err := storage.GetWebhooks()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to load webhooks: %v", err)
+		return
+	}
+	hookNames := map[string]string{}
+	var rows strings.Builder
+	for _, hook := range hooks {
+		hookNames[hook.ID] = hook.Name
+		events := make([]string, 0, len(hook.Events))
+		for _, event := range hook.Events {
+			events = append(events, webhookEventLabel(event))
+		}
+		state, toggleLabel, toggleValue := `<span class="import-status is-ok">активна</span>`, "Приостановить", "0"
+		if !hook.Active {
+			state, toggleLabel, toggleValue = `<span class="import-status is-skipped">приостановлена</span>`, "Включить", "1"
+		}
+		id := template.HTMLEscapeString(hook.ID)
+		actions := `<form method="POST" action="/settings/webhooks/test/` + id + `">` + CSRFHiddenInput(c) + `<button type="submit" class="btn btn-secondary btn-compact">Тестовое событие</button></form>` +
+			`<form method="POST" action="/settings/webhooks/toggle/` + id + `">` + CSRFHiddenInput(c) + `<input type="hidden" name="active" value="` + toggleValue + `"><button type="submit" class="btn btn-secondary btn-compact">` + toggleLabel + `</button></form>` +
+			`<form method="POST" action="/settings/webhooks/delete/` + id + `">` + CSRFHiddenInput(c) + `<button type="submit" class="btn btn-danger btn-compact" onclick="return confirm('Удалить подписку вместе с журналом доставок?')">Удалить</button></form>`
+		rows.WriteString(fmt.Sprintf(`<tr><td><strong>%s</strong><br><code>%s</code><br><span class="leave-balance-meta">%s, %s</span></td><td>%s</td><td><code>%s</code></td><td>%s</td><td><div class="table-actions">%s</div></td></tr>`,
+			template.HTMLEscapeString(hook.Name),
+			template.HTMLEscapeString(hook.URL),
+			template.HTMLEscapeString(formatClosureTime(hook.CreatedAt)),
+			template.HTMLEscapeString(hook.CreatedByName),
+			template.HTMLEscapeString(strings.Join(events, ", ")),
+			template.HTMLEscapeString(hook.Secret),
+			state,
+			actions))
+	}
+	if len(hooks) == 0 {
+		rows.WriteString(`<tr><td colspan="5">Подписок пока нет.</td></tr>`)
+	}
+
+	filter := strings.TrimSpace(c.Query("webhook"))
+	var deliveryRows strings.Builder
+	for _, delivery := range storage.GetWebhookDeliveries(filter, 100) {
+		result := "—"
+		if delivery.ResponseCode != 0 {
+			result = fmt.Sprintf("HTTP %d", delivery.ResponseCode)
+		}
+		if delivery.Error != "" && delivery.Status != "delivered" {
+			result = delivery.Error
+		}
+		deliveryRows.WriteString(fmt.Sprintf(`<tr><td>%s</td><td><a href="/settings/webhooks?webhook=%s">%s</a></td><td>%s</td><td>%s</td><td>%d</td><td>%s</td></tr>`,
+			template.HTMLEscapeString(formatClosureTime(delivery.CreatedAt)),
+			url.QueryEscape(delivery.WebhookID),
+			template.HTMLEscapeString(hookNames[delivery.WebhookID]),
+			template.HTMLEscapeString(webhookEventLabel(delivery.Event)),
+			webhookDeliveryStatus(delivery),
+			delivery.Attempts,
+			template.HTMLEscapeString(result)))
+	}
+	if deliveryRows.Len() == 0 {
+		deliveryRows.WriteString(`<tr><td colspan="6">Доставок пока не было.</td></tr>`)
+	}
+	logTitle := "Журнал доставок"
+	if name, ok := hookNames[filter]; ok {
+		logTitle += ": " + template.HTMLEscapeString(name) + ` <a href="/settings/webhooks" class="leave-balance-meta">все подписки</a>`
+	}
+
+	var eventBoxes strings.Builder
+	for _, event := range storage.WebhookEvents() {
+		eventBoxes.WriteString(`<label><input type="checkbox" name="events" value="` + template.HTMLEscapeString(event) + `"> ` + template.HTMLEscapeString(webhookEventLabel(event)) + `</label>`)
+	}
+
+	statusBlock := ""
+	if errMsg := strings.TrimSpace(c.Query("error")); errMsg != "" {
+		statusBlock = `<div class="form-error">` + template.HTMLEscapeString(errMsg) + `</div>`
+	} else if okMsg := strings.TrimSpace(c.Query("ok")); okMsg != "" {
+		statusBlock = `<div class="dashboard-alert-item is-success"><p>` + template.HTMLEscapeString(okMsg) + `</p></div>`
+	}
+
+	page := `<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, viewport-fit=cover"><title>Вебхуки</title><link rel="stylesheet" href="/static/css/style.css"></head><body>
+{{SIDEBAR_HTML}}
+<div class="main-content">
+<a href="/settings" class="back-link">← К настройкам</a>
+<div class="page-header"><h1>Вебхуки</h1><p>Сервис отправляет POST с JSON <code>{"id", "event", "createdAt", "data"}</code> на адрес подписки. Подпись — в заголовке <code>X-Webhook-Signature: sha256=&lt;hex&gt;</code>: HMAC-SHA256 строки <code>&lt;X-Webhook-Timestamp&gt;.&lt;тело&gt;</code> с секретом подписки. Ответ 2xx — доставлено; иначе повтор через 30 с, 2 мин, 10 мин и 1 ч.</p></div>
+{{STATUS_BLOCK}}
+<div class="card"><h2>Новая подписка</h2>
+<form method="POST" action="/settings/webhooks" class="mark-type-form">{{CSRF_FIELD}}
+<input type="text" name="name" placeholder="Название, напр. «Бухгалтерия»" required>
+<input type="url" name="url" placeholder="https://example.com/hooks/avayus" required>
+{{EVENTS}}
+<button type="submit" class="btn btn-primary">Добавить</button>
+</form></div>
+<div class="card"><div class="table-scroll"><table class="table"><thead><tr><th>Подписка</th><th>События</th><th>Секрет</th><th>Состояние</th><th></th></tr></thead><tbody>{{ROWS}}</tbody></table></div></div>
+<div class="card"><h2>{{LOG_TITLE}}</h2><div class="table-scroll"><table class="table"><thead><tr><th>Время</th><th>Подписка</th><th>Событие</th><th>Статус</th><th>Попыток</th><th>Результат</th></tr></thead><tbody>{{DELIVERIES}}</tbody></table></div></div>
+</div>
+</body></html>`
+
+	final := strings.Replace(page, "{{SIDEBAR_HTML}}", RenderSidebar(c, "settings"), 1)
+	final = strings.Replace(final, "{{STATUS_BLOCK}}", statusBlock, 1)
+	final = strings.Replace(final, "{{CSRF_FIELD}}", CSRFHiddenInput(c), 1)
+	final = strings.Replace(final, "{{EVENTS}}", eventBoxes.String(), 1)
+	final = strings.Replace(final, "{{ROWS}}", rows.String(), 1)
+	final = strings.Replace(final, "{{LOG_TITLE}}", logTitle, 1)
+	final = strings.Replace(final, "{{DELIVERIES}}", deliveryRows.String(), 1)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(final))
+}
+
+func CreateWebhook(c *gin.Context) {
+	hook, err := storage.CreateWebhook(c.PostForm("name"), c.PostForm("url"), c.PostFormArray("events"), c.GetString("userName"))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/settings/webhooks?error="+url.QueryEscape(humanizeWebhookError(err)))
+		return
+	}
+	security.LogEvent("webhook_created", fmt.Sprintf("webhook=%q url=%s events=%s by=%s", hook.Name, hook.URL, strings.Join(hook.Events, ","), c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/webhooks?ok="+url.QueryEscape("Подписка «"+hook.Name+"» добавлена. Секрет для проверки подписи — в таблице."))
+}
+
+func ToggleWebhook(c *gin.Context) {
+	hook, err := storage.SetWebhookActive(c.Param("id"), c.PostForm("active") == "1")
+	if err != nil {
+		c.Redirect(http.StatusFound, "/settings/webhooks?error="+url.QueryEscape(humanizeWebhookError(err)))
+		return
+	}
+	security.LogEvent("webhook_toggled", fmt.Sprintf("webhook=%q active=%t by=%s", hook.Name, hook.Active, c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/webhooks")
+}
+
+func DeleteWebhook(c *gin.Context) {
+	hook, err := storage.DeleteWebhook(c.Param("id"))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/settings/webhooks?error="+url.QueryEscape(humanizeWebhookError(err)))
+		return
+	}
+	security.LogEvent("webhook_deleted", fmt.Sprintf("webhook=%q url=%s by=%s", hook.Name, hook.URL, c.GetString("userName")))
+	c.Redirect(http.StatusFound, "/settings/webhooks")
+}
+
+// SendTestWebhook delivers a test event synchronously so the result is visible right away.
+func SendTestWebhook(c *gin.Context) {
+	delivery, err := webhooks.SendTest(c.Param("id"))
+	if err != nil && delivery.ID == "" {
+		c.Redirect(http.StatusFound, "/settings/webhooks?error="+url.QueryEscape(humanizeWebhookError(err)))
+		return
+	}
+	target := "/settings/webhooks?webhook=" + url.QueryEscape(delivery.WebhookID)
+	if delivery.Status != "delivered" {
+		c.Redirect(http.StatusFound, target+"&error="+url.QueryEscape("Тестовое событие не доставлено: "+delivery.Error))
+		return
+	}
+	c.Redirect(http.StatusFound, target+"&ok="+url.QueryEscape(fmt.Sprintf("Тестовое событие доставлено, ответ HTTP %d.", delivery.ResponseCode)))
+}
@@ -130,7 +130,7 @@ func WorkersPage(c *gin.Context) {
 		workersGridHTML.WriteString(cardHTML)
 	}
 	currentWorkersPath := "/workers?tab=" + template.URLQueryEscaper(selectedTab)
-	SetTopNavActions(c, `<div class="top-nav-toolbar"><a href="/workers/new" class="btn btn-primary" data-modal-url="/workers/new" data-modal-title="Добавить работника" data-modal-return="`+currentWorkersPath+`">Новый работник</a></div>`)
+	SetTopNavActions(c, `<div class="top-nav-toolbar"><a href="/workers/new" class="btn btn-primary" data-modal-url="/workers/new" data-modal-title="Добавить работника" data-modal-return="`+currentWorkersPath+`">Новый работник</a><a href="/import?kind=workers" class="btn btn-secondary">Импорт</a></div>`)
 
 	pageTemplate := `
 <!DOCTYPE html>
@@ -261,6 +261,7 @@ func WorkerProfilePage(c *gin.Context) {
 	})
 
 	totalHours := 0.0
+	pendingHours := 0.0
 	monthSalary := 0.0
 	var workerAssignments strings.Builder
 	var workerMarks strings.Builder
@@ -292,8 +293,14 @@ func WorkerProfilePage(c *gin.Context) {
 			workerMarks.WriteString(fmt.Sprintf(`<article class="schedule-entry-vertical structured-assignment"><div class="assignment-head"><strong>%s</strong><span>%s</span></div><div class="assignment-body"><div class="assignment-note"><span>Комментарий</span><p>%s</p></div><div class="info-card-actions"><a href="/schedule/edit/%s" class="btn btn-secondary" data-modal-url="/schedule/edit/%s" data-modal-title="Редактирование отметки" data-modal-return="%s">Редактировать</a><form action="/schedule/delete/%s" method="POST"><input type="hidden" name="return_to" value="%s">%s<button type="submit" class="btn btn-danger">Удалить</button></form></div></div></article>`, template.HTMLEscapeString(formatScheduleDateLabel(entry.Date)), template.HTMLEscapeString(specialMarkLabel(entry.UserMark)), commentHTML, template.HTMLEscapeString(entry.ID), template.HTMLEscapeString(entry.ID), returnToWorkerEsc, template.HTMLEscapeString(entry.ID), returnToWorkerEsc, csrfField))
 			continue
 		}
+		// Like the табель and payroll, the total counts approved entries only; pending ones are shown apart.
 		hoursVal, _ := strconv.ParseFloat(formatWorkHours(entry.StartTime, entry.EndTime, entry.LunchBreakMinutes), 64)
-		totalHours += hoursVal
+		switch {
+		case storage.IsTimesheetApproved(entry):
+			totalHours += hoursVal
+		case entry.ApprovalStatus == "pending":
+			pendingHours += hoursVal
+		}
 		if entry.Date != currentDate {
 			if currentDate != "" {
 				workerAssignments.WriteString(`</div></div>`)
@@ -319,8 +326,8 @@ func WorkerProfilePage(c *gin.Context) {
 	if workerMarks.Len() == 0 {
 		workerMarks.WriteString(`<p>Отметок за выбранный месяц нет.</p>`)
 	}
-	if worker.HourlyRate > 0 {
-		monthSalary = totalHours * worker.HourlyRate
+	if line, ok := workerPayrollLine(worker.ID, selectedMonth); ok {
+		monthSalary = line.Payable
 	}
 
 	monthNames := []string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
@@ -369,6 +376,7 @@ func WorkerProfilePage(c *gin.Context) {
 			<div class="profile-actions worker-profile-actions">
 				{{STATUS_BADGE}}
 				<a href="/schedule/new?worker_id={{WORKER_ID}}&return={{WORKER_RETURN_QUERY}}&special_mark=vacation" class="btn btn-primary" data-modal-url="/schedule/new?worker_id={{WORKER_ID}}&return={{WORKER_RETURN_QUERY}}&special_mark=vacation" data-modal-title="Добавить отметку" data-modal-return="/worker/{{WORKER_ID}}">Добавить отпуск/больничный/выходной</a>
+				<a href="/worker/{{WORKER_ID}}/statement.pdf?month={{SELECTED_MONTH_QUERY}}" class="btn btn-secondary">Выписка (PDF)</a>
 				<a href="/workers/edit/{{WORKER_ID}}" class="btn btn-secondary" data-modal-url="/workers/edit/{{WORKER_ID}}" data-modal-title="Редактировать работника" data-modal-return="/worker/{{WORKER_ID}}">Редактировать</a>
 			</div>
 		</div>
@@ -383,12 +391,15 @@ func WorkerProfilePage(c *gin.Context) {
         <div class="profile-grid profile-grid-split">
             <div class="placeholder-card">
                  <div class="history-header"><h2>История назначений</h2></div>
-			 <form method="GET" action="/worker/{{WORKER_ID}}" class="month-selector"><label for="month">Месяц:</label><select id="month" name="month" onchange="this.form.submit()">{{MONTH_OPTIONS}}</select><span><strong>Итого часов:</strong> {{TOTAL_HOURS}}</span>{{MONTH_SALARY}}</form>
+			 <form method="GET" action="/worker/{{WORKER_ID}}" class="month-selector"><label for="month">Месяц:</label><select id="month" name="month" onchange="this.form.submit()">{{MONTH_OPTIONS}}</select><span><strong>Итого часов:</strong> {{TOTAL_HOURS}}</span>{{PENDING_HOURS}}{{MONTH_SALARY}}</form>
                  <div class="schedule-vertical">{{ASSIGNMENTS_BY_DAY}}</div>
             </div>
-            <div class="placeholder-card">
-                 <div class="history-header"><h2>Отметки табеля</h2></div>
-                 <div class="schedule-vertical">{{MARKS_BY_DAY}}</div>
+            <div class="profile-side-column">
+                {{LEAVE_BALANCE}}
+                <div class="placeholder-card">
+                     <div class="history-header"><h2>Отметки табеля</h2></div>
+                     <div class="schedule-vertical">{{MARKS_BY_DAY}}</div>
+                </div>
             </div>
         </div>
     </div>
@@ -403,6 +414,7 @@ func WorkerProfilePage(c *gin.Context) {
 	finalHTML = strings.Replace(finalHTML, "{{INITIALS}}", template.HTMLEscapeString(strings.ToUpper(initials)), -1)
 	finalHTML = strings.Replace(finalHTML, "{{POSITION}}", template.HTMLEscapeString(worker.Position), -1)
 	finalHTML = strings.Replace(finalHTML, "{{WORKER_ID}}", template.HTMLEscapeString(worker.ID), -1)
+	finalHTML = strings.Replace(finalHTML, "{{SELECTED_MONTH_QUERY}}", url.QueryEscape(selectedMonth), -1)
 	finalHTML = strings.Replace(finalHTML, "{{WORKER_RETURN_QUERY}}", template.HTMLEscapeString(returnToWorkerQuery), -1)
 	finalHTML = strings.Replace(finalHTML, "{{CSRF_FIELD}}", CSRFHiddenInput(c), -1)
 	finalHTML = strings.Replace(finalHTML, "{{BIRTH_DATE}}", template.HTMLEscapeString(formattedBirthDate), -1)
@@ -415,6 +427,11 @@ func WorkerProfilePage(c *gin.Context) {
 	finalHTML = strings.Replace(finalHTML, "{{STATUS_BADGE}}", statusBadge, -1)
 	finalHTML = strings.Replace(finalHTML, "{{MONTH_OPTIONS}}", workerMonthOptions.String(), -1)
 	finalHTML = strings.Replace(finalHTML, "{{TOTAL_HOURS}}", fmt.Sprintf("%.2f", totalHours), -1)
+	pendingHoursHTML := ""
+	if pendingHours > 0 {
+		pendingHoursHTML = `<span><strong>Ждут согласования:</strong> ` + fmt.Sprintf("%.2f", pendingHours) + `</span>`
+	}
+	finalHTML = strings.Replace(finalHTML, "{{PENDING_HOURS}}", pendingHoursHTML, -1)
 	monthSalaryHTML := ""
 	if monthSalary > 0 {
 		monthSalaryHTML = `<span><strong>ЗП за месяц:</strong> ` + fmt.Sprintf("%.2f", monthSalary) + ` руб</span>`
@@ -422,6 +439,7 @@ func WorkerProfilePage(c *gin.Context) {
 	finalHTML = strings.Replace(finalHTML, "{{MONTH_SALARY}}", monthSalaryHTML, -1)
 	finalHTML = strings.Replace(finalHTML, "{{ASSIGNMENTS_BY_DAY}}", workerAssignments.String(), -1)
 	finalHTML = strings.Replace(finalHTML, "{{MARKS_BY_DAY}}", workerMarks.String(), -1)
+	finalHTML = strings.Replace(finalHTML, "{{LEAVE_BALANCE}}", renderLeaveBalanceCard(c, worker, "/worker/"+worker.ID), 1)
 	finalHTML = strings.Replace(finalHTML, "{{ASSIGNMENTS_SECTION}}", "", -1)
 
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(finalHTML))
@@ -461,6 +479,10 @@ func AddWorkerPage(c *gin.Context) {
                         <label for="position">Должность</label>
                         <input type="text" id="position" name="position" required>
                     </div>
+                    <div class="form-group">
+                        <label for="personnel_no">Табельный номер</label>
+                        <input type="text" id="personnel_no" name="personnel_no">
+                    </div>
                     <div class="form-group">
                         <label for="phone">Телефон</label>
                         <input type="tel" id="phone" name="phone">
@@ -521,6 +543,7 @@ func CreateWorker(c *gin.Context) {
 	newWorker := models.Worker{
 		Name:          c.PostForm("name"),
 		Position:      c.PostForm("position"),
+		PersonnelNo:   strings.TrimSpace(c.PostForm("personnel_no")),
 		Phone:         c.PostForm("phone"),
 		BirthDate:     c.PostForm("birth_date"),
 		HourlyRate:    rate,
@@ -580,6 +603,11 @@ func EditWorkerPage(c *gin.Context) {
                     <input type="text" id="position" name="position" value="{{POSITION}}" required>
                 </div>
 
+                <div class="form-group-edit">
+                    <label for="personnel_no">Табельный номер</label>
+                    <input type="text" id="personnel_no" name="personnel_no" value="{{PERSONNEL_NO}}">
+                </div>
+
                 <div class="form-group-edit form-group-phone">
                     <label for="phone">Телефон</label>
                     <input type="tel" id="phone" name="phone" value="{{PHONE}}">
@@ -646,6 +674,7 @@ func EditWorkerPage(c *gin.Context) {
 	finalHTML = strings.Replace(finalHTML, "{{WORKER_NAME}}", template.HTMLEscapeString(worker.Name), -1)
 	finalHTML = strings.Replace(finalHTML, "{{POSITION}}", template.HTMLEscapeString(worker.Position), -1)
 	finalHTML = strings.Replace(finalHTML, "{{PHONE}}", template.HTMLEscapeString(worker.Phone), -1)
+	finalHTML = strings.Replace(finalHTML, "{{PERSONNEL_NO}}", template.HTMLEscapeString(worker.PersonnelNo), -1)
 	finalHTML = strings.Replace(finalHTML, "{{BIRTH_DATE}}", template.HTMLEscapeString(worker.BirthDate), -1)
 	finalHTML = strings.Replace(finalHTML, "{{RATE}}", fmt.Sprintf("%.2f", worker.HourlyRate), -1)
 	statusBadge := `<div class="status-badge active"><svg viewBox="0 0 16 16"><path d="M8,0C3.6,0,0,3.6,0,8s3.6,8,8,8s8-3.6,8-8S12.4,0,8,0z M7,11.4L3.6,8L5,6.6l2,2l4-4L12.4,6L7,11.4z"/></svg>Активен</div>`
@@ -692,14 +721,20 @@ func UpdateWorker(c *gin.Context) {
 	// Update fields from the form
 	worker.Name = c.PostForm("name")
 	worker.Position = c.PostForm("position")
+	worker.PersonnelNo = strings.TrimSpace(c.PostForm("personnel_no"))
 	worker.Phone = c.PostForm("phone")
 	worker.BirthDate = c.PostForm("birth_date")
+	previousRate := worker.HourlyRate
 	worker.HourlyRate = rate
 
 	if err := storage.UpdateWorker(worker); err != nil {
 		c.String(http.StatusInternalServerError, "Failed to save updated worker data: %v", err)
 		return
 	}
+	if err := storage.RecordWorkerRateChange(worker, previousRate, c.GetString("userName")); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to save rate history: %v", err)
+		return
+	}
 
 	c.Redirect(http.StatusFound, "/worker/"+workerID)
 }
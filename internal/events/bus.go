@@ -0,0 +1,111 @@
+package events
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// The bus delivers events on a single goroutine in publish order. Publish only queues the event,
+// so the storage layer can call it while holding its own locks and subscribers are free to read
+// storage back.
+var (
+	busMutex    sync.Mutex
+	busCond     = sync.NewCond(&busMutex)
+	queue       []Event
+	handlers    []subscription
+	nextID      int
+	published   uint64
+	handled     uint64
+	dispatching bool
+)
+
+type subscription struct {
+	id      int
+	handler func(Event)
+}
+
+// Subscribe registers a handler for every event and returns a function that removes it.
+func Subscribe(handler func(Event)) func() {
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	nextID++
+	id := nextID
+	handlers = append(handlers, subscription{id: id, handler: handler})
+	return func() {
+		busMutex.Lock()
+		defer busMutex.Unlock()
+		for i := range handlers {
+			if handlers[i].id == id {
+				handlers = append(handlers[:i:i], handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// On subscribes to one event type: events.On(func(e events.WorkerFired) { ... }).
+func On[T Event](handler func(T)) func() {
+	return Subscribe(func(event Event) {
+		if typed, ok := event.(T); ok {
+			handler(typed)
+		}
+	})
+}
+
+// Publish queues the event for the subscribers; it never blocks on them.
+func Publish(event Event) {
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	queue = append(queue, event)
+	published++
+	if !dispatching {
+		dispatching = true
+		go dispatch()
+	}
+}
+
+// Flush waits until every event published before the call has been handled. It must not be
+// called from a subscriber.
+func Flush() {
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	target := published
+	for handled < target {
+		busCond.Wait()
+	}
+}
+
+func dispatch() {
+	busMutex.Lock()
+	for len(queue) > 0 {
+		event := queue[0]
+		queue = queue[1:]
+		current := make([]subscription, len(handlers))
+		copy(current, handlers)
+		busMutex.Unlock()
+
+		for _, sub := range current {
+			deliver(sub.handler, event)
+		}
+
+		busMutex.Lock()
+		handled++
+		busCond.Broadcast()
+	}
+	dispatching = false
+	busMutex.Unlock()
+}
+
+// deliver keeps a panicking subscriber from stopping the bus.
+func deliver(handler func(Event), event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("events: handler for %s panicked: %v\n%s", event.EventName(), r, debug.Stack())
+		}
+	}()
+	handler(event)
+}
@@ -0,0 +1,60 @@
+package events
+
+import "project/internal/models"
+
+// Event is a domain change published by the storage layer after it has been saved.
+type Event interface {
+	EventName() string
+}
+
+type WorkerCreated struct{ Worker models.Worker }
+
+type WorkerUpdated struct{ Worker, Previous models.Worker }
+
+// WorkerFired is published once, when an active worker is dismissed.
+type WorkerFired struct{ Worker models.Worker }
+
+type ObjectCreated struct{ Object models.Object }
+
+type ObjectUpdated struct{ Object, Previous models.Object }
+
+// ObjectStatusChanged follows ObjectUpdated when the status differs from the previous one.
+type ObjectStatusChanged struct {
+	Object         models.Object
+	PreviousStatus string
+}
+
+type ObjectDeleted struct{ Object models.Object }
+
+// EntryCreated is published for every schedule entry or mark, including those created by
+// imports and approved leave requests.
+type EntryCreated struct{ Entry models.TimesheetEntry }
+
+// EntryUpdated covers edits and approval decisions.
+type EntryUpdated struct{ Entry, Previous models.TimesheetEntry }
+
+type EntryDeleted struct{ Entry models.TimesheetEntry }
+
+type UserCreated struct{ User models.User }
+
+type UserUpdated struct{ User, Previous models.User }
+
+type UserDeleted struct{ User models.User }
+
+// LeaveRequestReviewed follows the approval (after the mark entries are created) or rejection.
+type LeaveRequestReviewed struct{ Request models.LeaveRequest }
+
+func (WorkerCreated) EventName() string        { return "worker.created" }
+func (WorkerUpdated) EventName() string        { return "worker.updated" }
+func (WorkerFired) EventName() string          { return "worker.fired" }
+func (ObjectCreated) EventName() string        { return "object.created" }
+func (ObjectUpdated) EventName() string        { return "object.updated" }
+func (ObjectStatusChanged) EventName() string  { return "object.status_changed" }
+func (ObjectDeleted) EventName() string        { return "object.deleted" }
+func (EntryCreated) EventName() string         { return "schedule.created" }
+func (EntryUpdated) EventName() string         { return "schedule.updated" }
+func (EntryDeleted) EventName() string         { return "schedule.deleted" }
+func (UserCreated) EventName() string          { return "user.created" }
+func (UserUpdated) EventName() string          { return "user.updated" }
+func (UserDeleted) EventName() string          { return "user.deleted" }
+func (LeaveRequestReviewed) EventName() string { return "leave.reviewed" }
@@ -0,0 +1,22 @@
+package models
+
+// Scopes of API tokens.
+const (
+	TokenScopeRead          = "read"           // GET requests of the JSON API
+	TokenScopeScheduleWrite = "schedule:write" // create, change and delete schedule entries via the JSON API
+	TokenScopeExport        = "export"         // Excel, Т-13, PDF and payroll downloads
+)
+
+// APIToken is a named credential for scripts; only the SHA-256 of the secret is stored.
+type APIToken struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Prefix        string   `json:"prefix"` // first characters of the secret, to tell tokens apart
+	TokenHash     string   `json:"tokenHash"`
+	Scopes        []string `json:"scopes"`
+	UserID        string   `json:"userId"` // the token acts with this user's role and worker link
+	CreatedAt     string   `json:"createdAt"`
+	CreatedByName string   `json:"createdByName,omitempty"`
+	LastUsedAt    string   `json:"lastUsedAt,omitempty"`
+	RevokedAt     string   `json:"revokedAt,omitempty"`
+}
@@ -6,4 +6,31 @@ type AppSettings struct {
 	TelegramBotUsername  string `json:"telegramBotUsername,omitempty"`
 	TelegramSiteURL      string `json:"telegramSiteUrl,omitempty"`
 	TelegramUpdateOffset int    `json:"telegramUpdateOffset,omitempty"`
+	// TelegramMode is how updates arrive: "polling" (default) or "webhook" at <site>/telegram/webhook.
+	TelegramMode          string                `json:"telegramMode,omitempty"`
+	TelegramWebhookSecret string                `json:"telegramWebhookSecret,omitempty"`
+	HourRules             HourRules             `json:"hourRules"`
+	PayrollExport         PayrollExportSettings `json:"payrollExport"`
+	Organization          Organization          `json:"organization"`
+}
+
+// Organization holds the requisites and signatories printed on official forms.
+type Organization struct {
+	Name             string `json:"name,omitempty"`
+	UNP              string `json:"unp,omitempty"` // учётный номер плательщика
+	Address          string `json:"address,omitempty"`
+	Department       string `json:"department,omitempty"`
+	ResponsibleTitle string `json:"responsibleTitle,omitempty"`
+	ResponsibleName  string `json:"responsibleName,omitempty"`
+	HeadTitle        string `json:"headTitle,omitempty"`
+	HeadName         string `json:"headName,omitempty"`
+	HRName           string `json:"hrName,omitempty"`
+}
+
+// HourRules configures how worked hours are split for payroll.
+type HourRules struct {
+	DailyOvertimeAfter  float64 `json:"dailyOvertimeAfter"`  // hours per working day before overtime starts
+	WeeklyOvertimeAfter float64 `json:"weeklyOvertimeAfter"` // regular hours per ISO week before overtime starts
+	NightStart          string  `json:"nightStart"`          // HH:MM
+	NightEnd            string  `json:"nightEnd"`            // HH:MM, next morning
 }
@@ -0,0 +1,31 @@
+package models
+
+// AttendanceRecord stores the actual clock-in/clock-out of one worker on one planned assignment.
+type AttendanceRecord struct {
+	ID                string `json:"id"`
+	EntryID           string `json:"entryId"`
+	WorkerID          string `json:"workerId"`
+	Date              string `json:"date"` // YYYY-MM-DD
+	StartTime         string `json:"startTime"`
+	EndTime           string `json:"endTime,omitempty"`
+	LunchBreakMinutes int    `json:"lunchBreakMinutes"`
+	Status            string `json:"status"`           // open | closed | confirmed
+	Source            string `json:"source,omitempty"` // web | telegram
+	Notes             string `json:"notes,omitempty"`
+	ConfirmedByID     string `json:"confirmedById,omitempty"`
+	ConfirmedByName   string `json:"confirmedByName,omitempty"`
+	ConfirmedAt       string `json:"confirmedAt,omitempty"`
+
+	Location *AttendanceLocation `json:"location,omitempty"`
+}
+
+// AttendanceLocation is the device position reported on check-in and its geofence verdict.
+type AttendanceLocation struct {
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AccuracyMeters float64 `json:"accuracyMeters,omitempty"`
+	ObjectID       string  `json:"objectId,omitempty"` // nearest object with a geofence
+	DistanceMeters int     `json:"distanceMeters,omitempty"`
+	Verified       bool    `json:"verified"` // false when none of the entry's objects has coordinates
+	OutOfZone      bool    `json:"outOfZone"`
+}
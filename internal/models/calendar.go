@@ -0,0 +1,8 @@
+package models
+
+// CalendarDay overrides the default Monday–Friday week of the production calendar for one date.
+type CalendarDay struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Kind string `json:"kind"` // holiday | weekend | workday | short
+	Note string `json:"note,omitempty"`
+}
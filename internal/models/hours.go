@@ -0,0 +1,19 @@
+package models
+
+// WorkedShift is one shift of a worker as it is paid: planned time or confirmed actual time.
+type WorkedShift struct {
+	Date  string // YYYY-MM-DD
+	Start string // HH:MM
+	End   string // HH:MM
+	Lunch int    // minutes
+}
+
+// HourBreakdown splits worked hours. Regular+Overtime+Weekend equals Total;
+// Night is a supplement and overlaps the other categories.
+type HourBreakdown struct {
+	Total    float64
+	Regular  float64
+	Overtime float64
+	Night    float64
+	Weekend  float64 // hours on weekends and public holidays
+}
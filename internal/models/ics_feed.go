@@ -0,0 +1,10 @@
+package models
+
+// ICSFeed holds a user's secret for the iCalendar subscription links. Calendar apps cannot log in,
+// so the token in the URL is the only credential; resetting it breaks the old links.
+type ICSFeed struct {
+	UserID        string `json:"userId"`
+	Token         string `json:"token"`
+	CreatedAt     string `json:"createdAt"`
+	LastFetchedAt string `json:"lastFetchedAt,omitempty"`
+}
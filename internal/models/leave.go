@@ -0,0 +1,42 @@
+package models
+
+// LeaveEntitlement is the yearly vacation allowance of one worker.
+type LeaveEntitlement struct {
+	WorkerID       string  `json:"workerId"`
+	DaysPerYear    float64 `json:"daysPerYear"`
+	AccrualStart   string  `json:"accrualStart"`   // YYYY-MM-DD, accrual runs monthly from this date
+	OpeningBalance float64 `json:"openingBalance"` // days carried in at AccrualStart
+	MaxCarryOver   float64 `json:"maxCarryOver"`   // cap of unused days moved to the next year, 0 = no cap
+	UpdatedAt      string  `json:"updatedAt,omitempty"`
+}
+
+// LeaveBalance is a computed snapshot; it is never stored.
+type LeaveBalance struct {
+	HasEntitlement bool
+	CarriedOver    float64 // balance brought into the current year
+	Accrued        float64 // accrued in the current year up to the date
+	Used           float64 // leave days booked in the current year, future bookings included
+	Available      float64
+}
+
+// LeaveRequest is a worker's request for time off; approval turns it into mark entries.
+type LeaveRequest struct {
+	ID             string   `json:"id"`
+	WorkerID       string   `json:"workerId"`
+	MarkCode       string   `json:"markCode"`
+	DateFrom       string   `json:"dateFrom"` // YYYY-MM-DD
+	DateTo         string   `json:"dateTo"`   // YYYY-MM-DD, inclusive
+	Comment        string   `json:"comment,omitempty"`
+	AttachmentFile string   `json:"attachmentFile,omitempty"` // file name inside storage/leave_attachments
+	AttachmentName string   `json:"attachmentName,omitempty"` // original file name
+	Source         string   `json:"source"`                   // web | telegram
+	Status         string   `json:"status"`                   // pending | approved | rejected
+	CreatedByID    string   `json:"createdById,omitempty"`
+	CreatedByName  string   `json:"createdByName,omitempty"`
+	CreatedAt      string   `json:"createdAt"`
+	ReviewedByID   string   `json:"reviewedById,omitempty"`
+	ReviewedByName string   `json:"reviewedByName,omitempty"`
+	ReviewedAt     string   `json:"reviewedAt,omitempty"`
+	ReviewComment  string   `json:"reviewComment,omitempty"`
+	EntryIDs       []string `json:"entryIds,omitempty"` // timesheet entries created on approval
+}
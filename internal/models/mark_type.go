@@ -0,0 +1,15 @@
+package models
+
+// MarkType is a catalogue entry for non-working day marks (vacation, sick leave, ...).
+type MarkType struct {
+	Code             string   `json:"code"` // short code shown in the табель, e.g. "ОТ"
+	Label            string   `json:"label"`
+	Aliases          []string `json:"aliases,omitempty"` // alternative spellings accepted on input
+	Color            string   `json:"color,omitempty"`   // #rrggbb
+	Paid             bool     `json:"paid"`
+	RequiresDocument bool     `json:"requiresDocument"`
+	AllowPeriod      bool     `json:"allowPeriod"`
+	DeductsLeave     bool     `json:"deductsLeave"` // days are taken from the vacation balance
+	Active           bool     `json:"active"`
+	SortOrder        int      `json:"sortOrder"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// MonthClosure records that the табель of a month was handed over and frozen.
+type MonthClosure struct {
+	Month        string `json:"month"`  // YYYY-MM
+	Status       string `json:"status"` // closed | reopened
+	ClosedByID   string `json:"closedById"`
+	ClosedByName string `json:"closedByName"`
+	ClosedAt     string `json:"closedAt"`
+	ReopenedByID string `json:"reopenedById,omitempty"`
+	ReopenedBy   string `json:"reopenedByName,omitempty"`
+	ReopenedAt   string `json:"reopenedAt,omitempty"`
+	ReopenReason string `json:"reopenReason,omitempty"`
+}
@@ -7,4 +7,8 @@ type Object struct {
 	Status            string `json:"status"` // in_progress | paused | completed
 	Address           string `json:"address"`
 	ResponsibleUserID string `json:"responsibleUserId"`
+	// Latitude/Longitude/GeofenceRadius describe the check-in zone; zero coordinates mean no geofence.
+	Latitude       float64 `json:"latitude,omitempty"`
+	Longitude      float64 `json:"longitude,omitempty"`
+	GeofenceRadius int     `json:"geofenceRadiusMeters,omitempty"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// PasswordLink is a one-time link that lets a new user choose their password. Only the SHA-256 of
+// the token is kept, so the stored file cannot be used to open the link.
+type PasswordLink struct {
+	UserID    string `json:"userId"`
+	TokenHash string `json:"tokenHash"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// RateChange is an effective-dated hourly rate. It applies to one worker or to a position,
+// optionally only on one object; the most specific rate wins.
+type RateChange struct {
+	ID            string  `json:"id"`
+	WorkerID      string  `json:"workerId,omitempty"`
+	Position      string  `json:"position,omitempty"`
+	ObjectID      string  `json:"objectId,omitempty"`
+	EffectiveFrom string  `json:"effectiveFrom"` // YYYY-MM-DD
+	HourlyRate    float64 `json:"hourlyRate"`
+	Note          string  `json:"note,omitempty"`
+	CreatedByName string  `json:"createdByName,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+// PayrollAdjustment is a one-off amount of a worker in a month.
+type PayrollAdjustment struct {
+	ID            string  `json:"id"`
+	WorkerID      string  `json:"workerId"`
+	Month         string  `json:"month"` // YYYY-MM
+	Kind          string  `json:"kind"`  // bonus | deduction | advance
+	Amount        float64 `json:"amount"`
+	Note          string  `json:"note,omitempty"`
+	CreatedByName string  `json:"createdByName,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+// PayrollLine is the monthly result of one worker.
+type PayrollLine struct {
+	WorkerID   string         `json:"workerId"`
+	WorkerName string         `json:"workerName"`
+	Position   string         `json:"position,omitempty"`
+	Hours      HourBreakdown  `json:"hours"`
+	PaidDays   int            `json:"paidDays"`
+	MarkDays   map[string]int `json:"markDays,omitempty"` // days per absence mark code
+	Rates      []float64      `json:"rates,omitempty"`    // distinct hourly rates applied in the month
+	Earned     float64        `json:"earned"`
+	Bonuses    float64        `json:"bonuses"`
+	Deductions float64        `json:"deductions"`
+	Advances   float64        `json:"advances"`
+	Payable    float64        `json:"payable"`
+}
+
+// PayrollExportColumn maps one payroll field to a column of the accounting export.
+type PayrollExportColumn struct {
+	Field  string `json:"field"` // see storage.PayrollExportFields; "mark:<code>" for mark-day counts
+	Header string `json:"header"`
+}
+
+// PayrollExportSettings is the column layout of the CSV/XLSX payroll export.
+type PayrollExportSettings struct {
+	Columns      []PayrollExportColumn `json:"columns"`
+	Delimiter    string                `json:"delimiter"`
+	DecimalComma bool                  `json:"decimalComma,omitempty"`
+}
+
+// PayrollSnapshot freezes the payroll of a month when it is closed.
+type PayrollSnapshot struct {
+	Month         string        `json:"month"`
+	CreatedAt     string        `json:"createdAt"`
+	CreatedByName string        `json:"createdByName,omitempty"`
+	Lines         []PayrollLine `json:"lines"`
+}
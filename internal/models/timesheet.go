@@ -10,7 +10,14 @@ type TimesheetEntry struct {
 	WorkerIDs         []string `json:"workerIds"`
 	ObjectIDs         []string `json:"objectIds"`
 	Notes             string   `json:"notes,omitempty"`
-	UserMark          string   `json:"userMark,omitempty"`
+	UserMark          string   `json:"userMark,omitempty"`    // code from the mark catalogue
+	DocumentRef       string   `json:"documentRef,omitempty"` // supporting document for marks that require one
 	CreatedByID       string   `json:"createdById,omitempty"`
 	CreatedByName     string   `json:"createdByName,omitempty"`
+	// ApprovalStatus is empty for approved entries (admin-made and legacy), otherwise pending | rejected.
+	ApprovalStatus  string `json:"approvalStatus,omitempty"`
+	ApprovalComment string `json:"approvalComment,omitempty"`
+	ReviewedByID    string `json:"reviewedById,omitempty"`
+	ReviewedByName  string `json:"reviewedByName,omitempty"`
+	ReviewedAt      string `json:"reviewedAt,omitempty"`
 }
@@ -0,0 +1,38 @@
+package models
+
+// Events sent to webhook subscribers.
+const (
+	WebhookEventScheduleCreated     = "schedule.created"
+	WebhookEventScheduleUpdated     = "schedule.updated"
+	WebhookEventScheduleDeleted     = "schedule.deleted"
+	WebhookEventWorkerFired         = "worker.fired"
+	WebhookEventObjectStatusChanged = "object.status_changed"
+	WebhookEventTest                = "test" // sent only by the "send test event" button
+)
+
+// Webhook is an outgoing subscription; payloads are signed with HMAC-SHA256 of Secret.
+type Webhook struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret"`
+	Events        []string `json:"events"`
+	Active        bool     `json:"active"`
+	CreatedAt     string   `json:"createdAt"`
+	CreatedByName string   `json:"createdByName,omitempty"`
+}
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of the last attempt.
+type WebhookDelivery struct {
+	ID            string `json:"id"`
+	WebhookID     string `json:"webhookId"`
+	Event         string `json:"event"`
+	Payload       string `json:"payload"`
+	Status        string `json:"status"` // pending | delivered | failed
+	Attempts      int    `json:"attempts"`
+	ResponseCode  int    `json:"responseCode,omitempty"`
+	Error         string `json:"error,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	LastAttemptAt string `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt string `json:"nextAttemptAt,omitempty"`
+}
@@ -5,6 +5,7 @@ type Worker struct {
 	ID            string  `json:"id"`
 	Name          string  `json:"name"`
 	Position      string  `json:"position"`
+	PersonnelNo   string  `json:"personnelNo,omitempty"` // табельный номер
 	Phone         string  `json:"phone,omitempty"`
 	HourlyRate    float64 `json:"hourlyRate,omitempty"`
 	BirthDate     string  `json:"birthDate,omitempty"`
@@ -33,6 +33,18 @@ func SetupRouter(r *gin.Engine) {
 	r.POST("/login", api.Login)
 	r.GET("/logout", api.Logout)
 
+	// One-time link from the welcome message for choosing the password; the token is the credential
+	r.GET("/password/:token", api.PasswordSetupPage)
+	r.POST("/password/:token", api.PasswordSetup)
+
+	// Calendar subscriptions: phones fetch them without a session, the token in the path is the credential
+	r.GET("/ics/:token/my.ics", api.ICSWorkerFeed)
+	r.GET("/ics/:token/all.ics", api.ICSAllObjectsFeed)
+	r.GET("/ics/:token/object/:id", api.ICSObjectFeed)
+
+	// Telegram bot updates in webhook mode, verified by the secret token header
+	r.POST("/telegram/webhook", api.TelegramWebhook)
+
 	authRequired := r.Group("/")
 	authRequired.Use(api.AuthRequired(), api.CSRFMiddleware())
 	{
@@ -40,14 +52,21 @@ func SetupRouter(r *gin.Engine) {
 
 		authRequired.GET("/workers", api.WorkersPage)
 		authRequired.GET("/worker/:id", api.WorkerProfilePage)
+		authRequired.GET("/worker/:id/statement.pdf", api.ExportWorkerStatementPDF)
 		authRequired.GET("/workers/new", api.AddWorkerPage)
 		authRequired.POST("/workers/new", api.CreateWorker)
 		authRequired.GET("/workers/edit/:id", api.EditWorkerPage)
 		authRequired.POST("/workers/edit/:id", api.UpdateWorker)
 		authRequired.POST("/workers/delete/:id", api.DeleteWorker)
+		authRequired.POST("/workers/leave/:id", api.SaveLeaveEntitlement)
+		authRequired.GET("/leave", api.LeavePage)
+		authRequired.POST("/leave", api.CreateLeaveRequest)
+		authRequired.POST("/leave/review/:id", api.ReviewLeaveRequest)
+		authRequired.GET("/leave/attachment/:id", api.LeaveAttachment)
 
 		authRequired.GET("/objects", api.ObjectsPage)
 		authRequired.GET("/object/:id", api.ObjectProfilePage)
+		authRequired.GET("/object/:id/week.pdf", api.ExportObjectWeekPDF)
 		authRequired.GET("/objects/new", api.AddObjectPage)
 		authRequired.POST("/objects/new", api.CreateObject)
 		authRequired.GET("/objects/edit/:id", api.EditObjectPage)
@@ -57,6 +76,15 @@ func SetupRouter(r *gin.Engine) {
 		// Schedule (назначения)
 		authRequired.GET("/schedule", api.SchedulePage)
 		authRequired.GET("/schedule/", api.SchedulePage)
+		authRequired.GET("/schedule/board", api.ScheduleBoardPage)
+		authRequired.POST("/schedule/board/assign", api.ScheduleBoardAssign)
+		authRequired.POST("/schedule/board/remove", api.ScheduleBoardRemove)
+		authRequired.GET("/schedule/approvals", api.ScheduleApprovalsPage)
+		authRequired.POST("/schedule/approvals/:id", api.ReviewScheduleEntry)
+		authRequired.GET("/schedule/copy", api.ScheduleCopyPage)
+		authRequired.POST("/schedule/copy", api.CopySchedule)
+		authRequired.GET("/schedule/ics", api.ICSFeedsPage)
+		authRequired.POST("/schedule/ics/reset", api.ResetICSFeed)
 		authRequired.GET("/schedule/new", api.AddSchedulePage)
 		authRequired.GET("/timesheets/new", api.AddSchedulePage)
 		authRequired.POST("/schedule/new", api.CreateScheduleEntry)
@@ -69,9 +97,19 @@ func SetupRouter(r *gin.Engine) {
 		authRequired.POST("/timesheets/delete/:id", api.DeleteScheduleEntry)
 
 		// Timesheet matrix (табель)
+		authRequired.GET("/attendance", api.AttendancePage)
+		authRequired.POST("/attendance/check-in", api.CheckIn)
+		authRequired.POST("/attendance/clock-in/:id", api.ClockIn)
+		authRequired.POST("/attendance/clock-out/:id", api.ClockOut)
+		authRequired.POST("/attendance/confirm/:id", api.ConfirmAttendanceRecord)
+
 		authRequired.GET("/timesheets", api.TimesheetsPage)
 		authRequired.GET("/timesheets/", api.TimesheetsPage)
 		authRequired.GET("/timesheets/export", api.ExportTimesheetsExcel)
+		authRequired.GET("/timesheets/export/t13", api.ExportTimesheetT13)
+		authRequired.GET("/timesheets/export/pdf", api.ExportTimesheetsPDF)
+		authRequired.POST("/timesheets/close", api.CloseTimesheetMonth)
+		authRequired.POST("/timesheets/reopen", api.ReopenTimesheetMonth)
 		authRequired.GET("/timesheet", api.TimesheetsPage)
 		authRequired.GET("/timesheet/", api.TimesheetsPage)
 		authRequired.GET("/tabel", api.TimesheetsPage)
@@ -82,6 +120,9 @@ func SetupRouter(r *gin.Engine) {
 		authRequired.GET("/improvements", api.ImprovementsPage)
 		authRequired.POST("/improvements/new", api.CreateImprovement)
 		authRequired.POST("/improvements/complete/:id", api.CompleteImprovement)
+
+		authRequired.GET("/api/openapi.json", api.OpenAPISpec(r))
+		authRequired.GET("/api/docs", api.APIDocsPage(r))
 	}
 
 	adminRequired := r.Group("/")
@@ -95,10 +136,84 @@ func SetupRouter(r *gin.Engine) {
 		adminRequired.POST("/users/delete/:id", api.DeleteUser)
 		adminRequired.GET("/settings", api.SettingsPage)
 		adminRequired.POST("/settings/backup", api.CreateBackup)
+		adminRequired.GET("/settings/marks", api.MarkTypesPage)
+		adminRequired.POST("/settings/marks", api.SaveMarkType)
+		adminRequired.POST("/settings/marks/delete/:code", api.DeleteMarkType)
+		adminRequired.GET("/settings/calendar", api.CalendarPage)
+		adminRequired.POST("/settings/calendar/day", api.SaveCalendarDay)
+		adminRequired.POST("/settings/calendar/delete", api.DeleteCalendarDay)
+		adminRequired.POST("/settings/calendar/fixed", api.AddFixedHolidays)
+		adminRequired.POST("/settings/calendar/import", api.ImportCalendar)
+		adminRequired.POST("/settings/hour-rules", api.SaveHourRules)
+		adminRequired.GET("/import", api.ImportPage)
+		adminRequired.POST("/import/preview", api.PreviewImport)
+		adminRequired.POST("/import/apply", api.ApplyImport)
+		adminRequired.GET("/timesheets/import", api.TabelImportPage)
+		adminRequired.POST("/timesheets/import/preview", api.PreviewTabelImport)
+		adminRequired.POST("/timesheets/import/apply", api.ApplyTabelImport)
+		adminRequired.GET("/payroll", api.PayrollPage)
+		adminRequired.POST("/payroll/adjustments", api.SavePayrollAdjustment)
+		adminRequired.POST("/payroll/adjustments/delete/:id", api.DeletePayrollAdjustment)
+		adminRequired.GET("/payroll/export", api.PayrollExportPage)
+		adminRequired.POST("/payroll/export", api.SavePayrollExportSettings)
+		adminRequired.GET("/payroll/export/download", api.DownloadPayrollExport)
+		adminRequired.GET("/payroll/rates", api.PayrollRatesPage)
+		adminRequired.POST("/payroll/rates", api.SaveRateChange)
+		adminRequired.POST("/payroll/rates/delete/:id", api.DeleteRateChange)
+		adminRequired.POST("/settings/organization", api.SaveOrganization)
+		adminRequired.GET("/settings/tokens", api.APITokensPage)
+		adminRequired.POST("/settings/tokens", api.CreateAPIToken)
+		adminRequired.POST("/settings/tokens/revoke/:id", api.RevokeAPIToken)
+		adminRequired.GET("/settings/webhooks", api.WebhooksPage)
+		adminRequired.POST("/settings/webhooks", api.CreateWebhook)
+		adminRequired.POST("/settings/webhooks/toggle/:id", api.ToggleWebhook)
+		adminRequired.POST("/settings/webhooks/delete/:id", api.DeleteWebhook)
+		adminRequired.POST("/settings/webhooks/test/:id", api.SendTestWebhook)
 		adminRequired.POST("/settings/telegram", api.SaveTelegramSettings)
 		adminRequired.POST("/settings/telegram/sync", api.SyncTelegramContacts)
 	}
 
+	// JSON API; with a session cookie, writes need the X-CSRF-Token header, API tokens go as Bearer.
+	v1 := r.Group("/api/v1")
+	v1.Use(api.APIAuthRequired(), api.CSRFMiddleware())
+	{
+		v1.GET("/workers", api.APIListWorkers)
+		v1.POST("/workers", api.APICreateWorker)
+		v1.GET("/workers/:id", api.APIGetWorker)
+		v1.PUT("/workers/:id", api.APIUpdateWorker)
+		v1.DELETE("/workers/:id", api.APIDeleteWorker)
+
+		v1.GET("/objects", api.APIListObjects)
+		v1.POST("/objects", api.APICreateObject)
+		v1.GET("/objects/:id", api.APIGetObject)
+		v1.PUT("/objects/:id", api.APIUpdateObject)
+		v1.DELETE("/objects/:id", api.APIDeleteObject)
+
+		v1.GET("/schedule", api.APIListSchedule)
+		v1.POST("/schedule", api.APICreateScheduleEntry)
+		v1.GET("/schedule/:id", api.APIGetScheduleEntry)
+		v1.PUT("/schedule/:id", api.APIUpdateScheduleEntry)
+		v1.DELETE("/schedule/:id", api.APIDeleteScheduleEntry)
+
+		v1.GET("/marks", api.APIListMarks)
+		v1.POST("/marks", api.APICreateMark)
+		v1.GET("/marks/:code", api.APIGetMark)
+		v1.PUT("/marks/:code", api.APIUpdateMark)
+		v1.DELETE("/marks/:code", api.APIDeleteMark)
+
+		v1.GET("/improvements", api.APIListImprovements)
+		v1.POST("/improvements", api.APICreateImprovement)
+		v1.GET("/improvements/:id", api.APIGetImprovement)
+		v1.PUT("/improvements/:id", api.APIUpdateImprovement)
+		v1.DELETE("/improvements/:id", api.APIDeleteImprovement)
+
+		v1.GET("/users", api.APIListUsers)
+		v1.POST("/users", api.APICreateUser)
+		v1.GET("/users/:id", api.APIGetUser)
+		v1.PUT("/users/:id", api.APIUpdateUser)
+		v1.DELETE("/users/:id", api.APIDeleteUser)
+	}
+
 	r.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/login")
 	})
@@ -0,0 +1,31 @@
+package security
+
+import (
+	"fmt"
+
+	"project/internal/events"
+)
+
+// SubscribeAudit writes dismissals, object status changes and account changes to the security log.
+func SubscribeAudit() {
+	events.On(func(e events.WorkerFired) {
+		LogEvent("worker_fired", fmt.Sprintf("worker=%s name=%q", e.Worker.ID, e.Worker.Name))
+	})
+	events.On(func(e events.ObjectStatusChanged) {
+		LogEvent("object_status_changed", fmt.Sprintf("object=%s name=%q from=%s to=%s", e.Object.ID, e.Object.Name, e.PreviousStatus, e.Object.Status))
+	})
+	events.On(func(e events.ObjectDeleted) {
+		LogEvent("object_deleted", fmt.Sprintf("object=%s name=%q", e.Object.ID, e.Object.Name))
+	})
+	events.On(func(e events.UserCreated) {
+		LogEvent("user_created", fmt.Sprintf("user=%s username=%s role=%s", e.User.ID, e.User.Username, e.User.Status))
+	})
+	events.On(func(e events.UserUpdated) {
+		if e.Previous.Status != e.User.Status {
+			LogEvent("user_role_changed", fmt.Sprintf("user=%s username=%s from=%s to=%s", e.User.ID, e.User.Username, e.Previous.Status, e.User.Status))
+		}
+	})
+	events.On(func(e events.UserDeleted) {
+		LogEvent("user_deleted", fmt.Sprintf("user=%s username=%s", e.User.ID, e.User.Username))
+	})
+}
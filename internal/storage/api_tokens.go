@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const apiTokenPrefix = "ak_"
+
+var (
+	apiTokens      []models.APIToken
+	apiTokensMutex sync.RWMutex
+	apiTokensFile  = "storage/api_tokens.json"
+)
+
+// ErrInvalidAPIToken covers unknown, revoked and orphaned tokens alike.
+var ErrInvalidAPIToken = errors.New("invalid api token")
+
+func LoadAPITokens() error {
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+
+	file, err := os.ReadFile(apiTokensFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			apiTokens = []models.APIToken{}
+			return saveAPITokens()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		apiTokens = []models.APIToken{}
+		return nil
+	}
+	return json.Unmarshal(file, &apiTokens)
+}
+
+func saveAPITokens() error {
+	data, err := json.MarshalIndent(apiTokens, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(apiTokensFile, data, 0o644)
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// APITokenScopes lists the scopes an admin can grant, in display order.
+func APITokenScopes() []string {
+	return []string{models.TokenScopeRead, models.TokenScopeScheduleWrite, models.TokenScopeExport}
+}
+
+func GetAPITokens() ([]models.APIToken, error) {
+	apiTokensMutex.RLock()
+	defer apiTokensMutex.RUnlock()
+
+	result := make([]models.APIToken, len(apiTokens))
+	copy(result, apiTokens)
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt > result[j].CreatedAt })
+	return result, nil
+}
+
+// CreateAPIToken stores a new token and returns its secret; the secret is not kept and cannot be shown again.
+func CreateAPIToken(name string, scopes []string, userID, createdByName string) (models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.APIToken{}, "", errors.New("token name is required")
+	}
+	allowed := map[string]bool{}
+	for _, scope := range APITokenScopes() {
+		allowed[scope] = true
+	}
+	clean := make([]string, 0, len(scopes))
+	for _, scope := range cleanStringSlice(scopes) {
+		if !allowed[scope] {
+			return models.APIToken{}, "", errors.New("unknown token scope")
+		}
+		clean = append(clean, scope)
+	}
+	if len(clean) == 0 {
+		return models.APIToken{}, "", errors.New("token scope is required")
+	}
+	if _, err := GetUserByID(userID); err != nil {
+		return models.APIToken{}, "", errors.New("token user not found")
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return models.APIToken{}, "", err
+	}
+	secret := apiTokenPrefix + hex.EncodeToString(buf)
+	token := models.APIToken{
+		ID:            uuid.New().String(),
+		Name:          name,
+		Prefix:        secret[:len(apiTokenPrefix)+6],
+		TokenHash:     hashAPIToken(secret),
+		Scopes:        clean,
+		UserID:        userID,
+		CreatedAt:     time.Now().Format(time.RFC3339),
+		CreatedByName: createdByName,
+	}
+
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+	apiTokens = append(apiTokens, token)
+	if err := saveAPITokens(); err != nil {
+		apiTokens = apiTokens[:len(apiTokens)-1]
+		return models.APIToken{}, "", err
+	}
+	return token, secret, nil
+}
+
+// AuthenticateAPIToken finds the active token for the secret and stamps its last use
+// (written to disk at most once a minute per token).
+func AuthenticateAPIToken(secret string) (models.APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return models.APIToken{}, ErrInvalidAPIToken
+	}
+	hash := hashAPIToken(secret)
+
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+	for i := range apiTokens {
+		if subtle.ConstantTimeCompare([]byte(apiTokens[i].TokenHash), []byte(hash)) != 1 {
+			continue
+		}
+		if apiTokens[i].RevokedAt != "" {
+			return models.APIToken{}, ErrInvalidAPIToken
+		}
+		now := time.Now()
+		last, err := time.Parse(time.RFC3339, apiTokens[i].LastUsedAt)
+		if err != nil || now.Sub(last) >= time.Minute {
+			apiTokens[i].LastUsedAt = now.Format(time.RFC3339)
+			_ = saveAPITokens()
+		}
+		return apiTokens[i], nil
+	}
+	return models.APIToken{}, ErrInvalidAPIToken
+}
+
+func RevokeAPIToken(id string) (models.APIToken, error) {
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+
+	for i := range apiTokens {
+		if apiTokens[i].ID != id {
+			continue
+		}
+		if apiTokens[i].RevokedAt != "" {
+			return apiTokens[i], nil
+		}
+		apiTokens[i].RevokedAt = time.Now().Format(time.RFC3339)
+		if err := saveAPITokens(); err != nil {
+			apiTokens[i].RevokedAt = ""
+			return models.APIToken{}, err
+		}
+		return apiTokens[i], nil
+	}
+	return models.APIToken{}, errors.New("api token not found")
+}
@@ -20,6 +20,7 @@ func LoadAppSettings() error {
 	defer appSettingsMutex.Unlock()
 
 	appSettings = models.AppSettings{}
+	normalizeAppSettings(&appSettings)
 	file, err := os.ReadFile(appSettingsFile)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -44,6 +45,16 @@ func normalizeAppSettings(settings *models.AppSettings) {
 	if settings.TelegramUpdateOffset < 0 {
 		settings.TelegramUpdateOffset = 0
 	}
+	if settings.TelegramMode != "webhook" {
+		settings.TelegramMode = "polling"
+	}
+	settings.TelegramWebhookSecret = strings.TrimSpace(settings.TelegramWebhookSecret)
+	normalizeHourRules(&settings.HourRules)
+	normalizePayrollExport(&settings.PayrollExport)
+	org := &settings.Organization
+	for _, field := range []*string{&org.Name, &org.UNP, &org.Address, &org.Department, &org.ResponsibleTitle, &org.ResponsibleName, &org.HeadTitle, &org.HeadName, &org.HRName} {
+		*field = strings.TrimSpace(*field)
+	}
 }
 
 func saveAppSettings() error {
@@ -71,3 +82,16 @@ func UpdateAppSettings(settings models.AppSettings) error {
 	appSettings = settings
 	return saveAppSettings()
 }
+
+// SetTelegramUpdateOffset stores the next getUpdates offset without touching the other settings,
+// which may have been edited while the bot was waiting for updates.
+func SetTelegramUpdateOffset(offset int) error {
+	appSettingsMutex.Lock()
+	defer appSettingsMutex.Unlock()
+
+	if offset <= appSettings.TelegramUpdateOffset {
+		return nil
+	}
+	appSettings.TelegramUpdateOffset = offset
+	return saveAppSettings()
+}
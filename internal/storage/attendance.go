@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	attendance      []models.AttendanceRecord
+	attendanceMutex sync.RWMutex
+	attendanceFile  = "storage/attendance.json"
+)
+
+func LoadAttendance() error {
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	file, err := os.ReadFile(attendanceFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			attendance = []models.AttendanceRecord{}
+			return saveAttendance()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		attendance = []models.AttendanceRecord{}
+		return nil
+	}
+	if err := json.Unmarshal(file, &attendance); err != nil {
+		return err
+	}
+	for i := range attendance {
+		attendance[i].Status = normalizeAttendanceStatus(attendance[i].Status)
+	}
+	return nil
+}
+
+func saveAttendance() error {
+	data, err := json.MarshalIndent(attendance, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(attendanceFile, data, 0o644)
+}
+
+func normalizeAttendanceStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "closed":
+		return "closed"
+	case "confirmed":
+		return "confirmed"
+	default:
+		return "open"
+	}
+}
+
+func validateAttendanceTimes(startTime, endTime string, lunch int) error {
+	start, err := time.Parse("15:04", startTime)
+	if err != nil {
+		return errors.New("invalid start time")
+	}
+	end, err := time.Parse("15:04", endTime)
+	if err != nil {
+		return errors.New("invalid end time")
+	}
+	if !end.After(start) {
+		return errors.New("end time must be after start time")
+	}
+	if lunch >= int(end.Sub(start).Minutes()) {
+		return errors.New("lunch break must be shorter than work interval")
+	}
+	return nil
+}
+
+func GetAttendanceRecords() ([]models.AttendanceRecord, error) {
+	attendanceMutex.RLock()
+	defer attendanceMutex.RUnlock()
+
+	result := make([]models.AttendanceRecord, len(attendance))
+	copy(result, attendance)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date == result[j].Date {
+			return result[i].StartTime < result[j].StartTime
+		}
+		return result[i].Date > result[j].Date
+	})
+	return result, nil
+}
+
+func GetAttendanceByID(id string) (models.AttendanceRecord, error) {
+	attendanceMutex.RLock()
+	defer attendanceMutex.RUnlock()
+
+	for _, record := range attendance {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	return models.AttendanceRecord{}, errors.New("attendance record not found")
+}
+
+func FindAttendance(entryID, workerID string) (models.AttendanceRecord, error) {
+	attendanceMutex.RLock()
+	defer attendanceMutex.RUnlock()
+
+	for _, record := range attendance {
+		if record.EntryID == entryID && record.WorkerID == workerID {
+			return record, nil
+		}
+	}
+	return models.AttendanceRecord{}, errors.New("attendance record not found")
+}
+
+// FindOpenAttendance returns the worker's shift that is clocked in but not out, whatever its date,
+// so a shift that runs past midnight can still be closed the next day.
+func FindOpenAttendance(workerID string) (models.AttendanceRecord, error) {
+	attendanceMutex.RLock()
+	defer attendanceMutex.RUnlock()
+
+	for _, record := range attendance {
+		if record.WorkerID == workerID && record.Status == "open" {
+			return record, nil
+		}
+	}
+	return models.AttendanceRecord{}, errors.New("not clocked in")
+}
+
+// ClockIn opens the actual-time record of a worker on a planned entry.
+// location is optional; when given it is checked against the geofences of the entry's objects.
+func ClockIn(entryID, workerID, source string, when time.Time, location *models.AttendanceLocation) (models.AttendanceRecord, error) {
+	entry, err := GetTimesheetByID(entryID)
+	if err != nil {
+		return models.AttendanceRecord{}, err
+	}
+	if isSpecialMark(entry.UserMark) {
+		return models.AttendanceRecord{}, errors.New("cannot clock in on special mark")
+	}
+	if entry.Date != when.Format("2006-01-02") {
+		return models.AttendanceRecord{}, errors.New("can only clock in on the day of the entry")
+	}
+	if err := ensureDateOpen(entry.Date); err != nil {
+		return models.AttendanceRecord{}, err
+	}
+	found := false
+	for _, wid := range entry.WorkerIDs {
+		if wid == workerID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return models.AttendanceRecord{}, errors.New("worker is not assigned to entry")
+	}
+	if location != nil {
+		evaluated := EvaluateGeofence(entry, location.Latitude, location.Longitude, location.AccuracyMeters)
+		location = &evaluated
+	}
+
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	for _, record := range attendance {
+		if record.EntryID == entryID && record.WorkerID == workerID {
+			return models.AttendanceRecord{}, errors.New("already clocked in")
+		}
+	}
+	record := models.AttendanceRecord{
+		ID:                uuid.New().String(),
+		EntryID:           entryID,
+		WorkerID:          workerID,
+		Date:              entry.Date,
+		StartTime:         when.Format("15:04"),
+		LunchBreakMinutes: entry.LunchBreakMinutes,
+		Status:            "open",
+		Source:            strings.TrimSpace(source),
+		Location:          location,
+	}
+	attendance = append(attendance, record)
+	if err := saveAttendance(); err != nil {
+		attendance = attendance[:len(attendance)-1]
+		return models.AttendanceRecord{}, err
+	}
+	return record, nil
+}
+
+// ClockOut closes an open record; the lunch break is shortened when the shift turned out shorter than planned.
+// Shifts are kept within their day, so a clock-out after midnight ends the record at 23:59 and the
+// actual time goes to the notes for the foreman.
+func ClockOut(entryID, workerID string, when time.Time) (models.AttendanceRecord, error) {
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	for i := range attendance {
+		if attendance[i].EntryID != entryID || attendance[i].WorkerID != workerID {
+			continue
+		}
+		if attendance[i].Status != "open" {
+			return models.AttendanceRecord{}, errors.New("already clocked out")
+		}
+		if err := ensureDateOpen(attendance[i].Date); err != nil {
+			return models.AttendanceRecord{}, err
+		}
+		record := attendance[i]
+		record.EndTime = when.Format("15:04")
+		if day := when.Format("2006-01-02"); day > record.Date {
+			record.EndTime = "23:59"
+			record.Notes = strings.TrimSpace(record.Notes + " Уход отмечен " + when.Format("02.01.2006 15:04") + ".")
+		}
+		start, _ := time.Parse("15:04", record.StartTime)
+		end, _ := time.Parse("15:04", record.EndTime)
+		if !end.After(start) {
+			return models.AttendanceRecord{}, errors.New("end time must be after start time")
+		}
+		if minutes := int(end.Sub(start).Minutes()); record.LunchBreakMinutes >= minutes {
+			record.LunchBreakMinutes = 0
+		}
+		record.Status = "closed"
+		previous := attendance[i]
+		attendance[i] = record
+		if err := saveAttendance(); err != nil {
+			attendance[i] = previous
+			return models.AttendanceRecord{}, err
+		}
+		return record, nil
+	}
+	return models.AttendanceRecord{}, errors.New("not clocked in")
+}
+
+// ConfirmAttendance stores the foreman-approved actual time; only confirmed records count as fact in reports.
+func ConfirmAttendance(id, startTime, endTime string, lunch int, notes, confirmedByID, confirmedByName string) error {
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	startTime = strings.TrimSpace(startTime)
+	endTime = strings.TrimSpace(endTime)
+	lunch = normalizeLunchBreak(lunch)
+	if err := validateAttendanceTimes(startTime, endTime, lunch); err != nil {
+		return err
+	}
+	for i := range attendance {
+		if attendance[i].ID != id {
+			continue
+		}
+		if err := ensureDateOpen(attendance[i].Date); err != nil {
+			return err
+		}
+		previous := attendance[i]
+		attendance[i].StartTime = startTime
+		attendance[i].EndTime = endTime
+		attendance[i].LunchBreakMinutes = lunch
+		attendance[i].Notes = strings.TrimSpace(notes)
+		attendance[i].Status = "confirmed"
+		attendance[i].ConfirmedByID = confirmedByID
+		attendance[i].ConfirmedByName = confirmedByName
+		attendance[i].ConfirmedAt = time.Now().Format(time.RFC3339)
+		if err := saveAttendance(); err != nil {
+			attendance[i] = previous
+			return err
+		}
+		return nil
+	}
+	return errors.New("attendance record not found")
+}
+
+func DeleteAttendance(id string) error {
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	for i := range attendance {
+		if attendance[i].ID == id {
+			if err := ensureDateOpen(attendance[i].Date); err != nil {
+				return err
+			}
+			attendance = append(attendance[:i], attendance[i+1:]...)
+			return saveAttendance()
+		}
+	}
+	return errors.New("attendance record not found")
+}
+
+// deleteAttendance drops the records that no longer belong to a planned shift; the records stay
+// as they were when the save fails.
+func deleteAttendance(drop func(models.AttendanceRecord) bool) error {
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	kept := make([]models.AttendanceRecord, 0, len(attendance))
+	for _, record := range attendance {
+		if !drop(record) {
+			kept = append(kept, record)
+		}
+	}
+	if len(kept) == len(attendance) {
+		return nil
+	}
+	previous := attendance
+	attendance = kept
+	if err := saveAttendance(); err != nil {
+		attendance = previous
+		return err
+	}
+	return nil
+}
+
+func deleteAttendanceForEntry(entryID string) error {
+	return deleteAttendance(func(record models.AttendanceRecord) bool { return record.EntryID == entryID })
+}
+
+// deleteWorkerAttendance drops the worker's record on an entry they were taken off.
+func deleteWorkerAttendance(entryID, workerID string) error {
+	return deleteAttendance(func(record models.AttendanceRecord) bool {
+		return record.EntryID == entryID && record.WorkerID == workerID
+	})
+}
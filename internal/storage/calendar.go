@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+// StandardDayHours is the norm of a regular working day; a shortened pre-holiday day is one hour less.
+const StandardDayHours = 8.0
+
+var (
+	calendarDays      []models.CalendarDay
+	calendarDaysMutex sync.RWMutex
+	calendarDaysFile  = "storage/production_calendar.json"
+)
+
+func LoadCalendar() error {
+	calendarDaysMutex.Lock()
+	defer calendarDaysMutex.Unlock()
+
+	file, err := os.ReadFile(calendarDaysFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			calendarDays = []models.CalendarDay{}
+			return saveCalendar()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		calendarDays = []models.CalendarDay{}
+		return nil
+	}
+	return json.Unmarshal(file, &calendarDays)
+}
+
+func saveCalendar() error {
+	sort.Slice(calendarDays, func(i, j int) bool { return calendarDays[i].Date < calendarDays[j].Date })
+	data, err := json.MarshalIndent(calendarDays, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(calendarDaysFile, data, 0o644)
+}
+
+// normalizeCalendarKind accepts the stored English kinds and the Russian words used in imported files.
+func normalizeCalendarKind(kind string) string {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "holiday", "праздник", "праздничный":
+		return "holiday"
+	case "weekend", "выходной":
+		return "weekend"
+	case "workday", "рабочий", "перенос":
+		return "workday"
+	case "short", "сокращённый", "сокращенный", "предпраздничный":
+		return "short"
+	default:
+		return ""
+	}
+}
+
+func normalizeCalendarDay(day *models.CalendarDay) error {
+	date, err := parseCalendarDate(day.Date)
+	if err != nil {
+		return err
+	}
+	day.Date = date.Format("2006-01-02")
+	day.Kind = normalizeCalendarKind(day.Kind)
+	if day.Kind == "" {
+		return errors.New("unknown calendar day kind")
+	}
+	day.Note = strings.TrimSpace(day.Note)
+	return nil
+}
+
+func parseCalendarDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid date format")
+}
+
+// GetCalendarDays returns the overrides of one year, by date.
+func GetCalendarDays(year int) ([]models.CalendarDay, error) {
+	calendarDaysMutex.RLock()
+	defer calendarDaysMutex.RUnlock()
+
+	prefix := fmt.Sprintf("%04d-", year)
+	result := make([]models.CalendarDay, 0)
+	for _, day := range calendarDays {
+		if strings.HasPrefix(day.Date, prefix) {
+			result = append(result, day)
+		}
+	}
+	return result, nil
+}
+
+// SetCalendarDay creates or replaces the override of one date.
+func SetCalendarDay(day models.CalendarDay) error {
+	if err := normalizeCalendarDay(&day); err != nil {
+		return err
+	}
+
+	calendarDaysMutex.Lock()
+	defer calendarDaysMutex.Unlock()
+
+	previous := make([]models.CalendarDay, len(calendarDays))
+	copy(previous, calendarDays)
+	replaced := false
+	for i := range calendarDays {
+		if calendarDays[i].Date == day.Date {
+			calendarDays[i] = day
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		calendarDays = append(calendarDays, day)
+	}
+	if err := saveCalendar(); err != nil {
+		calendarDays = previous
+		return err
+	}
+	return nil
+}
+
+func DeleteCalendarDay(date string) error {
+	calendarDaysMutex.Lock()
+	defer calendarDaysMutex.Unlock()
+
+	for i := range calendarDays {
+		if calendarDays[i].Date == date {
+			removed := calendarDays[i]
+			calendarDays = append(calendarDays[:i], calendarDays[i+1:]...)
+			if err := saveCalendar(); err != nil {
+				calendarDays = append(calendarDays, removed)
+				return err
+			}
+			return nil
+		}
+	}
+	return errors.New("calendar day not found")
+}
+
+// ImportCalendarYear replaces all overrides of the year; days outside the year are rejected.
+func ImportCalendarYear(year int, days []models.CalendarDay) error {
+	prefix := fmt.Sprintf("%04d-", year)
+	seen := map[string]struct{}{}
+	for i := range days {
+		if err := normalizeCalendarDay(&days[i]); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(days[i].Date, prefix) {
+			return fmt.Errorf("date %s is outside of year %d", days[i].Date, year)
+		}
+		if _, dup := seen[days[i].Date]; dup {
+			return fmt.Errorf("date %s is listed twice", days[i].Date)
+		}
+		seen[days[i].Date] = struct{}{}
+	}
+
+	calendarDaysMutex.Lock()
+	defer calendarDaysMutex.Unlock()
+
+	previous := calendarDays
+	next := make([]models.CalendarDay, 0, len(calendarDays)+len(days))
+	for _, day := range calendarDays {
+		if !strings.HasPrefix(day.Date, prefix) {
+			next = append(next, day)
+		}
+	}
+	calendarDays = append(next, days...)
+	if err := saveCalendar(); err != nil {
+		calendarDays = previous
+		return err
+	}
+	return nil
+}
+
+// ParseCalendarFile reads "date;kind;note" lines (comma or tab also work, # starts a comment).
+// Dates may be YYYY-MM-DD or DD.MM.YYYY; kinds are holiday/weekend/workday/short or their Russian names.
+func ParseCalendarFile(data []byte) ([]models.CalendarDay, error) {
+	days := make([]models.CalendarDay, 0)
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ';' || r == ',' || r == '\t' })
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected date and kind", i+1)
+		}
+		day := models.CalendarDay{Date: fields[0], Kind: fields[1]}
+		if len(fields) > 2 {
+			day.Note = strings.Join(fields[2:], ", ")
+		}
+		if err := normalizeCalendarDay(&day); err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// FixedHolidays lists the public holidays with a fixed date; movable ones (e.g. Радуница) are added by hand.
+func FixedHolidays(year int) []models.CalendarDay {
+	fixed := []struct {
+		month time.Month
+		day   int
+		note  string
+	}{
+		{time.January, 1, "Новый год"},
+		{time.January, 2, "Новый год"},
+		{time.January, 7, "Рождество Христово (православное)"},
+		{time.March, 8, "День женщин"},
+		{time.May, 1, "Праздник труда"},
+		{time.May, 9, "День Победы"},
+		{time.July, 3, "День Независимости"},
+		{time.November, 7, "День Октябрьской революции"},
+		{time.December, 25, "Рождество Христово (католическое)"},
+	}
+	days := make([]models.CalendarDay, 0, len(fixed))
+	for _, item := range fixed {
+		days = append(days, models.CalendarDay{Date: time.Date(year, item.month, item.day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), Kind: "holiday", Note: item.note})
+	}
+	return days
+}
+
+// CalendarDayInfo resolves a date to its kind and norm hours: overrides first, then Saturday/Sunday as weekends.
+func CalendarDayInfo(date time.Time) (kind string, normHours float64, note string) {
+	key := date.Format("2006-01-02")
+	calendarDaysMutex.RLock()
+	for _, day := range calendarDays {
+		if day.Date == key {
+			calendarDaysMutex.RUnlock()
+			switch day.Kind {
+			case "holiday", "weekend":
+				return day.Kind, 0, day.Note
+			case "short":
+				return day.Kind, StandardDayHours - 1, day.Note
+			default:
+				return day.Kind, StandardDayHours, day.Note
+			}
+		}
+	}
+	calendarDaysMutex.RUnlock()
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return "weekend", 0, ""
+	}
+	return "workday", StandardDayHours, ""
+}
+
+// MonthNorm returns working days and norm hours of a YYYY-MM month.
+func MonthNorm(month string) (workDays int, normHours float64) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return 0, 0
+	}
+	for day := start; day.Month() == start.Month(); day = day.AddDate(0, 0, 1) {
+		if _, hours, _ := CalendarDayInfo(day); hours > 0 {
+			workDays++
+			normHours += hours
+		}
+	}
+	return workDays, normHours
+}
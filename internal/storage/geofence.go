@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"math"
+
+	"project/internal/models"
+)
+
+// DefaultGeofenceRadius is used for objects that have coordinates but no explicit radius.
+const DefaultGeofenceRadius = 150
+
+const earthRadiusMeters = 6371000.0
+
+func normalizeGeofence(object *models.Object) error {
+	if object.Latitude == 0 && object.Longitude == 0 {
+		object.GeofenceRadius = 0
+		return nil
+	}
+	if object.Latitude < -90 || object.Latitude > 90 || object.Longitude < -180 || object.Longitude > 180 {
+		return errors.New("invalid coordinates")
+	}
+	if object.GeofenceRadius < 0 {
+		return errors.New("invalid geofence radius")
+	}
+	if object.GeofenceRadius == 0 {
+		object.GeofenceRadius = DefaultGeofenceRadius
+	}
+	return nil
+}
+
+// DistanceMeters returns the great-circle distance between two points.
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// EvaluateGeofence checks a reported position against the nearest geofenced object of the entry.
+// GPS accuracy is tolerated up to the size of the radius itself, so a poor fix next to the fence is not flagged.
+func EvaluateGeofence(entry models.TimesheetEntry, latitude, longitude, accuracy float64) models.AttendanceLocation {
+	location := models.AttendanceLocation{
+		Latitude:       latitude,
+		Longitude:      longitude,
+		AccuracyMeters: math.Max(accuracy, 0),
+	}
+	best := -1.0
+	for _, oid := range entry.ObjectIDs {
+		object, err := GetObjectByID(oid)
+		if err != nil || (object.Latitude == 0 && object.Longitude == 0) {
+			continue
+		}
+		distance := DistanceMeters(latitude, longitude, object.Latitude, object.Longitude)
+		radius := float64(object.GeofenceRadius)
+		if radius <= 0 {
+			radius = DefaultGeofenceRadius
+		}
+		outside := distance > radius+math.Min(location.AccuracyMeters, radius)
+		if best >= 0 && distance >= best {
+			continue
+		}
+		best = distance
+		location.ObjectID = object.ID
+		location.DistanceMeters = int(math.Round(distance))
+		location.Verified = true
+		location.OutOfZone = outside
+	}
+	return location
+}
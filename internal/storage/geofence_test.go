@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"project/internal/models"
+)
+
+// metersPerDegree is the length of one degree of latitude on the sphere DistanceMeters uses.
+const metersPerDegree = earthRadiusMeters * math.Pi / 180
+
+func useObjects(t *testing.T, list ...models.Object) {
+	t.Helper()
+	objectsMutex.Lock()
+	previous := objects
+	objects = list
+	objectsMutex.Unlock()
+	t.Cleanup(func() {
+		objectsMutex.Lock()
+		objects = previous
+		objectsMutex.Unlock()
+	})
+}
+
+func TestEvaluateGeofenceThreshold(t *testing.T) {
+	const lat, lon = 55.75, 37.62
+	useObjects(t,
+		models.Object{ID: "fenced", Latitude: lat, Longitude: lon, GeofenceRadius: 100},
+		models.Object{ID: "default", Latitude: lat, Longitude: lon},
+		models.Object{ID: "nowhere"},
+	)
+	tests := []struct {
+		name     string
+		objectID string
+		north    float64 // meters north of the object
+		accuracy float64
+		out      bool
+	}{
+		{"inside the radius", "fenced", 90, 0, false},
+		{"outside the radius", "fenced", 110, 0, true},
+		{"accuracy widens the fence", "fenced", 110, 20, false},
+		{"accuracy counts up to the radius", "fenced", 190, 500, false},
+		{"beyond twice the radius", "fenced", 210, 500, true},
+		{"negative accuracy is ignored", "fenced", 110, -50, true},
+		{"default radius inside", "default", 140, 0, false},
+		{"default radius outside", "default", 160, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := models.TimesheetEntry{ObjectIDs: []string{tt.objectID}}
+			location := EvaluateGeofence(entry, lat+tt.north/metersPerDegree, lon, tt.accuracy)
+			if !location.Verified || location.ObjectID != tt.objectID {
+				t.Fatalf("location %+v is not checked against %s", location, tt.objectID)
+			}
+			if location.OutOfZone != tt.out {
+				t.Errorf("out of zone = %v at %d m, want %v", location.OutOfZone, location.DistanceMeters, tt.out)
+			}
+		})
+	}
+
+	t.Run("object without coordinates", func(t *testing.T) {
+		location := EvaluateGeofence(models.TimesheetEntry{ObjectIDs: []string{"nowhere"}}, lat, lon, 0)
+		if location.Verified || location.OutOfZone {
+			t.Errorf("location %+v, want unverified", location)
+		}
+	})
+	t.Run("nearest object decides", func(t *testing.T) {
+		useObjects(t,
+			models.Object{ID: "far", Latitude: lat + 1000/metersPerDegree, Longitude: lon, GeofenceRadius: 100},
+			models.Object{ID: "near", Latitude: lat, Longitude: lon, GeofenceRadius: 100},
+		)
+		location := EvaluateGeofence(models.TimesheetEntry{ObjectIDs: []string{"far", "near"}}, lat+50/metersPerDegree, lon, 0)
+		if location.ObjectID != "near" || location.OutOfZone || location.DistanceMeters != 50 {
+			t.Errorf("location %+v, want 50 m inside near", location)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"project/internal/models"
+)
+
+const (
+	defaultDailyOvertimeAfter  = 8
+	defaultWeeklyOvertimeAfter = 40
+	defaultNightStart          = "22:00"
+	defaultNightEnd            = "06:00"
+)
+
+func normalizeHourRules(rules *models.HourRules) {
+	if rules.DailyOvertimeAfter <= 0 || rules.DailyOvertimeAfter > 24 {
+		rules.DailyOvertimeAfter = defaultDailyOvertimeAfter
+	}
+	if rules.WeeklyOvertimeAfter <= 0 || rules.WeeklyOvertimeAfter > 168 {
+		rules.WeeklyOvertimeAfter = defaultWeeklyOvertimeAfter
+	}
+	if _, err := time.Parse("15:04", strings.TrimSpace(rules.NightStart)); err != nil {
+		rules.NightStart = defaultNightStart
+	}
+	if _, err := time.Parse("15:04", strings.TrimSpace(rules.NightEnd)); err != nil {
+		rules.NightEnd = defaultNightEnd
+	}
+	rules.NightStart = strings.TrimSpace(rules.NightStart)
+	rules.NightEnd = strings.TrimSpace(rules.NightEnd)
+}
+
+func clockMinutes(value string) (int, bool) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return parsed.Hour()*60 + parsed.Minute(), true
+}
+
+func overlapMinutes(fromA, toA, fromB, toB int) int {
+	from := max(fromA, fromB)
+	to := min(toA, toB)
+	if to <= from {
+		return 0
+	}
+	return to - from
+}
+
+// shiftMinutes returns paid and night minutes of a shift. Lunch is taken from the day part first.
+func shiftMinutes(shift models.WorkedShift, rules models.HourRules) (paid, night int) {
+	start, ok := clockMinutes(shift.Start)
+	if !ok {
+		return 0, 0
+	}
+	end, ok := clockMinutes(shift.End)
+	if !ok || end <= start {
+		return 0, 0
+	}
+	nightStart, _ := clockMinutes(rules.NightStart)
+	nightEnd, _ := clockMinutes(rules.NightEnd)
+	if nightStart > nightEnd {
+		night = overlapMinutes(start, end, nightStart, 24*60) + overlapMinutes(start, end, 0, nightEnd)
+	} else {
+		night = overlapMinutes(start, end, nightStart, nightEnd)
+	}
+	span := end - start
+	paid = max(span-shift.Lunch, 0)
+	day := span - night
+	if shift.Lunch > day {
+		night = max(night-(shift.Lunch-day), 0)
+	}
+	return paid, night
+}
+
+// ClassifyHours splits the shifts of one worker for a YYYY-MM month. Shifts outside the month may be
+// passed in so weekly thresholds see the whole ISO week; only days of the month are counted.
+func ClassifyHours(shifts []models.WorkedShift, month string, rules models.HourRules) models.HourBreakdown {
+	normalizeHourRules(&rules)
+
+	type dayTotal struct {
+		date   time.Time
+		paid   int
+		night  int
+		inside bool
+	}
+	byDate := map[string]*dayTotal{}
+	for _, shift := range shifts {
+		date, err := time.Parse("2006-01-02", shift.Date)
+		if err != nil {
+			continue
+		}
+		paid, night := shiftMinutes(shift, rules)
+		total, ok := byDate[shift.Date]
+		if !ok {
+			total = &dayTotal{date: date, inside: strings.HasPrefix(shift.Date, month+"-")}
+			byDate[shift.Date] = total
+		}
+		total.paid += paid
+		total.night += night
+	}
+	days := make([]*dayTotal, 0, len(byDate))
+	for _, day := range byDate {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].date.Before(days[j].date) })
+
+	result := models.HourBreakdown{}
+	weeklyRegular := map[[2]int]float64{}
+	for _, day := range days {
+		hours := float64(day.paid) / 60
+		kind, _, _ := CalendarDayInfo(day.date)
+		weekend, regular, overtime := 0.0, 0.0, 0.0
+		if kind == "weekend" || kind == "holiday" {
+			weekend = hours
+		} else {
+			regular = math.Min(hours, rules.DailyOvertimeAfter)
+			overtime = hours - regular
+			year, week := day.date.ISOWeek()
+			key := [2]int{year, week}
+			if excess := weeklyRegular[key] + regular - rules.WeeklyOvertimeAfter; excess > 0 {
+				moved := math.Min(excess, regular)
+				regular -= moved
+				overtime += moved
+			}
+			weeklyRegular[key] += regular
+		}
+		if !day.inside {
+			continue
+		}
+		result.Total += hours
+		result.Regular += regular
+		result.Overtime += overtime
+		result.Weekend += weekend
+		result.Night += float64(day.night) / 60
+	}
+	result.Total = roundHours(result.Total)
+	result.Regular = roundHours(result.Regular)
+	result.Overtime = roundHours(result.Overtime)
+	result.Weekend = roundHours(result.Weekend)
+	result.Night = roundHours(result.Night)
+	return result
+}
+
+func roundHours(value float64) float64 {
+	return math.Round(value*100) / 100
+}
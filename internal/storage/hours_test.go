@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"testing"
+
+	"project/internal/models"
+)
+
+func TestClassifyHours(t *testing.T) {
+	// October 2026 starts on a Thursday; 5 October is a Monday and 10 October a Saturday.
+	week := func(start, end string, lunch int, dates ...string) []models.WorkedShift {
+		shifts := make([]models.WorkedShift, 0, len(dates))
+		for _, date := range dates {
+			shifts = append(shifts, models.WorkedShift{Date: date, Start: start, End: end, Lunch: lunch})
+		}
+		return shifts
+	}
+	tests := []struct {
+		name   string
+		shifts []models.WorkedShift
+		rules  models.HourRules
+		want   models.HourBreakdown
+	}{
+		{
+			name:   "day shift",
+			shifts: week("08:00", "17:00", 60, "2026-10-05"),
+			want:   models.HourBreakdown{Total: 8, Regular: 8},
+		},
+		{
+			name:   "daily overtime",
+			shifts: week("08:00", "20:00", 60, "2026-10-05"),
+			want:   models.HourBreakdown{Total: 11, Regular: 8, Overtime: 3},
+		},
+		{
+			name:   "evening into the night",
+			shifts: week("18:00", "23:30", 0, "2026-10-05"),
+			want:   models.HourBreakdown{Total: 5.5, Regular: 5.5, Night: 1.5},
+		},
+		{
+			name:   "early morning night part",
+			shifts: week("04:00", "12:00", 0, "2026-10-05"),
+			want:   models.HourBreakdown{Total: 8, Regular: 8, Night: 2},
+		},
+		{
+			name:   "lunch longer than the day part eats night hours",
+			shifts: week("20:00", "23:00", 150, "2026-10-05"),
+			want:   models.HourBreakdown{Total: 0.5, Regular: 0.5, Night: 0.5},
+		},
+		{
+			name:   "custom night interval",
+			shifts: week("18:00", "23:00", 0, "2026-10-05"),
+			rules:  models.HourRules{NightStart: "20:00", NightEnd: "05:00"},
+			want:   models.HourBreakdown{Total: 5, Regular: 5, Night: 3},
+		},
+		{
+			name:   "weekend",
+			shifts: week("08:00", "17:00", 60, "2026-10-10"),
+			want:   models.HourBreakdown{Total: 8, Weekend: 8},
+		},
+		{
+			name:   "night hours on a weekend",
+			shifts: week("16:00", "23:00", 0, "2026-10-10"),
+			want:   models.HourBreakdown{Total: 7, Weekend: 7, Night: 1},
+		},
+		{
+			name:   "weekly threshold",
+			shifts: week("08:00", "16:00", 0, "2026-10-05", "2026-10-06", "2026-10-07", "2026-10-08", "2026-10-09"),
+			rules:  models.HourRules{WeeklyOvertimeAfter: 30},
+			want:   models.HourBreakdown{Total: 40, Regular: 30, Overtime: 10},
+		},
+		{
+			name:   "days before the month count toward the week only",
+			shifts: week("08:00", "16:00", 0, "2026-09-28", "2026-09-29", "2026-09-30", "2026-10-01", "2026-10-02"),
+			rules:  models.HourRules{WeeklyOvertimeAfter: 30},
+			want:   models.HourBreakdown{Total: 16, Regular: 6, Overtime: 10},
+		},
+		{
+			name: "shifts of one day add up before the daily threshold",
+			shifts: append(week("06:00", "12:00", 0, "2026-10-05"),
+				week("13:00", "18:00", 0, "2026-10-05")...),
+			want: models.HourBreakdown{Total: 11, Regular: 8, Overtime: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyHours(tt.shifts, "2026-10", tt.rules); got != tt.want {
+				t.Errorf("ClassifyHours = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
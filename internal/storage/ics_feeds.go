@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+var (
+	icsFeeds      []models.ICSFeed
+	icsFeedsMutex sync.RWMutex
+	icsFeedsFile  = "storage/ics_feeds.json"
+)
+
+// ErrInvalidICSToken covers unknown tokens and tokens of deleted users.
+var ErrInvalidICSToken = errors.New("invalid calendar feed token")
+
+func LoadICSFeeds() error {
+	icsFeedsMutex.Lock()
+	defer icsFeedsMutex.Unlock()
+
+	icsFeeds = []models.ICSFeed{}
+	exists, err := loadJSONList(icsFeedsFile, &icsFeeds)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return saveICSFeeds()
+	}
+	return nil
+}
+
+func saveICSFeeds() error {
+	data, err := json.MarshalIndent(icsFeeds, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(icsFeedsFile, data, 0o644)
+}
+
+func newICSToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GetICSFeed returns the user's feed token, creating it on first use.
+func GetICSFeed(userID string) (models.ICSFeed, error) {
+	icsFeedsMutex.Lock()
+	defer icsFeedsMutex.Unlock()
+
+	for _, feed := range icsFeeds {
+		if feed.UserID == userID {
+			return feed, nil
+		}
+	}
+	token, err := newICSToken()
+	if err != nil {
+		return models.ICSFeed{}, err
+	}
+	feed := models.ICSFeed{UserID: userID, Token: token, CreatedAt: time.Now().Format(time.RFC3339)}
+	icsFeeds = append(icsFeeds, feed)
+	if err := saveICSFeeds(); err != nil {
+		icsFeeds = icsFeeds[:len(icsFeeds)-1]
+		return models.ICSFeed{}, err
+	}
+	return feed, nil
+}
+
+// ResetICSFeed issues a new token for the user; links with the old one stop working.
+func ResetICSFeed(userID string) (models.ICSFeed, error) {
+	token, err := newICSToken()
+	if err != nil {
+		return models.ICSFeed{}, err
+	}
+
+	icsFeedsMutex.Lock()
+	defer icsFeedsMutex.Unlock()
+	for i := range icsFeeds {
+		if icsFeeds[i].UserID != userID {
+			continue
+		}
+		previous := icsFeeds[i]
+		icsFeeds[i] = models.ICSFeed{UserID: userID, Token: token, CreatedAt: time.Now().Format(time.RFC3339)}
+		if err := saveICSFeeds(); err != nil {
+			icsFeeds[i] = previous
+			return models.ICSFeed{}, err
+		}
+		return icsFeeds[i], nil
+	}
+	feed := models.ICSFeed{UserID: userID, Token: token, CreatedAt: time.Now().Format(time.RFC3339)}
+	icsFeeds = append(icsFeeds, feed)
+	if err := saveICSFeeds(); err != nil {
+		icsFeeds = icsFeeds[:len(icsFeeds)-1]
+		return models.ICSFeed{}, err
+	}
+	return feed, nil
+}
+
+// AuthenticateICSToken finds the user of a feed token and stamps the fetch time
+// (written to disk at most once an hour per feed; calendar apps poll often).
+func AuthenticateICSToken(token string) (models.User, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return models.User{}, ErrInvalidICSToken
+	}
+
+	icsFeedsMutex.Lock()
+	userID := ""
+	for i := range icsFeeds {
+		if subtle.ConstantTimeCompare([]byte(icsFeeds[i].Token), []byte(token)) != 1 {
+			continue
+		}
+		userID = icsFeeds[i].UserID
+		now := time.Now()
+		last, err := time.Parse(time.RFC3339, icsFeeds[i].LastFetchedAt)
+		if err != nil || now.Sub(last) >= time.Hour {
+			icsFeeds[i].LastFetchedAt = now.Format(time.RFC3339)
+			_ = saveICSFeeds()
+		}
+		break
+	}
+	icsFeedsMutex.Unlock()
+
+	if userID == "" {
+		return models.User{}, ErrInvalidICSToken
+	}
+	user, err := GetUserByID(userID)
+	if err != nil {
+		return models.User{}, ErrInvalidICSToken
+	}
+	return user, nil
+}
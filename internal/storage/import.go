@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	MaxImportFileSize = 5 << 20
+	maxImportRows     = 5000
+	importUploadTTL   = 24 * time.Hour
+)
+
+var importsDir = "storage/imports"
+
+// importUpload keeps a parsed file between the preview and the apply step.
+type importUpload struct {
+	Kind      string     `json:"kind"`
+	FileName  string     `json:"fileName"`
+	Rows      [][]string `json:"rows"`
+	CreatedAt string     `json:"createdAt"`
+}
+
+// ParseImportTable reads the first sheet of an XLSX file or a CSV file (delimiter detected
+// from the first line) into trimmed rows; fully empty rows are dropped.
+func ParseImportTable(fileName string, data []byte) ([][]string, error) {
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx", ".xlsm":
+		book, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("invalid xlsx file")
+		}
+		defer book.Close()
+		sheets := book.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("file has no rows")
+		}
+		if rows, err = book.GetRows(sheets[0]); err != nil {
+			return nil, errors.New("invalid xlsx file")
+		}
+	case ".csv", ".txt":
+		text := strings.TrimPrefix(string(data), "\ufeff")
+		firstLine, _, _ := strings.Cut(text, "\n")
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		reader.Comma = ','
+		if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+			reader.Comma = ';'
+		} else if strings.Count(firstLine, "\t") > strings.Count(firstLine, ",") {
+			reader.Comma = '\t'
+		}
+		var err error
+		if rows, err = reader.ReadAll(); err != nil {
+			return nil, errors.New("invalid csv file")
+		}
+	default:
+		return nil, errors.New("unsupported file type")
+	}
+
+	result := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		empty := true
+		cleaned := make([]string, len(row))
+		for i, cell := range row {
+			cleaned[i] = strings.TrimSpace(cell)
+			if cleaned[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			result = append(result, cleaned)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("file has no rows")
+	}
+	if len(result) > maxImportRows {
+		return nil, errors.New("too many rows")
+	}
+	return result, nil
+}
+
+func importUploadPath(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", errors.New("import upload not found")
+	}
+	return filepath.Join(importsDir, id+".json"), nil
+}
+
+// SaveImportUpload stores parsed rows for the next step and drops stale uploads.
+func SaveImportUpload(kind, fileName string, rows [][]string) (string, error) {
+	if err := os.MkdirAll(importsDir, 0o755); err != nil {
+		return "", err
+	}
+	if stale, err := os.ReadDir(importsDir); err == nil {
+		for _, item := range stale {
+			if info, err := item.Info(); err == nil && time.Since(info.ModTime()) > importUploadTTL {
+				os.Remove(filepath.Join(importsDir, item.Name()))
+			}
+		}
+	}
+	id := uuid.New().String()
+	data, err := json.Marshal(importUpload{Kind: kind, FileName: fileName, Rows: rows, CreatedAt: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		return "", err
+	}
+	path, _ := importUploadPath(id)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func LoadImportUpload(id string) (kind, fileName string, rows [][]string, err error) {
+	path, err := importUploadPath(id)
+	if err != nil {
+		return "", "", nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", nil, errors.New("import upload not found")
+	}
+	var upload importUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return "", "", nil, err
+	}
+	return upload.Kind, upload.FileName, upload.Rows, nil
+}
+
+func DeleteImportUpload(id string) {
+	if path, err := importUploadPath(id); err == nil {
+		os.Remove(path)
+	}
+}
+
+// FindDuplicateWorker matches an active worker by phone, personnel number, or name with birth date.
+func FindDuplicateWorker(candidate models.Worker) (models.Worker, bool) {
+	workersMutex.RLock()
+	defer workersMutex.RUnlock()
+
+	phone := NormalizePhoneNumber(candidate.Phone)
+	for _, worker := range workers {
+		if worker.IsFired {
+			continue
+		}
+		if phone != "" && NormalizePhoneNumber(worker.Phone) == phone {
+			return worker, true
+		}
+		if candidate.PersonnelNo != "" && strings.EqualFold(worker.PersonnelNo, candidate.PersonnelNo) {
+			return worker, true
+		}
+		if strings.EqualFold(strings.TrimSpace(worker.Name), strings.TrimSpace(candidate.Name)) && worker.BirthDate == candidate.BirthDate {
+			return worker, true
+		}
+	}
+	return models.Worker{}, false
+}
+
+// FindDuplicateObject matches an object with the same name and address (case-insensitive).
+func FindDuplicateObject(candidate models.Object) (models.Object, bool) {
+	objectsMutex.RLock()
+	defer objectsMutex.RUnlock()
+
+	for _, object := range objects {
+		if strings.EqualFold(strings.TrimSpace(object.Name), strings.TrimSpace(candidate.Name)) &&
+			strings.EqualFold(strings.TrimSpace(object.Address), strings.TrimSpace(candidate.Address)) {
+			return object, true
+		}
+	}
+	return models.Object{}, false
+}
+
+// TabelImportRow is one worker line of a табель workbook: the name and the raw value per day.
+type TabelImportRow struct {
+	Name string
+	Days map[int]string
+}
+
+var tabelMonthPattern = regexp.MustCompile(`(?i)за\s+(\p{L}+)\s+(\d{4})`)
+
+// tabelMonthPrefixes recognises English month names (the export writes time.Month) and Russian ones in any case form.
+var tabelMonthPrefixes = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+	"янв": time.January, "фев": time.February, "мар": time.March, "апр": time.April, "мая": time.May, "май": time.May,
+	"июн": time.June, "июл": time.July, "авг": time.August, "сен": time.September, "окт": time.October, "ноя": time.November, "дек": time.December,
+}
+
+// ParseTabelRows reads rows in the layout of the табель Excel export: a "за <месяц> <год>" title,
+// a header row starting with "Работник" followed by day numbers, then one row per worker.
+// The month is empty when the title is missing.
+func ParseTabelRows(rows [][]string) (string, []TabelImportRow, error) {
+	month := ""
+	headerIndex := -1
+	for i, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		if month == "" {
+			if match := tabelMonthPattern.FindStringSubmatch(strings.Join(row, " ")); match != nil {
+				runes := []rune(strings.ToLower(match[1]))
+				if len(runes) >= 3 {
+					if m, ok := tabelMonthPrefixes[string(runes[:3])]; ok {
+						month = match[2] + "-" + fmt.Sprintf("%02d", int(m))
+					}
+				}
+			}
+		}
+		if strings.EqualFold(strings.TrimSpace(row[0]), "Работник") {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return "", nil, errors.New("tabel header not found")
+	}
+
+	dayColumns := map[int]int{}
+	for col, value := range rows[headerIndex] {
+		if col == 0 {
+			continue
+		}
+		day, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || day < 1 || day > 31 {
+			continue
+		}
+		dayColumns[col] = day
+	}
+	if len(dayColumns) == 0 {
+		return "", nil, errors.New("tabel header not found")
+	}
+
+	result := make([]TabelImportRow, 0)
+	for _, row := range rows[headerIndex+1:] {
+		if len(row) == 0 {
+			continue
+		}
+		name := strings.TrimSpace(row[0])
+		if name == "" || strings.HasPrefix(name, "Условные обозначения") || strings.HasPrefix(name, "Нет данных") {
+			break
+		}
+		item := TabelImportRow{Name: name, Days: map[int]string{}}
+		for col, day := range dayColumns {
+			if col < len(row) {
+				if value := strings.TrimSpace(row[col]); value != "" {
+					item.Days[day] = value
+				}
+			}
+		}
+		result = append(result, item)
+	}
+	return month, result, nil
+}
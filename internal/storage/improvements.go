@@ -65,21 +65,92 @@ func GetImprovements() ([]models.ImprovementItem, error) {
 	return result, nil
 }
 
-func AddImprovement(item models.ImprovementItem) error {
+func normalizeImprovement(item *models.ImprovementItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	item.Description = strings.TrimSpace(item.Description)
+	if item.Type != "bug" {
+		item.Type = "improvement"
+	}
+	if item.Status != "done" {
+		item.Status = "open"
+	}
+	if item.Title == "" || item.Description == "" {
+		return fmt.Errorf("title and description are required")
+	}
+	return nil
+}
+
+func AddImprovement(item models.ImprovementItem) (models.ImprovementItem, error) {
 	improvementsMutex.Lock()
 	defer improvementsMutex.Unlock()
 
+	if err := normalizeImprovement(&item); err != nil {
+		return models.ImprovementItem{}, err
+	}
 	if strings.TrimSpace(item.ID) == "" {
 		item.ID = fmt.Sprintf("imp-%d", time.Now().UnixNano())
 	}
 	if item.CreatedAt.IsZero() {
 		item.CreatedAt = time.Now()
 	}
-	if item.Status == "" {
-		item.Status = "open"
-	}
 	improvements = append(improvements, item)
-	return saveImprovementsLocked()
+	if err := saveImprovementsLocked(); err != nil {
+		improvements = improvements[:len(improvements)-1]
+		return models.ImprovementItem{}, err
+	}
+	return item, nil
+}
+
+func GetImprovementByID(id string) (models.ImprovementItem, error) {
+	improvementsMutex.Lock()
+	defer improvementsMutex.Unlock()
+
+	for _, item := range improvements {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return models.ImprovementItem{}, fmt.Errorf("improvement not found")
+}
+
+// UpdateImprovement replaces the editable fields; reopening clears the completion stamp.
+func UpdateImprovement(item models.ImprovementItem) error {
+	improvementsMutex.Lock()
+	defer improvementsMutex.Unlock()
+
+	if err := normalizeImprovement(&item); err != nil {
+		return err
+	}
+	for i := range improvements {
+		if improvements[i].ID == item.ID {
+			if item.Status == "open" {
+				item.DoneAt = time.Time{}
+				item.DoneByID = ""
+				item.DoneBy = ""
+			}
+			previous := improvements[i]
+			improvements[i] = item
+			if err := saveImprovementsLocked(); err != nil {
+				improvements[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("improvement not found")
+}
+
+func DeleteImprovement(id string) error {
+	improvementsMutex.Lock()
+	defer improvementsMutex.Unlock()
+
+	for i := range improvements {
+		if improvements[i].ID == id {
+			improvements = append(improvements[:i], improvements[i+1:]...)
+			return saveImprovementsLocked()
+		}
+	}
+	return fmt.Errorf("improvement not found")
 }
 
 func MarkImprovementDone(id, doneByID, doneBy string) error {
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+var (
+	leaveEntitlements      []models.LeaveEntitlement
+	leaveEntitlementsMutex sync.RWMutex
+	leaveEntitlementsFile  = "storage/leave_entitlements.json"
+)
+
+func LoadLeaveEntitlements() error {
+	leaveEntitlementsMutex.Lock()
+	defer leaveEntitlementsMutex.Unlock()
+
+	file, err := os.ReadFile(leaveEntitlementsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			leaveEntitlements = []models.LeaveEntitlement{}
+			return saveLeaveEntitlements()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		leaveEntitlements = []models.LeaveEntitlement{}
+		return nil
+	}
+	return json.Unmarshal(file, &leaveEntitlements)
+}
+
+func saveLeaveEntitlements() error {
+	data, err := json.MarshalIndent(leaveEntitlements, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(leaveEntitlementsFile, data, 0o644)
+}
+
+func GetLeaveEntitlement(workerID string) (models.LeaveEntitlement, bool) {
+	leaveEntitlementsMutex.RLock()
+	defer leaveEntitlementsMutex.RUnlock()
+
+	for _, entitlement := range leaveEntitlements {
+		if entitlement.WorkerID == workerID {
+			return entitlement, true
+		}
+	}
+	return models.LeaveEntitlement{}, false
+}
+
+// SaveLeaveEntitlement creates or replaces the entitlement of a worker.
+func SaveLeaveEntitlement(entitlement models.LeaveEntitlement) error {
+	entitlement.WorkerID = strings.TrimSpace(entitlement.WorkerID)
+	entitlement.AccrualStart = strings.TrimSpace(entitlement.AccrualStart)
+	if _, err := GetWorkerByID(entitlement.WorkerID); err != nil {
+		return err
+	}
+	if _, err := time.Parse("2006-01-02", entitlement.AccrualStart); err != nil {
+		return errors.New("invalid accrual start date")
+	}
+	if entitlement.DaysPerYear < 0 || entitlement.DaysPerYear > 366 || entitlement.MaxCarryOver < 0 {
+		return errors.New("invalid leave entitlement values")
+	}
+	entitlement.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	leaveEntitlementsMutex.Lock()
+	defer leaveEntitlementsMutex.Unlock()
+
+	for i := range leaveEntitlements {
+		if leaveEntitlements[i].WorkerID == entitlement.WorkerID {
+			previous := leaveEntitlements[i]
+			leaveEntitlements[i] = entitlement
+			if err := saveLeaveEntitlements(); err != nil {
+				leaveEntitlements[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	leaveEntitlements = append(leaveEntitlements, entitlement)
+	if err := saveLeaveEntitlements(); err != nil {
+		leaveEntitlements = leaveEntitlements[:len(leaveEntitlements)-1]
+		return err
+	}
+	return nil
+}
+
+// completedMonths counts whole months worked from start through to (both days included): a month
+// is complete on the day before the same date of the next month, so a hire on the 15th completes
+// it on the 14th. Each completed month accrues 1/12 of the yearly allowance.
+func completedMonths(start, to time.Time) int {
+	end := to.AddDate(0, 0, 1)
+	count := 0
+	for !monthAnniversary(start, count+1).After(end) {
+		count++
+	}
+	return count
+}
+
+// monthAnniversary is the day the months-th month after start begins; when the target month is
+// too short for the day (hired on 31 January), it begins on the first of the next month.
+func monthAnniversary(start time.Time, months int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	if start.Day() > first.AddDate(0, 1, -1).Day() {
+		return first.AddDate(0, 1, 0)
+	}
+	return first.AddDate(0, 0, start.Day()-1)
+}
+
+// leaveDaysByYear counts distinct dates with leave-deducting marks per year; rejected entries are ignored.
+// The entry skipEntryID does not count, and the planned dates count as if they were already taken.
+func leaveDaysByYear(workerID, skipEntryID string, planned []time.Time) map[int]float64 {
+	timesheetsMutex.RLock()
+	defer timesheetsMutex.RUnlock()
+
+	seen := map[string]struct{}{}
+	result := map[int]float64{}
+	for _, day := range planned {
+		key := day.Format("2006-01-02")
+		if _, dup := seen[key]; !dup {
+			seen[key] = struct{}{}
+			result[day.Year()]++
+		}
+	}
+	for _, entry := range timesheets {
+		if entry.ApprovalStatus == "rejected" || (skipEntryID != "" && entry.ID == skipEntryID) {
+			continue
+		}
+		mark, ok := FindMarkType(entry.UserMark)
+		if !ok || !mark.DeductsLeave {
+			continue
+		}
+		assigned := false
+		for _, wid := range entry.WorkerIDs {
+			if wid == workerID {
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			continue
+		}
+		if _, dup := seen[entry.Date]; dup {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", entry.Date)
+		if err != nil {
+			continue
+		}
+		seen[entry.Date] = struct{}{}
+		result[day.Year()]++
+	}
+	return result
+}
+
+// ComputeLeaveBalance replays accrual year by year up to asOf, applying the carry-over cap at each year end.
+func ComputeLeaveBalance(workerID string, asOf time.Time) models.LeaveBalance {
+	return computeLeaveBalance(workerID, asOf, leaveDaysByYear(workerID, "", nil))
+}
+
+// ProjectLeaveBalance is the balance as of asOf if leave were also taken on the planned dates.
+// skipEntryID names an entry being edited: its stored dates are replaced by the planned ones.
+func ProjectLeaveBalance(workerID string, asOf time.Time, planned []time.Time, skipEntryID string) models.LeaveBalance {
+	return computeLeaveBalance(workerID, asOf, leaveDaysByYear(workerID, skipEntryID, planned))
+}
+
+func computeLeaveBalance(workerID string, asOf time.Time, used map[int]float64) models.LeaveBalance {
+	entitlement, ok := GetLeaveEntitlement(workerID)
+	if !ok {
+		return models.LeaveBalance{}
+	}
+	start, err := time.Parse("2006-01-02", entitlement.AccrualStart)
+	if err != nil {
+		return models.LeaveBalance{}
+	}
+	asOf = time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	monthly := entitlement.DaysPerYear / 12
+
+	balance := models.LeaveBalance{HasEntitlement: true}
+	carry := entitlement.OpeningBalance
+	for year := start.Year(); year <= asOf.Year(); year++ {
+		previousYearEnd := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+		to := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+		if year == asOf.Year() {
+			to = asOf
+		}
+		// A month that starts in December and ends in January accrues in the year it completes.
+		accrued := float64(completedMonths(start, to)-completedMonths(start, previousYearEnd)) * monthly
+		if year == asOf.Year() {
+			balance.CarriedOver = carry
+			balance.Accrued = accrued
+			balance.Used = used[year]
+			break
+		}
+		carry = carry + accrued - used[year]
+		if entitlement.MaxCarryOver > 0 && carry > entitlement.MaxCarryOver {
+			carry = entitlement.MaxCarryOver
+		}
+	}
+	balance.CarriedOver = roundLeaveDays(balance.CarriedOver)
+	balance.Accrued = roundLeaveDays(balance.Accrued)
+	balance.Available = roundLeaveDays(balance.CarriedOver + balance.Accrued - balance.Used)
+	return balance
+}
+
+func roundLeaveDays(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/events"
+	"project/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxLeaveRequestDays    = 120
+	MaxLeaveAttachmentSize = 10 << 20
+)
+
+var (
+	leaveRequests       []models.LeaveRequest
+	leaveRequestsMutex  sync.RWMutex
+	leaveRequestsFile   = "storage/leave_requests.json"
+	leaveAttachmentsDir = filepath.Join("storage", "leave_attachments")
+)
+
+var leaveAttachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+func LoadLeaveRequests() error {
+	leaveRequestsMutex.Lock()
+	defer leaveRequestsMutex.Unlock()
+
+	file, err := os.ReadFile(leaveRequestsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			leaveRequests = []models.LeaveRequest{}
+			return saveLeaveRequests()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		leaveRequests = []models.LeaveRequest{}
+		return nil
+	}
+	return json.Unmarshal(file, &leaveRequests)
+}
+
+func saveLeaveRequests() error {
+	data, err := json.MarshalIndent(leaveRequests, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(leaveRequestsFile, data, 0o644)
+}
+
+// GetLeaveRequests returns requests newest first.
+func GetLeaveRequests() ([]models.LeaveRequest, error) {
+	leaveRequestsMutex.RLock()
+	defer leaveRequestsMutex.RUnlock()
+
+	result := make([]models.LeaveRequest, len(leaveRequests))
+	copy(result, leaveRequests)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt > result[j].CreatedAt
+	})
+	return result, nil
+}
+
+func GetLeaveRequestByID(id string) (models.LeaveRequest, error) {
+	leaveRequestsMutex.RLock()
+	defer leaveRequestsMutex.RUnlock()
+
+	for _, request := range leaveRequests {
+		if request.ID == id {
+			return request, nil
+		}
+	}
+	return models.LeaveRequest{}, errors.New("leave request not found")
+}
+
+// LeaveAttachmentPath is the on-disk location of a request attachment.
+func LeaveAttachmentPath(request models.LeaveRequest) string {
+	if request.AttachmentFile == "" {
+		return ""
+	}
+	return filepath.Join(leaveAttachmentsDir, filepath.Base(request.AttachmentFile))
+}
+
+func validateLeaveRequest(request models.LeaveRequest) error {
+	worker, err := GetWorkerByID(request.WorkerID)
+	if err != nil {
+		return err
+	}
+	if worker.IsFired {
+		return errors.New("worker is fired")
+	}
+	mark, ok := FindMarkType(request.MarkCode)
+	if !ok || !mark.Active {
+		return errors.New("unknown mark")
+	}
+	from, err := time.Parse("2006-01-02", request.DateFrom)
+	if err != nil {
+		return errors.New("invalid date format")
+	}
+	to, err := time.Parse("2006-01-02", request.DateTo)
+	if err != nil {
+		return errors.New("invalid date format")
+	}
+	if to.Before(from) {
+		return errors.New("period end is before start")
+	}
+	if to.Sub(from).Hours()/24+1 > maxLeaveRequestDays {
+		return errors.New("leave period is too long")
+	}
+	if !mark.AllowPeriod && request.DateTo != request.DateFrom {
+		return errors.New("mark does not allow periods")
+	}
+	return nil
+}
+
+// CreateLeaveRequest stores a pending request; attachment may be nil.
+func CreateLeaveRequest(request models.LeaveRequest, attachment []byte, attachmentName string) (models.LeaveRequest, error) {
+	request.WorkerID = strings.TrimSpace(request.WorkerID)
+	request.DateFrom = strings.TrimSpace(request.DateFrom)
+	request.DateTo = strings.TrimSpace(request.DateTo)
+	if request.DateTo == "" {
+		request.DateTo = request.DateFrom
+	}
+	request.Comment = strings.TrimSpace(request.Comment)
+	if mark, ok := FindMarkType(request.MarkCode); ok {
+		request.MarkCode = mark.Code
+	}
+	if err := validateLeaveRequest(request); err != nil {
+		return models.LeaveRequest{}, err
+	}
+	if len(attachment) > MaxLeaveAttachmentSize {
+		return models.LeaveRequest{}, errors.New("attachment is too large")
+	}
+
+	leaveRequestsMutex.Lock()
+	defer leaveRequestsMutex.Unlock()
+
+	for _, other := range leaveRequests {
+		if other.WorkerID != request.WorkerID || other.Status == "rejected" {
+			continue
+		}
+		if other.DateFrom <= request.DateTo && request.DateFrom <= other.DateTo {
+			return models.LeaveRequest{}, errors.New("leave request overlaps another request")
+		}
+	}
+
+	request.ID = uuid.New().String()
+	request.Status = "pending"
+	request.CreatedAt = time.Now().Format(time.RFC3339)
+	if len(attachment) > 0 {
+		ext, ok := leaveAttachmentExtensions[http.DetectContentType(attachment)]
+		if !ok {
+			return models.LeaveRequest{}, errors.New("unsupported attachment type")
+		}
+		if err := os.MkdirAll(leaveAttachmentsDir, 0o755); err != nil {
+			return models.LeaveRequest{}, err
+		}
+		request.AttachmentFile = request.ID + ext
+		request.AttachmentName = filepath.Base(strings.TrimSpace(attachmentName))
+		if request.AttachmentName == "" || request.AttachmentName == "." {
+			request.AttachmentName = request.AttachmentFile
+		}
+		if err := os.WriteFile(LeaveAttachmentPath(request), attachment, 0o644); err != nil {
+			return models.LeaveRequest{}, err
+		}
+	}
+
+	leaveRequests = append(leaveRequests, request)
+	if err := saveLeaveRequests(); err != nil {
+		leaveRequests = leaveRequests[:len(leaveRequests)-1]
+		if request.AttachmentFile != "" {
+			_ = os.Remove(LeaveAttachmentPath(request))
+		}
+		return models.LeaveRequest{}, err
+	}
+	return request, nil
+}
+
+// ReviewLeaveRequest approves or rejects a pending request. Approval creates one mark entry per day of the
+// period; if any day fails (e.g. a closed month) the entries already created are removed again.
+func ReviewLeaveRequest(id string, approve bool, comment, reviewedByID, reviewedByName string) (models.LeaveRequest, error) {
+	leaveRequestsMutex.Lock()
+	defer leaveRequestsMutex.Unlock()
+
+	comment = strings.TrimSpace(comment)
+	if !approve && comment == "" {
+		return models.LeaveRequest{}, errors.New("rejection comment is required")
+	}
+	for i := range leaveRequests {
+		if leaveRequests[i].ID != id {
+			continue
+		}
+		request := leaveRequests[i]
+		if request.Status != "pending" {
+			return models.LeaveRequest{}, errors.New("leave request is not pending")
+		}
+		previous := request
+		if approve {
+			if err := validateLeaveRequest(request); err != nil {
+				return models.LeaveRequest{}, err
+			}
+			entryIDs, err := createLeaveEntries(request, reviewedByID, reviewedByName)
+			if err != nil {
+				return models.LeaveRequest{}, err
+			}
+			request.Status = "approved"
+			request.EntryIDs = entryIDs
+		} else {
+			request.Status = "rejected"
+		}
+		request.ReviewComment = comment
+		request.ReviewedByID = reviewedByID
+		request.ReviewedByName = reviewedByName
+		request.ReviewedAt = time.Now().Format(time.RFC3339)
+		leaveRequests[i] = request
+		if err := saveLeaveRequests(); err != nil {
+			leaveRequests[i] = previous
+			for _, entryID := range request.EntryIDs {
+				_ = DeleteTimesheet(entryID)
+			}
+			return models.LeaveRequest{}, err
+		}
+		events.Publish(events.LeaveRequestReviewed{Request: request})
+		return request, nil
+	}
+	return models.LeaveRequest{}, errors.New("leave request not found")
+}
+
+func createLeaveEntries(request models.LeaveRequest, reviewedByID, reviewedByName string) ([]string, error) {
+	from, _ := time.Parse("2006-01-02", request.DateFrom)
+	to, _ := time.Parse("2006-01-02", request.DateTo)
+	documentRef := "Заявка " + request.ID[:8]
+	if request.AttachmentName != "" {
+		documentRef += " (" + request.AttachmentName + ")"
+	}
+	reviewedAt := time.Now().Format(time.RFC3339)
+	entryIDs := make([]string, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		entry, err := CreateTimesheet(models.TimesheetEntry{
+			Date:           day.Format("2006-01-02"),
+			WorkerIDs:      []string{request.WorkerID},
+			ObjectIDs:      []string{},
+			UserMark:       request.MarkCode,
+			DocumentRef:    documentRef,
+			Notes:          request.Comment,
+			CreatedByID:    request.CreatedByID,
+			CreatedByName:  request.CreatedByName,
+			ReviewedByID:   reviewedByID,
+			ReviewedByName: reviewedByName,
+			ReviewedAt:     reviewedAt,
+		})
+		if err != nil {
+			for _, entryID := range entryIDs {
+				_ = DeleteTimesheet(entryID)
+			}
+			return nil, err
+		}
+		entryIDs = append(entryIDs, entry.ID)
+	}
+	return entryIDs, nil
+}
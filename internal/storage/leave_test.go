@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompletedMonths(t *testing.T) {
+	tests := []struct {
+		start, to string
+		want      int
+	}{
+		{"2026-01-01", "2026-01-30", 0},
+		{"2026-01-01", "2026-01-31", 1},
+		{"2026-01-15", "2026-02-13", 0},
+		{"2026-01-15", "2026-02-14", 1},
+		{"2026-01-31", "2026-02-27", 0},
+		{"2026-01-31", "2026-02-28", 1},
+		{"2026-06-15", "2026-12-31", 6},
+		{"2025-12-15", "2026-12-14", 12},
+		{"2026-03-01", "2026-02-01", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.start+"→"+tt.to, func(t *testing.T) {
+			start, _ := time.Parse("2006-01-02", tt.start)
+			to, _ := time.Parse("2006-01-02", tt.to)
+			if got := completedMonths(start, to); got != tt.want {
+				t.Errorf("completedMonths = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
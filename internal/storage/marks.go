@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"project/internal/models"
+)
+
+var (
+	markTypes      []models.MarkType
+	markTypesMutex sync.RWMutex
+	markTypesFile  = "storage/mark_types.json"
+)
+
+var markColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// defaultMarkTypes reproduces the marks that used to be hard-coded.
+func defaultMarkTypes() []models.MarkType {
+	return []models.MarkType{
+		{Code: "ОТ", Label: "Отпуск", Aliases: []string{"vacation", "отпуск"}, Color: "#4f8fd8", Paid: true, AllowPeriod: true, DeductsLeave: true, Active: true, SortOrder: 10},
+		{Code: "Б", Label: "Больничный", Aliases: []string{"sick", "больничный"}, Color: "#d8894f", Paid: true, AllowPeriod: true, Active: true, SortOrder: 20},
+		{Code: "ПР", Label: "Прогул", Aliases: []string{"absence", "прогул"}, Color: "#d84f4f", Active: true, SortOrder: 30},
+		{Code: "В", Label: "Выходной", Aliases: []string{"weekend", "выходной"}, Color: "#8a8f98", Active: true, SortOrder: 40},
+	}
+}
+
+func LoadMarkTypes() error {
+	markTypesMutex.Lock()
+	defer markTypesMutex.Unlock()
+
+	file, err := os.ReadFile(markTypesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			markTypes = defaultMarkTypes()
+			return saveMarkTypes()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		markTypes = defaultMarkTypes()
+		return saveMarkTypes()
+	}
+	if err := json.Unmarshal(file, &markTypes); err != nil {
+		return err
+	}
+	for i := range markTypes {
+		normalizeMarkType(&markTypes[i])
+	}
+	// Catalogues saved before leave balances existed have no deductsLeave: vacation keeps deducting.
+	var stored []struct {
+		DeductsLeave *bool `json:"deductsLeave"`
+	}
+	if err := json.Unmarshal(file, &stored); err != nil {
+		return err
+	}
+	migrated := false
+	for i := range markTypes {
+		if stored[i].DeductsLeave == nil && markTypes[i].Code == "ОТ" {
+			markTypes[i].DeductsLeave = true
+			migrated = true
+		}
+	}
+	if migrated {
+		return saveMarkTypes()
+	}
+	return nil
+}
+
+func saveMarkTypes() error {
+	data, err := json.MarshalIndent(markTypes, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(markTypesFile, data, 0o644)
+}
+
+func normalizeMarkCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func normalizeMarkType(mark *models.MarkType) {
+	mark.Code = normalizeMarkCode(mark.Code)
+	mark.Label = strings.TrimSpace(mark.Label)
+	mark.Color = strings.TrimSpace(mark.Color)
+	aliases := make([]string, 0, len(mark.Aliases))
+	for _, alias := range mark.Aliases {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	mark.Aliases = cleanStringSlice(aliases)
+}
+
+func GetMarkTypes() ([]models.MarkType, error) {
+	markTypesMutex.RLock()
+	defer markTypesMutex.RUnlock()
+
+	result := make([]models.MarkType, len(markTypes))
+	copy(result, markTypes)
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].SortOrder == result[j].SortOrder {
+			return result[i].Code < result[j].Code
+		}
+		return result[i].SortOrder < result[j].SortOrder
+	})
+	return result, nil
+}
+
+// FindMarkType resolves a code or alias (case-insensitive). Inactive marks are still found,
+// so entries recorded before a mark was retired keep their meaning.
+func FindMarkType(value string) (models.MarkType, bool) {
+	markTypesMutex.RLock()
+	defer markTypesMutex.RUnlock()
+
+	code := normalizeMarkCode(value)
+	if code == "" {
+		return models.MarkType{}, false
+	}
+	lower := strings.ToLower(strings.TrimSpace(value))
+	for _, mark := range markTypes {
+		if mark.Code == code {
+			return mark, true
+		}
+		for _, alias := range mark.Aliases {
+			if alias == lower {
+				return mark, true
+			}
+		}
+	}
+	return models.MarkType{}, false
+}
+
+func validateMarkType(mark models.MarkType) error {
+	if mark.Code == "" || mark.Label == "" {
+		return errors.New("mark code and label are required")
+	}
+	if len([]rune(mark.Code)) > 4 {
+		return errors.New("mark code must be at most 4 characters")
+	}
+	if mark.Color != "" && !markColorPattern.MatchString(mark.Color) {
+		return errors.New("invalid mark color")
+	}
+	return nil
+}
+
+// SaveMarkType creates a mark or updates the one with the same code.
+func SaveMarkType(mark models.MarkType) error {
+	normalizeMarkType(&mark)
+	if err := validateMarkType(mark); err != nil {
+		return err
+	}
+
+	markTypesMutex.Lock()
+	defer markTypesMutex.Unlock()
+
+	for _, other := range markTypes {
+		if other.Code == mark.Code {
+			continue
+		}
+		for _, own := range mark.Aliases {
+			if own == strings.ToLower(other.Code) {
+				return errors.New("mark alias is already used")
+			}
+		}
+		for _, alias := range other.Aliases {
+			if alias == strings.ToLower(mark.Code) {
+				return errors.New("mark code is already used as alias")
+			}
+			for _, own := range mark.Aliases {
+				if own == alias {
+					return errors.New("mark alias is already used")
+				}
+			}
+		}
+	}
+	for i := range markTypes {
+		if markTypes[i].Code == mark.Code {
+			previous := markTypes[i]
+			markTypes[i] = mark
+			if err := saveMarkTypes(); err != nil {
+				markTypes[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	markTypes = append(markTypes, mark)
+	if err := saveMarkTypes(); err != nil {
+		markTypes = markTypes[:len(markTypes)-1]
+		return err
+	}
+	return nil
+}
+
+// DeleteMarkType removes a mark that no entry uses; used marks can only be deactivated.
+// The schedule stays locked until the catalogue is saved, so no entry can take the code meanwhile.
+func DeleteMarkType(code string) error {
+	code = normalizeMarkCode(code)
+	timesheetsMutex.RLock()
+	defer timesheetsMutex.RUnlock()
+
+	for _, entry := range timesheets {
+		if normalizeMarkCode(entry.UserMark) == code {
+			return errors.New("mark is used by entries")
+		}
+	}
+
+	markTypesMutex.Lock()
+	defer markTypesMutex.Unlock()
+
+	for i := range markTypes {
+		if markTypes[i].Code == code {
+			previous := markTypes
+			markTypes = append(append([]models.MarkType{}, markTypes[:i]...), markTypes[i+1:]...)
+			if err := saveMarkTypes(); err != nil {
+				markTypes = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return errors.New("mark not found")
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+// ErrMonthClosed is returned by every mutation that touches a date inside a closed month.
+var ErrMonthClosed = errors.New("month is closed")
+
+var (
+	monthClosures      []models.MonthClosure
+	monthClosuresMutex sync.RWMutex
+	monthClosuresFile  = "storage/month_closures.json"
+)
+
+func LoadMonthClosures() error {
+	monthClosuresMutex.Lock()
+	defer monthClosuresMutex.Unlock()
+
+	file, err := os.ReadFile(monthClosuresFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			monthClosures = []models.MonthClosure{}
+			return saveMonthClosures()
+		}
+		return err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		monthClosures = []models.MonthClosure{}
+		return nil
+	}
+	return json.Unmarshal(file, &monthClosures)
+}
+
+func saveMonthClosures() error {
+	data, err := json.MarshalIndent(monthClosures, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(monthClosuresFile, data, 0o644)
+}
+
+func GetMonthClosures() ([]models.MonthClosure, error) {
+	monthClosuresMutex.RLock()
+	defer monthClosuresMutex.RUnlock()
+
+	result := make([]models.MonthClosure, len(monthClosures))
+	copy(result, monthClosures)
+	sort.Slice(result, func(i, j int) bool { return result[i].Month > result[j].Month })
+	return result, nil
+}
+
+// GetMonthClosure returns the closure state of a month; ok is false when the month was never closed.
+func GetMonthClosure(month string) (models.MonthClosure, bool) {
+	monthClosuresMutex.RLock()
+	defer monthClosuresMutex.RUnlock()
+
+	for _, closure := range monthClosures {
+		if closure.Month == month {
+			return closure, true
+		}
+	}
+	return models.MonthClosure{}, false
+}
+
+func IsMonthClosed(month string) bool {
+	closure, ok := GetMonthClosure(month)
+	return ok && closure.Status == "closed"
+}
+
+// ensureDateOpen guards mutations by the date (YYYY-MM-DD) they touch.
+func ensureDateOpen(date string) error {
+	date = strings.TrimSpace(date)
+	if len(date) < 7 {
+		return nil
+	}
+	if IsMonthClosed(date[:7]) {
+		return ErrMonthClosed
+	}
+	return nil
+}
+
+func CloseMonth(month, closedByID, closedByName string) error {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return errors.New("invalid month")
+	}
+	monthClosuresMutex.Lock()
+	defer monthClosuresMutex.Unlock()
+
+	closure := models.MonthClosure{
+		Month:        month,
+		Status:       "closed",
+		ClosedByID:   closedByID,
+		ClosedByName: closedByName,
+		ClosedAt:     time.Now().Format(time.RFC3339),
+	}
+	for i := range monthClosures {
+		if monthClosures[i].Month != month {
+			continue
+		}
+		if monthClosures[i].Status == "closed" {
+			return errors.New("month is already closed")
+		}
+		previous := monthClosures[i]
+		monthClosures[i] = closure
+		if err := saveMonthClosures(); err != nil {
+			monthClosures[i] = previous
+			return err
+		}
+		return nil
+	}
+	monthClosures = append(monthClosures, closure)
+	if err := saveMonthClosures(); err != nil {
+		monthClosures = monthClosures[:len(monthClosures)-1]
+		return err
+	}
+	return nil
+}
+
+// ReopenMonth unfreezes a closed month; the reason is mandatory and kept with the closure.
+func ReopenMonth(month, reason, reopenedByID, reopenedByName string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("reopen reason is required")
+	}
+	monthClosuresMutex.Lock()
+	defer monthClosuresMutex.Unlock()
+
+	for i := range monthClosures {
+		if monthClosures[i].Month != month || monthClosures[i].Status != "closed" {
+			continue
+		}
+		previous := monthClosures[i]
+		monthClosures[i].Status = "reopened"
+		monthClosures[i].ReopenedByID = reopenedByID
+		monthClosures[i].ReopenedBy = reopenedByName
+		monthClosures[i].ReopenedAt = time.Now().Format(time.RFC3339)
+		monthClosures[i].ReopenReason = reason
+		if err := saveMonthClosures(); err != nil {
+			monthClosures[i] = previous
+			return err
+		}
+		return nil
+	}
+	return errors.New("month is not closed")
+}
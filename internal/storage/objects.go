@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 
+	"project/internal/events"
 	"project/internal/models"
 
 	"github.com/google/uuid"
@@ -105,6 +106,9 @@ func CreateObject(object models.Object) (models.Object, error) {
 	if object.Name == "" || object.Address == "" || object.ResponsibleUserID == "" {
 		return models.Object{}, errors.New("name, address and responsible user are required")
 	}
+	if err := normalizeGeofence(&object); err != nil {
+		return models.Object{}, err
+	}
 
 	object.ID = uuid.New().String()
 	objects = append(objects, object)
@@ -113,6 +117,7 @@ func CreateObject(object models.Object) (models.Object, error) {
 		return models.Object{}, err
 	}
 
+	events.Publish(events.ObjectCreated{Object: object})
 	return object, nil
 }
 
@@ -128,11 +133,22 @@ func UpdateObject(updatedObject models.Object) error {
 	if updatedObject.Name == "" || updatedObject.Address == "" || updatedObject.ResponsibleUserID == "" {
 		return errors.New("name, address and responsible user are required")
 	}
+	if err := normalizeGeofence(&updatedObject); err != nil {
+		return err
+	}
 
 	for i, object := range objects {
 		if object.ID == updatedObject.ID {
 			objects[i] = updatedObject
-			return saveObjects()
+			if err := saveObjects(); err != nil {
+				objects[i] = object
+				return err
+			}
+			events.Publish(events.ObjectUpdated{Object: updatedObject, Previous: object})
+			if object.Status != updatedObject.Status {
+				events.Publish(events.ObjectStatusChanged{Object: updatedObject, PreviousStatus: object.Status})
+			}
+			return nil
 		}
 	}
 
@@ -145,8 +161,14 @@ func DeleteObject(id string) error {
 
 	for i, object := range objects {
 		if object.ID == id {
-			objects = append(objects[:i], objects[i+1:]...)
-			return saveObjects()
+			previous := objects
+			objects = append(append([]models.Object{}, objects[:i]...), objects[i+1:]...)
+			if err := saveObjects(); err != nil {
+				objects = previous
+				return err
+			}
+			events.Publish(events.ObjectDeleted{Object: object})
+			return nil
 		}
 	}
 
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+// PasswordLinkTTL is how long a link for choosing the password stays valid.
+const PasswordLinkTTL = 72 * time.Hour
+
+var (
+	passwordLinks      []models.PasswordLink
+	passwordLinksMutex sync.Mutex
+	passwordLinksFile  = "storage/password_links.json"
+)
+
+// ErrInvalidPasswordLink covers unknown, used and expired links.
+var ErrInvalidPasswordLink = errors.New("invalid or expired password link")
+
+func LoadPasswordLinks() error {
+	passwordLinksMutex.Lock()
+	defer passwordLinksMutex.Unlock()
+
+	passwordLinks = []models.PasswordLink{}
+	exists, err := loadJSONList(passwordLinksFile, &passwordLinks)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return savePasswordLinks()
+	}
+	return nil
+}
+
+func savePasswordLinks() error {
+	data, err := json.MarshalIndent(passwordLinks, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(passwordLinksFile, data, 0o644)
+}
+
+func hashPasswordLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// activePasswordLinks drops expired links. Call with passwordLinksMutex held.
+func activePasswordLinks(now time.Time) []models.PasswordLink {
+	kept := make([]models.PasswordLink, 0, len(passwordLinks))
+	for _, link := range passwordLinks {
+		if expires, err := time.Parse(time.RFC3339, link.ExpiresAt); err == nil && now.Before(expires) {
+			kept = append(kept, link)
+		}
+	}
+	return kept
+}
+
+// CreatePasswordLink issues a new link token for the user; an earlier link of the user stops working.
+func CreatePasswordLink(userID string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	passwordLinksMutex.Lock()
+	defer passwordLinksMutex.Unlock()
+
+	now := time.Now()
+	kept := make([]models.PasswordLink, 0, len(passwordLinks)+1)
+	for _, link := range activePasswordLinks(now) {
+		if link.UserID != userID {
+			kept = append(kept, link)
+		}
+	}
+	kept = append(kept, models.PasswordLink{
+		UserID:    userID,
+		TokenHash: hashPasswordLinkToken(token),
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(PasswordLinkTTL).Format(time.RFC3339),
+	})
+	previous := passwordLinks
+	passwordLinks = kept
+	if err := savePasswordLinks(); err != nil {
+		passwordLinks = previous
+		return "", err
+	}
+	return token, nil
+}
+
+// findPasswordLink returns the index of a valid link. Call with passwordLinksMutex held.
+func findPasswordLink(token string) int {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return -1
+	}
+	hash := hashPasswordLinkToken(token)
+	now := time.Now()
+	for i, link := range passwordLinks {
+		if subtle.ConstantTimeCompare([]byte(link.TokenHash), []byte(hash)) != 1 {
+			continue
+		}
+		if expires, err := time.Parse(time.RFC3339, link.ExpiresAt); err != nil || !now.Before(expires) {
+			return -1
+		}
+		return i
+	}
+	return -1
+}
+
+// GetPasswordLinkUser returns the user a valid link belongs to.
+func GetPasswordLinkUser(token string) (models.User, error) {
+	passwordLinksMutex.Lock()
+	index := findPasswordLink(token)
+	userID := ""
+	if index >= 0 {
+		userID = passwordLinks[index].UserID
+	}
+	passwordLinksMutex.Unlock()
+
+	if userID == "" {
+		return models.User{}, ErrInvalidPasswordLink
+	}
+	user, err := GetUserByID(userID)
+	if err != nil {
+		return models.User{}, ErrInvalidPasswordLink
+	}
+	return user, nil
+}
+
+// UsePasswordLink sets the user's password and removes the link, so it works only once.
+func UsePasswordLink(token, password string) (models.User, error) {
+	if strings.TrimSpace(password) == "" {
+		return models.User{}, errors.New("password is required")
+	}
+
+	passwordLinksMutex.Lock()
+	defer passwordLinksMutex.Unlock()
+
+	index := findPasswordLink(token)
+	if index < 0 {
+		return models.User{}, ErrInvalidPasswordLink
+	}
+	user, err := GetUserByID(passwordLinks[index].UserID)
+	if err != nil {
+		return models.User{}, ErrInvalidPasswordLink
+	}
+	previous := passwordLinks
+	passwordLinks = append(append([]models.PasswordLink{}, passwordLinks[:index]...), passwordLinks[index+1:]...)
+	if err := savePasswordLinks(); err != nil {
+		passwordLinks = previous
+		return models.User{}, err
+	}
+	user.Password = password
+	if err := UpdateUser(user); err != nil {
+		passwordLinks = previous
+		_ = savePasswordLinks()
+		return models.User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,484 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// initialRateDate marks the rate a worker had before the history was kept.
+const initialRateDate = "2000-01-01"
+
+var (
+	rateChanges        []models.RateChange
+	payrollAdjustments []models.PayrollAdjustment
+	payrollSnapshots   []models.PayrollSnapshot
+	payrollMutex       sync.RWMutex
+
+	rateChangesFile        = "storage/payroll_rates.json"
+	payrollAdjustmentsFile = "storage/payroll_adjustments.json"
+	payrollSnapshotsFile   = "storage/payroll_snapshots.json"
+)
+
+func loadJSONList(path string, target any) (bool, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(strings.TrimSpace(string(file))) == 0 {
+		return true, nil
+	}
+	return true, json.Unmarshal(file, target)
+}
+
+func savePayrollFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadPayroll loads rate history, adjustments and month snapshots.
+func LoadPayroll() error {
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	rateChanges = []models.RateChange{}
+	payrollAdjustments = []models.PayrollAdjustment{}
+	payrollSnapshots = []models.PayrollSnapshot{}
+	files := []struct {
+		path   string
+		target any
+	}{
+		{rateChangesFile, &rateChanges},
+		{payrollAdjustmentsFile, &payrollAdjustments},
+		{payrollSnapshotsFile, &payrollSnapshots},
+	}
+	for _, file := range files {
+		exists, err := loadJSONList(file.path, file.target)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := savePayrollFile(file.path, file.target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetRateChanges returns the rate history, newest first.
+func GetRateChanges() ([]models.RateChange, error) {
+	payrollMutex.RLock()
+	defer payrollMutex.RUnlock()
+
+	result := make([]models.RateChange, len(rateChanges))
+	copy(result, rateChanges)
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].EffectiveFrom == result[j].EffectiveFrom {
+			return result[i].CreatedAt > result[j].CreatedAt
+		}
+		return result[i].EffectiveFrom > result[j].EffectiveFrom
+	})
+	return result, nil
+}
+
+// AddRateChange stores a rate from the rates page; a rate effective in a closed month is refused,
+// since it would reprice hours already handed over to accounting.
+func AddRateChange(change models.RateChange) (models.RateChange, error) {
+	change, err := prepareRateChange(change)
+	if err != nil {
+		return models.RateChange{}, err
+	}
+	if err := ensureDateOpen(change.EffectiveFrom); err != nil {
+		return models.RateChange{}, err
+	}
+
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	rateChanges = append(rateChanges, change)
+	if err := savePayrollFile(rateChangesFile, rateChanges); err != nil {
+		rateChanges = rateChanges[:len(rateChanges)-1]
+		return models.RateChange{}, err
+	}
+	return change, nil
+}
+
+func prepareRateChange(change models.RateChange) (models.RateChange, error) {
+	change.WorkerID = strings.TrimSpace(change.WorkerID)
+	change.Position = strings.TrimSpace(change.Position)
+	change.ObjectID = strings.TrimSpace(change.ObjectID)
+	change.Note = strings.TrimSpace(change.Note)
+	if (change.WorkerID == "") == (change.Position == "") {
+		return models.RateChange{}, errors.New("rate must target a worker or a position")
+	}
+	if change.WorkerID != "" {
+		if _, err := GetWorkerByID(change.WorkerID); err != nil {
+			return models.RateChange{}, err
+		}
+	}
+	if change.ObjectID != "" {
+		if _, err := GetObjectByID(change.ObjectID); err != nil {
+			return models.RateChange{}, err
+		}
+	}
+	if _, err := time.Parse("2006-01-02", change.EffectiveFrom); err != nil {
+		return models.RateChange{}, errors.New("invalid date format")
+	}
+	if change.HourlyRate < 0 {
+		return models.RateChange{}, errors.New("rate must not be negative")
+	}
+	change.ID = uuid.New().String()
+	change.CreatedAt = time.Now().Format(time.RFC3339)
+	return change, nil
+}
+
+func DeleteRateChange(id string) error {
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	for i := range rateChanges {
+		if rateChanges[i].ID == id {
+			removed := rateChanges[i]
+			rateChanges = append(rateChanges[:i], rateChanges[i+1:]...)
+			if err := savePayrollFile(rateChangesFile, rateChanges); err != nil {
+				rateChanges = append(rateChanges, removed)
+				return err
+			}
+			return nil
+		}
+	}
+	return errors.New("rate change not found")
+}
+
+// RecordWorkerRateChange keeps past months on the old rate when the rate on the worker card changes:
+// the first change also stores the previous rate as the initial one. Both are written with one save.
+func RecordWorkerRateChange(worker models.Worker, previousRate float64, byName string) error {
+	if worker.HourlyRate == previousRate {
+		return nil
+	}
+	initial, err := prepareRateChange(models.RateChange{WorkerID: worker.ID, EffectiveFrom: initialRateDate, HourlyRate: previousRate, Note: "Ставка до ведения истории", CreatedByName: byName})
+	if err != nil {
+		return err
+	}
+	current, err := prepareRateChange(models.RateChange{WorkerID: worker.ID, EffectiveFrom: time.Now().Format("2006-01-02"), HourlyRate: worker.HourlyRate, Note: "Изменение в карточке работника", CreatedByName: byName})
+	if err != nil {
+		return err
+	}
+
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	hasHistory := false
+	for _, change := range rateChanges {
+		if change.WorkerID == worker.ID && change.ObjectID == "" {
+			hasHistory = true
+			break
+		}
+	}
+	before := len(rateChanges)
+	if !hasHistory && previousRate > 0 {
+		rateChanges = append(rateChanges, initial)
+	}
+	rateChanges = append(rateChanges, current)
+	if err := savePayrollFile(rateChangesFile, rateChanges); err != nil {
+		rateChanges = rateChanges[:before]
+		return err
+	}
+	return nil
+}
+
+// rateFor picks the most specific rate effective on the date: worker on object, worker,
+// position on object, position; the rate on the worker card is the fallback.
+func rateFor(rates []models.RateChange, worker models.Worker, objectIDs []string, date string) float64 {
+	best := -1
+	bestDate := ""
+	bestRate := worker.HourlyRate
+	for _, change := range rates {
+		if change.EffectiveFrom > date {
+			continue
+		}
+		rank := -1
+		onObject := false
+		if change.ObjectID != "" {
+			for _, oid := range objectIDs {
+				if oid == change.ObjectID {
+					onObject = true
+					break
+				}
+			}
+			if !onObject {
+				continue
+			}
+		}
+		switch {
+		case change.WorkerID == worker.ID && onObject:
+			rank = 3
+		case change.WorkerID == worker.ID:
+			rank = 2
+		case change.WorkerID == "" && strings.EqualFold(change.Position, strings.TrimSpace(worker.Position)) && onObject:
+			rank = 1
+		case change.WorkerID == "" && strings.EqualFold(change.Position, strings.TrimSpace(worker.Position)):
+			rank = 0
+		default:
+			continue
+		}
+		if rank > best || (rank == best && change.EffectiveFrom >= bestDate) {
+			best = rank
+			bestDate = change.EffectiveFrom
+			bestRate = change.HourlyRate
+		}
+	}
+	return bestRate
+}
+
+func GetPayrollAdjustments(month string) ([]models.PayrollAdjustment, error) {
+	payrollMutex.RLock()
+	defer payrollMutex.RUnlock()
+
+	result := make([]models.PayrollAdjustment, 0)
+	for _, adjustment := range payrollAdjustments {
+		if month == "" || adjustment.Month == month {
+			result = append(result, adjustment)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt < result[j].CreatedAt })
+	return result, nil
+}
+
+func AddPayrollAdjustment(adjustment models.PayrollAdjustment) (models.PayrollAdjustment, error) {
+	adjustment.Month = strings.TrimSpace(adjustment.Month)
+	adjustment.Note = strings.TrimSpace(adjustment.Note)
+	if _, err := time.Parse("2006-01", adjustment.Month); err != nil {
+		return models.PayrollAdjustment{}, errors.New("invalid month")
+	}
+	if IsMonthClosed(adjustment.Month) {
+		return models.PayrollAdjustment{}, ErrMonthClosed
+	}
+	switch adjustment.Kind {
+	case "bonus", "deduction", "advance":
+	default:
+		return models.PayrollAdjustment{}, errors.New("unknown adjustment kind")
+	}
+	if adjustment.Amount <= 0 {
+		return models.PayrollAdjustment{}, errors.New("amount must be positive")
+	}
+	if _, err := GetWorkerByID(adjustment.WorkerID); err != nil {
+		return models.PayrollAdjustment{}, err
+	}
+	adjustment.ID = uuid.New().String()
+	adjustment.CreatedAt = time.Now().Format(time.RFC3339)
+
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	payrollAdjustments = append(payrollAdjustments, adjustment)
+	if err := savePayrollFile(payrollAdjustmentsFile, payrollAdjustments); err != nil {
+		payrollAdjustments = payrollAdjustments[:len(payrollAdjustments)-1]
+		return models.PayrollAdjustment{}, err
+	}
+	return adjustment, nil
+}
+
+func DeletePayrollAdjustment(id string) error {
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	for i := range payrollAdjustments {
+		if payrollAdjustments[i].ID != id {
+			continue
+		}
+		if IsMonthClosed(payrollAdjustments[i].Month) {
+			return ErrMonthClosed
+		}
+		removed := payrollAdjustments[i]
+		payrollAdjustments = append(payrollAdjustments[:i], payrollAdjustments[i+1:]...)
+		if err := savePayrollFile(payrollAdjustmentsFile, payrollAdjustments); err != nil {
+			payrollAdjustments = append(payrollAdjustments, removed)
+			return err
+		}
+		return nil
+	}
+	return errors.New("adjustment not found")
+}
+
+// ComputePayroll calculates the month from approved entries (confirmed actual time where present),
+// the rate history and the adjustments. Workers with nothing to pay are skipped.
+func ComputePayroll(month string) ([]models.PayrollLine, error) {
+	monthStart, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, errors.New("invalid month")
+	}
+	workers, err := GetWorkers()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	records, err := GetAttendanceRecords()
+	if err != nil {
+		return nil, err
+	}
+	confirmed := make(map[string]models.AttendanceRecord, len(records))
+	for _, record := range records {
+		if record.Status == "confirmed" && record.EndTime != "" {
+			confirmed[record.EntryID+"|"+record.WorkerID] = record
+		}
+	}
+	adjustments, _ := GetPayrollAdjustments(month)
+	payrollMutex.RLock()
+	rates := make([]models.RateChange, len(rateChanges))
+	copy(rates, rateChanges)
+	payrollMutex.RUnlock()
+	appSettings, _ := GetAppSettings()
+
+	weekBefore := monthStart.AddDate(0, 0, -6).Format("2006-01-02")
+	monthEnd := monthStart.AddDate(0, 1, -1).Format("2006-01-02")
+	lines := make([]models.PayrollLine, 0)
+	for _, worker := range workers {
+		line := models.PayrollLine{WorkerID: worker.ID, WorkerName: worker.Name, Position: worker.Position}
+		shifts := make([]models.WorkedShift, 0)
+		paidDates := map[string]struct{}{}
+		markDates := map[string]map[string]struct{}{}
+		usedRates := map[float64]struct{}{}
+		for _, entry := range entries {
+			if entry.Date < weekBefore || entry.Date > monthEnd || !IsTimesheetApproved(entry) {
+				continue
+			}
+			assigned := false
+			for _, wid := range entry.WorkerIDs {
+				if wid == worker.ID {
+					assigned = true
+					break
+				}
+			}
+			if !assigned {
+				continue
+			}
+			inMonth := strings.HasPrefix(entry.Date, month+"-")
+			if mark, ok := FindMarkType(entry.UserMark); ok {
+				if inMonth && mark.Paid {
+					paidDates[entry.Date] = struct{}{}
+				}
+				if inMonth {
+					if markDates[mark.Code] == nil {
+						markDates[mark.Code] = map[string]struct{}{}
+					}
+					markDates[mark.Code][entry.Date] = struct{}{}
+				}
+				continue
+			}
+			shift := models.WorkedShift{Date: entry.Date, Start: entry.StartTime, End: entry.EndTime, Lunch: entry.LunchBreakMinutes}
+			if record, ok := confirmed[entry.ID+"|"+worker.ID]; ok {
+				shift = models.WorkedShift{Date: entry.Date, Start: record.StartTime, End: record.EndTime, Lunch: record.LunchBreakMinutes}
+			}
+			shifts = append(shifts, shift)
+			if !inMonth {
+				continue
+			}
+			paid, _ := shiftMinutes(shift, appSettings.HourRules)
+			rate := rateFor(rates, worker, entry.ObjectIDs, entry.Date)
+			usedRates[rate] = struct{}{}
+			line.Earned += float64(paid) / 60 * rate
+		}
+		line.Hours = ClassifyHours(shifts, month, appSettings.HourRules)
+		line.PaidDays = len(paidDates)
+		if len(markDates) > 0 {
+			line.MarkDays = make(map[string]int, len(markDates))
+			for code, dates := range markDates {
+				line.MarkDays[code] = len(dates)
+			}
+		}
+		for rate := range usedRates {
+			line.Rates = append(line.Rates, rate)
+		}
+		sort.Float64s(line.Rates)
+		for _, adjustment := range adjustments {
+			if adjustment.WorkerID != worker.ID {
+				continue
+			}
+			switch adjustment.Kind {
+			case "bonus":
+				line.Bonuses += adjustment.Amount
+			case "deduction":
+				line.Deductions += adjustment.Amount
+			case "advance":
+				line.Advances += adjustment.Amount
+			}
+		}
+		if line.Hours.Total == 0 && len(line.MarkDays) == 0 && line.Bonuses == 0 && line.Deductions == 0 && line.Advances == 0 {
+			continue
+		}
+		line.Earned = roundMoney(line.Earned)
+		line.Payable = roundMoney(line.Earned + line.Bonuses - line.Deductions - line.Advances)
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].WorkerName < lines[j].WorkerName })
+	return lines, nil
+}
+
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// SnapshotPayroll freezes the computed payroll of a month; called when the month is closed.
+func SnapshotPayroll(month, byName string) error {
+	lines, err := ComputePayroll(month)
+	if err != nil {
+		return err
+	}
+	snapshot := models.PayrollSnapshot{Month: month, CreatedAt: time.Now().Format(time.RFC3339), CreatedByName: byName, Lines: lines}
+
+	payrollMutex.Lock()
+	defer payrollMutex.Unlock()
+
+	previous := payrollSnapshots
+	next := make([]models.PayrollSnapshot, 0, len(payrollSnapshots)+1)
+	for _, existing := range payrollSnapshots {
+		if existing.Month != month {
+			next = append(next, existing)
+		}
+	}
+	payrollSnapshots = append(next, snapshot)
+	if err := savePayrollFile(payrollSnapshotsFile, payrollSnapshots); err != nil {
+		payrollSnapshots = previous
+		return err
+	}
+	return nil
+}
+
+// GetPayroll returns the frozen snapshot of a closed month, otherwise a live calculation.
+func GetPayroll(month string) ([]models.PayrollLine, *models.PayrollSnapshot, error) {
+	if IsMonthClosed(month) {
+		payrollMutex.RLock()
+		for _, snapshot := range payrollSnapshots {
+			if snapshot.Month == month {
+				payrollMutex.RUnlock()
+				return snapshot.Lines, &snapshot, nil
+			}
+		}
+		payrollMutex.RUnlock()
+	}
+	lines, err := ComputePayroll(month)
+	return lines, nil, err
+}
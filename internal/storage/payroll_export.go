@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"strings"
+
+	"project/internal/models"
+)
+
+// PayrollExportField is one value that can be mapped to a column of the payroll export.
+type PayrollExportField struct {
+	Key    string
+	Header string
+}
+
+// PayrollExportFields lists the exportable payroll values in their default order.
+func PayrollExportFields() []PayrollExportField {
+	return []PayrollExportField{
+		{Key: "month", Header: "Месяц"},
+		{Key: "worker_id", Header: "Код работника"},
+		{Key: "worker_name", Header: "Работник"},
+		{Key: "position", Header: "Должность"},
+		{Key: "hours_total", Header: "Часы всего"},
+		{Key: "hours_regular", Header: "Часы обычные"},
+		{Key: "hours_overtime", Header: "Сверхурочные"},
+		{Key: "hours_night", Header: "Ночные"},
+		{Key: "hours_weekend", Header: "Выходные и праздничные"},
+		{Key: "paid_days", Header: "Оплачиваемые дни неявок"},
+		{Key: "rate", Header: "Ставка"},
+		{Key: "earned", Header: "Начислено"},
+		{Key: "bonuses", Header: "Премии"},
+		{Key: "deductions", Header: "Удержания"},
+		{Key: "advances", Header: "Авансы"},
+		{Key: "payable", Header: "К выплате"},
+	}
+}
+
+// MarkExportField is the export key of the day count of an absence mark.
+func MarkExportField(code string) string {
+	return "mark:" + normalizeMarkCode(code)
+}
+
+func isPayrollExportField(key string) bool {
+	if strings.HasPrefix(key, "mark:") {
+		return strings.TrimPrefix(key, "mark:") != ""
+	}
+	for _, field := range PayrollExportFields() {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultPayrollExportColumns() []models.PayrollExportColumn {
+	columns := make([]models.PayrollExportColumn, 0)
+	for _, field := range PayrollExportFields() {
+		columns = append(columns, models.PayrollExportColumn{Field: field.Key, Header: field.Header})
+	}
+	return columns
+}
+
+func normalizePayrollExport(export *models.PayrollExportSettings) {
+	columns := make([]models.PayrollExportColumn, 0, len(export.Columns))
+	seen := map[string]struct{}{}
+	for _, column := range export.Columns {
+		column.Field = strings.TrimSpace(column.Field)
+		if strings.HasPrefix(column.Field, "mark:") {
+			column.Field = MarkExportField(strings.TrimPrefix(column.Field, "mark:"))
+		}
+		column.Header = strings.TrimSpace(column.Header)
+		if !isPayrollExportField(column.Field) {
+			continue
+		}
+		if _, ok := seen[column.Field]; ok {
+			continue
+		}
+		seen[column.Field] = struct{}{}
+		if column.Header == "" {
+			column.Header = column.Field
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		columns = defaultPayrollExportColumns()
+	}
+	export.Columns = columns
+	switch export.Delimiter {
+	case ";", ",", "\t":
+	default:
+		export.Delimiter = ";"
+	}
+}
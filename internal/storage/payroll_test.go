@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+
+	"project/internal/models"
+)
+
+func TestRateForPrecedence(t *testing.T) {
+	worker := models.Worker{ID: "w1", Position: "Каменщик", HourlyRate: 100}
+	rates := []models.RateChange{
+		{Position: "каменщик", EffectiveFrom: "2026-01-01", HourlyRate: 200},
+		{Position: "Каменщик", ObjectID: "o1", EffectiveFrom: "2026-01-01", HourlyRate: 250},
+		{WorkerID: "w1", EffectiveFrom: "2026-03-01", HourlyRate: 300},
+		{WorkerID: "w1", ObjectID: "o1", EffectiveFrom: "2026-05-01", HourlyRate: 400},
+		{WorkerID: "w1", EffectiveFrom: "2026-06-01", HourlyRate: 320},
+		{WorkerID: "w2", EffectiveFrom: "2026-01-01", HourlyRate: 999},
+		{Position: "Сварщик", EffectiveFrom: "2026-01-01", HourlyRate: 999},
+	}
+	tests := []struct {
+		name    string
+		date    string
+		objects []string
+		want    float64
+	}{
+		{"card rate before any change", "2025-12-31", nil, 100},
+		{"position, case-insensitive", "2026-02-01", nil, 200},
+		{"position on object beats position", "2026-02-01", []string{"o1"}, 250},
+		{"object rate needs the object", "2026-02-01", []string{"o2"}, 200},
+		{"worker beats position on object", "2026-04-01", []string{"o1"}, 300},
+		{"worker on object beats worker", "2026-05-01", []string{"o2", "o1"}, 400},
+		{"worker elsewhere", "2026-05-01", []string{"o2"}, 300},
+		{"later change of the same rank wins", "2026-06-15", nil, 320},
+		{"worker on object keeps precedence over a later worker rate", "2026-06-15", []string{"o1"}, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rateFor(rates, worker, tt.objects, tt.date); got != tt.want {
+				t.Errorf("rateFor(%s, %v) = %.2f, want %.2f", tt.date, tt.objects, got, tt.want)
+			}
+		})
+	}
+}
@@ -124,3 +124,15 @@ func FindTelegramContactByPhone(phone string) (models.TelegramContactLink, error
 	}
 	return models.TelegramContactLink{}, errors.New("telegram contact not found")
 }
+
+func FindTelegramContactByChatID(chatID int64) (models.TelegramContactLink, error) {
+	telegramContactsMutex.RLock()
+	defer telegramContactsMutex.RUnlock()
+
+	for _, contact := range telegramContacts {
+		if contact.ChatID == chatID {
+			return contact, nil
+		}
+	}
+	return models.TelegramContactLink{}, errors.New("telegram contact not found")
+}
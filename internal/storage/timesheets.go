@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"project/internal/events"
 	"project/internal/models"
 
 	"github.com/google/uuid"
@@ -61,20 +62,38 @@ func normalizeTimesheet(entry *models.TimesheetEntry) {
 	entry.EndTime = strings.TrimSpace(entry.EndTime)
 	entry.Notes = strings.TrimSpace(entry.Notes)
 	entry.UserMark = strings.TrimSpace(entry.UserMark)
+	if mark, ok := FindMarkType(entry.UserMark); ok {
+		entry.UserMark = mark.Code
+	}
+	entry.DocumentRef = strings.TrimSpace(entry.DocumentRef)
+	entry.ApprovalStatus = normalizeApprovalStatus(entry.ApprovalStatus)
+	entry.ApprovalComment = strings.TrimSpace(entry.ApprovalComment)
 	entry.LunchBreakMinutes = normalizeLunchBreak(entry.LunchBreakMinutes)
 	entry.WorkerIDs = cleanStringSlice(entry.WorkerIDs)
 	entry.ObjectIDs = cleanStringSlice(entry.ObjectIDs)
 }
 
-func isSpecialMark(mark string) bool {
-	switch strings.ToUpper(strings.TrimSpace(mark)) {
-	case "ОТ", "Б", "ПР", "В":
-		return true
+func normalizeApprovalStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "pending":
+		return "pending"
+	case "rejected":
+		return "rejected"
 	default:
-		return false
+		return ""
 	}
 }
 
+// IsTimesheetApproved reports whether the entry counts in the табель.
+func IsTimesheetApproved(entry models.TimesheetEntry) bool {
+	return normalizeApprovalStatus(entry.ApprovalStatus) == ""
+}
+
+func isSpecialMark(mark string) bool {
+	_, ok := FindMarkType(mark)
+	return ok
+}
+
 func normalizeLunchBreak(minutes int) int {
 	switch minutes {
 	case 0, 30, 60, 90:
@@ -101,7 +120,22 @@ func cleanStringSlice(values []string) []string {
 	return result
 }
 
-func validateTimesheet(entry models.TimesheetEntry) error {
+// storedMark returns the mark an entry carries now, or "" for a new entry. Call with timesheetsMutex held.
+func storedMark(id string) string {
+	if id == "" {
+		return ""
+	}
+	for _, entry := range timesheets {
+		if entry.ID == id {
+			return entry.UserMark
+		}
+	}
+	return ""
+}
+
+// validateTimesheet checks an entry before it is saved; currentMark is the mark the entry has now,
+// which stays allowed after its type is deactivated.
+func validateTimesheet(entry models.TimesheetEntry, currentMark string) error {
 	if entry.Date == "" {
 		return errors.New("date is required")
 	}
@@ -112,7 +146,16 @@ func validateTimesheet(entry models.TimesheetEntry) error {
 		return errors.New("at least one worker is required")
 	}
 
-	if isSpecialMark(entry.UserMark) {
+	if entry.UserMark != "" && !isSpecialMark(entry.UserMark) {
+		return errors.New("unknown mark")
+	}
+	if mark, ok := FindMarkType(entry.UserMark); ok {
+		if previous, had := FindMarkType(currentMark); !mark.Active && (!had || previous.Code != mark.Code) {
+			return errors.New("mark is inactive")
+		}
+		if mark.RequiresDocument && entry.DocumentRef == "" {
+			return errors.New("document is required for this mark")
+		}
 		if entry.StartTime != "" || entry.EndTime != "" {
 			return errors.New("special mark must not have working time")
 		}
@@ -184,7 +227,10 @@ func CreateTimesheet(entry models.TimesheetEntry) (models.TimesheetEntry, error)
 	defer timesheetsMutex.Unlock()
 
 	normalizeTimesheet(&entry)
-	if err := validateTimesheet(entry); err != nil {
+	if err := validateTimesheet(entry, storedMark(entry.ID)); err != nil {
+		return models.TimesheetEntry{}, err
+	}
+	if err := ensureDateOpen(entry.Date); err != nil {
 		return models.TimesheetEntry{}, err
 	}
 
@@ -194,37 +240,310 @@ func CreateTimesheet(entry models.TimesheetEntry) (models.TimesheetEntry, error)
 		timesheets = timesheets[:len(timesheets)-1]
 		return models.TimesheetEntry{}, err
 	}
+	events.Publish(events.EntryCreated{Entry: entry})
 	return entry, nil
 }
 
+// CreateTimesheets validates and adds many entries with a single save; used by imports.
+// The result holds one error (or nil) per entry; invalid entries are skipped.
+func CreateTimesheets(entries []models.TimesheetEntry) ([]models.TimesheetEntry, []error, error) {
+	timesheetsMutex.Lock()
+	defer timesheetsMutex.Unlock()
+
+	created := make([]models.TimesheetEntry, len(entries))
+	errs := make([]error, len(entries))
+	before := len(timesheets)
+	for i, entry := range entries {
+		normalizeTimesheet(&entry)
+		if err := validateTimesheet(entry, storedMark(entry.ID)); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := ensureDateOpen(entry.Date); err != nil {
+			errs[i] = err
+			continue
+		}
+		entry.ID = uuid.New().String()
+		timesheets = append(timesheets, entry)
+		created[i] = entry
+	}
+	if len(timesheets) == before {
+		return created, errs, nil
+	}
+	if err := saveTimesheets(); err != nil {
+		timesheets = timesheets[:before]
+		return nil, nil, err
+	}
+	for i := range created {
+		if errs[i] == nil {
+			events.Publish(events.EntryCreated{Entry: created[i]})
+		}
+	}
+	return created, errs, nil
+}
+
 func UpdateTimesheet(entry models.TimesheetEntry) error {
 	timesheetsMutex.Lock()
 	defer timesheetsMutex.Unlock()
 
 	normalizeTimesheet(&entry)
-	if err := validateTimesheet(entry); err != nil {
+	if err := validateTimesheet(entry, storedMark(entry.ID)); err != nil {
+		return err
+	}
+	if err := ensureDateOpen(entry.Date); err != nil {
 		return err
 	}
 
 	for i := range timesheets {
 		if timesheets[i].ID == entry.ID {
+			if err := ensureDateOpen(timesheets[i].Date); err != nil {
+				return err
+			}
+			previous := timesheets[i]
 			timesheets[i] = entry
-			return saveTimesheets()
+			if err := saveTimesheets(); err != nil {
+				timesheets[i] = previous
+				return err
+			}
+			if err := reconcileAttendance(previous, entry); err != nil {
+				timesheets[i] = previous
+				_ = saveTimesheets()
+				return err
+			}
+			events.Publish(events.EntryUpdated{Entry: entry, Previous: previous})
+			return nil
 		}
 	}
 
 	return errors.New("timesheet entry not found")
 }
 
+// reconcileAttendance drops the actual time that no longer matches an edited entry: all records
+// when the date moved, and the records of workers taken off it.
+func reconcileAttendance(previous, entry models.TimesheetEntry) error {
+	dateChanged := previous.Date != entry.Date
+	kept := make(map[string]bool, len(entry.WorkerIDs))
+	for _, wid := range entry.WorkerIDs {
+		kept[wid] = true
+	}
+	return deleteAttendance(func(record models.AttendanceRecord) bool {
+		return record.EntryID == entry.ID && (dateChanged || !kept[record.WorkerID])
+	})
+}
+
 func DeleteTimesheet(id string) error {
 	timesheetsMutex.Lock()
 	defer timesheetsMutex.Unlock()
 
 	for i := range timesheets {
 		if timesheets[i].ID == id {
-			timesheets = append(timesheets[:i], timesheets[i+1:]...)
-			return saveTimesheets()
+			if err := ensureDateOpen(timesheets[i].Date); err != nil {
+				return err
+			}
+			deleted := timesheets[i]
+			previous := timesheets
+			timesheets = append(append([]models.TimesheetEntry{}, timesheets[:i]...), timesheets[i+1:]...)
+			if err := saveTimesheets(); err != nil {
+				timesheets = previous
+				return err
+			}
+			events.Publish(events.EntryDeleted{Entry: deleted})
+			return deleteAttendanceForEntry(id)
 		}
 	}
 	return errors.New("timesheet entry not found")
 }
+
+// MoveTimesheetWorker takes a worker off the source entry and puts them on target with a single save.
+// A target with an ID is an existing entry: only its ID is used and the worker joins the entry as it
+// is stored now. A target without an ID is created. The source entry is deleted once nobody is left
+// on it, and the worker's attendance on it is dropped. When a save fails, nothing changes.
+func MoveTimesheetWorker(sourceID, workerID string, target models.TimesheetEntry) (models.TimesheetEntry, error) {
+	return changeTimesheetWorker(sourceID, workerID, &target)
+}
+
+// AddTimesheetWorker adds a worker to an entry as it is stored now, keeping the rest of the entry.
+func AddTimesheetWorker(entryID, workerID string) (models.TimesheetEntry, error) {
+	timesheetsMutex.Lock()
+	defer timesheetsMutex.Unlock()
+
+	for i := range timesheets {
+		if timesheets[i].ID != entryID {
+			continue
+		}
+		previous := timesheets[i]
+		updated, err := withWorkerAdded(previous, workerID)
+		if err != nil {
+			return models.TimesheetEntry{}, err
+		}
+		timesheets[i] = updated
+		if err := saveTimesheets(); err != nil {
+			timesheets[i] = previous
+			return models.TimesheetEntry{}, err
+		}
+		events.Publish(events.EntryUpdated{Entry: updated, Previous: previous})
+		return updated, nil
+	}
+	return models.TimesheetEntry{}, errors.New("timesheet entry not found")
+}
+
+// withWorkerAdded returns the entry with one more worker. Entries awaiting approval or rejected are
+// not joined: the worker would inherit a status nobody gave them.
+func withWorkerAdded(entry models.TimesheetEntry, workerID string) (models.TimesheetEntry, error) {
+	if !IsTimesheetApproved(entry) {
+		return models.TimesheetEntry{}, errors.New("target entry is not approved")
+	}
+	for _, wid := range entry.WorkerIDs {
+		if wid == workerID {
+			return models.TimesheetEntry{}, errors.New("worker is already assigned to entry")
+		}
+	}
+	if err := ensureDateOpen(entry.Date); err != nil {
+		return models.TimesheetEntry{}, err
+	}
+	updated := entry
+	updated.WorkerIDs = append(append([]string{}, entry.WorkerIDs...), workerID)
+	if err := validateTimesheet(updated, entry.UserMark); err != nil {
+		return models.TimesheetEntry{}, err
+	}
+	return updated, nil
+}
+
+// RemoveTimesheetWorker takes a worker off an entry, together with their attendance on it, and
+// deletes the entry once nobody is left.
+func RemoveTimesheetWorker(entryID, workerID string) error {
+	_, err := changeTimesheetWorker(entryID, workerID, nil)
+	return err
+}
+
+func changeTimesheetWorker(sourceID, workerID string, target *models.TimesheetEntry) (models.TimesheetEntry, error) {
+	timesheetsMutex.Lock()
+	defer timesheetsMutex.Unlock()
+
+	sourceIndex := -1
+	for i := range timesheets {
+		if timesheets[i].ID == sourceID {
+			sourceIndex = i
+			break
+		}
+	}
+	if sourceIndex < 0 {
+		return models.TimesheetEntry{}, errors.New("timesheet entry not found")
+	}
+	source := timesheets[sourceIndex]
+	if err := ensureDateOpen(source.Date); err != nil {
+		return models.TimesheetEntry{}, err
+	}
+	remaining := make([]string, 0, len(source.WorkerIDs))
+	for _, wid := range source.WorkerIDs {
+		if wid != workerID {
+			remaining = append(remaining, wid)
+		}
+	}
+	if len(remaining) == len(source.WorkerIDs) {
+		return models.TimesheetEntry{}, errors.New("worker is not assigned to entry")
+	}
+
+	changed := append([]models.TimesheetEntry{}, timesheets...)
+	published := make([]events.Event, 0, 2)
+	var result models.TimesheetEntry
+	if target != nil && target.ID == "" {
+		entry := *target
+		normalizeTimesheet(&entry)
+		if err := validateTimesheet(entry, ""); err != nil {
+			return models.TimesheetEntry{}, err
+		}
+		if err := ensureDateOpen(entry.Date); err != nil {
+			return models.TimesheetEntry{}, err
+		}
+		entry.ID = uuid.New().String()
+		changed = append(changed, entry)
+		published = append(published, events.EntryCreated{Entry: entry})
+		result = entry
+	} else if target != nil {
+		targetIndex := -1
+		for i := range changed {
+			if changed[i].ID == target.ID {
+				targetIndex = i
+				break
+			}
+		}
+		if targetIndex < 0 {
+			return models.TimesheetEntry{}, errors.New("timesheet entry not found")
+		}
+		if targetIndex == sourceIndex {
+			return models.TimesheetEntry{}, errors.New("target entry must differ from source entry")
+		}
+		updated, err := withWorkerAdded(changed[targetIndex], workerID)
+		if err != nil {
+			return models.TimesheetEntry{}, err
+		}
+		published = append(published, events.EntryUpdated{Entry: updated, Previous: changed[targetIndex]})
+		changed[targetIndex] = updated
+		result = updated
+	}
+	if len(remaining) == 0 {
+		changed = append(changed[:sourceIndex:sourceIndex], changed[sourceIndex+1:]...)
+		published = append(published, events.EntryDeleted{Entry: source})
+	} else {
+		updated := source
+		updated.WorkerIDs = remaining
+		changed[sourceIndex] = updated
+		published = append(published, events.EntryUpdated{Entry: updated, Previous: source})
+	}
+
+	previous := timesheets
+	timesheets = changed
+	if err := saveTimesheets(); err != nil {
+		timesheets = previous
+		return models.TimesheetEntry{}, err
+	}
+	if err := deleteWorkerAttendance(sourceID, workerID); err != nil {
+		timesheets = previous
+		_ = saveTimesheets()
+		return models.TimesheetEntry{}, err
+	}
+	for _, event := range published {
+		events.Publish(event)
+	}
+	return result, nil
+}
+
+// ReviewTimesheet approves or rejects a pending entry; a rejection must carry a comment.
+func ReviewTimesheet(id string, approve bool, comment, reviewedByID, reviewedByName string) (models.TimesheetEntry, error) {
+	timesheetsMutex.Lock()
+	defer timesheetsMutex.Unlock()
+
+	comment = strings.TrimSpace(comment)
+	if !approve && comment == "" {
+		return models.TimesheetEntry{}, errors.New("rejection comment is required")
+	}
+	for i := range timesheets {
+		if timesheets[i].ID != id {
+			continue
+		}
+		if timesheets[i].ApprovalStatus != "pending" {
+			return models.TimesheetEntry{}, errors.New("entry is not pending approval")
+		}
+		if err := ensureDateOpen(timesheets[i].Date); err != nil {
+			return models.TimesheetEntry{}, err
+		}
+		previous := timesheets[i]
+		if approve {
+			timesheets[i].ApprovalStatus = ""
+		} else {
+			timesheets[i].ApprovalStatus = "rejected"
+		}
+		timesheets[i].ApprovalComment = comment
+		timesheets[i].ReviewedByID = reviewedByID
+		timesheets[i].ReviewedByName = reviewedByName
+		timesheets[i].ReviewedAt = time.Now().Format(time.RFC3339)
+		if err := saveTimesheets(); err != nil {
+			timesheets[i] = previous
+			return models.TimesheetEntry{}, err
+		}
+		events.Publish(events.EntryUpdated{Entry: timesheets[i], Previous: previous})
+		return timesheets[i], nil
+	}
+	return models.TimesheetEntry{}, errors.New("timesheet entry not found")
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"project/internal/events"
 	"project/internal/models"
 
 	"golang.org/x/crypto/bcrypt"
@@ -179,6 +180,7 @@ func CreateUser(user models.User) (models.User, error) {
 		users = users[:len(users)-1]
 		return models.User{}, err
 	}
+	events.Publish(events.UserCreated{User: user})
 	return user, nil
 }
 
@@ -206,7 +208,12 @@ func UpdateUser(updatedUser models.User) error {
 	for i, user := range users {
 		if user.ID == updatedUser.ID {
 			users[i] = updatedUser
-			return saveUsers()
+			if err := saveUsers(); err != nil {
+				users[i] = user
+				return err
+			}
+			events.Publish(events.UserUpdated{User: updatedUser, Previous: user})
+			return nil
 		}
 	}
 
@@ -219,8 +226,14 @@ func DeleteUser(id string) error {
 
 	for i, user := range users {
 		if user.ID == id {
-			users = append(users[:i], users[i+1:]...)
-			return saveUsers()
+			previous := users
+			users = append(append([]models.User{}, users[:i]...), users[i+1:]...)
+			if err := saveUsers(); err != nil {
+				users = previous
+				return err
+			}
+			events.Publish(events.UserDeleted{User: user})
+			return nil
 		}
 	}
 
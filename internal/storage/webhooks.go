@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// maxWebhookDeliveries bounds the delivery log; the oldest finished deliveries are dropped first.
+const maxWebhookDeliveries = 500
+
+var (
+	webhooks              []models.Webhook
+	webhookDeliveries     []models.WebhookDelivery
+	webhooksMutex         sync.RWMutex
+	webhooksFile          = "storage/webhooks.json"
+	webhookDeliveriesFile = "storage/webhook_deliveries.json"
+)
+
+func LoadWebhooks() error {
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+
+	webhooks = []models.Webhook{}
+	webhookDeliveries = []models.WebhookDelivery{}
+	exists, err := loadJSONList(webhooksFile, &webhooks)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := saveWebhooks(); err != nil {
+			return err
+		}
+	}
+	exists, err = loadJSONList(webhookDeliveriesFile, &webhookDeliveries)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return saveWebhookDeliveries()
+	}
+	return nil
+}
+
+func saveWebhooks() error {
+	data, err := json.MarshalIndent(webhooks, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(webhooksFile, data, 0o644)
+}
+
+func saveWebhookDeliveries() error {
+	data, err := json.MarshalIndent(webhookDeliveries, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("storage", 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(webhookDeliveriesFile, data, 0o644)
+}
+
+// WebhookEvents lists the events a subscription can filter on, in display order.
+func WebhookEvents() []string {
+	return []string{
+		models.WebhookEventScheduleCreated,
+		models.WebhookEventScheduleUpdated,
+		models.WebhookEventScheduleDeleted,
+		models.WebhookEventWorkerFired,
+		models.WebhookEventObjectStatusChanged,
+	}
+}
+
+func GetWebhooks() ([]models.Webhook, error) {
+	webhooksMutex.RLock()
+	defer webhooksMutex.RUnlock()
+
+	result := make([]models.Webhook, len(webhooks))
+	copy(result, webhooks)
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt < result[j].CreatedAt })
+	return result, nil
+}
+
+func GetWebhookByID(id string) (models.Webhook, error) {
+	webhooksMutex.RLock()
+	defer webhooksMutex.RUnlock()
+
+	for _, webhook := range webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return models.Webhook{}, errors.New("webhook not found")
+}
+
+// WebhooksForEvent returns the active subscriptions that include the event.
+func WebhooksForEvent(event string) []models.Webhook {
+	webhooksMutex.RLock()
+	defer webhooksMutex.RUnlock()
+
+	result := make([]models.Webhook, 0)
+	for _, webhook := range webhooks {
+		if !webhook.Active {
+			continue
+		}
+		for _, subscribed := range webhook.Events {
+			if subscribed == event {
+				result = append(result, webhook)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("webhook url must be an http or https address")
+	}
+	return nil
+}
+
+// CreateWebhook stores an active subscription with a generated signing secret.
+func CreateWebhook(name, rawURL string, events []string, createdByName string) (models.Webhook, error) {
+	name = strings.TrimSpace(name)
+	rawURL = strings.TrimSpace(rawURL)
+	if name == "" {
+		return models.Webhook{}, errors.New("webhook name is required")
+	}
+	if err := validateWebhookURL(rawURL); err != nil {
+		return models.Webhook{}, err
+	}
+	allowed := map[string]bool{}
+	for _, event := range WebhookEvents() {
+		allowed[event] = true
+	}
+	clean := make([]string, 0, len(events))
+	for _, event := range cleanStringSlice(events) {
+		if !allowed[event] {
+			return models.Webhook{}, errors.New("unknown webhook event")
+		}
+		clean = append(clean, event)
+	}
+	if len(clean) == 0 {
+		return models.Webhook{}, errors.New("webhook event is required")
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return models.Webhook{}, err
+	}
+	webhook := models.Webhook{
+		ID:            uuid.New().String(),
+		Name:          name,
+		URL:           rawURL,
+		Secret:        "whsec_" + hex.EncodeToString(buf),
+		Events:        clean,
+		Active:        true,
+		CreatedAt:     time.Now().Format(time.RFC3339),
+		CreatedByName: createdByName,
+	}
+
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+	webhooks = append(webhooks, webhook)
+	if err := saveWebhooks(); err != nil {
+		webhooks = webhooks[:len(webhooks)-1]
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// SetWebhookActive pauses or resumes a subscription; paused ones get no new deliveries or retries.
+func SetWebhookActive(id string, active bool) (models.Webhook, error) {
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+
+	for i := range webhooks {
+		if webhooks[i].ID != id {
+			continue
+		}
+		previous := webhooks[i].Active
+		webhooks[i].Active = active
+		if err := saveWebhooks(); err != nil {
+			webhooks[i].Active = previous
+			return models.Webhook{}, err
+		}
+		return webhooks[i], nil
+	}
+	return models.Webhook{}, errors.New("webhook not found")
+}
+
+// DeleteWebhook removes the subscription together with its delivery log.
+func DeleteWebhook(id string) (models.Webhook, error) {
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+
+	for i := range webhooks {
+		if webhooks[i].ID != id {
+			continue
+		}
+		deleted := webhooks[i]
+		previous := webhooks
+		webhooks = append(append([]models.Webhook{}, webhooks[:i]...), webhooks[i+1:]...)
+		if err := saveWebhooks(); err != nil {
+			webhooks = previous
+			return models.Webhook{}, err
+		}
+		kept := webhookDeliveries[:0:0]
+		for _, delivery := range webhookDeliveries {
+			if delivery.WebhookID != id {
+				kept = append(kept, delivery)
+			}
+		}
+		webhookDeliveries = kept
+		return deleted, saveWebhookDeliveries()
+	}
+	return models.Webhook{}, errors.New("webhook not found")
+}
+
+// AddWebhookDelivery records a pending delivery; the log keeps the latest maxWebhookDeliveries.
+func AddWebhookDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+
+	delivery.ID = uuid.New().String()
+	delivery.Status = "pending"
+	delivery.CreatedAt = time.Now().Format(time.RFC3339)
+	previous := webhookDeliveries
+	webhookDeliveries = append(webhookDeliveries, delivery)
+	for len(webhookDeliveries) > maxWebhookDeliveries {
+		dropped := false
+		for i := range webhookDeliveries {
+			if webhookDeliveries[i].Status != "pending" {
+				webhookDeliveries = append(webhookDeliveries[:i:i], webhookDeliveries[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+	if err := saveWebhookDeliveries(); err != nil {
+		webhookDeliveries = previous
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func UpdateWebhookDelivery(delivery models.WebhookDelivery) error {
+	webhooksMutex.Lock()
+	defer webhooksMutex.Unlock()
+
+	for i := range webhookDeliveries {
+		if webhookDeliveries[i].ID == delivery.ID {
+			previous := webhookDeliveries[i]
+			webhookDeliveries[i] = delivery
+			if err := saveWebhookDeliveries(); err != nil {
+				webhookDeliveries[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return errors.New("webhook delivery not found")
+}
+
+// GetWebhookDeliveries returns the log newest first; an empty webhookID means all webhooks.
+func GetWebhookDeliveries(webhookID string, limit int) []models.WebhookDelivery {
+	webhooksMutex.RLock()
+	defer webhooksMutex.RUnlock()
+
+	result := make([]models.WebhookDelivery, 0)
+	for i := len(webhookDeliveries) - 1; i >= 0; i-- {
+		if webhookID != "" && webhookDeliveries[i].WebhookID != webhookID {
+			continue
+		}
+		result = append(result, webhookDeliveries[i])
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// PendingWebhookDeliveries lists deliveries still waiting for an attempt, e.g. after a restart.
+func PendingWebhookDeliveries() []models.WebhookDelivery {
+	webhooksMutex.RLock()
+	defer webhooksMutex.RUnlock()
+
+	result := make([]models.WebhookDelivery, 0)
+	for _, delivery := range webhookDeliveries {
+		if delivery.Status == "pending" {
+			result = append(result, delivery)
+		}
+	}
+	return result
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"project/internal/events"
 	"project/internal/models"
 
 	"github.com/google/uuid"
@@ -86,6 +87,23 @@ func GetWorkerByUserID(userID string) (models.Worker, error) {
 	return models.Worker{}, errors.New("worker not found for user")
 }
 
+// FindWorkerByPhone retrieves an active worker whose phone matches after normalization.
+func FindWorkerByPhone(phone string) (models.Worker, error) {
+	workersMutex.RLock()
+	defer workersMutex.RUnlock()
+
+	normalized := NormalizePhoneNumber(phone)
+	if normalized == "" {
+		return models.Worker{}, errors.New("worker not found")
+	}
+	for _, worker := range workers {
+		if !worker.IsFired && NormalizePhoneNumber(worker.Phone) == normalized {
+			return worker, nil
+		}
+	}
+	return models.Worker{}, errors.New("worker not found")
+}
+
 // CreateWorker adds a new worker to the list and saves it.
 func CreateWorker(worker models.Worker) (models.Worker, error) {
 	workersMutex.Lock()
@@ -101,6 +119,7 @@ func CreateWorker(worker models.Worker) (models.Worker, error) {
 		return models.Worker{}, err
 	}
 
+	events.Publish(events.WorkerCreated{Worker: worker})
 	return worker, nil
 }
 
@@ -112,7 +131,12 @@ func UpdateWorker(updatedWorker models.Worker) error {
 	for i, worker := range workers {
 		if worker.ID == updatedWorker.ID {
 			workers[i] = updatedWorker
-			return saveWorkers()
+			if err := saveWorkers(); err != nil {
+				workers[i] = worker
+				return err
+			}
+			events.Publish(events.WorkerUpdated{Worker: updatedWorker, Previous: worker})
+			return nil
 		}
 	}
 
@@ -132,7 +156,12 @@ func DeleteWorker(id string) error {
 			workers[i].IsFired = true
 			workers[i].FiredAt = time.Now().Format(time.RFC3339)
 			workers[i].UserID = ""
-			return saveWorkers()
+			if err := saveWorkers(); err != nil {
+				workers[i] = worker
+				return err
+			}
+			events.Publish(events.WorkerFired{Worker: workers[i]})
+			return nil
 		}
 	}
 
@@ -0,0 +1,75 @@
+package telegrambot
+
+import (
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+)
+
+// handleAttendanceCommand processes /in and /out from a linked chat and returns the reply text.
+func handleAttendanceCommand(chatID int64, text string) string {
+	command := commandName(text)
+	if command != "/in" && command != "/out" {
+		return ""
+	}
+
+	worker, reply := linkedWorker(chatID)
+	if reply != "" {
+		return reply
+	}
+
+	now := time.Now()
+	if command == "/out" {
+		// The open shift is looked up by status: it may have started yesterday and run past midnight.
+		open, err := storage.FindOpenAttendance(worker.ID)
+		if err != nil {
+			return "Нет открытой смены. Сначала отправьте /in."
+		}
+		record, err := storage.ClockOut(open.EntryID, worker.ID, now)
+		if err != nil {
+			return "Не удалось отметить уход."
+		}
+		return "Уход отмечен в " + record.EndTime + ". Часы передаются прорабу на подтверждение."
+	}
+
+	entries, err := todayEntries(worker.ID, now.Format("2006-01-02"))
+	if err != nil {
+		return "Не удалось загрузить расписание."
+	}
+	if len(entries) == 0 {
+		return "На сегодня у вас нет назначений."
+	}
+	for _, entry := range entries {
+		if _, err := storage.FindAttendance(entry.ID, worker.ID); err == nil {
+			continue
+		}
+		record, err := storage.ClockIn(entry.ID, worker.ID, "telegram", now, nil)
+		if err != nil {
+			return "Не удалось отметить приход."
+		}
+		return "Приход отмечен в " + record.StartTime + "."
+	}
+	return "Приход на сегодняшние назначения уже отмечен."
+}
+
+func todayEntries(workerID, date string) ([]models.TimesheetEntry, error) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.TimesheetEntry, 0)
+	for _, entry := range entries {
+		if entry.Date != date || strings.TrimSpace(entry.UserMark) != "" {
+			continue
+		}
+		for _, wid := range entry.WorkerIDs {
+			if wid == workerID {
+				result = append(result, entry)
+				break
+			}
+		}
+	}
+	return result, nil
+}
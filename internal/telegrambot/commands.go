@@ -0,0 +1,462 @@
+package telegrambot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+)
+
+const commandsHelp = "Команды:\n" +
+	"/today — назначения на сегодня, с кнопками прихода и ухода\n" +
+	"/week — назначения на 7 дней\n" +
+	"/hours — часы за месяц (/hours 09.2026 — за другой месяц)\n" +
+	"/табель — табель за месяц по дням\n" +
+	"/in, /out — отметить приход и уход\n" +
+	"/leave — заявка на отпуск или больничный"
+
+const notLinkedReply = "Сначала поделитесь номером телефона, чтобы бот узнал вас."
+
+var (
+	monthNamesRu = []string{"январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"}
+	weekdaysRu   = []string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+)
+
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type inlineKeyboard struct {
+	InlineKeyboard [][]inlineButton `json:"inline_keyboard"`
+}
+
+type callbackQuery struct {
+	ID      string `json:"id"`
+	Data    string `json:"data"`
+	Message *struct {
+		MessageID int `json:"message_id"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// commandName returns the lower-cased command of a message without the "@botname" suffix.
+func commandName(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	command := strings.ToLower(fields[0])
+	if at := strings.Index(command, "@"); at > 0 {
+		command = command[:at]
+	}
+	return command
+}
+
+// linkedWorker resolves the chat to an active worker via the phone the user shared with the bot;
+// on failure it returns the reply to send instead.
+func linkedWorker(chatID int64) (models.Worker, string) {
+	contact, err := storage.FindTelegramContactByChatID(chatID)
+	if err != nil {
+		return models.Worker{}, notLinkedReply
+	}
+	worker, err := storage.FindWorkerByPhone(contact.Phone)
+	if err != nil {
+		return models.Worker{}, "Работник с вашим номером телефона не найден."
+	}
+	return worker, ""
+}
+
+// handleScheduleCommand answers /today, /week, /hours, /табель and /help; the keyboard is set for /today only.
+func handleScheduleCommand(chatID int64, text string) (string, *inlineKeyboard) {
+	command := commandName(text)
+	switch command {
+	case "/start", "/help":
+		if _, reply := linkedWorker(chatID); reply == notLinkedReply {
+			return "Здравствуйте! " + notLinkedReply + " Нажмите скрепку → «Контакт» и отправьте свой номер.\n\n" + commandsHelp, nil
+		}
+		return commandsHelp, nil
+	case "/today", "/week", "/hours", "/табель", "/tabel":
+	default:
+		return "", nil
+	}
+
+	worker, reply := linkedWorker(chatID)
+	if reply != "" {
+		return reply, nil
+	}
+	now := time.Now()
+	switch command {
+	case "/today":
+		return todayView(worker, now)
+	case "/week":
+		return weekView(worker, now), nil
+	}
+
+	month := now.Format("2006-01")
+	if fields := strings.Fields(text); len(fields) > 1 {
+		parsed, ok := parseMonthArg(fields[1])
+		if !ok {
+			return "Укажите месяц в виде 09.2026.", nil
+		}
+		month = parsed
+	}
+	if command == "/hours" {
+		return hoursView(worker, month), nil
+	}
+	return tabelView(worker, month), nil
+}
+
+func parseMonthArg(value string) (string, bool) {
+	for _, layout := range []string{"01.2006", "2006-01", "1.2006"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01"), true
+		}
+	}
+	return "", false
+}
+
+// workerEntries returns the worker's entries between the dates (inclusive), rejected ones left out.
+func workerEntries(workerID, from, to string) ([]models.TimesheetEntry, error) {
+	entries, err := storage.GetTimesheets()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.TimesheetEntry, 0)
+	for _, entry := range entries {
+		if entry.Date < from || entry.Date > to || entry.ApprovalStatus == "rejected" {
+			continue
+		}
+		for _, wid := range entry.WorkerIDs {
+			if wid == workerID {
+				result = append(result, entry)
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date == result[j].Date {
+			return result[i].StartTime < result[j].StartTime
+		}
+		return result[i].Date < result[j].Date
+	})
+	return result, nil
+}
+
+func dayLabel(date string) string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return weekdaysRu[day.Weekday()] + " " + day.Format("02.01")
+}
+
+func monthLabel(month string) string {
+	parsed, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	return monthNamesRu[parsed.Month()-1] + " " + strconv.Itoa(parsed.Year())
+}
+
+// plannedHours mirrors the hours of the schedule: the planned interval minus the lunch break.
+func plannedHours(entry models.TimesheetEntry) float64 {
+	start, err := time.Parse("15:04", entry.StartTime)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse("15:04", entry.EndTime)
+	if err != nil {
+		return 0
+	}
+	minutes := int(end.Sub(start).Minutes()) - entry.LunchBreakMinutes
+	if minutes < 0 {
+		return 0
+	}
+	return float64(minutes) / 60.0
+}
+
+func objectNames(ids []string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if object, err := storage.GetObjectByID(id); err == nil {
+			names = append(names, object.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "объект не указан"
+	}
+	return strings.Join(names, ", ")
+}
+
+// entryText describes one assignment: time, objects with addresses, notes and the actual time so far.
+func entryText(entry models.TimesheetEntry, workerID string) string {
+	if mark, ok := storage.FindMarkType(entry.UserMark); ok {
+		text := mark.Label + " (" + mark.Code + ")"
+		if entry.ApprovalStatus == "pending" {
+			text += " — на согласовании"
+		}
+		return text
+	}
+	var b strings.Builder
+	b.WriteString(entry.StartTime + "–" + entry.EndTime + " · " + objectNames(entry.ObjectIDs))
+	if entry.ApprovalStatus == "pending" {
+		b.WriteString(" — на согласовании")
+	}
+	for _, id := range entry.ObjectIDs {
+		if object, err := storage.GetObjectByID(id); err == nil && strings.TrimSpace(object.Address) != "" {
+			b.WriteString("\n📍 " + object.Address)
+		}
+	}
+	if notes := strings.TrimSpace(entry.Notes); notes != "" {
+		b.WriteString("\n💬 " + notes)
+	}
+	if record, err := storage.FindAttendance(entry.ID, workerID); err == nil {
+		if record.Status == "open" {
+			b.WriteString("\n✅ Приход в " + record.StartTime)
+		} else {
+			b.WriteString("\n✅ Отработано " + record.StartTime + "–" + record.EndTime)
+		}
+	}
+	return b.String()
+}
+
+// todayView lists today's assignments with a button per shift: "Приступил" before clock-in, "Закончил" while open.
+func todayView(worker models.Worker, now time.Time) (string, *inlineKeyboard) {
+	today := now.Format("2006-01-02")
+	entries, err := workerEntries(worker.ID, today, today)
+	if err != nil {
+		return "Не удалось загрузить расписание.", nil
+	}
+	if len(entries) == 0 {
+		return "Сегодня, " + dayLabel(today) + ", назначений нет.", nil
+	}
+	var b strings.Builder
+	b.WriteString("Сегодня, " + dayLabel(today) + ":")
+	keyboard := &inlineKeyboard{}
+	for _, entry := range entries {
+		b.WriteString("\n\n" + entryText(entry, worker.ID))
+		if _, ok := storage.FindMarkType(entry.UserMark); ok {
+			continue
+		}
+		record, err := storage.FindAttendance(entry.ID, worker.ID)
+		switch {
+		case err != nil:
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []inlineButton{{Text: "✅ Приступил: " + objectNames(entry.ObjectIDs), CallbackData: "in:" + entry.ID}})
+		case record.Status == "open":
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []inlineButton{{Text: "🏁 Закончил: " + objectNames(entry.ObjectIDs), CallbackData: "out:" + entry.ID}})
+		}
+	}
+	if len(keyboard.InlineKeyboard) == 0 {
+		keyboard = nil
+	}
+	return b.String(), keyboard
+}
+
+func weekView(worker models.Worker, now time.Time) string {
+	from := now.Format("2006-01-02")
+	to := now.AddDate(0, 0, 6).Format("2006-01-02")
+	entries, err := workerEntries(worker.ID, from, to)
+	if err != nil {
+		return "Не удалось загрузить расписание."
+	}
+	if len(entries) == 0 {
+		return "На ближайшие 7 дней назначений нет."
+	}
+	var b strings.Builder
+	b.WriteString("Назначения на 7 дней:")
+	currentDate := ""
+	for _, entry := range entries {
+		if entry.Date != currentDate {
+			currentDate = entry.Date
+			b.WriteString("\n\n" + strings.ToUpper(dayLabel(entry.Date)))
+		}
+		b.WriteString("\n" + entryText(entry, worker.ID))
+	}
+	return b.String()
+}
+
+// hoursView totals the planned hours of the month like the worker page does, separating entries under review.
+func hoursView(worker models.Worker, month string) string {
+	entries, err := workerEntries(worker.ID, month+"-01", month+"-31")
+	if err != nil {
+		return "Не удалось загрузить расписание."
+	}
+	total, pending := 0.0, 0.0
+	shifts := 0
+	marks := map[string]int{}
+	markOrder := make([]string, 0)
+	for _, entry := range entries {
+		if mark, ok := storage.FindMarkType(entry.UserMark); ok {
+			if marks[mark.Code] == 0 {
+				markOrder = append(markOrder, mark.Code)
+			}
+			marks[mark.Code]++
+			continue
+		}
+		if entry.ApprovalStatus == "pending" {
+			pending += plannedHours(entry)
+			continue
+		}
+		total += plannedHours(entry)
+		shifts++
+	}
+	_, norm := storage.MonthNorm(month)
+	lines := []string{
+		fmt.Sprintf("Часы за %s: %.2f ч, смен: %d.", monthLabel(month), total, shifts),
+		fmt.Sprintf("Норма по производственному календарю: %.0f ч.", norm),
+	}
+	if pending > 0 {
+		lines = append(lines, fmt.Sprintf("Ещё на согласовании: %.2f ч.", pending))
+	}
+	for _, code := range markOrder {
+		lines = append(lines, fmt.Sprintf("Отметки %s: %d дн.", code, marks[code]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tabelView prints the month day by day with the codes of the табель: Я, РВ, В or the mark code,
+// and the hours; confirmed actual time replaces the plan as in the Т-13 export.
+func tabelView(worker models.Worker, month string) string {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "Укажите месяц в виде 09.2026."
+	}
+	entries, err := workerEntries(worker.ID, month+"-01", month+"-31")
+	if err != nil {
+		return "Не удалось загрузить расписание."
+	}
+	var b strings.Builder
+	b.WriteString("Табель за " + monthLabel(month) + ", " + worker.Name + ":")
+	workedDays, totalHours := 0, 0.0
+	for day := start; day.Month() == start.Month(); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		code, hours, mark := "", 0.0, false
+		for _, entry := range entries {
+			if entry.Date != date || !storage.IsTimesheetApproved(entry) {
+				continue
+			}
+			if markType, ok := storage.FindMarkType(entry.UserMark); ok {
+				code, mark = markType.Code, true
+				break
+			}
+			entryHours := plannedHours(entry)
+			if record, err := storage.FindAttendance(entry.ID, worker.ID); err == nil && record.Status == "confirmed" {
+				entryHours = plannedHours(models.TimesheetEntry{StartTime: record.StartTime, EndTime: record.EndTime, LunchBreakMinutes: record.LunchBreakMinutes})
+			}
+			hours += entryHours
+		}
+		kind, _, _ := storage.CalendarDayInfo(day)
+		offDay := kind == "weekend" || kind == "holiday"
+		switch {
+		case mark:
+		case hours > 0 && offDay:
+			code = "РВ"
+		case hours > 0:
+			code = "Я"
+		case offDay:
+			code = "В"
+		default:
+			code = "—"
+		}
+		line := "\n" + dayLabel(date) + "  " + code
+		if hours > 0 {
+			line += " " + strconv.FormatFloat(hours, 'f', -1, 64)
+			workedDays++
+			totalHours += hours
+		}
+		b.WriteString(line)
+	}
+	b.WriteString(fmt.Sprintf("\n\nИтого: %d дн., %.2f ч. Учтены только согласованные записи.", workedDays, totalHours))
+	return b.String()
+}
+
+// handleCallback runs the inline buttons of /today and refreshes the message in place.
+func handleCallback(settings models.AppSettings, query callbackQuery) {
+	if query.Message == nil {
+		_ = answerCallback(settings, query.ID, "")
+		return
+	}
+	chatID := query.Message.Chat.ID
+	worker, reply := linkedWorker(chatID)
+	if reply != "" {
+		_ = answerCallback(settings, query.ID, reply)
+		return
+	}
+
+	action, entryID, _ := strings.Cut(query.Data, ":")
+	now := time.Now()
+	notice := ""
+	entry, err := storage.GetTimesheetByID(entryID)
+	switch {
+	case err != nil:
+		notice = "Назначение не найдено, отправьте /today ещё раз."
+	case action == "in" && entry.Date != now.Format("2006-01-02"):
+		notice = "Назначение уже не на сегодня, отправьте /today ещё раз."
+	case action == "in":
+		var record models.AttendanceRecord
+		if record, err = storage.ClockIn(entry.ID, worker.ID, "telegram", now, nil); err == nil {
+			notice = "Приход отмечен в " + record.StartTime + "."
+		}
+	case action == "out":
+		var record models.AttendanceRecord
+		if record, err = storage.ClockOut(entry.ID, worker.ID, now); err == nil {
+			notice = "Уход отмечен в " + record.EndTime + ". Часы передаются прорабу на подтверждение."
+		}
+	default:
+		notice = "Кнопка устарела, отправьте /today ещё раз."
+	}
+	if notice == "" {
+		notice = humanizeAttendanceError(err)
+	}
+	_ = answerCallback(settings, query.ID, notice)
+
+	text, keyboard := todayView(worker, now)
+	_ = callBot(settings, "editMessageText", map[string]any{
+		"chat_id":      chatID,
+		"message_id":   query.Message.MessageID,
+		"text":         text,
+		"reply_markup": keyboardMarkup(keyboard),
+	})
+}
+
+func humanizeAttendanceError(err error) string {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, storage.ErrMonthClosed):
+		return "Месяц закрыт, отметки не меняются."
+	case strings.Contains(msg, "already clocked in"):
+		return "Приход уже отмечен."
+	case strings.Contains(msg, "already clocked out"):
+		return "Уход уже отмечен."
+	case strings.Contains(msg, "not clocked in"):
+		return "Сначала отметьте приход."
+	case strings.Contains(msg, "end time must be after"):
+		return "Уход не может быть раньше прихода."
+	case strings.Contains(msg, "not assigned"):
+		return "Вы не назначены на эту смену."
+	case strings.Contains(msg, "day of the entry"):
+		return "Приход отмечается только в день смены."
+	default:
+		return "Не удалось сохранить отметку."
+	}
+}
+
+// keyboardMarkup removes the buttons when the keyboard is nil (editMessageText keeps them otherwise).
+func keyboardMarkup(keyboard *inlineKeyboard) inlineKeyboard {
+	if keyboard == nil {
+		return inlineKeyboard{InlineKeyboard: [][]inlineButton{}}
+	}
+	return *keyboard
+}
+
+func answerCallback(settings models.AppSettings, queryID, text string) error {
+	return callBot(settings, "answerCallbackQuery", map[string]any{"callback_query_id": queryID, "text": text})
+}
@@ -0,0 +1,185 @@
+package telegrambot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+)
+
+const leaveCommandHelp = "Формат: /leave 01.11.2026 14.11.2026 ОТ комментарий. Вторая дата и тип необязательны (по умолчанию отпуск на один день). Фото больничного можно отправить с этой командой в подписи."
+
+type telegramAttachment struct {
+	FileID   string
+	FileName string
+	FileSize int
+}
+
+// messageAttachment picks the largest photo or the document of a message.
+func messageAttachment(update updateResult) *telegramAttachment {
+	if update.Message.Document != nil {
+		return &telegramAttachment{FileID: update.Message.Document.FileID, FileName: update.Message.Document.FileName, FileSize: update.Message.Document.FileSize}
+	}
+	if len(update.Message.Photo) == 0 {
+		return nil
+	}
+	largest := update.Message.Photo[len(update.Message.Photo)-1]
+	return &telegramAttachment{FileID: largest.FileID, FileName: "photo.jpg", FileSize: largest.FileSize}
+}
+
+func parseLeaveDate(value string) (string, bool) {
+	for _, layout := range []string{"02.01.2006", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// handleLeaveCommand turns /leave into a pending leave request and returns the reply text.
+func handleLeaveCommand(settings models.AppSettings, chatID int64, text string, attachment *telegramAttachment) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	command := strings.ToLower(fields[0])
+	if at := strings.Index(command, "@"); at > 0 {
+		command = command[:at]
+	}
+	if command != "/leave" {
+		return ""
+	}
+
+	contact, err := storage.FindTelegramContactByChatID(chatID)
+	if err != nil {
+		return "Сначала поделитесь номером телефона, чтобы бот узнал вас."
+	}
+	worker, err := storage.FindWorkerByPhone(contact.Phone)
+	if err != nil {
+		return "Работник с вашим номером телефона не найден."
+	}
+
+	args := fields[1:]
+	if len(args) == 0 {
+		return leaveCommandHelp
+	}
+	dateFrom, ok := parseLeaveDate(args[0])
+	if !ok {
+		return leaveCommandHelp
+	}
+	args = args[1:]
+	dateTo := dateFrom
+	if len(args) > 0 {
+		if parsed, ok := parseLeaveDate(args[0]); ok {
+			dateTo = parsed
+			args = args[1:]
+		}
+	}
+	markCode := ""
+	if mark, ok := storage.FindMarkType("vacation"); ok {
+		markCode = mark.Code
+	}
+	if len(args) > 0 {
+		if mark, ok := storage.FindMarkType(args[0]); ok {
+			markCode = mark.Code
+			args = args[1:]
+		}
+	}
+
+	var content []byte
+	fileName := ""
+	if attachment != nil {
+		content, err = downloadFile(settings, attachment)
+		if err != nil {
+			return "Не удалось загрузить вложение, попробуйте ещё раз или приложите документ в приложении."
+		}
+		fileName = attachment.FileName
+	}
+
+	name := strings.TrimSpace(contact.FirstName + " " + contact.LastName)
+	if name == "" {
+		name = worker.Name
+	}
+	request, err := storage.CreateLeaveRequest(models.LeaveRequest{
+		WorkerID:      worker.ID,
+		MarkCode:      markCode,
+		DateFrom:      dateFrom,
+		DateTo:        dateTo,
+		Comment:       strings.Join(args, " "),
+		Source:        "telegram",
+		CreatedByID:   worker.UserID,
+		CreatedByName: name,
+	}, content, fileName)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "overlaps"):
+			return "На эти даты уже есть заявка."
+		case strings.Contains(err.Error(), "attachment"):
+			return "Приложите фото или PDF размером до 10 МБ."
+		default:
+			return "Не удалось создать заявку. " + leaveCommandHelp
+		}
+	}
+	return fmt.Sprintf("Заявка отправлена: %s с %s по %s. Ответ придёт после рассмотрения администратором.", request.MarkCode, request.DateFrom, request.DateTo)
+}
+
+// downloadFile fetches an attachment through getFile; files above the leave attachment limit are refused.
+func downloadFile(settings models.AppSettings, attachment *telegramAttachment) ([]byte, error) {
+	if attachment.FileSize > storage.MaxLeaveAttachmentSize {
+		return nil, errors.New("attachment is too large")
+	}
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Get(apiURL(settings.TelegramBotToken, "getFile") + "?file_id=" + url.QueryEscape(attachment.FileID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var payload botResponse[struct {
+		FilePath string `json:"file_path"`
+	}]
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if !payload.OK || payload.Result.FilePath == "" {
+		return nil, errors.New("telegram getFile failed")
+	}
+
+	fileResp, err := client.Get(apiBaseURL + "/file/bot" + settings.TelegramBotToken + "/" + payload.Result.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fileResp.Body.Close()
+	if fileResp.StatusCode != http.StatusOK {
+		return nil, errors.New("telegram file download failed")
+	}
+	return io.ReadAll(io.LimitReader(fileResp.Body, storage.MaxLeaveAttachmentSize+1))
+}
+
+// SendLeaveReviewedNotification tells the worker about the decision on a leave request.
+func SendLeaveReviewedNotification(request models.LeaveRequest) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	worker, err := storage.GetWorkerByID(request.WorkerID)
+	if err != nil {
+		return err
+	}
+	contact, err := storage.FindTelegramContactByPhone(worker.Phone)
+	if err != nil {
+		return ErrChatNotLinked
+	}
+	message := fmt.Sprintf("Заявка %s с %s по %s одобрена, отметки внесены в табель.", request.MarkCode, request.DateFrom, request.DateTo)
+	if request.Status == "rejected" {
+		message = fmt.Sprintf("Заявка %s с %s по %s отклонена: %s", request.MarkCode, request.DateFrom, request.DateTo, request.ReviewComment)
+	}
+	return sendMessage(settings, contact.ChatID, message)
+}
@@ -2,11 +2,14 @@ package telegrambot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"project/internal/models"
@@ -14,8 +17,9 @@ import (
 )
 
 var (
-	ErrBotNotConfigured = errors.New("telegram bot is not configured")
-	ErrChatNotLinked    = errors.New("telegram chat is not linked to this phone")
+	ErrBotNotConfigured  = errors.New("telegram bot is not configured")
+	ErrChatNotLinked     = errors.New("telegram chat is not linked to this phone")
+	ErrSiteNotConfigured = errors.New("site address is not configured")
 )
 
 type SyncSummary struct {
@@ -30,8 +34,20 @@ type botResponse[T any] struct {
 }
 
 type updateResult struct {
-	UpdateID int `json:"update_id"`
-	Message  struct {
+	UpdateID      int            `json:"update_id"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+	Message       struct {
+		Text    string `json:"text"`
+		Caption string `json:"caption"`
+		Photo   []struct {
+			FileID   string `json:"file_id"`
+			FileSize int    `json:"file_size"`
+		} `json:"photo"`
+		Document *struct {
+			FileID   string `json:"file_id"`
+			FileName string `json:"file_name"`
+			FileSize int    `json:"file_size"`
+		} `json:"document"`
 		Chat struct {
 			ID int64 `json:"id"`
 		} `json:"chat"`
@@ -48,8 +64,21 @@ type updateResult struct {
 	} `json:"message"`
 }
 
+// apiBaseURL is the Bot API server; SetAPIBaseURL points it at a self-hosted or fake server.
+var apiBaseURL = "https://api.telegram.org"
+
+// updatesMutex serializes update handling, so the poller, the webhook and the manual sync never
+// process the same update twice.
+var updatesMutex sync.Mutex
+
+func SetAPIBaseURL(base string) {
+	if base = strings.TrimRight(strings.TrimSpace(base), "/"); base != "" {
+		apiBaseURL = base
+	}
+}
+
 func apiURL(token, method string) string {
-	return "https://api.telegram.org/bot" + token + "/" + method
+	return apiBaseURL + "/bot" + token + "/" + method
 }
 
 func loadSettings() (models.AppSettings, error) {
@@ -63,101 +92,158 @@ func loadSettings() (models.AppSettings, error) {
 	return settings, nil
 }
 
+// SyncContacts fetches the pending updates once. It is refused while the background worker
+// receives updates, since Telegram allows only one getUpdates client at a time.
 func SyncContacts() (SyncSummary, error) {
+	if Receiving() {
+		return SyncSummary{}, ErrWorkerReceiving
+	}
 	settings, err := loadSettings()
 	if err != nil {
 		return SyncSummary{}, err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, apiURL(settings.TelegramBotToken, "getUpdates"), nil)
+	updatesMutex.Lock()
+	defer updatesMutex.Unlock()
+
+	updates, err := getUpdates(context.Background(), settings, 1)
 	if err != nil {
 		return SyncSummary{}, err
 	}
+	return processUpdates(settings, updates)
+}
+
+// getUpdates waits up to timeout seconds for updates after the stored offset.
+func getUpdates(ctx context.Context, settings models.AppSettings, timeout int) ([]updateResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL(settings.TelegramBotToken, "getUpdates"), nil)
+	if err != nil {
+		return nil, err
+	}
 	q := req.URL.Query()
-	if settings.TelegramUpdateOffset > 0 {
-		q.Set("offset", fmt.Sprintf("%d", settings.TelegramUpdateOffset))
+	if current, err := storage.GetAppSettings(); err == nil && current.TelegramUpdateOffset > 0 {
+		q.Set("offset", fmt.Sprintf("%d", current.TelegramUpdateOffset))
 	}
-	q.Set("timeout", "1")
+	q.Set("timeout", strconv.Itoa(timeout))
+	q.Set("allowed_updates", `["message","callback_query"]`)
 	req.URL.RawQuery = q.Encode()
 
-	client := &http.Client{Timeout: 8 * time.Second}
+	client := &http.Client{Timeout: time.Duration(timeout)*time.Second + 8*time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return SyncSummary{}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var payload botResponse[[]updateResult]
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return SyncSummary{}, err
+		return nil, err
 	}
 	if !payload.OK {
 		if strings.TrimSpace(payload.Description) != "" {
-			return SyncSummary{}, errors.New(payload.Description)
+			return nil, errors.New(payload.Description)
 		}
-		return SyncSummary{}, errors.New("telegram getUpdates failed")
+		return nil, errors.New("telegram getUpdates failed")
 	}
+	return payload.Result, nil
+}
 
+// processUpdates handles a batch and moves the stored offset past it; updates below the offset
+// were handled before (a webhook retried by Telegram) and are skipped. Call with updatesMutex held.
+func processUpdates(settings models.AppSettings, updates []updateResult) (SyncSummary, error) {
+	offset := 0
+	if current, err := storage.GetAppSettings(); err == nil {
+		offset = current.TelegramUpdateOffset
+	}
 	summary := SyncSummary{}
-	maxUpdateID := settings.TelegramUpdateOffset
-	for _, update := range payload.Result {
+	next := offset
+	for _, update := range updates {
+		if update.UpdateID < offset {
+			continue
+		}
 		summary.Processed++
-		if update.UpdateID >= maxUpdateID {
-			maxUpdateID = update.UpdateID + 1
+		if update.UpdateID >= next {
+			next = update.UpdateID + 1
 		}
-		if update.Message.Contact == nil || update.Message.Chat.ID == 0 {
-			continue
+		if processUpdate(settings, update) {
+			summary.Linked++
 		}
+	}
+	if summary.Processed > 0 {
+		recordUpdates(summary.Processed)
+	}
+	return summary, storage.SetTelegramUpdateOffset(next)
+}
 
-		firstName := strings.TrimSpace(update.Message.Contact.FirstName)
-		lastName := strings.TrimSpace(update.Message.Contact.LastName)
-		if firstName == "" {
-			firstName = strings.TrimSpace(update.Message.From.FirstName)
+// processUpdate answers a command or a button, or links the shared contact; it reports whether a
+// phone was linked to the chat.
+func processUpdate(settings models.AppSettings, update updateResult) bool {
+	if update.CallbackQuery != nil {
+		handleCallback(settings, *update.CallbackQuery)
+		return false
+	}
+	if update.Message.Chat.ID == 0 {
+		return false
+	}
+	if update.Message.Contact == nil {
+		text := update.Message.Text
+		if text == "" {
+			text = update.Message.Caption
 		}
-		if lastName == "" {
-			lastName = strings.TrimSpace(update.Message.From.LastName)
+		reply, keyboard := handleScheduleCommand(update.Message.Chat.ID, text)
+		if reply == "" {
+			reply = handleAttendanceCommand(update.Message.Chat.ID, text)
 		}
-
-		if err := storage.UpsertTelegramContact(models.TelegramContactLink{
-			Phone:     update.Message.Contact.PhoneNumber,
-			ChatID:    update.Message.Chat.ID,
-			Username:  update.Message.From.Username,
-			FirstName: firstName,
-			LastName:  lastName,
-			UpdatedAt: time.Now().Format(time.RFC3339),
-		}); err == nil {
-			summary.Linked++
+		if reply == "" {
+			reply = handleLeaveCommand(settings, update.Message.Chat.ID, text, messageAttachment(update))
 		}
+		if reply != "" {
+			_ = sendMessageWithKeyboard(settings, update.Message.Chat.ID, reply, keyboard)
+		}
+		return false
 	}
 
-	settings.TelegramUpdateOffset = maxUpdateID
-	if err := storage.UpdateAppSettings(settings); err != nil {
-		return summary, err
+	firstName := strings.TrimSpace(update.Message.Contact.FirstName)
+	lastName := strings.TrimSpace(update.Message.Contact.LastName)
+	if firstName == "" {
+		firstName = strings.TrimSpace(update.Message.From.FirstName)
+	}
+	if lastName == "" {
+		lastName = strings.TrimSpace(update.Message.From.LastName)
 	}
 
-	return summary, nil
+	err := storage.UpsertTelegramContact(models.TelegramContactLink{
+		Phone:     update.Message.Contact.PhoneNumber,
+		ChatID:    update.Message.Chat.ID,
+		Username:  update.Message.From.Username,
+		FirstName: firstName,
+		LastName:  lastName,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	})
+	return err == nil
 }
 
-func SendAccountCreatedNotification(user models.User, plainPassword string) error {
-	if strings.TrimSpace(plainPassword) == "" {
-		return nil
-	}
-
+// SendAccountCreatedNotification sends the new user the site, the login and a one-time link for
+// choosing the password; the password itself never goes through Telegram.
+func SendAccountCreatedNotification(user models.User) error {
 	settings, err := loadSettings()
 	if err != nil {
 		return err
 	}
+	siteURL := strings.TrimRight(strings.TrimSpace(settings.TelegramSiteURL), "/")
+	if siteURL == "" {
+		return ErrSiteNotConfigured
+	}
 
+	// Without the background worker the contact may still be waiting in getUpdates.
 	_, _ = SyncContacts()
 
 	contact, err := storage.FindTelegramContactByPhone(user.Phone)
 	if err != nil {
 		return ErrChatNotLinked
 	}
-
-	siteURL := strings.TrimSpace(settings.TelegramSiteURL)
-	if siteURL == "" {
-		siteURL = "Укажите адрес сайта в настройках"
+	token, err := storage.CreatePasswordLink(user.ID)
+	if err != nil {
+		return err
 	}
 
 	message := strings.Join([]string{
@@ -165,22 +251,43 @@ func SendAccountCreatedNotification(user models.User, plainPassword string) erro
 		"",
 		"Сайт: " + siteURL,
 		"Логин: " + strings.TrimSpace(user.Username),
-		"Пароль: " + plainPassword,
+		"",
+		"Придумайте пароль по ссылке (действует " + strconv.Itoa(int(storage.PasswordLinkTTL.Hours())) + " ч и срабатывает один раз):",
+		siteURL + "/password/" + token,
 		"",
 		"Как установить PWA:",
 		"iPhone / Safari: откройте сайт, нажмите «Поделиться» -> «На экран Домой».",
 		"Android / Chrome: откройте сайт, меню браузера -> «Добавить на главный экран» или «Установить приложение».",
-		"",
-		"После первого входа удалите это сообщение с паролем.",
 	}, "\n")
 
-	body, _ := json.Marshal(map[string]any{
-		"chat_id":                  contact.ChatID,
+	return sendMessage(settings, contact.ChatID, message)
+}
+
+func sendMessage(settings models.AppSettings, chatID int64, message string) error {
+	return sendMessageWithKeyboard(settings, chatID, message, nil)
+}
+
+func sendMessageWithKeyboard(settings models.AppSettings, chatID int64, message string, keyboard *inlineKeyboard) error {
+	payload := map[string]any{
+		"chat_id":                  chatID,
 		"text":                     message,
 		"disable_web_page_preview": true,
-	})
+	}
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+	return callBot(settings, "sendMessage", payload)
+}
 
-	req, err := http.NewRequest(http.MethodPost, apiURL(settings.TelegramBotToken, "sendMessage"), bytes.NewReader(body))
+func callBot(settings models.AppSettings, method string, payload map[string]any) error {
+	return callBotContext(context.Background(), settings, method, payload)
+}
+
+// callBotContext posts a JSON request to a Bot API method and turns "ok": false into an error.
+func callBotContext(ctx context.Context, settings models.AppSettings, method string, payload map[string]any) error {
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL(settings.TelegramBotToken, method), bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -193,15 +300,15 @@ func SendAccountCreatedNotification(user models.User, plainPassword string) erro
 	}
 	defer resp.Body.Close()
 
-	var payload botResponse[map[string]any]
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+	var result botResponse[any]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return err
 	}
-	if !payload.OK {
-		if strings.TrimSpace(payload.Description) != "" {
-			return errors.New(payload.Description)
+	if !result.OK {
+		if strings.TrimSpace(result.Description) != "" {
+			return errors.New(result.Description)
 		}
-		return errors.New("telegram sendMessage failed")
+		return errors.New("telegram " + method + " failed")
 	}
 	return nil
 }
@@ -0,0 +1,30 @@
+package telegrambot
+
+import (
+	"errors"
+	"log"
+
+	"project/internal/events"
+	"project/internal/storage"
+)
+
+// Subscribe sends the welcome message to new users and the decision on leave requests.
+func Subscribe() {
+	events.On(func(e events.UserCreated) {
+		if e.User.Status != "user" {
+			return
+		}
+		// The form deletes the user again when linking the worker fails.
+		if _, err := storage.GetUserByID(e.User.ID); err != nil {
+			return
+		}
+		if err := SendAccountCreatedNotification(e.User); err != nil && !errors.Is(err, ErrChatNotLinked) && !errors.Is(err, ErrBotNotConfigured) {
+			log.Printf("telegram: welcome message for user %s: %v", e.User.Username, err)
+		}
+	})
+	events.On(func(e events.LeaveRequestReviewed) {
+		if err := SendLeaveReviewedNotification(e.Request); err != nil && !errors.Is(err, ErrChatNotLinked) && !errors.Is(err, ErrBotNotConfigured) {
+			log.Printf("telegram: leave decision for request %s: %v", e.Request.ID, err)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package telegrambot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"project/internal/models"
+)
+
+// The worker receives bot updates for as long as the server runs. In polling mode it keeps a long
+// getUpdates request open and backs off on errors; in webhook mode it registers
+// <site>/telegram/webhook with a secret token and HandleWebhook processes what Telegram posts.
+// Saving the bot settings calls Reload, which restarts the worker with the new token and mode.
+
+const (
+	pollTimeout = 25 // seconds one getUpdates request waits for updates
+	minBackoff  = time.Second
+	maxBackoff  = 5 * time.Minute
+)
+
+// WebhookPath is where Telegram posts updates in webhook mode.
+const WebhookPath = "/telegram/webhook"
+
+var (
+	ErrWorkerReceiving  = errors.New("updates are received by the background worker")
+	ErrWebhookForbidden = errors.New("webhook secret token mismatch")
+	ErrWebhookNeedsSite = errors.New("webhook mode needs an https site address")
+	ErrMalformedUpdate  = errors.New("malformed telegram update")
+)
+
+// Status is what the settings page shows about the worker.
+type Status struct {
+	Mode          string // polling | webhook
+	State         string // stopped | idle | polling | webhook | retrying
+	StartedAt     string
+	LastContactAt string // last successful exchange with Telegram
+	LastUpdateAt  string // last update processed
+	Processed     int
+	LastError     string
+	LastErrorAt   string
+	RetryAt       string
+	WebhookURL    string
+}
+
+var (
+	status      = Status{State: "stopped"}
+	statusMutex sync.Mutex
+
+	workerParent context.Context
+	workerCancel context.CancelFunc
+	workerDone   chan struct{}
+	workerMutex  sync.Mutex
+)
+
+func GetStatus() Status {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	return status
+}
+
+// Receiving reports whether the worker is running, so a manual getUpdates would compete with it.
+func Receiving() bool {
+	return GetStatus().State != "stopped"
+}
+
+func updateStatus(change func(*Status)) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	change(&status)
+}
+
+// recordContact notes a successful exchange with Telegram; an empty state keeps the current one.
+func recordContact(state string) {
+	updateStatus(func(s *Status) {
+		if state != "" {
+			s.State = state
+		}
+		s.LastContactAt = time.Now().Format(time.RFC3339)
+		s.RetryAt = ""
+	})
+}
+
+func recordUpdates(count int) {
+	updateStatus(func(s *Status) {
+		s.Processed += count
+		s.LastUpdateAt = time.Now().Format(time.RFC3339)
+	})
+}
+
+func recordError(err error) {
+	log.Printf("telegram: %v", err)
+	updateStatus(func(s *Status) {
+		s.LastError = err.Error()
+		s.LastErrorAt = time.Now().Format(time.RFC3339)
+	})
+}
+
+// Start runs the worker until ctx is cancelled or Stop is called.
+func Start(ctx context.Context) {
+	workerMutex.Lock()
+	defer workerMutex.Unlock()
+
+	if workerCancel != nil {
+		return
+	}
+	workerParent = ctx
+	startLocked()
+}
+
+func startLocked() {
+	ctx, cancel := context.WithCancel(workerParent)
+	done := make(chan struct{})
+	workerCancel, workerDone = cancel, done
+	updateStatus(func(s *Status) { s.State = "idle" })
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+}
+
+// Reload restarts a running worker so it picks up changed settings.
+func Reload() {
+	workerMutex.Lock()
+	defer workerMutex.Unlock()
+
+	if workerCancel == nil {
+		return
+	}
+	workerCancel()
+	<-workerDone
+	startLocked()
+}
+
+// Stop cancels the worker and waits until the update being processed is finished, or until ctx expires.
+// The worker counts as stopped either way, so a later Start runs a new one.
+func Stop(ctx context.Context) error {
+	workerMutex.Lock()
+	defer workerMutex.Unlock()
+
+	if workerCancel == nil {
+		return nil
+	}
+	workerCancel()
+	done := workerDone
+	workerCancel, workerDone = nil, nil
+	defer updateStatus(func(s *Status) { s.State = "stopped" })
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func run(ctx context.Context) {
+	settings, err := loadSettings()
+	updateStatus(func(s *Status) {
+		s.Mode = settings.TelegramMode
+		s.State = "idle"
+		s.StartedAt = time.Now().Format(time.RFC3339)
+		s.LastError, s.LastErrorAt, s.RetryAt, s.WebhookURL = "", "", "", ""
+	})
+	if err != nil {
+		// Nothing to do until a token is saved, which calls Reload.
+		<-ctx.Done()
+		return
+	}
+	if settings.TelegramMode == "webhook" {
+		registerWebhook(ctx, settings)
+		return
+	}
+	poll(ctx, settings)
+}
+
+// backoff records the error and waits before the next attempt; false means the worker is stopping.
+func backoff(ctx context.Context, delay *time.Duration, err error) bool {
+	recordError(err)
+	updateStatus(func(s *Status) {
+		s.State = "retrying"
+		s.RetryAt = time.Now().Add(*delay).Format(time.RFC3339)
+	})
+	timer := time.NewTimer(*delay)
+	defer timer.Stop()
+	*delay = min(*delay*2, maxBackoff)
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// poll works with the settings it was started with; Reload restarts it when they change.
+func poll(ctx context.Context, settings models.AppSettings) {
+	delay := minBackoff
+	webhookRemoved := false
+	for ctx.Err() == nil {
+		if !webhookRemoved {
+			// getUpdates is refused while a webhook is registered, e.g. after switching modes.
+			if err := callBotContext(ctx, settings, "deleteWebhook", map[string]any{}); err != nil {
+				if ctx.Err() != nil || !backoff(ctx, &delay, err) {
+					return
+				}
+				continue
+			}
+			webhookRemoved = true
+		}
+		updateStatus(func(s *Status) { s.State = "polling" })
+		updates, err := getUpdates(ctx, settings, pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil || !backoff(ctx, &delay, err) {
+				return
+			}
+			continue
+		}
+		delay = minBackoff
+		recordContact("polling")
+
+		// A batch is finished even when shutdown starts meanwhile, so no reply is lost halfway.
+		updatesMutex.Lock()
+		_, err = processUpdates(settings, updates)
+		updatesMutex.Unlock()
+		if err != nil {
+			recordError(err)
+		}
+	}
+}
+
+// NewWebhookSecret returns a random secret_token for setWebhook (allowed characters only).
+func NewWebhookSecret() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// webhookURL is the address Telegram posts to; it needs the site address from the settings.
+func webhookURL(site string) (string, error) {
+	site = strings.TrimRight(strings.TrimSpace(site), "/")
+	if !strings.HasPrefix(site, "https://") {
+		return "", ErrWebhookNeedsSite
+	}
+	return site + WebhookPath, nil
+}
+
+// registerWebhook calls setWebhook until it succeeds. The webhook stays registered on shutdown so
+// Telegram keeps the updates until the server is back.
+func registerWebhook(ctx context.Context, settings models.AppSettings) {
+	url, err := webhookURL(settings.TelegramSiteURL)
+	if err != nil {
+		recordError(err)
+		<-ctx.Done()
+		return
+	}
+	delay := minBackoff
+	for {
+		err := callBotContext(ctx, settings, "setWebhook", map[string]any{
+			"url":             url,
+			"secret_token":    settings.TelegramWebhookSecret,
+			"allowed_updates": []string{"message", "callback_query"},
+		})
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || !backoff(ctx, &delay, err) {
+			return
+		}
+	}
+	recordContact("webhook")
+	updateStatus(func(s *Status) { s.WebhookURL = url })
+	<-ctx.Done()
+}
+
+// HandleWebhook processes one update posted by Telegram after checking the secret token header.
+func HandleWebhook(secretToken string, body io.Reader) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return ErrWebhookForbidden
+	}
+	if settings.TelegramMode != "webhook" || settings.TelegramWebhookSecret == "" ||
+		subtle.ConstantTimeCompare([]byte(secretToken), []byte(settings.TelegramWebhookSecret)) != 1 {
+		return ErrWebhookForbidden
+	}
+	var update updateResult
+	if err := json.NewDecoder(io.LimitReader(body, 1<<20)).Decode(&update); err != nil {
+		return ErrMalformedUpdate
+	}
+
+	updatesMutex.Lock()
+	defer updatesMutex.Unlock()
+
+	recordContact("")
+	if _, err := processUpdates(settings, []updateResult{update}); err != nil {
+		recordError(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package telegrambot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+)
+
+// botCall is one request the fake Bot API received.
+type botCall struct {
+	method  string
+	payload map[string]any
+}
+
+// fakeBotAPI answers every method with ok; getUpdates hands out the queued updates once and
+// otherwise waits a little, like a long poll without news.
+type fakeBotAPI struct {
+	mutex   sync.Mutex
+	updates []updateResult
+	calls   chan botCall
+}
+
+func newFakeBotAPI(t *testing.T, updates ...updateResult) *fakeBotAPI {
+	t.Helper()
+	api := &fakeBotAPI{updates: updates, calls: make(chan botCall, 64)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		payload := map[string]any{}
+		if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+			_ = json.Unmarshal(body, &payload)
+		}
+		api.calls <- botCall{method: method, payload: payload}
+
+		var result any = true
+		if method == "getUpdates" {
+			api.mutex.Lock()
+			updates := api.updates
+			api.updates = nil
+			api.mutex.Unlock()
+			if len(updates) == 0 {
+				select {
+				case <-time.After(50 * time.Millisecond):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			result = append([]updateResult{}, updates...)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	previous := apiBaseURL
+	SetAPIBaseURL(server.URL)
+	t.Cleanup(func() { apiBaseURL = previous })
+	return api
+}
+
+// waitCall returns the next call of the method, skipping the others.
+func (api *fakeBotAPI) waitCall(t *testing.T, method string) botCall {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case call := <-api.calls:
+			if call.method == method {
+				return call
+			}
+		case <-timeout:
+			t.Fatalf("no %s call", method)
+		}
+	}
+}
+
+// noCall fails when the method is called within a short while.
+func (api *fakeBotAPI) noCall(t *testing.T, method string) {
+	t.Helper()
+	timeout := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case call := <-api.calls:
+			if call.method == method {
+				t.Fatalf("unexpected %s call: %v", method, call.payload)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// useSettings stores the bot settings in an empty temporary storage directory.
+func useSettings(t *testing.T, settings models.AppSettings) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	for _, load := range []func() error{storage.LoadAppSettings, storage.LoadTelegramContacts, storage.LoadWorkers} {
+		if err := load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.UpdateAppSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func stopWorker(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+}
+
+func helpUpdate(id int, chatID int64) updateResult {
+	var update updateResult
+	update.UpdateID = id
+	update.Message.Text = "/help"
+	update.Message.Chat.ID = chatID
+	return update
+}
+
+func TestPollingAnswersUpdates(t *testing.T) {
+	useSettings(t, models.AppSettings{TelegramBotToken: "123:abc", TelegramMode: "polling"})
+	api := newFakeBotAPI(t, helpUpdate(10, 42))
+
+	Start(context.Background())
+	t.Cleanup(func() { stopWorker(t) })
+
+	api.waitCall(t, "deleteWebhook")
+	reply := api.waitCall(t, "sendMessage")
+	if reply.payload["chat_id"] != float64(42) || !strings.Contains(reply.payload["text"].(string), "/today") {
+		t.Errorf("reply %v", reply.payload)
+	}
+	api.waitCall(t, "getUpdates")
+	if settings, _ := storage.GetAppSettings(); settings.TelegramUpdateOffset != 11 {
+		t.Errorf("offset %d, want 11", settings.TelegramUpdateOffset)
+	}
+	if status := GetStatus(); status.State != "polling" || status.Processed < 1 || status.LastContactAt == "" {
+		t.Errorf("status %+v", status)
+	}
+}
+
+func TestStartAfterStopTimedOut(t *testing.T) {
+	useSettings(t, models.AppSettings{TelegramBotToken: "123:abc", TelegramMode: "polling"})
+	api := newFakeBotAPI(t, helpUpdate(20, 42))
+
+	// Holding the update lock keeps the worker busy with the first batch, so Stop cannot finish.
+	updatesMutex.Lock()
+	Start(context.Background())
+	api.waitCall(t, "getUpdates")
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Stop(expired); !errors.Is(err, context.Canceled) {
+		t.Fatalf("stop with an expired context: %v", err)
+	}
+	if Receiving() {
+		t.Error("worker still counts as receiving after Stop")
+	}
+	updatesMutex.Unlock()
+
+	Start(context.Background())
+	t.Cleanup(func() { stopWorker(t) })
+	if !Receiving() {
+		t.Fatal("Start after a timed-out Stop did not start the worker")
+	}
+	api.waitCall(t, "deleteWebhook")
+}
+
+func TestWebhookChecksSecretToken(t *testing.T) {
+	useSettings(t, models.AppSettings{
+		TelegramBotToken:      "123:abc",
+		TelegramMode:          "webhook",
+		TelegramSiteURL:       "https://timesheet.example.com/",
+		TelegramWebhookSecret: "s3cret",
+	})
+	api := newFakeBotAPI(t)
+
+	Start(context.Background())
+	t.Cleanup(func() { stopWorker(t) })
+
+	registered := api.waitCall(t, "setWebhook")
+	if registered.payload["url"] != "https://timesheet.example.com"+WebhookPath || registered.payload["secret_token"] != "s3cret" {
+		t.Errorf("setWebhook %v", registered.payload)
+	}
+
+	body, _ := json.Marshal(helpUpdate(30, 77))
+	if err := HandleWebhook("wrong", strings.NewReader(string(body))); !errors.Is(err, ErrWebhookForbidden) {
+		t.Errorf("wrong secret: %v", err)
+	}
+	if err := HandleWebhook("", strings.NewReader(string(body))); !errors.Is(err, ErrWebhookForbidden) {
+		t.Errorf("missing secret: %v", err)
+	}
+	api.noCall(t, "sendMessage")
+
+	if err := HandleWebhook("s3cret", strings.NewReader("{")); !errors.Is(err, ErrMalformedUpdate) {
+		t.Errorf("malformed update: %v", err)
+	}
+	if err := HandleWebhook("s3cret", strings.NewReader(string(body))); err != nil {
+		t.Fatalf("webhook update: %v", err)
+	}
+	if reply := api.waitCall(t, "sendMessage"); reply.payload["chat_id"] != float64(77) {
+		t.Errorf("reply %v", reply.payload)
+	}
+
+	// Telegram retries a webhook it did not see answered; the update is handled only once.
+	if err := HandleWebhook("s3cret", strings.NewReader(string(body))); err != nil {
+		t.Fatalf("repeated update: %v", err)
+	}
+	api.noCall(t, "sendMessage")
+}
@@ -0,0 +1,178 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// retryDelays are the pauses before the 2nd, 3rd, ... attempt; after the last one the delivery fails.
+var retryDelays = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// envelope is the JSON body of every delivery.
+type envelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt string      `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign returns the X-Webhook-Signature value: HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func payload(event string, data interface{}) (string, error) {
+	body, err := json.Marshal(envelope{
+		ID:        uuid.New().String(),
+		Event:     event,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Data:      data,
+	})
+	return string(body), err
+}
+
+// Emit queues the event for every active webhook subscribed to it; delivery runs in the background.
+func Emit(event string, data interface{}) {
+	subscribers := storage.WebhooksForEvent(event)
+	if len(subscribers) == 0 {
+		return
+	}
+	body, err := payload(event, data)
+	if err != nil {
+		log.Printf("webhooks: encode %s: %v", event, err)
+		return
+	}
+	for _, webhook := range subscribers {
+		delivery, err := storage.AddWebhookDelivery(models.WebhookDelivery{WebhookID: webhook.ID, Event: event, Payload: body})
+		if err != nil {
+			log.Printf("webhooks: queue %s for %s: %v", event, webhook.Name, err)
+			continue
+		}
+		go attempt(delivery)
+	}
+}
+
+// SendTest delivers a test event to the webhook right away, even if it is paused, and returns
+// the result; a failed test event is not retried.
+func SendTest(webhookID string) (models.WebhookDelivery, error) {
+	webhook, err := storage.GetWebhookByID(webhookID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	body, err := payload(models.WebhookEventTest, map[string]string{"webhookId": webhook.ID, "name": webhook.Name})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery, err := storage.AddWebhookDelivery(models.WebhookDelivery{WebhookID: webhook.ID, Event: models.WebhookEventTest, Payload: body})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery = send(webhook, delivery)
+	if delivery.Status == "pending" {
+		delivery.Status = "failed"
+		delivery.NextAttemptAt = ""
+	}
+	return delivery, storage.UpdateWebhookDelivery(delivery)
+}
+
+// Resume schedules the deliveries left pending by the previous run.
+func Resume() {
+	for _, delivery := range storage.PendingWebhookDeliveries() {
+		delivery := delivery
+		time.AfterFunc(time.Until(parseTime(delivery.NextAttemptAt)), func() { attempt(delivery) })
+	}
+}
+
+// attempt makes one delivery attempt and schedules the next one when it fails and retries remain.
+func attempt(delivery models.WebhookDelivery) {
+	webhook, err := storage.GetWebhookByID(delivery.WebhookID)
+	if err != nil {
+		return
+	}
+	if !webhook.Active {
+		delivery.Status = "failed"
+		delivery.Error = "webhook is paused"
+		delivery.NextAttemptAt = ""
+	} else {
+		delivery = send(webhook, delivery)
+	}
+	if err := storage.UpdateWebhookDelivery(delivery); err != nil {
+		log.Printf("webhooks: save delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if delivery.Status == "pending" {
+		next := delivery
+		time.AfterFunc(time.Until(parseTime(delivery.NextAttemptAt)), func() { attempt(next) })
+	}
+}
+
+// send posts the payload once and records the outcome; a non-2xx answer or a network error
+// leaves the delivery pending with NextAttemptAt set while retries remain.
+func send(webhook models.Webhook, delivery models.WebhookDelivery) models.WebhookDelivery {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = now.Format(time.RFC3339)
+	delivery.ResponseCode = 0
+	delivery.Error = ""
+	delivery.NextAttemptAt = ""
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Webhook-Event", delivery.Event)
+		req.Header.Set("X-Webhook-Delivery", delivery.ID)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Signature", Sign(webhook.Secret, timestamp, body))
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err == nil {
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+			resp.Body.Close()
+			delivery.ResponseCode = resp.StatusCode
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				delivery.Status = "delivered"
+				return delivery
+			}
+			err = fmt.Errorf("HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+		}
+	}
+	delivery.Error = err.Error()
+	if delivery.Attempts > len(retryDelays) {
+		delivery.Status = "failed"
+		return delivery
+	}
+	delivery.Status = "pending"
+	delivery.NextAttemptAt = now.Add(retryDelays[delivery.Attempts-1]).Format(time.RFC3339)
+	return delivery
+}
+
+// parseTime reads NextAttemptAt; a missing or broken value means "now".
+func parseTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Now()
+	}
+	return parsed
+}
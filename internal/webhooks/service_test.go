@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"project/internal/models"
+	"project/internal/storage"
+)
+
+// receivedRequest is what the test endpoint saw of one delivery.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// endpoint answers deliveries with the given status codes in turn (the last one repeats).
+type endpoint struct {
+	mutex    sync.Mutex
+	codes    []int
+	received []receivedRequest
+	arrived  chan struct{}
+}
+
+func newEndpoint(t *testing.T, codes ...int) (*endpoint, *httptest.Server) {
+	t.Helper()
+	e := &endpoint{codes: codes, arrived: make(chan struct{}, 16)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.mutex.Lock()
+		code := e.codes[min(len(e.received), len(e.codes)-1)]
+		e.received = append(e.received, receivedRequest{header: r.Header.Clone(), body: body})
+		e.mutex.Unlock()
+		w.WriteHeader(code)
+		e.arrived <- struct{}{}
+	}))
+	t.Cleanup(server.Close)
+	return e, server
+}
+
+func (e *endpoint) requests() []receivedRequest {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]receivedRequest(nil), e.received...)
+}
+
+// useTempStorage points the relative storage paths at an empty directory for the test.
+func useTempStorage(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if err := storage.LoadWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func useRetryDelays(t *testing.T, delays ...time.Duration) {
+	t.Helper()
+	previous := retryDelays
+	retryDelays = delays
+	t.Cleanup(func() { retryDelays = previous })
+}
+
+func createWebhook(t *testing.T, url string) models.Webhook {
+	t.Helper()
+	webhook, err := storage.CreateWebhook("test", url, []string{models.WebhookEventScheduleCreated}, "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func lastDelivery(t *testing.T, webhookID string) models.WebhookDelivery {
+	t.Helper()
+	log := storage.GetWebhookDeliveries(webhookID, 0)
+	if len(log) == 0 {
+		t.Fatal("delivery log is empty")
+	}
+	return log[0]
+}
+
+func TestDeliveryIsSigned(t *testing.T) {
+	useTempStorage(t)
+	e, server := newEndpoint(t, http.StatusNoContent)
+	webhook := createWebhook(t, server.URL)
+
+	delivery, err := SendTest(webhook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := e.requests()
+	if len(requests) != 1 {
+		t.Fatalf("endpoint got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(req.header.Get("X-Webhook-Timestamp") + "." + string(req.body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get("X-Webhook-Signature") != want {
+		t.Errorf("signature %q, want %q", req.header.Get("X-Webhook-Signature"), want)
+	}
+	if req.header.Get("X-Webhook-Event") != models.WebhookEventTest || req.header.Get("X-Webhook-Delivery") != delivery.ID {
+		t.Errorf("event/delivery headers %q/%q", req.header.Get("X-Webhook-Event"), req.header.Get("X-Webhook-Delivery"))
+	}
+	if string(req.body) != delivery.Payload {
+		t.Errorf("body %s differs from the logged payload %s", req.body, delivery.Payload)
+	}
+
+	logged := lastDelivery(t, webhook.ID)
+	if logged.ID != delivery.ID || logged.Status != "delivered" || logged.Attempts != 1 || logged.ResponseCode != http.StatusNoContent {
+		t.Errorf("logged delivery %+v", logged)
+	}
+}
+
+func TestServerErrorSchedulesRetry(t *testing.T) {
+	useTempStorage(t)
+	useRetryDelays(t, time.Hour)
+	_, server := newEndpoint(t, http.StatusInternalServerError)
+	webhook := createWebhook(t, server.URL)
+
+	delivery, err := storage.AddWebhookDelivery(models.WebhookDelivery{WebhookID: webhook.ID, Event: models.WebhookEventScheduleCreated, Payload: `{}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	attempt(delivery)
+
+	logged := lastDelivery(t, webhook.ID)
+	if logged.Status != "pending" || logged.Attempts != 1 || logged.ResponseCode != http.StatusInternalServerError || logged.Error == "" {
+		t.Fatalf("logged delivery %+v", logged)
+	}
+	next, err := time.Parse(time.RFC3339, logged.NextAttemptAt)
+	if err != nil {
+		t.Fatalf("next attempt %q: %v", logged.NextAttemptAt, err)
+	}
+	if next.Before(before.Add(time.Hour-time.Second)) || next.After(time.Now().Add(time.Hour)) {
+		t.Errorf("next attempt at %s, want about an hour from now", logged.NextAttemptAt)
+	}
+}
+
+func TestFailedDeliveryIsRetried(t *testing.T) {
+	useTempStorage(t)
+	useRetryDelays(t, 10*time.Millisecond, 10*time.Millisecond)
+	e, server := newEndpoint(t, http.StatusBadGateway, http.StatusOK)
+	webhook := createWebhook(t, server.URL)
+
+	Emit(models.WebhookEventScheduleCreated, map[string]string{"id": "entry-1"})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-e.arrived:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("endpoint got %d of 2 attempts", i)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	logged := lastDelivery(t, webhook.ID)
+	for logged.Status == "pending" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		logged = lastDelivery(t, webhook.ID)
+	}
+	if logged.Status != "delivered" || logged.Attempts != 2 || logged.ResponseCode != http.StatusOK || logged.NextAttemptAt != "" {
+		t.Errorf("logged delivery %+v", logged)
+	}
+	requests := e.requests()
+	if requests[0].header.Get("X-Webhook-Delivery") != requests[1].header.Get("X-Webhook-Delivery") || string(requests[0].body) != string(requests[1].body) {
+		t.Error("the retry is not the same delivery")
+	}
+}
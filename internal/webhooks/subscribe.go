@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"project/internal/events"
+	"project/internal/models"
+)
+
+// Subscribe forwards the domain events that have a webhook equivalent.
+func Subscribe() {
+	events.On(func(e events.EntryCreated) { Emit(models.WebhookEventScheduleCreated, e.Entry) })
+	events.On(func(e events.EntryUpdated) { Emit(models.WebhookEventScheduleUpdated, e.Entry) })
+	events.On(func(e events.EntryDeleted) { Emit(models.WebhookEventScheduleDeleted, e.Entry) })
+	events.On(func(e events.WorkerFired) { Emit(models.WebhookEventWorkerFired, e.Worker) })
+	events.On(func(e events.ObjectStatusChanged) {
+		Emit(models.WebhookEventObjectStatusChanged, map[string]interface{}{"object": e.Object, "previousStatus": e.PreviousStatus})
+	})
+}